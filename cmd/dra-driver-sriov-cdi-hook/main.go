@@ -0,0 +1,100 @@
+// dra-driver-sriov-cdi-hook is run by the container runtime as a CDI
+// createRuntime or poststop hook of the containers using SR-IOV claim devices.
+// It reads the OCI state of the container from stdin and asks the driver to
+// attach or detach the device network of the pod sandbox.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/urfave/cli/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cdihook"
+)
+
+func main() {
+	if err := newApp().Run(os.Args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func newApp() *cli.App {
+	var (
+		socketPath string
+		claimUID   string
+		deviceName string
+		podUID     string
+		timeout    time.Duration
+	)
+
+	return &cli.App{
+		Name:            "dra-driver-sriov-cdi-hook",
+		Usage:           "CDI hook attaching SR-IOV claim device networks on runtimes without NRI.",
+		ArgsUsage:       "add|del",
+		HideHelpCommand: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "socket",
+				Usage:       "Socket of the dra-driver-sriov CDI hook server.",
+				Required:    true,
+				Destination: &socketPath,
+			},
+			&cli.StringFlag{
+				Name:        "claim-uid",
+				Usage:       "UID of the ResourceClaim of the device.",
+				Required:    true,
+				Destination: &claimUID,
+			},
+			&cli.StringFlag{
+				Name:        "device",
+				Usage:       "Name of the claim device.",
+				Required:    true,
+				Destination: &deviceName,
+			},
+			&cli.StringFlag{
+				Name:        "pod-uid",
+				Usage:       "UID of the pod the claim is prepared for.",
+				Required:    true,
+				Destination: &podUID,
+			},
+			&cli.DurationFlag{
+				Name:        "timeout",
+				Usage:       "Timeout of the request to the driver.",
+				Value:       2 * time.Minute,
+				Destination: &timeout,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() != 1 {
+				return fmt.Errorf("expected one action argument, got %v", c.Args().Slice())
+			}
+			action := c.Args().First()
+			if action != cdihook.ActionAdd && action != cdihook.ActionDel {
+				return fmt.Errorf("unsupported action %q", action)
+			}
+
+			state := &specs.State{}
+			if err := json.NewDecoder(os.Stdin).Decode(state); err != nil {
+				return fmt.Errorf("failed to decode container state: %w", err)
+			}
+			spec, err := cdihook.ReadBundleSpec(state.Bundle)
+			if err != nil {
+				return err
+			}
+			req, err := cdihook.NewRequest(action, claimUID, deviceName, podUID, state, spec)
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithTimeout(c.Context, timeout)
+			defer cancel()
+			return cdihook.Send(ctx, socketPath, req)
+		},
+	}
+}
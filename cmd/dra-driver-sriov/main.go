@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/urfave/cli/v2"
 
@@ -15,16 +16,24 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cdi"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cdihook"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cni"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/controller"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/devicestate"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/driver"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/events"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/flags"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/introspection"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/metrics"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/nodestate"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/nodeview"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/nri"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/tracing"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 
 	sriovdrav1alpha1 "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/sriovdra/v1alpha1"
@@ -70,6 +79,12 @@ func newApp() *cli.App {
 			Destination: &flagsOptions.KubeletPluginsDirectoryPath,
 			EnvVars:     []string{"KUBELET_PLUGINS_DIRECTORY_PATH"},
 		},
+		&cli.StringFlag{
+			Name:        "pod-uid",
+			Usage:       "UID of the driver pod, set to run a new driver instance next to the old one during rolling updates (requires Kubernetes 1.33 or later). Empty disables rolling updates.",
+			Destination: &flagsOptions.PodUID,
+			EnvVars:     []string{"POD_UID"},
+		},
 		&cli.IntFlag{
 			Name:        "healthcheck-port",
 			Usage:       "Port to start a gRPC healthcheck service. When positive, a literal port number. When zero, a random port is allocated. When negative, the healthcheck service is disabled.",
@@ -77,6 +92,13 @@ func newApp() *cli.App {
 			Destination: &flagsOptions.HealthcheckPort,
 			EnvVars:     []string{"HEALTHCHECK_PORT"},
 		},
+		&cli.IntFlag{
+			Name:        "healthcheck-http-port",
+			Usage:       "Port to serve the liveness and readiness checks over HTTP on /livez and /readyz. When zero, a random port is allocated. When negative, the HTTP service is disabled.",
+			Value:       -1,
+			Destination: &flagsOptions.HealthcheckHTTPPort,
+			EnvVars:     []string{"HEALTHCHECK_HTTP_PORT"},
+		},
 		&cli.StringFlag{
 			Name:        "default-interface-prefix",
 			Usage:       "Default interface prefix to be used for the virtual functions.",
@@ -98,13 +120,68 @@ func newApp() *cli.App {
 			Destination: &flagsOptions.Namespace,
 			EnvVars:     []string{"NAMESPACE"},
 		},
+		&cli.StringFlag{
+			Name:        "cni-cache-dir",
+			Usage:       "Absolute path to the directory where CNI results are cached. It must persist across driver restarts so CNI DEL and CHECK receive the ADD result.",
+			Value:       "/var/lib/cni",
+			Destination: &flagsOptions.CNICacheDir,
+			EnvVars:     []string{"CNI_CACHE_DIR"},
+		},
+		&cli.DurationFlag{
+			Name:        "cni-check-interval",
+			Usage:       "Interval between CNI CHECK operations for running pods. Zero disables the periodic check.",
+			Value:       60 * time.Second,
+			Destination: &flagsOptions.CNICheckInterval,
+			EnvVars:     []string{"CNI_CHECK_INTERVAL"},
+		},
 		&cli.StringFlag{
 			Name:        "configuration-mode",
-			Usage:       "Configuration mode: STANDALONE or MULTUS.",
+			Usage:       "Configuration mode: STANDALONE, MULTUS, or HYBRID to select STANDALONE or MULTUS per claim.",
 			Value:       string(consts.ConfigurationModeStandalone),
 			Destination: &flagsOptions.ConfigurationMode,
 			EnvVars:     []string{"CONFIGURATION_MODE"},
 		},
+		&cli.StringFlag{
+			Name:        "network-attach-method",
+			Usage:       "How networks are attached in STANDALONE mode: nri, or cdi-hook for runtimes without NRI.",
+			Value:       string(consts.NetworkAttachMethodNRI),
+			Destination: &flagsOptions.NetworkAttachMethod,
+			EnvVars:     []string{"NETWORK_ATTACH_METHOD"},
+		},
+		&cli.StringFlag{
+			Name:        "cdi-hook-path",
+			Usage:       "Host path of the dra-driver-sriov-cdi-hook binary run by the container runtime with the cdi-hook network attach method.",
+			Value:       "/opt/dra-driver-sriov/bin/dra-driver-sriov-cdi-hook",
+			Destination: &flagsOptions.CDIHookPath,
+			EnvVars:     []string{"CDI_HOOK_PATH"},
+		},
+		&cli.StringFlag{
+			Name:        "metrics-bind-address",
+			Usage:       "The address the Prometheus /metrics endpoint binds to, \"0\" disables it.",
+			Value:       ":8080",
+			Destination: &flagsOptions.MetricsBindAddress,
+			EnvVars:     []string{"METRICS_BIND_ADDRESS"},
+		},
+		&cli.StringFlag{
+			Name:        "tracing-endpoint",
+			Usage:       "The host:port of the OTLP gRPC collector traces are exported to, empty disables tracing.",
+			Destination: &flagsOptions.TracingEndpoint,
+			EnvVars:     []string{"TRACING_ENDPOINT"},
+		},
+		&cli.BoolFlag{
+			Name:        "tracing-insecure",
+			Usage:       "Export traces to the OTLP collector without TLS.",
+			Value:       false,
+			Destination: &flagsOptions.TracingInsecure,
+			EnvVars:     []string{"TRACING_INSECURE"},
+		},
+		&cli.Float64Flag{
+			Name:        "tracing-sampling-ratio",
+			Usage:       "Ratio of the traces started by the driver that are sampled, between 0 and 1. Traces continued from the kubelet follow its sampling decision.",
+			Value:       1,
+			Destination: &flagsOptions.TracingSamplingRatio,
+			EnvVars:     []string{"TRACING_SAMPLING_RATIO"},
+		},
 	}
 	cliFlags = append(cliFlags, flagsOptions.KubeClientConfig.Flags()...)
 	cliFlags = append(cliFlags, flagsOptions.LoggingConfig.Flags()...)
@@ -146,6 +223,12 @@ func RunPlugin(ctx context.Context, config *types.Config) error {
 	logger := klog.FromContext(ctx)
 	ctrl.SetLogger(logger)
 
+	attachMethod := consts.NetworkAttachMethod(config.Flags.NetworkAttachMethod)
+	if attachMethod != consts.NetworkAttachMethodNRI && attachMethod != consts.NetworkAttachMethodCDIHook {
+		return fmt.Errorf("unsupported network attach method %q", config.Flags.NetworkAttachMethod)
+	}
+	standalone := consts.ConfigurationMode(config.Flags.ConfigurationMode) != consts.ConfigurationModeMultus
+
 	err := os.MkdirAll(config.DriverPluginPath(), 0750)
 	if err != nil {
 		return err
@@ -164,15 +247,44 @@ func RunPlugin(ctx context.Context, config *types.Config) error {
 		return fmt.Errorf("path for cdi file generation is not a directory: %q", config.Flags.CdiRoot)
 	}
 
+	err = os.MkdirAll(config.Flags.CNICacheDir, 0750)
+	if err != nil {
+		return fmt.Errorf("failed to create CNI cache directory %q: %w", config.Flags.CNICacheDir, err)
+	}
+
 	ctx, stop := signal.NotifyContext(ctx, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 	defer stop()
 	ctx, cancel := context.WithCancelCause(ctx)
 	config.CancelMainCtx = cancel
 
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+		Endpoint:      config.Flags.TracingEndpoint,
+		Insecure:      config.Flags.TracingInsecure,
+		SamplingRatio: config.Flags.TracingSamplingRatio,
+		NodeName:      config.Flags.NodeName,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error(err, "Failed to flush traces")
+		}
+	}()
+
+	recorder, stopRecorder := events.NewRecorder(ctx, config.K8sClient.Interface, config.Flags.NodeName)
+	defer stopRecorder()
+	config.EventRecorder = recorder
+
 	cdiHandler, err := cdi.NewHandler(config.Flags.CdiRoot)
 	if err != nil {
 		return fmt.Errorf("unable to create CDI handler: %v", err)
 	}
+	if standalone && attachMethod == consts.NetworkAttachMethodCDIHook {
+		cdiHandler.SetNetworkHook(config.Flags.CDIHookPath, config.CDIHookSocketPath())
+	}
 
 	// create device state manager
 	deviceStateManager, err := devicestate.NewManager(config, cdiHandler, devicestate.NewDeviceInfoStore())
@@ -185,6 +297,9 @@ func RunPlugin(ctx context.Context, config *types.Config) error {
 	if err != nil {
 		return err
 	}
+	if err := metrics.RegisterVFStatsCollector(podManager); err != nil {
+		return fmt.Errorf("failed to register VF statistics collector: %w", err)
+	}
 
 	// start driver
 	dvr, err := driver.Start(ctx, config, deviceStateManager, podManager, cdiHandler)
@@ -216,9 +331,10 @@ func RunPlugin(ctx context.Context, config *types.Config) error {
 	}
 
 	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
-		Scheme: flags.Scheme,
-		Logger: logger,
-		Cache:  cacheOpts,
+		Scheme:  flags.Scheme,
+		Logger:  logger,
+		Cache:   cacheOpts,
+		Metrics: metricsserver.Options{BindAddress: config.Flags.MetricsBindAddress},
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create controller manager: %w", err)
@@ -226,6 +342,7 @@ func RunPlugin(ctx context.Context, config *types.Config) error {
 
 	// create and setup resource policy controller
 	resourcePolicyController := controller.NewSriovResourcePolicyReconciler(config.K8sClient.Client, config.Flags.NodeName, config.Flags.Namespace, deviceStateManager)
+	resourcePolicyController.SetEventRecorder(config.EventRecorder)
 	if err := resourcePolicyController.SetupWithManager(mgr); err != nil {
 		return fmt.Errorf("failed to setup resource policy controller: %w", err)
 	}
@@ -247,14 +364,53 @@ func RunPlugin(ctx context.Context, config *types.Config) error {
 		return fmt.Errorf("cache not synced")
 	}
 	logger.Info("Cache synced")
+	dvr.AddReadinessCheck("controller-cache", func(ctx context.Context) error {
+		informer, err := mgr.GetCache().GetInformer(ctx, &sriovdrav1alpha1.SriovResourcePolicy{}, cache.BlockUntilSynced(false))
+		if err != nil {
+			return fmt.Errorf("failed to get SriovResourcePolicy informer: %w", err)
+		}
+		if !informer.HasSynced() {
+			return fmt.Errorf("SriovResourcePolicy cache is not synced")
+		}
+		return nil
+	})
+
+	// the introspection server and the SriovDraNodeState read the state of the
+	// node from the same sources
+	nodeSources := nodeview.Sources{
+		Devices:  deviceStateManager,
+		Policies: resourcePolicyController,
+		Claims:   podManager,
+		Publish:  dvr,
+	}
+	introspectionServer := introspection.NewServer(config.IntrospectionSocketPath(), nodeSources)
+	if err := introspectionServer.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start introspection server: %w", err)
+	}
+
+	// report the devices, policy results and allocations of the node in its
+	// SriovDraNodeState
+	nodestate.NewWriter(config.K8sClient.Client, config.Flags.NodeName, config.Flags.Namespace, nodeSources).Start(ctx)
 
 	// create cni runtime
-	cniRuntime := cni.New(consts.DriverName, []string{"/opt/cni/bin"})
+	cniRuntime := cni.New(consts.DriverName, []string{"/opt/cni/bin"}, config.Flags.CNICacheDir)
 
-	// register to NRI unless MULTUS mode is set
+	// register to NRI unless MULTUS mode is set, runtimes without NRI get
+	// the networks attached from CDI hooks served by the driver
 	var nriPlugin *nri.Plugin
-	if consts.ConfigurationMode(config.Flags.ConfigurationMode) != consts.ConfigurationModeMultus {
-		nriPlugin, err = nri.NewNRIPlugin(config, podManager, cniRuntime, dvr)
+	var hookServer *cdihook.Server
+	switch {
+	case standalone && attachMethod == consts.NetworkAttachMethodCDIHook:
+		nriPlugin = nri.NewCDIHookPlugin(config, podManager, cniRuntime, dvr, dvr)
+		nriPlugin.StartWorkers(ctx)
+		dvr.SetNetworkDetacher(nriPlugin)
+		hookServer = cdihook.NewServer(config.CDIHookSocketPath(), nriPlugin)
+		if err := hookServer.Start(ctx); err != nil {
+			return fmt.Errorf("failed to start CDI hook server: %w", err)
+		}
+		logger.Info("CDI hook network attachment started", "hookPath", config.Flags.CDIHookPath)
+	case standalone:
+		nriPlugin, err = nri.NewNRIPlugin(config, podManager, cniRuntime, dvr, dvr)
 		if err != nil {
 			return fmt.Errorf("failed to create NRI plugin: %w", err)
 		}
@@ -262,8 +418,9 @@ func RunPlugin(ctx context.Context, config *types.Config) error {
 		if err != nil {
 			return fmt.Errorf("failed to start NRI plugin: %w", err)
 		}
+		dvr.AddReadinessCheck("nri", nriPlugin.Connected)
 		logger.Info("NRI plugin started")
-	} else {
+	default:
 		logger.Info("NRI plugin disabled due to MULTUS configuration mode")
 	}
 
@@ -277,7 +434,11 @@ func RunPlugin(ctx context.Context, config *types.Config) error {
 		logger.Error(err, "error from context")
 	}
 	logger.V(1).Info("Shutting down")
-	if nriPlugin != nil {
+	introspectionServer.Stop()
+	if hookServer != nil {
+		hookServer.Stop()
+		nriPlugin.StopWorkers()
+	} else if nriPlugin != nil {
 		nriPlugin.Stop()
 	}
 	err = dvr.Shutdown(logger)
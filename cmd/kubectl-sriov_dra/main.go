@@ -0,0 +1,171 @@
+// kubectl-sriov_dra is the kubectl sriov-dra plugin. It lists the SR-IOV
+// devices the driver publishes with the SriovResourcePolicy and
+// DeviceAttributes selecting them, the claims they are allocated to with
+// their network data, and the inconsistencies found between them.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/urfave/cli/v2"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/flags"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/inventory"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// errIssuesFound makes check exit with an error when issues are found.
+var errIssuesFound = errors.New("issues found")
+
+type options struct {
+	kubeconfig  string
+	kubeContext string
+	namespace   string
+	nodeName    string
+	output      string
+}
+
+func main() {
+	if err := newApp().Run(os.Args); err != nil {
+		if !errors.Is(err, errIssuesFound) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func newApp() *cli.App {
+	opts := &options{}
+
+	return &cli.App{
+		Name:            "kubectl sriov-dra",
+		Usage:           "Inventory and troubleshooting of the SR-IOV DRA driver devices and claims.",
+		HideHelpCommand: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "kubeconfig",
+				Usage:       "Path to the kubeconfig file, the kubectl defaults apply when empty.",
+				Destination: &opts.kubeconfig,
+			},
+			&cli.StringFlag{
+				Name:        "context",
+				Usage:       "Name of the kubeconfig context to use.",
+				Destination: &opts.kubeContext,
+			},
+			&cli.StringFlag{
+				Name:        "namespace",
+				Aliases:     []string{"n"},
+				Usage:       "Namespace of the driver SriovResourcePolicy and DeviceAttributes objects.",
+				Value:       "dra-driver-sriov",
+				Destination: &opts.namespace,
+			},
+			&cli.StringFlag{
+				Name:        "node",
+				Usage:       "Only report the devices and claims of this node.",
+				Destination: &opts.nodeName,
+			},
+			&cli.StringFlag{
+				Name:        "output",
+				Aliases:     []string{"o"},
+				Usage:       "Output format, table or json.",
+				Value:       outputTable,
+				Destination: &opts.output,
+			},
+		},
+		Before: func(c *cli.Context) error {
+			if opts.output != outputTable && opts.output != outputJSON {
+				return fmt.Errorf("unsupported output format %q", opts.output)
+			}
+			return nil
+		},
+		Commands: []*cli.Command{
+			{
+				Name:  "devices",
+				Usage: "List the published devices per node and PF with the policy selecting them.",
+				Action: func(c *cli.Context) error {
+					report, err := collect(c.Context, opts)
+					if err != nil {
+						return err
+					}
+					return writeOutput(c.App.Writer, opts.output, report.Devices, func(w io.Writer) error {
+						return inventory.PrintDevices(w, report.Devices)
+					})
+				},
+			},
+			{
+				Name:  "claims",
+				Usage: "List the claims with their pods, VFs and network data.",
+				Action: func(c *cli.Context) error {
+					report, err := collect(c.Context, opts)
+					if err != nil {
+						return err
+					}
+					return writeOutput(c.App.Writer, opts.output, report.Claims, func(w io.Writer) error {
+						return inventory.PrintClaims(w, report.Claims)
+					})
+				},
+			},
+			{
+				Name:  "check",
+				Usage: "Report inconsistencies between the published devices, the claims and the pods.",
+				Action: func(c *cli.Context) error {
+					report, err := collect(c.Context, opts)
+					if err != nil {
+						return err
+					}
+					if len(report.Issues) == 0 && opts.output == outputTable {
+						fmt.Fprintln(c.App.Writer, "No issues found.")
+						return nil
+					}
+					if err := writeOutput(c.App.Writer, opts.output, report.Issues, func(w io.Writer) error {
+						return inventory.PrintIssues(w, report.Issues)
+					}); err != nil {
+						return err
+					}
+					if len(report.Issues) > 0 {
+						return errIssuesFound
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func collect(ctx context.Context, opts *options) (*inventory.Report, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = opts.kubeconfig
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		loadingRules, &clientcmd.ConfigOverrides{CurrentContext: opts.kubeContext}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	c, err := client.New(restConfig, client.Options{Scheme: flags.Scheme})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	return inventory.Collect(ctx, c, inventory.Options{
+		PolicyNamespace: opts.namespace,
+		NodeName:        opts.nodeName,
+	})
+}
+
+func writeOutput(w io.Writer, output string, v any, table func(io.Writer) error) error {
+	if output == outputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	return table(w)
+}
@@ -22,51 +22,186 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	resourceapi "k8s.io/api/resource/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
 )
 
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
 
 // SriovResourcePolicy defines a policy for advertising SR-IOV devices as Kubernetes resources.
 // Devices matching the policy's resource filters are advertised in the ResourceSlice.
 type SriovResourcePolicy struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
-	Spec              SriovResourcePolicySpec `json:"spec"`
+	Spec              SriovResourcePolicySpec   `json:"spec"`
+	Status            SriovResourcePolicyStatus `json:"status,omitempty"`
 }
 
 // SriovResourcePolicySpec is the spec for a SriovResourcePolicy
 type SriovResourcePolicySpec struct {
 	NodeSelector *corev1.NodeSelector `json:"nodeSelector,omitempty"`
-	Configs      []Config             `json:"configs,omitempty"`
+	// Priority orders the policies selecting the same device: the device is
+	// advertised through the policy with the highest priority. Policies of
+	// equal priority are ordered by name, the configs of a policy by position.
+	Priority int32 `json:"priority,omitempty"`
+	// +kubebuilder:validation:Enum=Exclusive;Merge
+	// OverlapMode tells what happens to the devices this policy selects that a
+	// config taking precedence already selected. Exclusive, the default, leaves
+	// them to that config. Merge adds the attributes of this policy the
+	// configs taking precedence do not set.
+	OverlapMode OverlapMode `json:"overlapMode,omitempty"`
+	Configs     []Config    `json:"configs,omitempty"`
 }
 
-// Config pairs a device selection (ResourceFilters) with an optional set of
-// extra attributes to apply (DeviceAttributesSelector). Devices matching the
-// filters are advertised regardless of whether a DeviceAttributesSelector is set.
+// OverlapMode is how a policy applies to devices selected by a config taking precedence
+type OverlapMode string
+
+const (
+	// OverlapModeExclusive leaves the devices to the config taking precedence.
+	OverlapModeExclusive OverlapMode = "Exclusive"
+	// OverlapModeMerge adds the attributes not set by the configs taking
+	// precedence.
+	OverlapModeMerge OverlapMode = "Merge"
+)
+
+// Config pairs a device selection (ResourceFilters and Selectors) with an
+// optional set of extra attributes to apply (DeviceAttributesSelector). Devices
+// matching the filters and all the selectors are advertised regardless of
+// whether a DeviceAttributesSelector is set.
 type Config struct {
 	// DeviceAttributesSelector selects DeviceAttributes objects by label.
 	// Attributes from all matching DeviceAttributes are merged and applied
 	// to devices selected by ResourceFilters. Optional.
 	DeviceAttributesSelector *metav1.LabelSelector `json:"deviceAttributesSelector,omitempty"`
 	ResourceFilters          []ResourceFilter      `json:"resourceFilters,omitempty"`
+	// Selectors are evaluated against the attributes discovered on each
+	// device, a device must match all of them. Optional.
+	// +listType=atomic
+	// +kubebuilder:validation:MaxItems=32
+	Selectors []DeviceSelector `json:"selectors,omitempty"`
+}
+
+// DeviceSelector selects devices, like the selectors of a DRA DeviceClass.
+type DeviceSelector struct {
+	// CEL selects devices with a CEL expression.
+	// +required
+	CEL *CELDeviceSelector `json:"cel"`
+}
+
+// CELDeviceSelector selects devices with a CEL expression evaluated in the
+// environment of DRA DeviceClass selectors: the attributes of the device are
+// available as device.attributes["<domain>"].<name>, e.g.
+// device.attributes["sriovnetwork.k8snetworkplumbingwg.io"].vendor == "8086".
+type CELDeviceSelector struct {
+	// Expression must evaluate to a bool. Compilation errors and expressions
+	// exceeding the cost limit are reported in the Valid condition of the
+	// policy, the config then selects no devices.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=10240
+	Expression string `json:"expression"`
 }
 
 // ResourceFilter is a filter for a resource
 type ResourceFilter struct {
-	Vendors        []string `json:"vendors,omitempty"`
-	Devices        []string `json:"devices,omitempty"`
-	PciAddresses   []string `json:"pciAddresses,omitempty"`
+	Vendors      []string `json:"vendors,omitempty"`
+	Devices      []string `json:"devices,omitempty"`
+	PciAddresses []string `json:"pciAddresses,omitempty"`
+	// +kubebuilder:validation:items:Pattern=`^[^#]+(#[0-9]+(-[0-9]+)?(,[0-9]+(-[0-9]+)?)*)?$`
+	// PF names, optionally restricted to VF index ranges of the PF, e.g.
+	// "eth0" or "eth0#0-7,12".
 	PfNames        []string `json:"pfNames,omitempty"`
 	PfPciAddresses []string `json:"pfPciAddresses,omitempty"`
 	Drivers        []string `json:"drivers,omitempty"`
 	// +kubebuilder:validation:Enum=eth;ib;ethernet;infiniband
 	// NIC Link Type. Accepted values: "eth", "ib", "ethernet", "infiniband".
 	LinkType string `json:"linkType,omitempty"`
+	// +kubebuilder:validation:items:Pattern=`^[0-9]+(-[0-9]+)?(,[0-9]+(-[0-9]+)?)*$`
+	// VF indices or index ranges on their PF, e.g. "0-7" or "8,10-15".
+	VfIndices []string `json:"vfIndices,omitempty"`
+	// NUMA nodes of the PF, -1 selects devices without NUMA information.
+	NumaNodes []int64 `json:"numaNodes,omitempty"`
+	// RdmaCapable selects RDMA capable VFs when true and the other VFs when false.
+	RdmaCapable *bool `json:"rdmaCapable,omitempty"`
+	// +kubebuilder:validation:Enum=legacy;switchdev
+	// E-Switch mode of the PF.
+	EswitchMode string `json:"eswitchMode,omitempty"`
+}
+
+// SriovResourcePolicyStatus is the status of a SriovResourcePolicy
+type SriovResourcePolicyStatus struct {
+	// Nodes are the results of the policy on each node it applies to. Each
+	// entry is written by the driver of its node and lists the first devices
+	// of each result, all the devices of a node are listed in its
+	// SriovDraNodeState.
+	// +listType=map
+	// +listMapKey=nodeName
+	Nodes []PolicyNodeStatus `json:"nodes,omitempty"`
+	// Conditions report whether the policy is valid.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// PolicyNodeStatus is the result of a SriovResourcePolicy on a node
+type PolicyNodeStatus struct {
+	NodeName string `json:"nodeName"`
+	// MatchedDevices is the number of devices advertised through the policy.
+	MatchedDevices int32 `json:"matchedDevices"`
+	// ShadowedDevices is the number of devices the policy selects that keep
+	// the attributes of a config taking precedence, which may belong to the
+	// same policy.
+	// +optional
+	ShadowedDevices int32 `json:"shadowedDevices,omitempty"`
+	// MergedDevices is the number of devices advertised through another
+	// policy the policy adds attributes to.
+	// +optional
+	MergedDevices int32 `json:"mergedDevices,omitempty"`
+	// Matched are the first MaxPolicyStatusDevices devices advertised
+	// through the policy, by name.
+	// +kubebuilder:validation:MaxItems=16
+	// +optional
+	Matched []string `json:"matched,omitempty"`
+	// Shadowed are the first MaxPolicyStatusDevices shadowed devices, by
+	// name, with the policy they keep the attributes of.
+	// +kubebuilder:validation:MaxItems=16
+	// +optional
+	Shadowed []OverlappingDevice `json:"shadowed,omitempty"`
+	// Merged are the first MaxPolicyStatusDevices merged devices, by name,
+	// with the policy advertising them.
+	// +kubebuilder:validation:MaxItems=16
+	// +optional
+	Merged []OverlappingDevice `json:"merged,omitempty"`
+	// Truncated is true when a device list holds fewer devices than its
+	// count.
+	// +optional
+	Truncated bool `json:"truncated,omitempty"`
+	// Error is the error applying the policies on the node, or evaluating the
+	// selectors of the policy on its devices.
+	Error string `json:"error,omitempty"`
 }
 
+// MaxPolicyStatusDevices is the number of devices listed per node and kind
+// of result in the status of a SriovResourcePolicy.
+const MaxPolicyStatusDevices = 16
+
+// OverlappingDevice is a device selected by several policies
+type OverlappingDevice struct {
+	Name string `json:"name"`
+	// Policy is the SriovResourcePolicy the device is advertised through.
+	Policy string `json:"policy"`
+}
+
+// Condition types of a SriovResourcePolicy.
+const (
+	// PolicyValid is False when the selectors of the policy cannot be parsed.
+	// It only depends on the spec of the policy, so the drivers of all nodes
+	// agree on it.
+	PolicyValid = "Valid"
+)
+
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
@@ -106,6 +241,110 @@ type DeviceAttributesList struct {
 	Items           []DeviceAttributes `json:"items"`
 }
 
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
+
+// SriovDraNodeState reports the SR-IOV devices discovered on a node, which
+// of them are advertised and the allocations prepared on it. It is named
+// after the node and written by the driver running there.
+type SriovDraNodeState struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Status            SriovDraNodeStateStatus `json:"status,omitempty"`
+}
+
+// SriovDraNodeStateStatus is the state of the SR-IOV devices of a node
+type SriovDraNodeStateStatus struct {
+	// PhysicalFunctions are the SR-IOV physical functions of the node with
+	// their virtual functions.
+	PhysicalFunctions []PhysicalFunctionStatus `json:"physicalFunctions,omitempty"`
+	// ExcludedDevices are the devices that are not advertised, with the reason.
+	ExcludedDevices []ExcludedDevice `json:"excludedDevices,omitempty"`
+	// Allocations are the devices prepared for pods on the node.
+	Allocations []PreparedAllocation `json:"allocations,omitempty"`
+	// Conditions report the health of the device discovery and of the
+	// ResourceSlice publication.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// PhysicalFunctionStatus describes a discovered SR-IOV physical function
+type PhysicalFunctionStatus struct {
+	// Name is the network interface name of the PF.
+	Name        string `json:"name"`
+	PciAddress  string `json:"pciAddress"`
+	VendorID    string `json:"vendorID,omitempty"`
+	DeviceID    string `json:"deviceID,omitempty"`
+	EswitchMode string `json:"eswitchMode,omitempty"`
+	LinkType    string `json:"linkType,omitempty"`
+	NumaNode    string `json:"numaNode,omitempty"`
+	// VirtualFunctions are the VFs of the PF.
+	VirtualFunctions []VirtualFunctionStatus `json:"virtualFunctions,omitempty"`
+}
+
+// VirtualFunctionStatus describes a discovered SR-IOV virtual function
+type VirtualFunctionStatus struct {
+	// Name is the device name used in the ResourceSlice.
+	Name       string `json:"name"`
+	PciAddress string `json:"pciAddress"`
+	// Attributes are the discovered attributes of the VF.
+	Attributes map[resourceapi.QualifiedName]resourceapi.DeviceAttribute `json:"attributes,omitempty"`
+	// Advertised is true when the VF is published in the ResourceSlice.
+	Advertised bool `json:"advertised"`
+	// Policy is the SriovResourcePolicy that selected the VF, if any.
+	Policy string `json:"policy,omitempty"`
+}
+
+// ExcludedDevice is a device that is not advertised
+type ExcludedDevice struct {
+	// Name is the device name, empty for devices that are not VFs.
+	Name       string `json:"name,omitempty"`
+	PciAddress string `json:"pciAddress"`
+	// Reason is a CamelCase reason for the exclusion.
+	Reason  string `json:"reason"`
+	Message string `json:"message,omitempty"`
+}
+
+// PreparedAllocation is a device prepared for a ResourceClaim of a pod
+type PreparedAllocation struct {
+	Device         string    `json:"device"`
+	PciAddress     string    `json:"pciAddress,omitempty"`
+	ClaimNamespace string    `json:"claimNamespace"`
+	ClaimName      string    `json:"claimName"`
+	ClaimUID       types.UID `json:"claimUID"`
+	PodUID         types.UID `json:"podUID,omitempty"`
+	PodName        string    `json:"podName,omitempty"`
+	// Phase is the lifecycle phase of the device, e.g. Prepared or Attached.
+	Phase string `json:"phase,omitempty"`
+}
+
+// Condition types and reasons of a SriovDraNodeState.
+const (
+	// NodeStateDevicesDiscovered is True when SR-IOV devices were discovered.
+	NodeStateDevicesDiscovered = "DevicesDiscovered"
+	// NodeStateResourcesPublished is True when the last ResourceSlice
+	// publication succeeded.
+	NodeStateResourcesPublished = "ResourcesPublished"
+
+	// ExclusionReasonNoNetworkInterface excludes a PF without a network
+	// interface, and with it all of its VFs.
+	ExclusionReasonNoNetworkInterface = "NoNetworkInterface"
+	// ExclusionReasonNotSelected excludes a VF no SriovResourcePolicy selects.
+	ExclusionReasonNotSelected = "NotSelectedByPolicy"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// SriovDraNodeStateList contains a list of SriovDraNodeState
+type SriovDraNodeStateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SriovDraNodeState `json:"items"`
+}
+
 // NormalizeLinkType normalizes a link type string to a canonical form.
 // "eth" and "ethernet" (case-insensitive) map to consts.LinkTypeEthernet,
 // "ib" and "infiniband" (case-insensitive) map to consts.LinkTypeInfiniband.
@@ -32,6 +32,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&SriovResourcePolicyList{},
 		&DeviceAttributes{},
 		&DeviceAttributesList{},
+		&SriovDraNodeState{},
+		&SriovDraNodeStateList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
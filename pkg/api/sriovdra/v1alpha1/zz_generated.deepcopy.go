@@ -27,6 +27,21 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CELDeviceSelector) DeepCopyInto(out *CELDeviceSelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CELDeviceSelector.
+func (in *CELDeviceSelector) DeepCopy() *CELDeviceSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(CELDeviceSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Config) DeepCopyInto(out *Config) {
 	*out = *in
@@ -42,6 +57,13 @@ func (in *Config) DeepCopyInto(out *Config) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Selectors != nil {
+		in, out := &in.Selectors, &out.Selectors
+		*out = make([]DeviceSelector, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Config.
@@ -134,6 +156,123 @@ func (in *DeviceAttributesSpec) DeepCopy() *DeviceAttributesSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceSelector) DeepCopyInto(out *DeviceSelector) {
+	*out = *in
+	if in.CEL != nil {
+		in, out := &in.CEL, &out.CEL
+		*out = new(CELDeviceSelector)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceSelector.
+func (in *DeviceSelector) DeepCopy() *DeviceSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExcludedDevice) DeepCopyInto(out *ExcludedDevice) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExcludedDevice.
+func (in *ExcludedDevice) DeepCopy() *ExcludedDevice {
+	if in == nil {
+		return nil
+	}
+	out := new(ExcludedDevice)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OverlappingDevice) DeepCopyInto(out *OverlappingDevice) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OverlappingDevice.
+func (in *OverlappingDevice) DeepCopy() *OverlappingDevice {
+	if in == nil {
+		return nil
+	}
+	out := new(OverlappingDevice)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PhysicalFunctionStatus) DeepCopyInto(out *PhysicalFunctionStatus) {
+	*out = *in
+	if in.VirtualFunctions != nil {
+		in, out := &in.VirtualFunctions, &out.VirtualFunctions
+		*out = make([]VirtualFunctionStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PhysicalFunctionStatus.
+func (in *PhysicalFunctionStatus) DeepCopy() *PhysicalFunctionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PhysicalFunctionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyNodeStatus) DeepCopyInto(out *PolicyNodeStatus) {
+	*out = *in
+	if in.Matched != nil {
+		in, out := &in.Matched, &out.Matched
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Shadowed != nil {
+		in, out := &in.Shadowed, &out.Shadowed
+		*out = make([]OverlappingDevice, len(*in))
+		copy(*out, *in)
+	}
+	if in.Merged != nil {
+		in, out := &in.Merged, &out.Merged
+		*out = make([]OverlappingDevice, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyNodeStatus.
+func (in *PolicyNodeStatus) DeepCopy() *PolicyNodeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyNodeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreparedAllocation) DeepCopyInto(out *PreparedAllocation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreparedAllocation.
+func (in *PreparedAllocation) DeepCopy() *PreparedAllocation {
+	if in == nil {
+		return nil
+	}
+	out := new(PreparedAllocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceFilter) DeepCopyInto(out *ResourceFilter) {
 	*out = *in
@@ -167,6 +306,21 @@ func (in *ResourceFilter) DeepCopyInto(out *ResourceFilter) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.VfIndices != nil {
+		in, out := &in.VfIndices, &out.VfIndices
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NumaNodes != nil {
+		in, out := &in.NumaNodes, &out.NumaNodes
+		*out = make([]int64, len(*in))
+		copy(*out, *in)
+	}
+	if in.RdmaCapable != nil {
+		in, out := &in.RdmaCapable, &out.RdmaCapable
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceFilter.
@@ -179,12 +333,110 @@ func (in *ResourceFilter) DeepCopy() *ResourceFilter {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SriovDraNodeState) DeepCopyInto(out *SriovDraNodeState) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SriovDraNodeState.
+func (in *SriovDraNodeState) DeepCopy() *SriovDraNodeState {
+	if in == nil {
+		return nil
+	}
+	out := new(SriovDraNodeState)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SriovDraNodeState) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SriovDraNodeStateList) DeepCopyInto(out *SriovDraNodeStateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SriovDraNodeState, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SriovDraNodeStateList.
+func (in *SriovDraNodeStateList) DeepCopy() *SriovDraNodeStateList {
+	if in == nil {
+		return nil
+	}
+	out := new(SriovDraNodeStateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SriovDraNodeStateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SriovDraNodeStateStatus) DeepCopyInto(out *SriovDraNodeStateStatus) {
+	*out = *in
+	if in.PhysicalFunctions != nil {
+		in, out := &in.PhysicalFunctions, &out.PhysicalFunctions
+		*out = make([]PhysicalFunctionStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExcludedDevices != nil {
+		in, out := &in.ExcludedDevices, &out.ExcludedDevices
+		*out = make([]ExcludedDevice, len(*in))
+		copy(*out, *in)
+	}
+	if in.Allocations != nil {
+		in, out := &in.Allocations, &out.Allocations
+		*out = make([]PreparedAllocation, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SriovDraNodeStateStatus.
+func (in *SriovDraNodeStateStatus) DeepCopy() *SriovDraNodeStateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SriovDraNodeStateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SriovResourcePolicy) DeepCopyInto(out *SriovResourcePolicy) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SriovResourcePolicy.
@@ -263,3 +515,54 @@ func (in *SriovResourcePolicySpec) DeepCopy() *SriovResourcePolicySpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SriovResourcePolicyStatus) DeepCopyInto(out *SriovResourcePolicyStatus) {
+	*out = *in
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]PolicyNodeStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SriovResourcePolicyStatus.
+func (in *SriovResourcePolicyStatus) DeepCopy() *SriovResourcePolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SriovResourcePolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualFunctionStatus) DeepCopyInto(out *VirtualFunctionStatus) {
+	*out = *in
+	if in.Attributes != nil {
+		in, out := &in.Attributes, &out.Attributes
+		*out = make(map[resourcev1.QualifiedName]resourcev1.DeviceAttribute, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualFunctionStatus.
+func (in *VirtualFunctionStatus) DeepCopy() *VirtualFunctionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualFunctionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
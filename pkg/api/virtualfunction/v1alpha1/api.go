@@ -30,6 +30,13 @@ const (
 	Version   = "v1alpha1"
 
 	VfConfigKind = "VfConfig"
+
+	// AttachModeCNI attaches the VF to the pod by running the CNI plugins of
+	// the NetworkAttachmentDefinition or inline CNI config.
+	AttachModeCNI = "cni"
+	// AttachModeNetlink attaches the VF netdev to the pod with netlink only,
+	// without invoking any CNI plugin.
+	AttachModeNetlink = "netlink"
 )
 
 // Decoder implements a decoder for objects in this API group.
@@ -46,6 +53,64 @@ type VfConfig struct {
 	IfName                string `json:"ifName,omitempty"`
 	NetAttachDefName      string `json:"netAttachDefName,omitempty"`
 	NetAttachDefNamespace string `json:"netAttachDefNamespace,omitempty"`
+	// CNIConfig is an inline CNI network configuration (single plugin or
+	// conflist). When set it is used instead of a NetworkAttachmentDefinition.
+	CNIConfig *runtime.RawExtension `json:"cniConfig,omitempty"`
+	// AttachMode selects how the VF is attached to the pod in STANDALONE
+	// mode, either "cni" (default) or "netlink". No NetworkAttachmentDefinition
+	// or CNI config is needed in netlink attach mode.
+	AttachMode string `json:"attachMode,omitempty"`
+	// MTU, MAC, Addresses and Routes configure the VF netdev in netlink
+	// attach mode. Addresses are in CIDR notation.
+	MTU       int      `json:"mtu,omitempty"`
+	MAC       string   `json:"mac,omitempty"`
+	Addresses []string `json:"addresses,omitempty"`
+	Routes    []Route  `json:"routes,omitempty"`
+	// RuntimeConfig holds the CNI capability arguments passed to the plugins
+	// declaring the matching capabilities in cni attach mode.
+	RuntimeConfig *RuntimeConfig `json:"runtimeConfig,omitempty"`
+	// ConfigurationMode selects whether the driver attaches the VF
+	// ("STANDALONE") or Multus does with the device-info written by the
+	// driver ("MULTUS"). Only honored on nodes running in HYBRID mode, the
+	// node mode applies otherwise.
+	ConfigurationMode string `json:"configurationMode,omitempty"`
+}
+
+// Route is a static route added to the pod network namespace in netlink
+// attach mode. Destination is in CIDR notation, Gateway is optional.
+type Route struct {
+	Destination string `json:"dst"`
+	Gateway     string `json:"gw,omitempty"`
+}
+
+// RuntimeConfig holds the CNI capability arguments of a VF, following the
+// runtimeConfig of Multus network selection elements.
+type RuntimeConfig struct {
+	// IPs are requested addresses in CIDR notation ("ips" capability).
+	IPs []string `json:"ips,omitempty"`
+	// MAC is the requested hardware address ("mac" capability).
+	MAC string `json:"mac,omitempty"`
+	// Bandwidth limits the VF traffic ("bandwidth" capability).
+	Bandwidth *BandwidthEntry `json:"bandwidth,omitempty"`
+	// PortMappings forward host ports to the pod ("portMappings" capability).
+	PortMappings []PortMapEntry `json:"portMappings,omitempty"`
+}
+
+// BandwidthEntry is the bandwidth capability argument, rates are in bits per
+// second and bursts in bits.
+type BandwidthEntry struct {
+	IngressRate  int `json:"ingressRate"`
+	IngressBurst int `json:"ingressBurst"`
+	EgressRate   int `json:"egressRate"`
+	EgressBurst  int `json:"egressBurst"`
+}
+
+// PortMapEntry is a port mapping capability argument.
+type PortMapEntry struct {
+	HostPort      int    `json:"hostPort"`
+	ContainerPort int    `json:"containerPort"`
+	Protocol      string `json:"protocol,omitempty"`
+	HostIP        string `json:"hostIP,omitempty"`
 }
 
 // DefaultGpuConfig provides the default GPU configuration.
@@ -69,9 +134,74 @@ func (c *VfConfig) Override(other *VfConfig) {
 	if other.IfName != "" {
 		c.IfName = other.IfName
 	}
-	if other.NetAttachDefName != "" {
+	// the net attach def name and the inline CNI config both set the
+	// network, a config setting one of them replaces the other
+	if other.NetAttachDefName != "" || other.HasCNIConfig() {
 		c.NetAttachDefName = other.NetAttachDefName
+		c.CNIConfig = other.CNIConfig.DeepCopy()
+	}
+	if other.AttachMode != "" {
+		c.AttachMode = other.AttachMode
+	}
+	if other.MTU != 0 {
+		c.MTU = other.MTU
+	}
+	if other.MAC != "" {
+		c.MAC = other.MAC
+	}
+	if len(other.Addresses) > 0 {
+		c.Addresses = append([]string(nil), other.Addresses...)
+	}
+	if len(other.Routes) > 0 {
+		c.Routes = append([]Route(nil), other.Routes...)
+	}
+	if other.RuntimeConfig != nil {
+		c.RuntimeConfig = other.RuntimeConfig.DeepCopy()
+	}
+	if other.ConfigurationMode != "" {
+		c.ConfigurationMode = other.ConfigurationMode
+	}
+}
+
+// IsNetlinkAttachMode reports whether the VF is attached with netlink instead
+// of CNI plugins.
+func (c *VfConfig) IsNetlinkAttachMode() bool {
+	return c.AttachMode == AttachModeNetlink
+}
+
+// IsMultusConfigurationMode reports whether Multus attaches the VF.
+func (c *VfConfig) IsMultusConfigurationMode() bool {
+	return consts.ConfigurationMode(c.ConfigurationMode) == consts.ConfigurationModeMultus
+}
+
+// HasCNIConfig reports whether an inline CNI config is set.
+func (c *VfConfig) HasCNIConfig() bool {
+	return c.CNIConfig != nil && len(c.CNIConfig.Raw) > 0
+}
+
+// CapabilityArgs returns the CNI capability arguments of the runtime config,
+// keyed by capability name. It returns nil when no runtime config is set.
+func (c *VfConfig) CapabilityArgs() map[string]interface{} {
+	if c.RuntimeConfig == nil {
+		return nil
+	}
+	args := map[string]interface{}{}
+	if len(c.RuntimeConfig.IPs) > 0 {
+		args["ips"] = c.RuntimeConfig.IPs
+	}
+	if c.RuntimeConfig.MAC != "" {
+		args["mac"] = c.RuntimeConfig.MAC
+	}
+	if c.RuntimeConfig.Bandwidth != nil {
+		args["bandwidth"] = c.RuntimeConfig.Bandwidth
+	}
+	if len(c.RuntimeConfig.PortMappings) > 0 {
+		args["portMappings"] = c.RuntimeConfig.PortMappings
+	}
+	if len(args) == 0 {
+		return nil
 	}
+	return args
 }
 
 // Normalize updates a VfConfig config with implied default values.
@@ -20,6 +20,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
 )
@@ -81,48 +82,250 @@ var _ = Describe("VfConfig", func() {
 				err := config.Validate()
 				Expect(err).NotTo(HaveOccurred())
 			})
-		})
 
-		Context("Error Cases", func() {
-			It("should return error when Driver is empty", func() {
+			It("should validate config when Driver is empty, keeping the kernel driver", func() {
 				config := &VfConfig{
 					Driver:           "",
 					NetAttachDefName: "test-network",
 				}
-				err := config.Validate()
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(Equal("no driver set"))
+				Expect(config.Validate()).To(Succeed())
 			})
 
-			It("should return error when NetAttachDefName is empty", func() {
+			It("should validate config when NetAttachDefName is empty, leaving the network to the configuration mode of the node", func() {
 				config := &VfConfig{
 					Driver:           "vfio-pci",
 					NetAttachDefName: "",
 				}
-				err := config.Validate()
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(Equal("no net attach def name set"))
+				Expect(config.Validate()).To(Succeed())
 			})
 
-			It("should return error when both Driver and NetAttachDefName are empty", func() {
+			It("should validate config when both Driver and NetAttachDefName are empty", func() {
 				config := &VfConfig{
 					Driver:           "",
 					NetAttachDefName: "",
 				}
-				err := config.Validate()
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(Equal("no driver set"))
+				Expect(config.Validate()).To(Succeed())
+			})
+
+			It("should validate default config without modifications", func() {
+				Expect(DefaultVfConfig().Validate()).To(Succeed())
 			})
+		})
 
-			It("should return error for default config without modifications", func() {
-				config := DefaultVfConfig()
+		Context("Error Cases", func() {
+			It("should return error when both NetAttachDefName and CNIConfig are set", func() {
+				config := &VfConfig{
+					Driver:           "vfio-pci",
+					NetAttachDefName: "test-network",
+					CNIConfig:        &runtime.RawExtension{Raw: []byte(`{"cniVersion":"1.0.0","type":"sriov"}`)},
+				}
 				err := config.Validate()
 				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("mutually exclusive"))
+			})
+
+			It("should return error for an invalid inline CNI config", func() {
+				config := &VfConfig{CNIConfig: &runtime.RawExtension{Raw: []byte(`{"type":`)}}
+				Expect(config.Validate()).To(MatchError(ContainSubstring("invalid cni config")))
 			})
 		})
 	})
 
+	Describe("ValidateCNIConfig", func() {
+		It("should accept an empty inline config", func() {
+			config := &VfConfig{}
+			Expect(config.ValidateCNIConfig()).To(Succeed())
+		})
+
+		It("should accept a single plugin config", func() {
+			config := &VfConfig{
+				CNIConfig: &runtime.RawExtension{Raw: []byte(`{"cniVersion":"1.0.0","name":"net","type":"sriov","vlan":100}`)},
+			}
+			Expect(config.ValidateCNIConfig()).To(Succeed())
+		})
+
+		It("should accept a conflist without a name", func() {
+			config := &VfConfig{
+				CNIConfig: &runtime.RawExtension{Raw: []byte(`{"cniVersion":"1.0.0","plugins":[{"type":"sriov"},{"type":"tuning"}]}`)},
+			}
+			Expect(config.ValidateCNIConfig()).To(Succeed())
+		})
+
+		It("should reject a plugin config without a type", func() {
+			config := &VfConfig{
+				CNIConfig: &runtime.RawExtension{Raw: []byte(`{"cniVersion":"1.0.0","name":"net"}`)},
+			}
+			err := config.ValidateCNIConfig()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("missing 'type'"))
+		})
+
+		It("should reject a conflist without plugins", func() {
+			config := &VfConfig{
+				CNIConfig: &runtime.RawExtension{Raw: []byte(`{"cniVersion":"1.0.0","name":"net","plugins":[]}`)},
+			}
+			Expect(config.ValidateCNIConfig()).NotTo(Succeed())
+		})
+
+		It("should reject a config that is not a JSON object", func() {
+			config := &VfConfig{
+				CNIConfig: &runtime.RawExtension{Raw: []byte(`["sriov"]`)},
+			}
+			Expect(config.ValidateCNIConfig()).NotTo(Succeed())
+		})
+	})
+
+	Describe("ValidateAttachConfig", func() {
+		It("should accept a netlink config without net attach def", func() {
+			config := &VfConfig{
+				Driver:     "iavf",
+				AttachMode: AttachModeNetlink,
+				MTU:        9000,
+				MAC:        "02:00:00:00:00:01",
+				Addresses:  []string{"192.168.1.10/24", "fd00::10/64"},
+				Routes:     []Route{{Destination: "10.0.0.0/8", Gateway: "192.168.1.1"}, {Destination: "0.0.0.0/0"}},
+			}
+			Expect(config.Validate()).To(Succeed())
+		})
+
+		It("should reject an unknown attach mode", func() {
+			config := &VfConfig{AttachMode: "macvlan"}
+			Expect(config.ValidateAttachConfig()).To(MatchError(ContainSubstring("unsupported attach mode")))
+		})
+
+		It("should reject netlink settings in cni attach mode", func() {
+			config := &VfConfig{Driver: "iavf", NetAttachDefName: "net", MTU: 1500}
+			Expect(config.Validate()).To(MatchError(ContainSubstring("only supported in \"netlink\" attach mode")))
+		})
+
+		It("should reject an invalid mac", func() {
+			config := &VfConfig{AttachMode: AttachModeNetlink, MAC: "not-a-mac"}
+			Expect(config.ValidateAttachConfig()).To(MatchError(ContainSubstring("invalid mac")))
+		})
+
+		It("should reject an address without prefix length", func() {
+			config := &VfConfig{AttachMode: AttachModeNetlink, Addresses: []string{"192.168.1.10"}}
+			Expect(config.ValidateAttachConfig()).To(MatchError(ContainSubstring("invalid address")))
+		})
+
+		It("should reject an invalid route gateway", func() {
+			config := &VfConfig{AttachMode: AttachModeNetlink, Routes: []Route{{Destination: "10.0.0.0/8", Gateway: "gw"}}}
+			Expect(config.ValidateAttachConfig()).To(MatchError(ContainSubstring("invalid route gateway")))
+		})
+	})
+
+	Describe("ValidateConfigurationMode", func() {
+		It("should accept a MULTUS claim", func() {
+			config := &VfConfig{Driver: "iavf", NetAttachDefName: "net", ConfigurationMode: "MULTUS"}
+			Expect(config.Validate()).To(Succeed())
+			Expect(config.IsMultusConfigurationMode()).To(BeTrue())
+		})
+
+		It("should reject an unknown configuration mode", func() {
+			config := &VfConfig{ConfigurationMode: "HYBRID"}
+			Expect(config.ValidateConfigurationMode()).To(MatchError(ContainSubstring("unsupported configuration mode")))
+		})
+
+		It("should reject driver attach settings in MULTUS configuration mode", func() {
+			config := &VfConfig{ConfigurationMode: "MULTUS", AttachMode: AttachModeNetlink}
+			Expect(config.ValidateConfigurationMode()).To(MatchError(ContainSubstring("not supported in \"MULTUS\" configuration mode")))
+		})
+	})
+
+	Describe("ValidateRuntimeConfig", func() {
+		It("should accept all capability args", func() {
+			config := &VfConfig{
+				Driver:           "iavf",
+				NetAttachDefName: "net",
+				RuntimeConfig: &RuntimeConfig{
+					IPs:          []string{"10.0.0.5/24", "fd00::5/64"},
+					MAC:          "02:00:00:00:00:05",
+					Bandwidth:    &BandwidthEntry{IngressRate: 1000000, IngressBurst: 100000},
+					PortMappings: []PortMapEntry{{HostPort: 8080, ContainerPort: 80, Protocol: "TCP"}},
+				},
+			}
+			Expect(config.Validate()).To(Succeed())
+		})
+
+		It("should reject the runtime config in netlink attach mode", func() {
+			config := &VfConfig{AttachMode: AttachModeNetlink, RuntimeConfig: &RuntimeConfig{MAC: "02:00:00:00:00:05"}}
+			Expect(config.ValidateRuntimeConfig()).To(MatchError(ContainSubstring("only supported in \"cni\" attach mode")))
+		})
+
+		It("should reject an ip without prefix length", func() {
+			config := &VfConfig{RuntimeConfig: &RuntimeConfig{IPs: []string{"10.0.0.5"}}}
+			Expect(config.ValidateRuntimeConfig()).To(MatchError(ContainSubstring("invalid runtime config ip")))
+		})
+
+		It("should reject a bandwidth rate without burst", func() {
+			config := &VfConfig{RuntimeConfig: &RuntimeConfig{Bandwidth: &BandwidthEntry{EgressRate: 1000}}}
+			Expect(config.ValidateRuntimeConfig()).To(MatchError(ContainSubstring("rate and burst must be set together")))
+		})
+
+		It("should reject an out of range port mapping", func() {
+			config := &VfConfig{RuntimeConfig: &RuntimeConfig{PortMappings: []PortMapEntry{{HostPort: 70000, ContainerPort: 80}}}}
+			Expect(config.ValidateRuntimeConfig()).To(MatchError(ContainSubstring("host port 70000 out of range")))
+		})
+
+		It("should reject an unsupported port mapping protocol", func() {
+			config := &VfConfig{RuntimeConfig: &RuntimeConfig{PortMappings: []PortMapEntry{{HostPort: 8080, ContainerPort: 80, Protocol: "icmp"}}}}
+			Expect(config.ValidateRuntimeConfig()).To(MatchError(ContainSubstring("unsupported protocol")))
+		})
+	})
+
+	Describe("CapabilityArgs", func() {
+		It("should return nil without runtime config", func() {
+			Expect((&VfConfig{}).CapabilityArgs()).To(BeNil())
+			Expect((&VfConfig{RuntimeConfig: &RuntimeConfig{}}).CapabilityArgs()).To(BeNil())
+		})
+
+		It("should key the set capability args by capability name", func() {
+			bandwidth := &BandwidthEntry{EgressRate: 1000, EgressBurst: 100}
+			portMappings := []PortMapEntry{{HostPort: 8080, ContainerPort: 80}}
+			config := &VfConfig{RuntimeConfig: &RuntimeConfig{Bandwidth: bandwidth, PortMappings: portMappings}}
+			Expect(config.CapabilityArgs()).To(Equal(map[string]interface{}{
+				"bandwidth":    bandwidth,
+				"portMappings": portMappings,
+			}))
+		})
+	})
+
 	Describe("Override", func() {
+		It("should override the netlink attach settings", func() {
+			base := &VfConfig{AttachMode: AttachModeCNI, Addresses: []string{"10.0.0.1/24"}}
+			other := &VfConfig{
+				AttachMode: AttachModeNetlink,
+				MTU:        9000,
+				MAC:        "02:00:00:00:00:01",
+				Addresses:  []string{"192.168.1.10/24"},
+				Routes:     []Route{{Destination: "0.0.0.0/0", Gateway: "192.168.1.1"}},
+			}
+
+			base.Override(other)
+
+			Expect(base.IsNetlinkAttachMode()).To(BeTrue())
+			Expect(base.MTU).To(Equal(9000))
+			Expect(base.MAC).To(Equal("02:00:00:00:00:01"))
+			Expect(base.Addresses).To(Equal([]string{"192.168.1.10/24"}))
+			Expect(base.Routes).To(Equal(other.Routes))
+			other.Addresses[0] = "changed"
+			Expect(base.Addresses[0]).To(Equal("192.168.1.10/24"))
+		})
+
+		It("should replace the net attach def name and the inline CNI config by each other", func() {
+			cniConfig := &runtime.RawExtension{Raw: []byte(`{"cniVersion":"1.0.0","type":"sriov"}`)}
+			base := &VfConfig{NetAttachDefName: "net1"}
+
+			base.Override(&VfConfig{CNIConfig: cniConfig})
+			Expect(base.NetAttachDefName).To(BeEmpty())
+			Expect(base.CNIConfig).To(Equal(cniConfig))
+			Expect(base.Validate()).To(Succeed())
+
+			base.Override(&VfConfig{NetAttachDefName: "net2"})
+			Expect(base.NetAttachDefName).To(Equal("net2"))
+			Expect(base.CNIConfig).To(BeNil())
+		})
+
 		Context("Override All Fields", func() {
 			It("should override all fields when other has all fields set", func() {
 				base := &VfConfig{
@@ -1,15 +1,180 @@
 package v1alpha1
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
 
-// Validate ensures that GpuConfig has a valid set of values.
+	"github.com/containernetworking/cni/libcni"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+)
+
+// Validate ensures that VfConfig has a valid set of values. It validates the
+// config of a request once merged, as the settings depend on each other and
+// may be set by different configs. An empty driver keeps the kernel driver of
+// the VF. The net attach def name is only required in STANDALONE mode, which
+// the node may select, so it is checked when the device is prepared.
 func (c *VfConfig) Validate() error {
-	if c.Driver == "" {
-		return fmt.Errorf("no driver set")
+	if c.IsNetlinkAttachMode() {
+		if err := c.ValidateRuntimeConfig(); err != nil {
+			return err
+		}
+		if err := c.ValidateConfigurationMode(); err != nil {
+			return err
+		}
+		return c.ValidateAttachConfig()
+	}
+	if c.NetAttachDefName != "" && c.HasCNIConfig() {
+		return fmt.Errorf("net attach def name and inline cni config are mutually exclusive")
+	}
+
+	if err := c.ValidateAttachConfig(); err != nil {
+		return err
+	}
+	if err := c.ValidateRuntimeConfig(); err != nil {
+		return err
+	}
+	if err := c.ValidateConfigurationMode(); err != nil {
+		return err
+	}
+	return c.ValidateCNIConfig()
+}
+
+// ValidateRuntimeConfig ensures that the CNI capability arguments, if any,
+// parse. They are only allowed in cni attach mode.
+func (c *VfConfig) ValidateRuntimeConfig() error {
+	if c.RuntimeConfig == nil {
+		return nil
 	}
-	if c.NetAttachDefName == "" {
-		return fmt.Errorf("no net attach def name set")
+	if c.IsNetlinkAttachMode() {
+		return fmt.Errorf("runtime config is only supported in %q attach mode", AttachModeCNI)
 	}
 
+	for _, ip := range c.RuntimeConfig.IPs {
+		if _, _, err := net.ParseCIDR(ip); err != nil {
+			return fmt.Errorf("invalid runtime config ip: %w", err)
+		}
+	}
+	if c.RuntimeConfig.MAC != "" {
+		if _, err := net.ParseMAC(c.RuntimeConfig.MAC); err != nil {
+			return fmt.Errorf("invalid runtime config mac: %w", err)
+		}
+	}
+	if bandwidth := c.RuntimeConfig.Bandwidth; bandwidth != nil {
+		if bandwidth.IngressRate < 0 || bandwidth.IngressBurst < 0 || bandwidth.EgressRate < 0 || bandwidth.EgressBurst < 0 {
+			return fmt.Errorf("invalid runtime config bandwidth: rates and bursts must not be negative")
+		}
+		if (bandwidth.IngressRate > 0) != (bandwidth.IngressBurst > 0) || (bandwidth.EgressRate > 0) != (bandwidth.EgressBurst > 0) {
+			return fmt.Errorf("invalid runtime config bandwidth: rate and burst must be set together")
+		}
+	}
+	for _, portMapping := range c.RuntimeConfig.PortMappings {
+		if portMapping.ContainerPort < 1 || portMapping.ContainerPort > 65535 {
+			return fmt.Errorf("invalid runtime config port mapping: container port %d out of range", portMapping.ContainerPort)
+		}
+		if portMapping.HostPort < 1 || portMapping.HostPort > 65535 {
+			return fmt.Errorf("invalid runtime config port mapping: host port %d out of range", portMapping.HostPort)
+		}
+		switch strings.ToLower(portMapping.Protocol) {
+		case "", "tcp", "udp", "sctp":
+		default:
+			return fmt.Errorf("invalid runtime config port mapping: unsupported protocol %q", portMapping.Protocol)
+		}
+		if portMapping.HostIP != "" && net.ParseIP(portMapping.HostIP) == nil {
+			return fmt.Errorf("invalid runtime config port mapping: invalid host ip %q", portMapping.HostIP)
+		}
+	}
+	return nil
+}
+
+// ValidateAttachConfig ensures that the attach mode is known and that the
+// netlink attach settings parse. The netlink settings are only allowed in
+// netlink attach mode, where the CNI configuration, if any, is ignored.
+func (c *VfConfig) ValidateAttachConfig() error {
+	switch c.AttachMode {
+	case "", AttachModeCNI:
+		if c.MTU != 0 || c.MAC != "" || len(c.Addresses) > 0 || len(c.Routes) > 0 {
+			return fmt.Errorf("mtu, mac, addresses and routes are only supported in %q attach mode", AttachModeNetlink)
+		}
+		return nil
+	case AttachModeNetlink:
+	default:
+		return fmt.Errorf("unsupported attach mode %q, expected %q or %q", c.AttachMode, AttachModeCNI, AttachModeNetlink)
+	}
+
+	if c.MTU < 0 {
+		return fmt.Errorf("invalid mtu %d", c.MTU)
+	}
+	if c.MAC != "" {
+		if _, err := net.ParseMAC(c.MAC); err != nil {
+			return fmt.Errorf("invalid mac: %w", err)
+		}
+	}
+	for _, address := range c.Addresses {
+		if _, _, err := net.ParseCIDR(address); err != nil {
+			return fmt.Errorf("invalid address: %w", err)
+		}
+	}
+	for _, route := range c.Routes {
+		if _, _, err := net.ParseCIDR(route.Destination); err != nil {
+			return fmt.Errorf("invalid route destination: %w", err)
+		}
+		if route.Gateway != "" && net.ParseIP(route.Gateway) == nil {
+			return fmt.Errorf("invalid route gateway %q", route.Gateway)
+		}
+	}
+	return nil
+}
+
+// ValidateConfigurationMode ensures that the claim configuration mode is
+// known. Multus claims are attached by Multus, the driver attach settings do
+// not apply to them.
+func (c *VfConfig) ValidateConfigurationMode() error {
+	switch consts.ConfigurationMode(c.ConfigurationMode) {
+	case "", consts.ConfigurationModeStandalone:
+		return nil
+	case consts.ConfigurationModeMultus:
+	default:
+		return fmt.Errorf("unsupported configuration mode %q, expected %q or %q", c.ConfigurationMode, consts.ConfigurationModeStandalone, consts.ConfigurationModeMultus)
+	}
+
+	if c.IsNetlinkAttachMode() || c.RuntimeConfig != nil || c.HasCNIConfig() {
+		return fmt.Errorf("attach mode, runtime config and cni config are not supported in %q configuration mode", consts.ConfigurationModeMultus)
+	}
+	return nil
+}
+
+// ValidateCNIConfig ensures that the inline CNI config, if any, parses as a
+// single plugin config or as a conflist.
+func (c *VfConfig) ValidateCNIConfig() error {
+	if !c.HasCNIConfig() {
+		return nil
+	}
+
+	var rawConfig map[string]interface{}
+	if err := json.Unmarshal(c.CNIConfig.Raw, &rawConfig); err != nil {
+		return fmt.Errorf("invalid cni config: %w", err)
+	}
+
+	if _, isList := rawConfig["plugins"]; isList {
+		// the network name is injected at attach time when missing
+		if _, ok := rawConfig["name"]; !ok {
+			rawConfig["name"] = GroupName
+		}
+		raw, err := json.Marshal(rawConfig)
+		if err != nil {
+			return fmt.Errorf("invalid cni config: %w", err)
+		}
+		if _, err := libcni.NetworkConfFromBytes(raw); err != nil {
+			return fmt.Errorf("invalid cni config: %w", err)
+		}
+		return nil
+	}
+
+	if _, err := libcni.NetworkPluginConfFromBytes(c.CNIConfig.Raw); err != nil {
+		return fmt.Errorf("invalid cni config: %w", err)
+	}
 	return nil
 }
@@ -3,12 +3,14 @@ package cdi
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	cdiapi "tags.cncf.io/container-device-interface/pkg/cdi"
 	cdiparser "tags.cncf.io/container-device-interface/pkg/parser"
 	cdispec "tags.cncf.io/container-device-interface/specs-go"
 
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cdihook"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 )
@@ -23,6 +25,11 @@ const (
 
 type Handler struct {
 	cache *cdiapi.Cache
+
+	// networkHookPath and networkHookSocket are set when networks are
+	// attached from CDI hooks instead of NRI.
+	networkHookPath   string
+	networkHookSocket string
 }
 
 func NewHandler(cdiRootPath string) (*Handler, error) {
@@ -70,6 +77,14 @@ func (cdi *Handler) CreateCommonSpecFile() error {
 	return cdi.cache.WriteSpec(spec, specName)
 }
 
+// SetNetworkHook adds createRuntime and poststop hooks running hookPath to the
+// claim devices, the hook sends the pod sandbox of the container to the driver
+// on socketPath to attach or detach the device network.
+func (cdi *Handler) SetNetworkHook(hookPath, socketPath string) {
+	cdi.networkHookPath = hookPath
+	cdi.networkHookSocket = socketPath
+}
+
 func (cdi *Handler) CreateClaimSpecFile(preparedDevices types.PreparedDevices) error {
 	claimUID := string(preparedDevices[0].ClaimNamespacedName.UID)
 	specName := cdiapi.GenerateTransientSpecName(cdiVendor, cdiClass, claimUID)
@@ -84,6 +99,14 @@ func (cdi *Handler) CreateClaimSpecFile(preparedDevices types.PreparedDevices) e
 			Name:           fmt.Sprintf("%s-%s", claimUID, device.Device.DeviceName),
 			ContainerEdits: *device.ContainerEdits.ContainerEdits,
 		}
+		// Multus attaches the devices of MULTUS claims in HYBRID mode
+		if cdi.networkHookPath != "" && (device.Config == nil || !device.Config.IsMultusConfigurationMode()) {
+			// copy the hooks so the prepared device edits are left untouched
+			hooks := append([]*cdispec.Hook{}, cdiDevice.ContainerEdits.Hooks...)
+			cdiDevice.ContainerEdits.Hooks = append(hooks,
+				cdi.networkHook(cdihook.ActionAdd, "createRuntime", device),
+				cdi.networkHook(cdihook.ActionDel, "poststop", device))
+		}
 
 		spec.Devices = append(spec.Devices, cdiDevice)
 	}
@@ -96,6 +119,20 @@ func (cdi *Handler) CreateClaimSpecFile(preparedDevices types.PreparedDevices) e
 	return cdi.cache.WriteSpec(spec, specName)
 }
 
+func (cdi *Handler) networkHook(action, hookName string, device *types.PreparedDevice) *cdispec.Hook {
+	return &cdispec.Hook{
+		HookName: hookName,
+		Path:     cdi.networkHookPath,
+		Args: []string{
+			filepath.Base(cdi.networkHookPath), action,
+			"--socket", cdi.networkHookSocket,
+			"--claim-uid", string(device.ClaimNamespacedName.UID),
+			"--device", device.Device.DeviceName,
+			"--pod-uid", device.PodUID,
+		},
+	}
+}
+
 func (cdi *Handler) CreateGlobalPodSpecFile(podUID string, pciAddresses []string) error {
 	envs := []string{fmt.Sprintf("SRIOVNETWORK_PCI_ADDRESSES=%s", strings.Join(pciAddresses, ","))}
 	specName := cdiapi.GenerateTransientSpecName(cdiVendor, cdiClass, podUID)
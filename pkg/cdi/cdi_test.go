@@ -2,6 +2,7 @@ package cdi_test
 
 import (
 	"os"
+	"path/filepath"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -133,6 +134,35 @@ var _ = Describe("CDI Handler", func() {
 			// We can't easily verify the contents, but no error indicates success
 		})
 
+		It("should add the network hooks when set", func() {
+			preparedDevices[0].PodUID = podUID
+			handler.SetNetworkHook("/opt/bin/dra-driver-sriov-cdi-hook", "/var/lib/kubelet/plugins/sriovnetwork.k8snetworkplumbingwg.io/cdi-hook.sock")
+
+			err := handler.CreateClaimSpecFile(preparedDevices)
+			Expect(err).NotTo(HaveOccurred())
+
+			specFiles, err := filepath.Glob(filepath.Join(tempDir, "*"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(specFiles).To(HaveLen(1))
+			spec, err := cdiapi.ReadSpec(specFiles[0], 0)
+			Expect(err).NotTo(HaveOccurred())
+
+			hooks := spec.Devices[0].ContainerEdits.Hooks
+			Expect(hooks).To(HaveLen(2))
+			Expect(hooks[0].HookName).To(Equal("createRuntime"))
+			Expect(hooks[0].Path).To(Equal("/opt/bin/dra-driver-sriov-cdi-hook"))
+			Expect(hooks[0].Args).To(Equal([]string{
+				"dra-driver-sriov-cdi-hook", "add",
+				"--socket", "/var/lib/kubelet/plugins/sriovnetwork.k8snetworkplumbingwg.io/cdi-hook.sock",
+				"--claim-uid", claimUID,
+				"--device", deviceName,
+				"--pod-uid", podUID,
+			}))
+			Expect(hooks[1].HookName).To(Equal("poststop"))
+			Expect(hooks[1].Args[1]).To(Equal("del"))
+			Expect(preparedDevices[0].ContainerEdits.Hooks).To(BeEmpty())
+		})
+
 		It("should handle multiple devices in claim", func() {
 			// Add another device to the claim
 			preparedDevices = append(preparedDevices, &draTypes.PreparedDevice{
@@ -0,0 +1,105 @@
+// Package cdihook attaches networks on container runtimes without NRI. The
+// driver adds createRuntime and poststop hooks to the claim CDI specs, the hook
+// binary forwards the pod sandbox of the container to the driver over a unix
+// socket and the driver runs CNI ADD or DEL for it.
+package cdihook
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+const (
+	// ActionAdd attaches the device to the pod sandbox, sent by createRuntime.
+	ActionAdd = "add"
+	// ActionDel detaches the device once the pod sandbox is gone, sent by poststop.
+	ActionDel = "del"
+)
+
+// Annotations set by containerd and CRI-O on every container of a pod.
+var (
+	sandboxIDAnnotations    = []string{"io.kubernetes.cri.sandbox-id", "io.kubernetes.cri-o.SandboxID"}
+	podNameAnnotations      = []string{"io.kubernetes.cri.sandbox-name", "io.kubernetes.pod.name"}
+	podNamespaceAnnotations = []string{"io.kubernetes.cri.sandbox-namespace", "io.kubernetes.pod.namespace"}
+)
+
+// Request is sent by the hook to the driver for a device of a claim.
+type Request struct {
+	Action           string `json:"action"`
+	ClaimUID         string `json:"claimUID"`
+	DeviceName       string `json:"deviceName"`
+	PodUID           string `json:"podUID"`
+	PodName          string `json:"podName,omitempty"`
+	PodNamespace     string `json:"podNamespace,omitempty"`
+	SandboxID        string `json:"sandboxID"`
+	NetworkNamespace string `json:"networkNamespace"`
+}
+
+// Response is returned by the driver, Error is empty on success.
+type Response struct {
+	Error string `json:"error,omitempty"`
+}
+
+// NewRequest builds the request of a hook from the OCI state of the container
+// and its bundle configuration. The network namespace is the one of the pod
+// sandbox, which every container of the pod joins.
+func NewRequest(action, claimUID, deviceName, podUID string, state *specs.State, spec *specs.Spec) (*Request, error) {
+	annotations := spec.Annotations
+	if len(annotations) == 0 {
+		annotations = state.Annotations
+	}
+
+	req := &Request{
+		Action:       action,
+		ClaimUID:     claimUID,
+		DeviceName:   deviceName,
+		PodUID:       podUID,
+		SandboxID:    lookupAnnotation(annotations, sandboxIDAnnotations),
+		PodName:      lookupAnnotation(annotations, podNameAnnotations),
+		PodNamespace: lookupAnnotation(annotations, podNamespaceAnnotations),
+	}
+	if req.SandboxID == "" {
+		return nil, fmt.Errorf("container %s has no pod sandbox annotation", state.ID)
+	}
+
+	if spec.Linux != nil {
+		for _, namespace := range spec.Linux.Namespaces {
+			if namespace.Type == specs.NetworkNamespace {
+				req.NetworkNamespace = namespace.Path
+			}
+		}
+	}
+	if req.NetworkNamespace == "" && state.Pid > 0 {
+		req.NetworkNamespace = fmt.Sprintf("/proc/%d/ns/net", state.Pid)
+	}
+	if req.NetworkNamespace == "" && action == ActionAdd {
+		return nil, fmt.Errorf("container %s has no network namespace", state.ID)
+	}
+	return req, nil
+}
+
+// ReadBundleSpec reads the OCI runtime configuration of a container bundle.
+func ReadBundleSpec(bundle string) (*specs.Spec, error) {
+	raw, err := os.ReadFile(filepath.Join(bundle, "config.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle config: %w", err)
+	}
+	spec := &specs.Spec{}
+	if err := json.Unmarshal(raw, spec); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal bundle config: %w", err)
+	}
+	return spec, nil
+}
+
+func lookupAnnotation(annotations map[string]string, keys []string) string {
+	for _, key := range keys {
+		if value := annotations[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
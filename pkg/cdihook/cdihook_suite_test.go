@@ -0,0 +1,13 @@
+package cdihook_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestCDIHook(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "CDI Hook Suite")
+}
@@ -0,0 +1,157 @@
+package cdihook_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cdihook"
+)
+
+type fakeHandler struct {
+	requests []*cdihook.Request
+	err      error
+}
+
+func (f *fakeHandler) AttachDevice(_ context.Context, req *cdihook.Request) error {
+	f.requests = append(f.requests, req)
+	return f.err
+}
+
+func (f *fakeHandler) DetachDevice(_ context.Context, req *cdihook.Request) error {
+	f.requests = append(f.requests, req)
+	return f.err
+}
+
+var _ = Describe("CDI hook", func() {
+	Context("NewRequest", func() {
+		var (
+			state *specs.State
+			spec  *specs.Spec
+		)
+
+		BeforeEach(func() {
+			state = &specs.State{ID: "container-id", Pid: 1234}
+			spec = &specs.Spec{
+				Annotations: map[string]string{
+					"io.kubernetes.cri.sandbox-id":        "sandbox-id",
+					"io.kubernetes.cri.sandbox-name":      "pod-name",
+					"io.kubernetes.cri.sandbox-namespace": "default",
+				},
+				Linux: &specs.Linux{
+					Namespaces: []specs.LinuxNamespace{
+						{Type: specs.PIDNamespace},
+						{Type: specs.NetworkNamespace, Path: "/var/run/netns/cni-1234"},
+					},
+				},
+			}
+		})
+
+		It("reads the pod sandbox from containerd annotations", func() {
+			req, err := cdihook.NewRequest(cdihook.ActionAdd, "claim-uid", "dev", "pod-uid", state, spec)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(req).To(Equal(&cdihook.Request{
+				Action:           cdihook.ActionAdd,
+				ClaimUID:         "claim-uid",
+				DeviceName:       "dev",
+				PodUID:           "pod-uid",
+				PodName:          "pod-name",
+				PodNamespace:     "default",
+				SandboxID:        "sandbox-id",
+				NetworkNamespace: "/var/run/netns/cni-1234",
+			}))
+		})
+
+		It("reads the pod sandbox from CRI-O annotations", func() {
+			spec.Annotations = map[string]string{
+				"io.kubernetes.cri-o.SandboxID": "sandbox-id",
+				"io.kubernetes.pod.name":        "pod-name",
+				"io.kubernetes.pod.namespace":   "default",
+			}
+			req, err := cdihook.NewRequest(cdihook.ActionAdd, "claim-uid", "dev", "pod-uid", state, spec)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(req.SandboxID).To(Equal("sandbox-id"))
+			Expect(req.PodName).To(Equal("pod-name"))
+		})
+
+		It("falls back to the network namespace of the container process", func() {
+			spec.Linux.Namespaces = []specs.LinuxNamespace{{Type: specs.NetworkNamespace}}
+			req, err := cdihook.NewRequest(cdihook.ActionAdd, "claim-uid", "dev", "pod-uid", state, spec)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(req.NetworkNamespace).To(Equal("/proc/1234/ns/net"))
+		})
+
+		It("fails without a pod sandbox annotation", func() {
+			spec.Annotations = nil
+			_, err := cdihook.NewRequest(cdihook.ActionAdd, "claim-uid", "dev", "pod-uid", state, spec)
+			Expect(err).To(MatchError(ContainSubstring("no pod sandbox annotation")))
+		})
+	})
+
+	Context("ReadBundleSpec", func() {
+		It("reads the bundle configuration", func() {
+			bundle := GinkgoT().TempDir()
+			Expect(os.WriteFile(filepath.Join(bundle, "config.json"), []byte(`{"annotations":{"io.kubernetes.cri.sandbox-id":"sandbox-id"}}`), 0o600)).To(Succeed())
+
+			spec, err := cdihook.ReadBundleSpec(bundle)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.Annotations).To(HaveKeyWithValue("io.kubernetes.cri.sandbox-id", "sandbox-id"))
+		})
+	})
+
+	Context("Server", func() {
+		var (
+			ctx        context.Context
+			handler    *fakeHandler
+			server     *cdihook.Server
+			socketPath string
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			handler = &fakeHandler{}
+			// unix socket paths are limited in length, keep it short
+			dir, err := os.MkdirTemp("", "cdihook")
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(os.RemoveAll, dir)
+			socketPath = filepath.Join(dir, "hook.sock")
+
+			server = cdihook.NewServer(socketPath, handler)
+			Expect(server.Start(ctx)).To(Succeed())
+			DeferCleanup(server.Stop)
+		})
+
+		It("dispatches the requests to the handler", func() {
+			add := &cdihook.Request{Action: cdihook.ActionAdd, ClaimUID: "claim-uid", DeviceName: "dev", PodUID: "pod-uid", SandboxID: "sandbox-id"}
+			del := &cdihook.Request{Action: cdihook.ActionDel, ClaimUID: "claim-uid", DeviceName: "dev", PodUID: "pod-uid", SandboxID: "sandbox-id"}
+			Expect(cdihook.Send(ctx, socketPath, add)).To(Succeed())
+			Expect(cdihook.Send(ctx, socketPath, del)).To(Succeed())
+			Expect(handler.requests).To(Equal([]*cdihook.Request{add, del}))
+		})
+
+		It("returns the handler errors", func() {
+			handler.err = errors.New("CNI ADD failed")
+			err := cdihook.Send(ctx, socketPath, &cdihook.Request{Action: cdihook.ActionAdd})
+			Expect(err).To(MatchError("CNI ADD failed"))
+		})
+
+		It("rejects unknown actions", func() {
+			err := cdihook.Send(ctx, socketPath, &cdihook.Request{Action: "restart"})
+			Expect(err).To(MatchError(ContainSubstring("unsupported action")))
+			Expect(handler.requests).To(BeEmpty())
+		})
+
+		It("replaces a stale socket", func() {
+			server.Stop()
+			Expect(os.WriteFile(socketPath, nil, 0o600)).To(Succeed())
+			server = cdihook.NewServer(socketPath, handler)
+			Expect(server.Start(ctx)).To(Succeed())
+			Expect(cdihook.Send(ctx, socketPath, &cdihook.Request{Action: cdihook.ActionDel})).To(Succeed())
+		})
+	})
+})
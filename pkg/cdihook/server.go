@@ -0,0 +1,117 @@
+package cdihook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/unixsocket"
+)
+
+// Handler attaches and detaches the devices of the hook requests.
+type Handler interface {
+	AttachDevice(ctx context.Context, req *Request) error
+	DetachDevice(ctx context.Context, req *Request) error
+}
+
+// Server serves the hook requests on a unix socket.
+type Server struct {
+	*unixsocket.Server
+	handler Handler
+}
+
+// NewServer creates a server for the hook requests on socketPath.
+func NewServer(socketPath string, handler Handler) *Server {
+	s := &Server{handler: handler}
+	s.Server = unixsocket.NewServer("cdihook", socketPath, s)
+	return s
+}
+
+// ServeHTTP handles a hook request.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := klog.FromContext(r.Context()).WithName("cdihook")
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req := &Request{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeResponse(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+	logger.V(2).Info("CDI hook request", "action", req.Action, "claimUID", req.ClaimUID, "deviceName", req.DeviceName, "pod.UID", req.PodUID, "sandboxID", req.SandboxID)
+
+	var err error
+	switch req.Action {
+	case ActionAdd:
+		err = s.handler.AttachDevice(r.Context(), req)
+	case ActionDel:
+		err = s.handler.DetachDevice(r.Context(), req)
+	default:
+		writeResponse(w, http.StatusBadRequest, fmt.Errorf("unsupported action %q", req.Action))
+		return
+	}
+	if err != nil {
+		logger.Error(err, "CDI hook request failed", "action", req.Action, "claimUID", req.ClaimUID, "deviceName", req.DeviceName, "pod.UID", req.PodUID)
+		writeResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, nil)
+}
+
+func writeResponse(w http.ResponseWriter, status int, err error) {
+	resp := Response{}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// Send sends a hook request to the driver listening on socketPath.
+func Send(ctx context.Context, socketPath string, req *Request) error {
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://cdi-hook/", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to send request to %s: %w", socketPath, err)
+	}
+	defer func() { _ = httpResp.Body.Close() }()
+
+	resp := &Response{}
+	if err := json.NewDecoder(httpResp.Body).Decode(resp); err != nil {
+		return fmt.Errorf("failed to decode response (status %d): %w", httpResp.StatusCode, err)
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request failed with status %d", httpResp.StatusCode)
+	}
+	return nil
+}
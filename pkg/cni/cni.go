@@ -22,16 +22,22 @@ package cni
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/containerd/nri/pkg/api"
 	"github.com/containernetworking/cni/libcni"
+	cnitypes "github.com/containernetworking/cni/pkg/types"
 	cni100 "github.com/containernetworking/cni/pkg/types/100"
 	netattdefclientutils "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/utils"
+	"go.opentelemetry.io/otel/trace"
 	resourcev1 "k8s.io/api/resource/v1"
 	"k8s.io/klog/v2"
 
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/metrics"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/tracing"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 )
 
@@ -40,12 +46,18 @@ import (
 type Runtime struct {
 	CNIConfig  libcni.CNI
 	DriverName string
+	// Netlink attaches the devices configured in netlink attach mode
+	// without running any CNI plugin.
+	Netlink Interface
 }
 
 // New creates and returns a new CNI Runtime instance.
+// Results of CNI ADD are cached in cacheDir so DEL and CHECK receive them as
+// prevResult, the directory must persist across driver restarts.
 func New(
 	driverName string,
 	cniPath []string,
+	cacheDir string,
 ) *Runtime {
 	exec := &RawExec{
 		Stderr: os.Stderr,
@@ -53,8 +65,9 @@ func New(
 	}
 
 	rntm := &Runtime{
-		CNIConfig:  libcni.NewCNIConfig(cniPath, exec),
+		CNIConfig:  libcni.NewCNIConfigWithCacheDir(cniPath, cacheDir, exec),
 		DriverName: driverName,
+		Netlink:    newNetlinkAttacher(cacheDir),
 	}
 
 	return rntm
@@ -66,39 +79,67 @@ func New(
 // to update the ResourceClaim's status with allocated device information.
 // If a request fails, an error is returned together with the previous successful device status up to date.
 // If the status of a device is already set, CNI ADD will be skipped and the existing status will be preserved.
+// Conflist configurations are executed as a plugin chain and the final chained result is reported.
+// Devices in netlink attach mode are attached by the netlink attacher instead.
 func (rntm *Runtime) AttachNetwork(ctx context.Context, pod *api.PodSandbox, podNetworkNamespace string, deviceConfig *types.PreparedDevice) (*resourcev1.NetworkDeviceData, map[string]interface{}, error) {
-	rt := &libcni.RuntimeConf{
-		ContainerID: pod.Id,
-		NetNS:       podNetworkNamespace,
-		IfName:      deviceConfig.IfName,
-		Args: [][2]string{
-			{"IgnoreUnknown", "true"},
-			{"K8S_POD_NAMESPACE", pod.Namespace},
-			{"K8S_POD_NAME", pod.Name},
-			{"K8S_POD_INFRA_CONTAINER_ID", pod.Id},
-			{"K8S_POD_UID", pod.Uid},
-		},
-	}
-	rawNetConf, err := netattdefclientutils.GetCNIConfigFromSpec(deviceConfig.NetAttachDefConfig, rntm.DriverName)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to GetCNIConfigFromSpec: %v", err)
+	ctx, span := startSpan(ctx, "CNI ADD", pod, deviceConfig)
+	start := time.Now()
+	netData, resultMap, err := rntm.attachNetwork(ctx, pod, podNetworkNamespace, deviceConfig)
+	metrics.ObserveCNIOperation(metrics.CNIAdd, start, err)
+	tracing.End(span, err)
+	return netData, resultMap, err
+}
+
+func (rntm *Runtime) attachNetwork(ctx context.Context, pod *api.PodSandbox, podNetworkNamespace string, deviceConfig *types.PreparedDevice) (*resourcev1.NetworkDeviceData, map[string]interface{}, error) {
+	if isNetlinkAttached(deviceConfig) {
+		return rntm.Netlink.AttachNetwork(ctx, pod, podNetworkNamespace, deviceConfig)
 	}
+	rt := buildRuntimeConf(pod, podNetworkNamespace, deviceConfig)
 
-	pluginConf, err := libcni.NetworkPluginConfFromBytes(rawNetConf)
+	netConf, err := rntm.parseNetworkConfig(deviceConfig)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to NetworkPluginConfFromBytes: %v", err)
+		return nil, nil, err
 	}
-	klog.FromContext(ctx).V(3).Info("Runtime.AttachNetwork", "deviceConfig", deviceConfig)
 
-	cniResult, err := rntm.CNIConfig.AddNetwork(ctx, pluginConf, rt)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to AddNetwork: %v", err)
+	klog.FromContext(ctx).V(3).Info("Runtime.AttachNetwork", "deviceConfig", deviceConfig, "conflist", netConf.list != nil)
+
+	var cniResult cnitypes.Result
+	if netConf.list != nil {
+		cniResult, err = rntm.CNIConfig.AddNetworkList(ctx, netConf.list, rt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to AddNetworkList: %v", err)
+		}
+	} else {
+		cniResult, err = rntm.CNIConfig.AddNetwork(ctx, netConf.plugin, rt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to AddNetwork: %v", err)
+		}
 	}
+
 	if cniResult == nil {
 		return nil, nil, fmt.Errorf("cni result is nil")
 	}
 
 	klog.FromContext(ctx).V(3).Info("Runtime.AttachedNetwork", "cniResult", cniResult)
+
+	return networkDataFromResult(cniResult)
+}
+
+// startSpan starts the span of a CNI operation for a device of a pod.
+func startSpan(ctx context.Context, name string, pod *api.PodSandbox, deviceConfig *types.PreparedDevice) (context.Context, trace.Span) {
+	claim := deviceConfig.ClaimNamespacedName
+	attrs := append(tracing.ClaimAttributes(claim.Namespace, claim.Name, claim.UID),
+		tracing.AttributePodUID.String(pod.GetUid()),
+		tracing.AttributePodName.String(pod.GetName()),
+		tracing.AttributeSandboxID.String(pod.GetId()),
+		tracing.AttributeDeviceName.String(deviceConfig.Device.DeviceName),
+		tracing.AttributePciAddress.String(deviceConfig.PciAddress))
+	return tracing.Start(ctx, name, attrs...)
+}
+
+// networkDataFromResult converts a CNI result to the device network data and
+// to a generic map holding the full CNI 1.0.0 result.
+func networkDataFromResult(cniResult cnitypes.Result) (*resourcev1.NetworkDeviceData, map[string]interface{}, error) {
 	// Convert to NetworkDeviceData (minimal info)
 	netData, err := cniResultToNetworkData(cniResult)
 	if err != nil {
@@ -129,9 +170,141 @@ func (rntm *Runtime) DetachNetwork(
 	pod *api.PodSandbox,
 	podNetworkNamespace string,
 	deviceConfig *types.PreparedDevice,
+) error {
+	ctx, span := startSpan(ctx, "CNI DEL", pod, deviceConfig)
+	start := time.Now()
+	err := rntm.detachNetwork(ctx, pod, podNetworkNamespace, deviceConfig)
+	metrics.ObserveCNIOperation(metrics.CNIDel, start, err)
+	tracing.End(span, err)
+	return err
+}
+
+func (rntm *Runtime) detachNetwork(
+	ctx context.Context,
+	pod *api.PodSandbox,
+	podNetworkNamespace string,
+	deviceConfig *types.PreparedDevice,
 ) error {
 	klog.FromContext(ctx).Info("Runtime.DetachNetwork", "deviceConfig", deviceConfig)
-	rt := &libcni.RuntimeConf{
+	if isNetlinkAttached(deviceConfig) {
+		return rntm.Netlink.DetachNetwork(ctx, pod, podNetworkNamespace, deviceConfig)
+	}
+	rt := buildRuntimeConf(pod, podNetworkNamespace, deviceConfig)
+
+	netConf, err := rntm.parseNetworkConfig(deviceConfig)
+	if err != nil {
+		return err
+	}
+
+	klog.FromContext(ctx).V(3).Info("Runtime.DetachNetwork", "deviceConfig", deviceConfig, "conflist", netConf.list != nil)
+
+	if netConf.list != nil {
+		if err := rntm.CNIConfig.DelNetworkList(ctx, netConf.list, rt); err != nil {
+			return fmt.Errorf("failed to DelNetworkList: %v", err)
+		}
+		return nil
+	}
+
+	if err := rntm.CNIConfig.DelNetwork(ctx, netConf.plugin, rt); err != nil {
+		return fmt.Errorf("failed to DelNetwork: %v", err)
+	}
+
+	return nil
+}
+
+// CheckNetwork runs the CNI CHECK operation for a device attached to a pod.
+// The cached ADD result is passed to the plugins as prevResult. Networks whose
+// configuration version does not support CHECK are reported as healthy.
+func (rntm *Runtime) CheckNetwork(
+	ctx context.Context,
+	pod *api.PodSandbox,
+	podNetworkNamespace string,
+	deviceConfig *types.PreparedDevice,
+) error {
+	if isNetlinkAttached(deviceConfig) {
+		return rntm.Netlink.CheckNetwork(ctx, pod, podNetworkNamespace, deviceConfig)
+	}
+	rt := buildRuntimeConf(pod, podNetworkNamespace, deviceConfig)
+
+	netConf, err := rntm.parseNetworkConfig(deviceConfig)
+	if err != nil {
+		return err
+	}
+
+	klog.FromContext(ctx).V(5).Info("Runtime.CheckNetwork", "deviceConfig", deviceConfig, "conflist", netConf.list != nil)
+
+	if netConf.list != nil {
+		err = rntm.CNIConfig.CheckNetworkList(ctx, netConf.list, rt)
+	} else {
+		err = rntm.CNIConfig.CheckNetwork(ctx, netConf.plugin, rt)
+	}
+	if errors.Is(err, libcni.ErrorCheckNotSupp) {
+		klog.FromContext(ctx).V(5).Info("CNI CHECK not supported by network configuration, skipping", "deviceName", deviceConfig.Device.DeviceName)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to CheckNetwork: %v", err)
+	}
+
+	return nil
+}
+
+// isNetlinkAttached reports whether the device is attached with netlink
+// instead of CNI plugins.
+func isNetlinkAttached(deviceConfig *types.PreparedDevice) bool {
+	return deviceConfig.Config != nil && deviceConfig.Config.IsNetlinkAttachMode()
+}
+
+// networkConfig is the parsed CNI configuration of a prepared device.
+// Exactly one of plugin or list is set.
+type networkConfig struct {
+	plugin *libcni.PluginConfig
+	list   *libcni.NetworkConfigList
+}
+
+// parseNetworkConfig parses the CNI configuration of a prepared device either
+// as a single plugin configuration or as a conflist (plugin chain).
+func (rntm *Runtime) parseNetworkConfig(deviceConfig *types.PreparedDevice) (*networkConfig, error) {
+	rawNetConf, err := netattdefclientutils.GetCNIConfigFromSpec(deviceConfig.NetAttachDefConfig, rntm.DriverName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to GetCNIConfigFromSpec: %v", err)
+	}
+
+	if isConfList(rawNetConf) {
+		confList, err := libcni.NetworkConfFromBytes(rawNetConf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to NetworkConfFromBytes: %v", err)
+		}
+		return &networkConfig{list: confList}, nil
+	}
+
+	pluginConf, err := libcni.NetworkPluginConfFromBytes(rawNetConf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to NetworkPluginConfFromBytes: %v", err)
+	}
+	return &networkConfig{plugin: pluginConf}, nil
+}
+
+// isConfList reports whether the raw CNI configuration is a conflist.
+func isConfList(rawNetConf []byte) bool {
+	var probe struct {
+		Plugins json.RawMessage `json:"plugins"`
+	}
+	if err := json.Unmarshal(rawNetConf, &probe); err != nil {
+		return false
+	}
+	return probe.Plugins != nil
+}
+
+// buildRuntimeConf builds the CNI runtime configuration for a pod sandbox and device.
+// The capability arguments of the device config are only injected by libcni
+// into plugins declaring the matching capabilities.
+func buildRuntimeConf(pod *api.PodSandbox, podNetworkNamespace string, deviceConfig *types.PreparedDevice) *libcni.RuntimeConf {
+	var capabilityArgs map[string]interface{}
+	if deviceConfig.Config != nil {
+		capabilityArgs = deviceConfig.Config.CapabilityArgs()
+	}
+	return &libcni.RuntimeConf{
 		ContainerID: pod.Id,
 		NetNS:       podNetworkNamespace,
 		IfName:      deviceConfig.IfName,
@@ -142,21 +315,6 @@ func (rntm *Runtime) DetachNetwork(
 			{"K8S_POD_INFRA_CONTAINER_ID", pod.Id},
 			{"K8S_POD_UID", pod.Uid},
 		},
+		CapabilityArgs: capabilityArgs,
 	}
-	rawNetConf, err := netattdefclientutils.GetCNIConfigFromSpec(deviceConfig.NetAttachDefConfig, rntm.DriverName)
-	if err != nil {
-		return fmt.Errorf("failed to GetCNIConfigFromSpec: %v", err)
-	}
-
-	pluginConf, err := libcni.NetworkPluginConfFromBytes(rawNetConf)
-	if err != nil {
-		return fmt.Errorf("failed to NetworkPluginConfFromBytes: %v", err)
-	}
-	klog.FromContext(ctx).V(3).Info("Runtime.DetachNetwork", "deviceConfig", deviceConfig)
-	err = rntm.CNIConfig.DelNetwork(ctx, pluginConf, rt)
-	if err != nil {
-		return fmt.Errorf("failed to DelNetwork: %v", err)
-	}
-
-	return nil
 }
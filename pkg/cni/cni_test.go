@@ -3,16 +3,75 @@ package cni_test
 import (
 	"bytes"
 	"context"
+	"errors"
+	"net"
 	"os"
 
 	"github.com/containerd/nri/pkg/api"
+	"github.com/containernetworking/cni/libcni"
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+	cni100 "github.com/containernetworking/cni/pkg/types/100"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	resourcev1 "k8s.io/api/resource/v1"
 
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cni"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cni/mock"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 )
 
+// fakeCNI records the libcni calls made by the runtime and returns a canned result.
+type fakeCNI struct {
+	libcni.CNI
+	result      cnitypes.Result
+	addCalls    int
+	delCalls    int
+	addList     *libcni.NetworkConfigList
+	delList     *libcni.NetworkConfigList
+	checkCalls  int
+	checkList   *libcni.NetworkConfigList
+	checkErr    error
+	runtimeConf *libcni.RuntimeConf
+}
+
+func (f *fakeCNI) AddNetwork(_ context.Context, _ *libcni.PluginConfig, rt *libcni.RuntimeConf) (cnitypes.Result, error) {
+	f.addCalls++
+	f.runtimeConf = rt
+	return f.result, nil
+}
+
+func (f *fakeCNI) DelNetwork(_ context.Context, _ *libcni.PluginConfig, rt *libcni.RuntimeConf) error {
+	f.delCalls++
+	f.runtimeConf = rt
+	return nil
+}
+
+func (f *fakeCNI) AddNetworkList(_ context.Context, list *libcni.NetworkConfigList, rt *libcni.RuntimeConf) (cnitypes.Result, error) {
+	f.addList = list
+	f.runtimeConf = rt
+	return f.result, nil
+}
+
+func (f *fakeCNI) DelNetworkList(_ context.Context, list *libcni.NetworkConfigList, rt *libcni.RuntimeConf) error {
+	f.delList = list
+	f.runtimeConf = rt
+	return nil
+}
+
+func (f *fakeCNI) CheckNetwork(_ context.Context, _ *libcni.PluginConfig, rt *libcni.RuntimeConf) error {
+	f.checkCalls++
+	f.runtimeConf = rt
+	return f.checkErr
+}
+
+func (f *fakeCNI) CheckNetworkList(_ context.Context, list *libcni.NetworkConfigList, rt *libcni.RuntimeConf) error {
+	f.checkList = list
+	f.runtimeConf = rt
+	return f.checkErr
+}
+
 var _ = Describe("CNI", func() {
 	var (
 		runtime *cni.Runtime
@@ -25,7 +84,7 @@ var _ = Describe("CNI", func() {
 		ctx = context.Background()
 
 		// Create runtime
-		runtime = cni.New("test-driver", []string{"/opt/cni/bin"})
+		runtime = cni.New("test-driver", []string{"/opt/cni/bin"}, "/var/lib/cni")
 
 		pod = &api.PodSandbox{
 			Id:        "test-container-id",
@@ -42,7 +101,7 @@ var _ = Describe("CNI", func() {
 			driverName := "test-driver"
 			cniPath := []string{"/opt/cni/bin"}
 
-			runtime := cni.New(driverName, cniPath)
+			runtime := cni.New(driverName, cniPath, "/var/lib/cni")
 
 			Expect(runtime).NotTo(BeNil())
 			Expect(runtime.DriverName).To(Equal(driverName))
@@ -50,7 +109,7 @@ var _ = Describe("CNI", func() {
 		})
 
 		It("should handle empty CNI path", func() {
-			runtime := cni.New("test-driver", []string{})
+			runtime := cni.New("test-driver", []string{}, "/var/lib/cni")
 
 			Expect(runtime).NotTo(BeNil())
 			Expect(runtime.DriverName).To(Equal("test-driver"))
@@ -58,7 +117,7 @@ var _ = Describe("CNI", func() {
 
 		It("should handle multiple CNI paths", func() {
 			paths := []string{"/opt/cni/bin", "/usr/local/bin"}
-			runtime := cni.New("test-driver", paths)
+			runtime := cni.New("test-driver", paths, "/var/lib/cni")
 
 			Expect(runtime).NotTo(BeNil())
 			Expect(runtime.DriverName).To(Equal("test-driver"))
@@ -115,6 +174,207 @@ var _ = Describe("CNI", func() {
 		})
 	})
 
+	Context("Conflist support", func() {
+		var fake *fakeCNI
+
+		BeforeEach(func() {
+			_, ipNet, err := net.ParseCIDR("10.1.2.3/24")
+			Expect(err).NotTo(HaveOccurred())
+			fake = &fakeCNI{
+				result: &cni100.Result{
+					CNIVersion: "1.0.0",
+					Interfaces: []*cni100.Interface{
+						{Name: "net1", Mac: "aa:bb:cc:dd:ee:ff", Sandbox: netNS},
+					},
+					IPs: []*cni100.IPConfig{{Address: *ipNet}},
+				},
+			}
+			runtime.CNIConfig = fake
+		})
+
+		It("should execute a conflist as a plugin chain on attach", func() {
+			device := &types.PreparedDevice{
+				IfName:             "net1",
+				NetAttachDefConfig: `{"cniVersion":"1.0.0","name":"chain","plugins":[{"type":"sriov","deviceID":"0000:01:00.1"},{"type":"tuning"}]}`,
+			}
+			netData, resultMap, err := runtime.AttachNetwork(ctx, pod, netNS, device)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fake.addCalls).To(Equal(0))
+			Expect(fake.addList).NotTo(BeNil())
+			Expect(fake.addList.Plugins).To(HaveLen(2))
+			Expect(fake.addList.Plugins[0].Network.Type).To(Equal("sriov"))
+			Expect(fake.addList.Plugins[1].Network.Type).To(Equal("tuning"))
+			Expect(fake.runtimeConf.IfName).To(Equal("net1"))
+			Expect(netData.InterfaceName).To(Equal("net1"))
+			Expect(netData.HardwareAddress).To(Equal("aa:bb:cc:dd:ee:ff"))
+			Expect(resultMap).To(HaveKey("interfaces"))
+		})
+
+		It("should inject the network name into a conflist without one", func() {
+			device := &types.PreparedDevice{
+				IfName:             "net1",
+				NetAttachDefConfig: `{"cniVersion":"1.0.0","plugins":[{"type":"sriov"}]}`,
+			}
+			_, _, err := runtime.AttachNetwork(ctx, pod, netNS, device)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fake.addList.Name).To(Equal("test-driver"))
+		})
+
+		It("should keep using a single plugin config on attach", func() {
+			device := &types.PreparedDevice{
+				IfName:             "net1",
+				NetAttachDefConfig: `{"cniVersion":"1.0.0","name":"single","type":"sriov"}`,
+			}
+			_, _, err := runtime.AttachNetwork(ctx, pod, netNS, device)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fake.addCalls).To(Equal(1))
+			Expect(fake.addList).To(BeNil())
+		})
+
+		It("should execute a conflist as a plugin chain on detach", func() {
+			device := &types.PreparedDevice{
+				IfName:             "net1",
+				NetAttachDefConfig: `{"cniVersion":"1.0.0","name":"chain","plugins":[{"type":"sriov"},{"type":"bandwidth"}]}`,
+			}
+			Expect(runtime.DetachNetwork(ctx, pod, netNS, device)).To(Succeed())
+			Expect(fake.delCalls).To(Equal(0))
+			Expect(fake.delList).NotTo(BeNil())
+			Expect(fake.delList.Plugins).To(HaveLen(2))
+		})
+
+		It("should reject a conflist with an empty plugin chain", func() {
+			device := &types.PreparedDevice{
+				IfName:             "net1",
+				NetAttachDefConfig: `{"cniVersion":"1.0.0","name":"chain","plugins":[]}`,
+			}
+			_, _, err := runtime.AttachNetwork(ctx, pod, netNS, device)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to NetworkConfFromBytes"))
+		})
+	})
+
+	Context("CheckNetwork", func() {
+		var fake *fakeCNI
+
+		BeforeEach(func() {
+			fake = &fakeCNI{}
+			runtime.CNIConfig = fake
+		})
+
+		It("should check a single plugin config", func() {
+			device := &types.PreparedDevice{
+				IfName:             "net1",
+				NetAttachDefConfig: `{"cniVersion":"1.0.0","name":"single","type":"sriov"}`,
+			}
+			Expect(runtime.CheckNetwork(ctx, pod, netNS, device)).To(Succeed())
+			Expect(fake.checkCalls).To(Equal(1))
+			Expect(fake.runtimeConf.IfName).To(Equal("net1"))
+			Expect(fake.runtimeConf.ContainerID).To(Equal(pod.Id))
+		})
+
+		It("should pass the runtime config as capability args", func() {
+			device := &types.PreparedDevice{
+				IfName:             "net1",
+				NetAttachDefConfig: `{"cniVersion":"1.0.0","name":"single","type":"sriov","capabilities":{"mac":true,"ips":true}}`,
+				Config: &configapi.VfConfig{
+					RuntimeConfig: &configapi.RuntimeConfig{
+						IPs: []string{"10.0.0.5/24"},
+						MAC: "02:00:00:00:00:05",
+					},
+				},
+			}
+			Expect(runtime.CheckNetwork(ctx, pod, netNS, device)).To(Succeed())
+			Expect(fake.runtimeConf.CapabilityArgs).To(Equal(map[string]interface{}{
+				"ips": []string{"10.0.0.5/24"},
+				"mac": "02:00:00:00:00:05",
+			}))
+		})
+
+		It("should check a conflist as a plugin chain", func() {
+			device := &types.PreparedDevice{
+				IfName:             "net1",
+				NetAttachDefConfig: `{"cniVersion":"1.0.0","name":"chain","plugins":[{"type":"sriov"},{"type":"tuning"}]}`,
+			}
+			Expect(runtime.CheckNetwork(ctx, pod, netNS, device)).To(Succeed())
+			Expect(fake.checkCalls).To(Equal(0))
+			Expect(fake.checkList).NotTo(BeNil())
+			Expect(fake.checkList.Plugins).To(HaveLen(2))
+		})
+
+		It("should ignore configurations that do not support CHECK", func() {
+			fake.checkErr = libcni.ErrorCheckNotSupp
+			device := &types.PreparedDevice{
+				IfName:             "net1",
+				NetAttachDefConfig: `{"cniVersion":"0.3.1","name":"single","type":"sriov"}`,
+			}
+			Expect(runtime.CheckNetwork(ctx, pod, netNS, device)).To(Succeed())
+		})
+
+		It("should return the CHECK error", func() {
+			fake.checkErr = errors.New("interface missing")
+			device := &types.PreparedDevice{
+				IfName:             "net1",
+				NetAttachDefConfig: `{"cniVersion":"1.0.0","name":"single","type":"sriov"}`,
+			}
+			err := runtime.CheckNetwork(ctx, pod, netNS, device)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("interface missing"))
+		})
+	})
+
+	Context("Netlink attach mode", func() {
+		var (
+			fake    *fakeCNI
+			netlink *mock.MockInterface
+			device  *types.PreparedDevice
+		)
+
+		BeforeEach(func() {
+			fake = &fakeCNI{}
+			runtime.CNIConfig = fake
+			netlink = mock.NewMockInterface(gomock.NewController(GinkgoT()))
+			runtime.Netlink = netlink
+			device = &types.PreparedDevice{
+				IfName:     "net1",
+				PciAddress: "0000:01:00.1",
+				Config: &configapi.VfConfig{
+					AttachMode: configapi.AttachModeNetlink,
+					Addresses:  []string{"192.168.1.10/24"},
+				},
+			}
+		})
+
+		It("should attach with netlink without running CNI plugins", func() {
+			networkData := &resourcev1.NetworkDeviceData{InterfaceName: "net1", IPs: []string{"192.168.1.10/24"}}
+			netlink.EXPECT().AttachNetwork(gomock.Any(), pod, netNS, device).Return(networkData, nil, nil)
+
+			data, _, err := runtime.AttachNetwork(ctx, pod, netNS, device)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data).To(Equal(networkData))
+			Expect(fake.addCalls).To(BeZero())
+		})
+
+		It("should detach and check with netlink", func() {
+			netlink.EXPECT().DetachNetwork(gomock.Any(), pod, netNS, device).Return(nil)
+			netlink.EXPECT().CheckNetwork(gomock.Any(), pod, netNS, device).Return(errors.New("link net1 is down"))
+
+			Expect(runtime.DetachNetwork(ctx, pod, netNS, device)).To(Succeed())
+			Expect(runtime.CheckNetwork(ctx, pod, netNS, device)).To(MatchError(ContainSubstring("link net1 is down")))
+			Expect(fake.delCalls).To(BeZero())
+			Expect(fake.checkCalls).To(BeZero())
+		})
+
+		It("should run CNI plugins in cni attach mode", func() {
+			fake.result = &cni100.Result{CNIVersion: "1.0.0"}
+			device.Config = &configapi.VfConfig{AttachMode: configapi.AttachModeCNI}
+			device.NetAttachDefConfig = `{"cniVersion":"1.0.0","name":"single","type":"sriov"}`
+
+			_, _, err := runtime.AttachNetwork(ctx, pod, netNS, device)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fake.addCalls).To(Equal(1))
+		})
+	})
+
 	Context("RawExec", func() {
 		var rawExec *cni.RawExec
 
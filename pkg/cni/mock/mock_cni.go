@@ -59,6 +59,20 @@ func (mr *MockInterfaceMockRecorder) AttachNetwork(ctx, pod, podNetworkNamespace
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AttachNetwork", reflect.TypeOf((*MockInterface)(nil).AttachNetwork), ctx, pod, podNetworkNamespace, deviceConfig)
 }
 
+// CheckNetwork mocks base method.
+func (m *MockInterface) CheckNetwork(ctx context.Context, pod *api.PodSandbox, podNetworkNamespace string, deviceConfig *types.PreparedDevice) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckNetwork", ctx, pod, podNetworkNamespace, deviceConfig)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckNetwork indicates an expected call of CheckNetwork.
+func (mr *MockInterfaceMockRecorder) CheckNetwork(ctx, pod, podNetworkNamespace, deviceConfig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckNetwork", reflect.TypeOf((*MockInterface)(nil).CheckNetwork), ctx, pod, podNetworkNamespace, deviceConfig)
+}
+
 // DetachNetwork mocks base method.
 func (m *MockInterface) DetachNetwork(ctx context.Context, pod *api.PodSandbox, podNetworkNamespace string, deviceConfig *types.PreparedDevice) error {
 	m.ctrl.T.Helper()
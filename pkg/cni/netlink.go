@@ -0,0 +1,395 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cni
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/containerd/nri/pkg/api"
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+	cni100 "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/vishvananda/netlink"
+	resourcev1 "k8s.io/api/resource/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// netlinkStateDir is the directory below the CNI cache directory holding the
+// host state of the VF netdevs attached with netlink.
+const netlinkStateDir = "dra-sriov-netlink"
+
+// netlinkAttacher attaches VF netdevs to pods with netlink only: the netdev is
+// moved into the pod network namespace, renamed and configured from the
+// VfConfig, and moved back with its original name on detach.
+type netlinkAttacher struct {
+	cacheDir string
+}
+
+var _ Interface = (*netlinkAttacher)(nil)
+
+// linkState is the host state of a VF netdev saved before it is moved into a
+// pod, it is restored when the netdev is detached.
+type linkState struct {
+	Name         string `json:"name"`
+	MTU          int    `json:"mtu"`
+	HardwareAddr string `json:"mac,omitempty"`
+	Up           bool   `json:"up,omitempty"`
+}
+
+func newNetlinkAttacher(cacheDir string) *netlinkAttacher {
+	return &netlinkAttacher{cacheDir: cacheDir}
+}
+
+// AttachNetwork moves the VF netdev into the pod network namespace, renames it
+// to the device interface name and applies the MTU, MAC, addresses and routes
+// of the VfConfig. The returned network data matches the one of the CNI path.
+// When the attach fails the netdev is moved back with its host state.
+func (a *netlinkAttacher) AttachNetwork(ctx context.Context, pod *api.PodSandbox, podNetworkNamespace string, deviceConfig *types.PreparedDevice) (*resourcev1.NetworkDeviceData, map[string]interface{}, error) {
+	logger := klog.FromContext(ctx).WithName("netlinkAttacher")
+	config := deviceConfig.Config
+
+	hostName, err := vfNetdevName(deviceConfig.PciAddress)
+	if err != nil {
+		return nil, nil, err
+	}
+	hostHandle, err := host.GetHelpers().NewNetlinkHandle("")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer hostHandle.Close()
+	link, err := hostHandle.LinkByName(hostName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get link %s: %v", hostName, err)
+	}
+	state := &linkState{
+		Name:         link.Attrs().Name,
+		MTU:          link.Attrs().MTU,
+		HardwareAddr: link.Attrs().HardwareAddr.String(),
+		Up:           link.Attrs().Flags&net.FlagUp != 0,
+	}
+	if err := a.saveLinkState(deviceConfig.PciAddress, state); err != nil {
+		return nil, nil, err
+	}
+
+	podHandle, err := host.GetHelpers().NewNetlinkHandle(podNetworkNamespace)
+	if err != nil {
+		return nil, nil, errors.Join(err, a.removeLinkState(deviceConfig.PciAddress))
+	}
+	defer podHandle.Close()
+
+	logger.V(3).Info("Moving VF netdev into pod network namespace", "link", hostName, "ifName", deviceConfig.IfName, "pod.UID", pod.Uid)
+	if err := hostHandle.LinkSetDown(link); err != nil {
+		return nil, nil, errors.Join(fmt.Errorf("failed to set link %s down: %v", hostName, err),
+			a.rollback(hostHandle, nil, deviceConfig, state))
+	}
+	if err := hostHandle.LinkSetNsFd(link, podHandle.NsFd()); err != nil {
+		return nil, nil, errors.Join(fmt.Errorf("failed to move link %s to network namespace %s: %v", hostName, podNetworkNamespace, err),
+			a.rollback(hostHandle, nil, deviceConfig, state))
+	}
+
+	podLink, err := podHandle.LinkByName(hostName)
+	if err != nil {
+		return nil, nil, errors.Join(fmt.Errorf("failed to get link %s in network namespace %s: %v", hostName, podNetworkNamespace, err),
+			a.rollback(hostHandle, podHandle, deviceConfig, state))
+	}
+	result, err := configurePodLink(podHandle, podLink, podNetworkNamespace, deviceConfig)
+	if err != nil {
+		// move the netdev back so the device can be attached again
+		return nil, nil, errors.Join(err, a.rollback(hostHandle, podHandle, deviceConfig, state))
+	}
+
+	logger.V(3).Info("Attached VF netdev", "ifName", deviceConfig.IfName, "mtu", config.MTU, "addresses", config.Addresses)
+	return networkDataFromResult(result)
+}
+
+// rollback undoes a failed attach: the VF netdev is moved back from the pod
+// network namespace when podHandle is set and its host state is restored.
+// The saved state is kept when the netdev cannot be restored, so detaching
+// the device retries.
+func (a *netlinkAttacher) rollback(hostHandle, podHandle host.NetlinkHandle, deviceConfig *types.PreparedDevice, state *linkState) error {
+	if podHandle != nil {
+		if err := detachPodLink(podHandle, deviceConfig.IfName, state); err != nil {
+			return err
+		}
+	}
+	if err := restoreHostLink(hostHandle, deviceConfig.PciAddress, state); err != nil {
+		return err
+	}
+	return a.removeLinkState(deviceConfig.PciAddress)
+}
+
+// DetachNetwork moves the VF netdev back to the host network namespace and
+// restores its original name, MTU, MAC and state. When the pod network
+// namespace is already gone the kernel has moved the netdev back, only its
+// state is restored. Devices that are not attached are ignored.
+func (a *netlinkAttacher) DetachNetwork(ctx context.Context, pod *api.PodSandbox, podNetworkNamespace string, deviceConfig *types.PreparedDevice) error {
+	logger := klog.FromContext(ctx).WithName("netlinkAttacher")
+	state, err := a.loadLinkState(deviceConfig.PciAddress)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.V(3).Info("VF netdev is not attached, skipping detach", "pciAddress", deviceConfig.PciAddress, "pod.UID", pod.Uid)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if podNetworkNamespace != "" {
+		podHandle, err := host.GetHelpers().NewNetlinkHandle(podNetworkNamespace)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			// the kernel moved the netdev back with the namespace
+		case err != nil:
+			return err
+		default:
+			err := detachPodLink(podHandle, deviceConfig.IfName, state)
+			podHandle.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	hostHandle, err := host.GetHelpers().NewNetlinkHandle("")
+	if err != nil {
+		return err
+	}
+	defer hostHandle.Close()
+	if err := restoreHostLink(hostHandle, deviceConfig.PciAddress, state); err != nil {
+		return err
+	}
+
+	logger.V(3).Info("Detached VF netdev", "ifName", deviceConfig.IfName, "link", state.Name, "pod.UID", pod.Uid)
+	return a.removeLinkState(deviceConfig.PciAddress)
+}
+
+// CheckNetwork verifies that the VF netdev is present and up in the pod
+// network namespace.
+func (a *netlinkAttacher) CheckNetwork(_ context.Context, _ *api.PodSandbox, podNetworkNamespace string, deviceConfig *types.PreparedDevice) error {
+	handle, err := host.GetHelpers().NewNetlinkHandle(podNetworkNamespace)
+	if err != nil {
+		return err
+	}
+	defer handle.Close()
+
+	link, err := handle.LinkByName(deviceConfig.IfName)
+	if err != nil {
+		return fmt.Errorf("failed to get link %s: %v", deviceConfig.IfName, err)
+	}
+	if link.Attrs().Flags&net.FlagUp == 0 {
+		return fmt.Errorf("link %s is down", deviceConfig.IfName)
+	}
+	return nil
+}
+
+// configurePodLink renames and configures the VF netdev moved into the pod
+// network namespace and returns the resulting configuration as a CNI result.
+func configurePodLink(handle host.NetlinkHandle, link netlink.Link, podNetworkNamespace string, deviceConfig *types.PreparedDevice) (*cni100.Result, error) {
+	config := deviceConfig.Config
+	if err := handle.LinkSetName(link, deviceConfig.IfName); err != nil {
+		return nil, fmt.Errorf("failed to rename link %s to %s: %v", link.Attrs().Name, deviceConfig.IfName, err)
+	}
+	if config.MTU > 0 {
+		if err := handle.LinkSetMTU(link, config.MTU); err != nil {
+			return nil, fmt.Errorf("failed to set mtu of link %s: %v", deviceConfig.IfName, err)
+		}
+	}
+	if config.MAC != "" {
+		mac, err := net.ParseMAC(config.MAC)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mac %q: %v", config.MAC, err)
+		}
+		if err := handle.LinkSetHardwareAddr(link, mac); err != nil {
+			return nil, fmt.Errorf("failed to set mac of link %s: %v", deviceConfig.IfName, err)
+		}
+	}
+	if err := handle.LinkSetUp(link); err != nil {
+		return nil, fmt.Errorf("failed to set link %s up: %v", deviceConfig.IfName, err)
+	}
+
+	result := &cni100.Result{CNIVersion: cni100.ImplementedSpecVersion}
+	for _, address := range config.Addresses {
+		addr, err := netlink.ParseAddr(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q: %v", address, err)
+		}
+		if err := handle.AddrAdd(link, addr); err != nil {
+			return nil, fmt.Errorf("failed to add address %s to link %s: %v", address, deviceConfig.IfName, err)
+		}
+		result.IPs = append(result.IPs, &cni100.IPConfig{Interface: cni100.Int(0), Address: *addr.IPNet})
+	}
+	for _, route := range config.Routes {
+		_, dst, err := net.ParseCIDR(route.Destination)
+		if err != nil {
+			return nil, fmt.Errorf("invalid route destination %q: %v", route.Destination, err)
+		}
+		gw := net.ParseIP(route.Gateway)
+		if err := handle.RouteAdd(&netlink.Route{LinkIndex: link.Attrs().Index, Dst: dst, Gw: gw}); err != nil {
+			return nil, fmt.Errorf("failed to add route %s to link %s: %v", route.Destination, deviceConfig.IfName, err)
+		}
+		result.Routes = append(result.Routes, &cnitypes.Route{Dst: *dst, GW: gw})
+	}
+
+	// refresh the link to report the MAC in effect
+	link, err := handle.LinkByIndex(link.Attrs().Index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get link %s: %v", deviceConfig.IfName, err)
+	}
+	result.Interfaces = []*cni100.Interface{{
+		Name:    deviceConfig.IfName,
+		Mac:     link.Attrs().HardwareAddr.String(),
+		Mtu:     link.Attrs().MTU,
+		Sandbox: podNetworkNamespace,
+	}}
+	return result, nil
+}
+
+// detachPodLink moves the VF netdev from the pod network namespace back to the
+// host one. The netdev is looked up by its pod and host names, as an attach
+// may fail before renaming it. Netdevs no longer in the pod are ignored, the
+// kernel moves them back when the namespace is destroyed.
+func detachPodLink(handle host.NetlinkHandle, ifName string, state *linkState) error {
+	for _, name := range []string{ifName, state.Name} {
+		link, err := handle.LinkByName(name)
+		if errors.As(err, &netlink.LinkNotFoundError{}) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get link %s in pod network namespace: %v", name, err)
+		}
+		return releaseLink(handle, link, state)
+	}
+	return nil
+}
+
+// releaseLink renames the netdev back to its host name inside the pod network
+// namespace, so it cannot clash with a host netdev, and moves it to the
+// network namespace of the driver, which runs in the host network.
+func releaseLink(handle host.NetlinkHandle, link netlink.Link, state *linkState) error {
+	if err := handle.LinkSetDown(link); err != nil {
+		return fmt.Errorf("failed to set link %s down: %v", link.Attrs().Name, err)
+	}
+	if link.Attrs().Name != state.Name {
+		if err := handle.LinkSetName(link, state.Name); err != nil {
+			return fmt.Errorf("failed to rename link %s to %s: %v", link.Attrs().Name, state.Name, err)
+		}
+	}
+	if err := handle.LinkSetNsPid(link, os.Getpid()); err != nil {
+		return fmt.Errorf("failed to move link %s to host network namespace: %v", state.Name, err)
+	}
+	return nil
+}
+
+// restoreHostLink restores the name, MTU, MAC and state of a VF netdev back in
+// the host network namespace.
+func restoreHostLink(handle host.NetlinkHandle, pciAddress string, state *linkState) error {
+	name, err := vfNetdevName(pciAddress)
+	if err != nil {
+		return err
+	}
+	link, err := handle.LinkByName(name)
+	if err != nil {
+		return fmt.Errorf("failed to get link %s: %v", name, err)
+	}
+	if name != state.Name {
+		if err := handle.LinkSetDown(link); err != nil {
+			return fmt.Errorf("failed to set link %s down: %v", name, err)
+		}
+		if err := handle.LinkSetName(link, state.Name); err != nil {
+			return fmt.Errorf("failed to rename link %s to %s: %v", name, state.Name, err)
+		}
+	}
+	if state.MTU > 0 && link.Attrs().MTU != state.MTU {
+		if err := handle.LinkSetMTU(link, state.MTU); err != nil {
+			return fmt.Errorf("failed to restore mtu of link %s: %v", state.Name, err)
+		}
+	}
+	if state.HardwareAddr != "" && link.Attrs().HardwareAddr.String() != state.HardwareAddr {
+		mac, err := net.ParseMAC(state.HardwareAddr)
+		if err != nil {
+			return fmt.Errorf("invalid saved mac %q of link %s: %v", state.HardwareAddr, state.Name, err)
+		}
+		if err := handle.LinkSetHardwareAddr(link, mac); err != nil {
+			return fmt.Errorf("failed to restore mac of link %s: %v", state.Name, err)
+		}
+	}
+	if state.Up {
+		if err := handle.LinkSetUp(link); err != nil {
+			return fmt.Errorf("failed to set link %s up: %v", state.Name, err)
+		}
+	}
+	return nil
+}
+
+// vfNetdevName returns the name of the netdev of a VF in the host network
+// namespace.
+func vfNetdevName(pciAddress string) (string, error) {
+	names, err := host.GetHelpers().GetNetDevicesFromPci(pciAddress)
+	if err != nil {
+		return "", fmt.Errorf("failed to get netdev of device %s: %v", pciAddress, err)
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("no netdev found for device %s, netlink attach mode requires a kernel network driver", pciAddress)
+	}
+	return names[0], nil
+}
+
+func (a *netlinkAttacher) linkStatePath(pciAddress string) string {
+	return filepath.Join(a.cacheDir, netlinkStateDir, pciAddress+".json")
+}
+
+// saveLinkState stores the host state of a VF netdev in the CNI cache
+// directory so it survives driver restarts.
+func (a *netlinkAttacher) saveLinkState(pciAddress string, state *linkState) error {
+	path := a.linkStatePath(pciAddress)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create netlink state directory: %v", err)
+	}
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal link state: %v", err)
+	}
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		return fmt.Errorf("failed to write link state of device %s: %v", pciAddress, err)
+	}
+	return nil
+}
+
+func (a *netlinkAttacher) loadLinkState(pciAddress string) (*linkState, error) {
+	raw, err := os.ReadFile(a.linkStatePath(pciAddress))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read link state of device %s: %w", pciAddress, err)
+	}
+	state := &linkState{}
+	if err := json.Unmarshal(raw, state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal link state of device %s: %v", pciAddress, err)
+	}
+	return state, nil
+}
+
+func (a *netlinkAttacher) removeLinkState(pciAddress string) error {
+	if err := os.Remove(a.linkStatePath(pciAddress)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove link state of device %s: %v", pciAddress, err)
+	}
+	return nil
+}
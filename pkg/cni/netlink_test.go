@@ -0,0 +1,205 @@
+package cni
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/containerd/nri/pkg/api"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/vishvananda/netlink"
+	"go.uber.org/mock/gomock"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	mock_host "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host/mock"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("Netlink attacher", func() {
+	const (
+		podNetNS = "/proc/12345/ns/net"
+		hostName = "ens1f0v1"
+	)
+
+	var (
+		ctx         context.Context
+		mockHost    *mock_host.MockInterface
+		hostHandle  *mock_host.MockNetlinkHandle
+		podHandle   *mock_host.MockNetlinkHandle
+		origHelpers host.Interface
+		attacher    *netlinkAttacher
+		pod         *api.PodSandbox
+		device      *types.PreparedDevice
+		mac         net.HardwareAddr
+		hostLink    *netlink.Device
+		podLink     *netlink.Device
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		mockCtrl := gomock.NewController(GinkgoT())
+		mockHost = mock_host.NewMockInterface(mockCtrl)
+		hostHandle = mock_host.NewMockNetlinkHandle(mockCtrl)
+		podHandle = mock_host.NewMockNetlinkHandle(mockCtrl)
+		_ = host.GetHelpers()
+		origHelpers = host.Helpers
+		host.Helpers = mockHost
+
+		mockHost.EXPECT().NewNetlinkHandle("").Return(hostHandle, nil).AnyTimes()
+		mockHost.EXPECT().NewNetlinkHandle(podNetNS).Return(podHandle, nil).AnyTimes()
+		mockHost.EXPECT().GetNetDevicesFromPci("0000:01:00.1").Return([]string{hostName}, nil).AnyTimes()
+		hostHandle.EXPECT().Close().AnyTimes()
+		podHandle.EXPECT().Close().AnyTimes()
+		podHandle.EXPECT().NsFd().Return(42).AnyTimes()
+
+		attacher = newNetlinkAttacher(GinkgoT().TempDir())
+		pod = &api.PodSandbox{Id: "sandbox-id", Uid: "pod-uid"}
+		device = &types.PreparedDevice{
+			IfName:     "net1",
+			PciAddress: "0000:01:00.1",
+			Config: &configapi.VfConfig{
+				AttachMode: configapi.AttachModeNetlink,
+				MTU:        9000,
+				Addresses:  []string{"192.168.1.10/24"},
+			},
+		}
+		var err error
+		mac, err = net.ParseMAC("aa:bb:cc:dd:ee:01")
+		Expect(err).NotTo(HaveOccurred())
+		hostLink = &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: hostName, Index: 7, MTU: 1500, HardwareAddr: mac, Flags: net.FlagUp}}
+		podLink = &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "net1", Index: 7, MTU: 9000, HardwareAddr: mac}}
+	})
+
+	AfterEach(func() {
+		host.Helpers = origHelpers
+	})
+
+	Context("AttachNetwork", func() {
+		It("moves the netdev into the pod and configures it", func() {
+			movedLink := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: hostName, Index: 7, MTU: 1500, HardwareAddr: mac}}
+			hostHandle.EXPECT().LinkByName(hostName).Return(hostLink, nil)
+			hostHandle.EXPECT().LinkSetDown(hostLink).Return(nil)
+			hostHandle.EXPECT().LinkSetNsFd(hostLink, 42).Return(nil)
+			podHandle.EXPECT().LinkByName(hostName).Return(movedLink, nil)
+			podHandle.EXPECT().LinkSetName(movedLink, "net1").Return(nil)
+			podHandle.EXPECT().LinkSetMTU(movedLink, 9000).Return(nil)
+			podHandle.EXPECT().LinkSetUp(movedLink).Return(nil)
+			podHandle.EXPECT().AddrAdd(movedLink, gomock.Any()).Return(nil)
+			podHandle.EXPECT().LinkByIndex(7).Return(podLink, nil)
+
+			data, _, err := attacher.AttachNetwork(ctx, pod, podNetNS, device)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data.InterfaceName).To(Equal("net1"))
+			Expect(data.HardwareAddress).To(Equal("aa:bb:cc:dd:ee:01"))
+
+			state, err := attacher.loadLinkState(device.PciAddress)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state).To(Equal(&linkState{Name: hostName, MTU: 1500, HardwareAddr: "aa:bb:cc:dd:ee:01", Up: true}))
+		})
+
+		It("restores the host netdev when it cannot be moved into the pod", func() {
+			hostHandle.EXPECT().LinkByName(hostName).Return(hostLink, nil).Times(2)
+			hostHandle.EXPECT().LinkSetDown(hostLink).Return(nil)
+			hostHandle.EXPECT().LinkSetNsFd(hostLink, 42).Return(errors.New("operation not permitted"))
+			hostHandle.EXPECT().LinkSetUp(hostLink).Return(nil)
+
+			_, _, err := attacher.AttachNetwork(ctx, pod, podNetNS, device)
+			Expect(err).To(MatchError(ContainSubstring("operation not permitted")))
+
+			_, err = attacher.loadLinkState(device.PciAddress)
+			Expect(err).To(MatchError(os.ErrNotExist))
+		})
+
+		It("moves the netdev back when it cannot be configured", func() {
+			movedLink := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: hostName, Index: 7, MTU: 1500, HardwareAddr: mac}}
+			hostHandle.EXPECT().LinkByName(hostName).Return(hostLink, nil).Times(2)
+			hostHandle.EXPECT().LinkSetDown(hostLink).Return(nil)
+			hostHandle.EXPECT().LinkSetNsFd(hostLink, 42).Return(nil)
+			podHandle.EXPECT().LinkByName(hostName).Return(movedLink, nil)
+			podHandle.EXPECT().LinkSetName(movedLink, "net1").Return(nil)
+			podHandle.EXPECT().LinkSetMTU(movedLink, 9000).Return(nil)
+			podHandle.EXPECT().LinkSetUp(movedLink).Return(nil)
+			podHandle.EXPECT().AddrAdd(movedLink, gomock.Any()).Return(errors.New("file exists"))
+			podHandle.EXPECT().LinkByName("net1").Return(podLink, nil)
+			podHandle.EXPECT().LinkSetDown(podLink).Return(nil)
+			podHandle.EXPECT().LinkSetName(podLink, hostName).Return(nil)
+			podHandle.EXPECT().LinkSetNsPid(podLink, os.Getpid()).Return(nil)
+			hostHandle.EXPECT().LinkSetUp(hostLink).Return(nil)
+
+			_, _, err := attacher.AttachNetwork(ctx, pod, podNetNS, device)
+			Expect(err).To(MatchError(ContainSubstring("file exists")))
+
+			_, err = attacher.loadLinkState(device.PciAddress)
+			Expect(err).To(MatchError(os.ErrNotExist))
+		})
+
+		It("keeps the saved state when the netdev cannot be moved back", func() {
+			hostHandle.EXPECT().LinkByName(hostName).Return(hostLink, nil)
+			hostHandle.EXPECT().LinkSetDown(hostLink).Return(nil)
+			hostHandle.EXPECT().LinkSetNsFd(hostLink, 42).Return(nil)
+			podHandle.EXPECT().LinkByName(hostName).Return(nil, errors.New("no such device"))
+			podHandle.EXPECT().LinkByName("net1").Return(nil, netlink.LinkNotFoundError{})
+			podHandle.EXPECT().LinkByName(hostName).Return(nil, netlink.LinkNotFoundError{})
+			hostHandle.EXPECT().LinkByName(hostName).Return(nil, errors.New("link not found"))
+
+			_, _, err := attacher.AttachNetwork(ctx, pod, podNetNS, device)
+			Expect(err).To(MatchError(ContainSubstring("no such device")))
+			Expect(err).To(MatchError(ContainSubstring("link not found")))
+
+			_, err = attacher.loadLinkState(device.PciAddress)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("DetachNetwork", func() {
+		BeforeEach(func() {
+			Expect(attacher.saveLinkState(device.PciAddress, &linkState{
+				Name: hostName, MTU: 1500, HardwareAddr: "aa:bb:cc:dd:ee:01", Up: true,
+			})).To(Succeed())
+		})
+
+		It("moves the netdev back and restores its host state", func() {
+			configured := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: hostName, Index: 7, MTU: 9000}}
+			podHandle.EXPECT().LinkByName("net1").Return(podLink, nil)
+			podHandle.EXPECT().LinkSetDown(podLink).Return(nil)
+			podHandle.EXPECT().LinkSetName(podLink, hostName).Return(nil)
+			podHandle.EXPECT().LinkSetNsPid(podLink, os.Getpid()).Return(nil)
+			hostHandle.EXPECT().LinkByName(hostName).Return(configured, nil)
+			hostHandle.EXPECT().LinkSetMTU(configured, 1500).Return(nil)
+			hostHandle.EXPECT().LinkSetHardwareAddr(configured, mac).Return(nil)
+			hostHandle.EXPECT().LinkSetUp(configured).Return(nil)
+
+			Expect(attacher.DetachNetwork(ctx, pod, podNetNS, device)).To(Succeed())
+
+			_, err := attacher.loadLinkState(device.PciAddress)
+			Expect(err).To(MatchError(os.ErrNotExist))
+		})
+
+		It("only restores the host state when the pod network namespace is gone", func() {
+			goneNetNS := "/proc/999/ns/net"
+			mockHost.EXPECT().NewNetlinkHandle(goneNetNS).Return(nil, fmt.Errorf("failed to open network namespace %s: %w", goneNetNS, os.ErrNotExist))
+			hostHandle.EXPECT().LinkByName(hostName).Return(hostLink, nil)
+			hostHandle.EXPECT().LinkSetUp(hostLink).Return(nil)
+
+			Expect(attacher.DetachNetwork(ctx, pod, goneNetNS, device)).To(Succeed())
+		})
+
+		It("ignores devices that are not attached", func() {
+			Expect(attacher.removeLinkState(device.PciAddress)).To(Succeed())
+
+			Expect(attacher.DetachNetwork(ctx, pod, podNetNS, device)).To(Succeed())
+		})
+	})
+
+	Context("CheckNetwork", func() {
+		It("reports a netdev that is down", func() {
+			podHandle.EXPECT().LinkByName("net1").Return(podLink, nil)
+
+			Expect(attacher.CheckNetwork(ctx, pod, podNetNS, device)).To(MatchError("link net1 is down"))
+		})
+	})
+})
@@ -79,7 +79,8 @@ var (
 
 // ReservedAttributes is the set of attribute keys populated by driver discovery
 // (DiscoverSriovDevices). Policy-defined DeviceAttributes must NOT override these
-// keys — any attempt will be silently skipped with a warning log.
+// keys — any attempt is skipped with a warning log and a ReservedAttributeConflict
+// event on the policy.
 var ReservedAttributes = map[resourceapi.QualifiedName]bool{
 	AttributeVendorID:           true,
 	AttributeDeviceID:           true,
@@ -97,13 +98,58 @@ var ReservedAttributes = map[resourceapi.QualifiedName]bool{
 	AttributeNUMANode:           true,
 }
 
+// Conditions reported on the allocated devices of a ResourceClaim.
+const (
+	// DeviceConditionNetworkReady reports the result of the periodic CNI CHECK.
+	DeviceConditionNetworkReady = "NetworkReady"
+
+	DeviceReasonCNICheckSucceeded = "CNICheckSucceeded"
+	DeviceReasonCNICheckFailed    = "CNICheckFailed"
+)
+
 type ConfigurationMode string
 
 const (
 	ConfigurationModeStandalone ConfigurationMode = "STANDALONE"
 	ConfigurationModeMultus     ConfigurationMode = "MULTUS"
+	// ConfigurationModeHybrid keeps NRI running and lets each claim select
+	// STANDALONE (default) or MULTUS through its VfConfig.
+	ConfigurationModeHybrid ConfigurationMode = "HYBRID"
+)
+
+// NetworkAttachMethod selects how networks are attached to pods in STANDALONE
+// mode.
+type NetworkAttachMethod string
+
+const (
+	// NetworkAttachMethodNRI attaches networks on NRI pod sandbox events.
+	NetworkAttachMethodNRI NetworkAttachMethod = "nri"
+	// NetworkAttachMethodCDIHook attaches networks from CDI createRuntime and
+	// poststop hooks, for runtimes without NRI.
+	NetworkAttachMethodCDIHook NetworkAttachMethod = "cdi-hook"
+
+	// CDIHookSocketName is the name of the socket, in the driver plugin
+	// directory, the CDI network hook sends its requests to.
+	CDIHookSocketName = "cdi-hook.sock"
 )
 
+// DriverPluginCheckpointBackupFile is the previous checkpoint, in the driver
+// plugin directory, loaded when the checkpoint is corrupted.
+const DriverPluginCheckpointBackupFile = "checkpoint-last-good.json"
+
+// Lock files, in the driver plugin directory, shared by the driver instances
+// running side by side during a rolling update. The checkpoint lock is held
+// while the checkpoint is read or written, the NRI lock by the instance whose
+// NRI plugin is registered to the runtime.
+const (
+	DriverPluginCheckpointLockFile = "checkpoint.lock"
+	NRIPluginLockFile              = "nri.lock"
+)
+
+// IntrospectionSocketName is the name of the socket, in the driver plugin
+// directory, serving the read-only introspection API.
+const IntrospectionSocketName = "introspection.sock"
+
 var Backoff = wait.Backoff{
 	Duration: 100 * time.Millisecond, // Initial delay
 	Factor:   2.0,                    // Exponential factor
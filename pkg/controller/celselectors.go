@@ -0,0 +1,91 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	resourceapi "k8s.io/api/resource/v1"
+	dracel "k8s.io/dynamic-resource-allocation/cel"
+	"k8s.io/utils/lru"
+
+	sriovdrav1alpha1 "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/sriovdra/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+)
+
+// maxCompiledExpressions bounds the number of compiled expressions cached.
+const maxCompiledExpressions = 1024
+
+// compiledExpressions caches the compilation results of the selector
+// expressions by expression, the policies are resolved on every reconcile.
+var compiledExpressions = lru.New(maxCompiledExpressions)
+
+// compileSelectors compiles the CEL expressions of selectors. Like for the
+// selectors of a DeviceClass, expressions whose estimated cost exceeds the DRA
+// limit are rejected, which is why the expressions are not taken from the
+// cache of compiled expressions of the DRA library: it skips cost estimation.
+func compileSelectors(selectors []sriovdrav1alpha1.DeviceSelector) ([]dracel.CompilationResult, error) {
+	compiled := make([]dracel.CompilationResult, 0, len(selectors))
+	for i, selector := range selectors {
+		if selector.CEL == nil {
+			return nil, fmt.Errorf("selector %d: cel is required", i)
+		}
+		result := compileExpression(selector.CEL.Expression)
+		if result.Error != nil {
+			return nil, fmt.Errorf("selector %d: %w", i, result.Error)
+		}
+		if result.MaxCost > resourceapi.CELSelectorExpressionMaxCost {
+			return nil, fmt.Errorf("selector %d: estimated cost %d exceeds the limit of %d",
+				i, result.MaxCost, resourceapi.CELSelectorExpressionMaxCost)
+		}
+		compiled = append(compiled, result)
+	}
+	return compiled, nil
+}
+
+// compileExpression compiles expression or returns its cached compilation
+// result, which includes its estimated cost.
+func compileExpression(expression string) dracel.CompilationResult {
+	if result, ok := compiledExpressions.Get(expression); ok {
+		return result.(dracel.CompilationResult)
+	}
+	result := dracel.GetCompiler(dracel.Features{}).CompileCELExpression(expression, dracel.Options{})
+	compiledExpressions.Add(expression, result)
+	return result
+}
+
+// deviceMatchesSelectors checks if a device matches all the compiled selectors.
+// The attributes of the device without a domain are in the domain of the
+// driver. Evaluation is bounded by the runtime cost limit of the selectors.
+func deviceMatchesSelectors(device resourceapi.Device, selectors []dracel.CompilationResult) (bool, error) {
+	input := dracel.Device{
+		Driver:     consts.DriverName,
+		Attributes: device.Attributes,
+		Capacity:   device.Capacity,
+	}
+	for i, selector := range selectors {
+		matches, _, err := selector.DeviceMatches(context.Background(), input)
+		if err != nil {
+			return false, fmt.Errorf("selector %d: %w", i, err)
+		}
+		if !matches {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -249,6 +249,36 @@ var _ = Describe("SriovResourcePolicyReconciler (envtest)", func() {
 		}, 5*time.Second, 200*time.Millisecond).Should(BeTrue())
 	})
 
+	It("should record the node results in the policy status", func(ctx SpecContext) {
+		// sorts after rp-with-attrs, which already advertises all devices
+		policy := &sriovdrav1alpha1.SriovResourcePolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "rp-zz-shadowed", Namespace: "dra-driver-sriov"},
+			Spec:       sriovdrav1alpha1.SriovResourcePolicySpec{Configs: []sriovdrav1alpha1.Config{{}}},
+		}
+		Expect(k8sClient.Create(ctx, policy)).To(Succeed())
+
+		Eventually(func(g Gomega) {
+			shadowed := &sriovdrav1alpha1.SriovResourcePolicy{}
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(policy), shadowed)).To(Succeed())
+			g.Expect(shadowed.Status.Nodes).To(HaveLen(1))
+			g.Expect(shadowed.Status.Nodes[0].NodeName).To(Equal("test-node"))
+			g.Expect(shadowed.Status.Nodes[0].MatchedDevices).To(BeZero())
+			g.Expect(shadowed.Status.Nodes[0].ShadowedDevices).To(Equal(int32(2)))
+			g.Expect(shadowed.Status.Nodes[0].Shadowed).To(HaveLen(2))
+			g.Expect(shadowed.Status.Nodes[0].Shadowed).To(HaveEach(HaveField("Policy", "rp-with-attrs")))
+
+			winner := &sriovdrav1alpha1.SriovResourcePolicy{}
+			g.Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "dra-driver-sriov", Name: "rp-with-attrs"}, winner)).To(Succeed())
+			g.Expect(winner.Status.Nodes).To(HaveLen(1))
+			g.Expect(winner.Status.Nodes[0].MatchedDevices).To(Equal(int32(2)))
+			g.Expect(winner.Status.Nodes[0].Matched).To(HaveLen(2))
+			g.Expect(winner.Status.Conditions).To(ContainElement(And(
+				HaveField("Type", sriovdrav1alpha1.PolicyValid),
+				HaveField("Status", metav1.ConditionTrue),
+			)))
+		}, 5*time.Second, 200*time.Millisecond).Should(Succeed())
+	})
+
 	It("should requeue when node is missing (direct Reconcile call)", func(ctx SpecContext) {
 		bogus := controller.NewSriovResourcePolicyReconciler(k8sClient, "missing-node", "dra-driver-sriov", nil)
 		result, err := bogus.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "irrelevant", Namespace: "dra-driver-sriov"}})
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	sriovdrav1alpha1 "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/sriovdra/v1alpha1"
+)
+
+// indexRange is an inclusive range of VF indices.
+type indexRange struct {
+	first, last int64
+}
+
+// indexRanges is a set of VF index ranges.
+type indexRanges []indexRange
+
+// parseIndexRanges parses comma separated VF indices and index ranges such as
+// "0-7,9".
+func parseIndexRanges(s string) (indexRanges, error) {
+	var ranges indexRanges
+	for _, part := range strings.Split(s, ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		if !isRange {
+			last = first
+		}
+		firstIndex, err := strconv.ParseInt(first, 10, 64)
+		if err != nil || firstIndex < 0 {
+			return nil, fmt.Errorf("invalid VF index %q in %q", first, s)
+		}
+		lastIndex, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || lastIndex < 0 {
+			return nil, fmt.Errorf("invalid VF index %q in %q", last, s)
+		}
+		if firstIndex > lastIndex {
+			return nil, fmt.Errorf("invalid VF index range %q in %q", part, s)
+		}
+		ranges = append(ranges, indexRange{first: firstIndex, last: lastIndex})
+	}
+	return ranges, nil
+}
+
+func (r indexRanges) contains(index int64) bool {
+	for _, ir := range r {
+		if index >= ir.first && index <= ir.last {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPfName splits a pfNames entry "name#ranges" into the PF name and its VF
+// index ranges, the ranges are nil when the entry has none.
+func splitPfName(entry string) (string, indexRanges, error) {
+	name, ranges, hasRanges := strings.Cut(entry, "#")
+	if !hasRanges {
+		return name, nil, nil
+	}
+	parsed, err := parseIndexRanges(ranges)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid pfNames entry %q: %w", entry, err)
+	}
+	return name, parsed, nil
+}
+
+// resourceFilterError returns the error of the first VF index range of filter
+// failing to parse, a filter with such a range never matches.
+func resourceFilterError(filter sriovdrav1alpha1.ResourceFilter) error {
+	for _, entry := range filter.PfNames {
+		if _, _, err := splitPfName(entry); err != nil {
+			return err
+		}
+	}
+	for _, entry := range filter.VfIndices {
+		if _, err := parseIndexRanges(entry); err != nil {
+			return fmt.Errorf("invalid vfIndices entry: %w", err)
+		}
+	}
+	return nil
+}
+
+// pfNameMatches checks if a VF with the given PF name and index matches one of
+// the pfNames entries.
+func pfNameMatches(entries []string, pfName string, vfIndex *int64) bool {
+	for _, entry := range entries {
+		name, ranges, err := splitPfName(entry)
+		if err != nil || name != pfName {
+			continue
+		}
+		if ranges == nil || (vfIndex != nil && ranges.contains(*vfIndex)) {
+			return true
+		}
+	}
+	return false
+}
+
+// vfIndexMatches checks if index is in one of the vfIndices entries.
+func vfIndexMatches(entries []string, index int64) bool {
+	for _, entry := range entries {
+		ranges, err := parseIndexRanges(entry)
+		if err == nil && ranges.contains(index) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sriovdrav1alpha1 "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/sriovdra/v1alpha1"
+)
+
+// policyResult is the outcome of a policy on the node.
+type policyResult struct {
+	// applies is true when the nodeSelector of the policy matches the node.
+	applies bool
+	matched []string
+	// shadowed and merged are the devices selected by a config taking
+	// precedence, with the policy advertising them, the policy either left
+	// or added attributes to.
+	shadowed map[string]string
+	merged   map[string]string
+	// errors are the errors of the spec of the policy, they are the same on
+	// all nodes.
+	errors []string
+	// selectorErr is the error evaluating the selectors of the policy on the
+	// devices of the node.
+	selectorErr string
+	applyErr    string
+}
+
+// policyResults are the results of the policies by name.
+type policyResults map[string]*policyResult
+
+// of returns the result of the named policy, results are not recorded in a nil
+// policyResults.
+func (p policyResults) of(name string) *policyResult {
+	if p == nil {
+		return &policyResult{}
+	}
+	result, ok := p[name]
+	if !ok {
+		result = &policyResult{}
+		p[name] = result
+	}
+	return result
+}
+
+func (p *policyResult) shadow(deviceName, policy string) {
+	if p.shadowed == nil {
+		p.shadowed = make(map[string]string)
+	}
+	p.shadowed[deviceName] = policy
+}
+
+func (p *policyResult) merge(deviceName, policy string) {
+	if p.merged == nil {
+		p.merged = make(map[string]string)
+	}
+	p.merged[deviceName] = policy
+}
+
+// nodeStatus is the entry of the node in the status of the policy. It counts
+// the devices and lists the first MaxPolicyStatusDevices of each result, the
+// SriovDraNodeState of the node lists all of them.
+func (p *policyResult) nodeStatus(nodeName string) sriovdrav1alpha1.PolicyNodeStatus {
+	var errs []string
+	for _, err := range []string{p.applyErr, p.selectorErr} {
+		if err != "" {
+			errs = append(errs, err)
+		}
+	}
+	matched := append([]string(nil), p.matched...)
+	sort.Strings(matched)
+	status := sriovdrav1alpha1.PolicyNodeStatus{
+		NodeName:        nodeName,
+		MatchedDevices:  int32(len(p.matched)),
+		ShadowedDevices: int32(len(p.shadowed)),
+		MergedDevices:   int32(len(p.merged)),
+		Shadowed:        overlappingDevices(p.shadowed),
+		Merged:          overlappingDevices(p.merged),
+		Error:           strings.Join(errs, "; "),
+	}
+	if len(matched) > sriovdrav1alpha1.MaxPolicyStatusDevices {
+		matched = matched[:sriovdrav1alpha1.MaxPolicyStatusDevices]
+	}
+	if len(matched) > 0 {
+		status.Matched = matched
+	}
+	status.Truncated = len(status.Matched) < len(p.matched) ||
+		len(status.Shadowed) < len(p.shadowed) ||
+		len(status.Merged) < len(p.merged)
+	return status
+}
+
+// overlappingDevices lists the first MaxPolicyStatusDevices overlaps by device
+// name, with the policy advertising each device.
+func overlappingDevices(overlaps map[string]string) []sriovdrav1alpha1.OverlappingDevice {
+	var devices []sriovdrav1alpha1.OverlappingDevice
+	for name, policy := range overlaps {
+		devices = append(devices, sriovdrav1alpha1.OverlappingDevice{Name: name, Policy: policy})
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].Name < devices[j].Name })
+	if len(devices) > sriovdrav1alpha1.MaxPolicyStatusDevices {
+		devices = devices[:sriovdrav1alpha1.MaxPolicyStatusDevices]
+	}
+	return devices
+}
+
+func (p *policyResult) validCondition(generation int64) metav1.Condition {
+	if len(p.errors) > 0 {
+		return metav1.Condition{
+			Type:               sriovdrav1alpha1.PolicyValid,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: generation,
+			Reason:             "InvalidSelector",
+			Message:            strings.Join(p.errors, "; "),
+		}
+	}
+	return metav1.Condition{
+		Type:               sriovdrav1alpha1.PolicyValid,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "Valid",
+	}
+}
+
+// policyStatus returns the status of policy with the result of the node. The
+// entry of the node is dropped when the policy does not apply to it, and the
+// entries of nodes for which nodeExists returns false are dropped.
+func (r *SriovResourcePolicyReconciler) policyStatus(policy *sriovdrav1alpha1.SriovResourcePolicy, result *policyResult, nodeExists func(string) bool) sriovdrav1alpha1.SriovResourcePolicyStatus {
+	status := *policy.Status.DeepCopy()
+	nodes := make([]sriovdrav1alpha1.PolicyNodeStatus, 0, len(status.Nodes)+1)
+	for _, node := range status.Nodes {
+		if node.NodeName != r.nodeName && nodeExists(node.NodeName) {
+			nodes = append(nodes, node)
+		}
+	}
+	if result.applies {
+		nodes = append(nodes, result.nodeStatus(r.nodeName))
+		sort.Slice(nodes, func(i, j int) bool { return nodes[i].NodeName < nodes[j].NodeName })
+	}
+	status.Nodes = nodes
+	meta.SetStatusCondition(&status.Conditions, result.validCondition(policy.Generation))
+	return status
+}
+
+// policyStatusBackoff is the backoff of the status updates of a policy. The
+// drivers of all nodes update the same policies and reconcile together when a
+// policy changes, so it retries longer than retry.DefaultRetry, with jitter to
+// spread the drivers.
+var policyStatusBackoff = wait.Backoff{
+	Steps:    8,
+	Duration: 50 * time.Millisecond,
+	Factor:   2,
+	Jitter:   1,
+	Cap:      2 * time.Second,
+}
+
+// policyStatusRequeueDelay is the delay before reconciling again when the
+// status of a policy still conflicts once policyStatusBackoff is exhausted.
+const policyStatusRequeueDelay = 10 * time.Second
+
+// updatePolicyStatus records the result of the node in the status of policy.
+// The drivers of all nodes update the same policies, each one only writes the
+// entry of its node, drops the entries of deleted nodes and retries on
+// conflicts with the latest policy. It returns the conflict error when the
+// retries run out.
+func (r *SriovResourcePolicyReconciler) updatePolicyStatus(ctx context.Context, policy *sriovdrav1alpha1.SriovResourcePolicy, result *policyResult, nodeExists func(string) bool) error {
+	if equality.Semantic.DeepEqual(r.policyStatus(policy, result, nodeExists), policy.Status) {
+		return nil
+	}
+	latest := policy.DeepCopy()
+	first := true
+	return retry.RetryOnConflict(policyStatusBackoff, func() error {
+		if !first {
+			if err := r.Get(ctx, client.ObjectKeyFromObject(policy), latest); err != nil {
+				return err
+			}
+		}
+		first = false
+		status := r.policyStatus(latest, result, nodeExists)
+		if equality.Semantic.DeepEqual(status, latest.Status) {
+			return nil
+		}
+		latest.Status = status
+		return r.Status().Update(ctx, latest)
+	})
+}
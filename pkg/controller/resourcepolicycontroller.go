@@ -18,7 +18,12 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -27,9 +32,13 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -40,6 +49,8 @@ import (
 	sriovdrav1alpha1 "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/sriovdra/v1alpha1"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/devicestate"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/events"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 )
 
 const (
@@ -53,6 +64,15 @@ type SriovResourcePolicyReconciler struct {
 	namespace          string
 	log                klog.Logger
 	deviceStateManager devicestate.DeviceState
+	recorder           record.EventRecorder
+	// nodeReader lists the nodes, from the cache of the manager once the
+	// controller is set up.
+	nodeReader client.Reader
+
+	// matches records the policy and DeviceAttributes each advertised
+	// device got its attributes from in the last reconcile.
+	mu      sync.Mutex
+	matches map[string]drasriovtypes.DevicePolicyMatch
 }
 
 // NewSriovResourcePolicyReconciler creates a new SriovResourcePolicyReconciler
@@ -62,10 +82,17 @@ func NewSriovResourcePolicyReconciler(client client.Client, nodeName, namespace
 		deviceStateManager: deviceStateManager,
 		nodeName:           nodeName,
 		namespace:          namespace,
+		nodeReader:         client,
 		log:                klog.Background().WithName("SriovResourcePolicy"),
 	}
 }
 
+// SetEventRecorder sets the recorder of the Warning events reporting policies
+// the driver cannot apply.
+func (r *SriovResourcePolicyReconciler) SetEventRecorder(recorder record.EventRecorder) {
+	r.recorder = recorder
+}
+
 // Reconcile handles reconciliation of SriovResourcePolicy and DeviceAttributes resources.
 // It builds the full picture of which devices to advertise and which attributes to apply.
 func (r *SriovResourcePolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -98,22 +125,82 @@ func (r *SriovResourcePolicyReconciler) Reconcile(ctx context.Context, req ctrl.
 
 	// Find matching resource policies for this node
 	var matchingPolicies []*sriovdrav1alpha1.SriovResourcePolicy
+	results := make(policyResults, len(resourcePolicyList.Items))
 	for i := range resourcePolicyList.Items {
 		policy := &resourcePolicyList.Items[i]
-		if r.matchesNodeSelector(node.Labels, policy.Spec.NodeSelector) {
+		result := results.of(policy.Name)
+		if err := nodeSelectorError(policy.Spec.NodeSelector); err != nil {
+			events.Warning(r.recorder, events.ReasonInvalidSelector,
+				fmt.Sprintf("invalid nodeSelector: %v", err), events.PolicyReference(policy))
+		}
+		result.errors = policyErrors(policy)
+		if matchesNodeSelector(node.Labels, policy.Spec.NodeSelector) {
 			matchingPolicies = append(matchingPolicies, policy)
+			result.applies = true
 		}
 	}
 
-	policyDevices := r.getPolicyDeviceMap(matchingPolicies, deviceAttrList.Items)
-	if err := r.deviceStateManager.UpdatePolicyDevices(ctx, policyDevices); err != nil {
-		r.log.Error(err, "Failed to update policy devices")
-		return ctrl.Result{}, err
+	allocatableDevices := r.deviceStateManager.GetAllocatableDevices()
+	if len(matchingPolicies) == 0 {
+		r.log.Info("No matching SriovResourcePolicy found for node", "nodeName", r.nodeName)
+	}
+	policyDevices, matches := resolvePolicies(r.log, r.recorder, r.nodeName, matchingPolicies, deviceAttrList.Items, allocatableDevices, results)
+	r.log.Info("Policy devices resolved",
+		"matchingDevices", len(policyDevices),
+		"totalDevices", len(allocatableDevices))
+	r.mu.Lock()
+	r.matches = matches
+	r.mu.Unlock()
+	updateErr := r.deviceStateManager.UpdatePolicyDevices(ctx, policyDevices)
+	if updateErr != nil {
+		r.log.Error(updateErr, "Failed to update policy devices")
+		for _, policy := range matchingPolicies {
+			events.Warning(r.recorder, events.ReasonPolicyUpdateFailed,
+				fmt.Sprintf("failed to apply policy on node %s: %v", r.nodeName, updateErr), events.PolicyReference(policy))
+			results.of(policy.Name).applyErr = updateErr.Error()
+		}
+	}
+
+	var statusErrs []error
+	statusConflict := false
+	nodeExists := r.nodeExists(ctx)
+	for i := range resourcePolicyList.Items {
+		policy := &resourcePolicyList.Items[i]
+		if err := r.updatePolicyStatus(ctx, policy, results.of(policy.Name), nodeExists); err != nil {
+			if apierrors.IsConflict(err) {
+				r.log.Info("SriovResourcePolicy status still conflicts, requeueing", "policyName", policy.Name)
+				statusConflict = true
+				continue
+			}
+			r.log.Error(err, "Failed to update SriovResourcePolicy status", "policyName", policy.Name)
+			statusErrs = append(statusErrs, err)
+		}
+	}
+	if updateErr != nil {
+		return ctrl.Result{}, updateErr
+	}
+	if len(statusErrs) > 0 {
+		return ctrl.Result{}, errors.Join(statusErrs...)
+	}
+	if statusConflict {
+		return ctrl.Result{RequeueAfter: wait.Jitter(policyStatusRequeueDelay, 1)}, nil
 	}
 
 	return ctrl.Result{}, nil
 }
 
+// DevicePolicyMatches returns the policy and DeviceAttributes each advertised
+// device got its attributes from, keyed by device name.
+func (r *SriovResourcePolicyReconciler) DevicePolicyMatches() map[string]drasriovtypes.DevicePolicyMatch {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	matches := make(map[string]drasriovtypes.DevicePolicyMatch, len(r.matches))
+	for name, match := range r.matches {
+		matches[name] = match
+	}
+	return matches
+}
+
 // getPolicyDeviceMap builds the full map of device name -> attributes for all
 // devices matched by the given policies. DeviceAttributes are resolved via
 // each config's DeviceAttributesSelector.
@@ -121,72 +208,245 @@ func (r *SriovResourcePolicyReconciler) getPolicyDeviceMap(
 	policies []*sriovdrav1alpha1.SriovResourcePolicy,
 	allDeviceAttrs []sriovdrav1alpha1.DeviceAttributes,
 ) map[string]map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
-	policyDevices := make(map[string]map[resourceapi.QualifiedName]resourceapi.DeviceAttribute)
+	policyDevices, _ := resolvePolicies(r.log, r.recorder, r.nodeName, policies, allDeviceAttrs, r.deviceStateManager.GetAllocatableDevices(), nil)
+	return policyDevices
+}
 
-	if len(policies) == 0 {
-		r.log.Info("No matching SriovResourcePolicy found for node", "nodeName", r.nodeName)
-		return policyDevices
+// MatchDevicePolicies returns the policy and DeviceAttributes selecting each
+// of devices on a node with the given labels, as the node plugin resolves
+// them. Devices selected by no policy are left out. devices must only hold
+// the attributes discovered by the driver, the policies select devices by
+// these attributes alone.
+func MatchDevicePolicies(
+	logger klog.Logger,
+	nodeLabels map[string]string,
+	policies []sriovdrav1alpha1.SriovResourcePolicy,
+	allDeviceAttrs []sriovdrav1alpha1.DeviceAttributes,
+	devices drasriovtypes.AllocatableDevices,
+) map[string]drasriovtypes.DevicePolicyMatch {
+	var matchingPolicies []*sriovdrav1alpha1.SriovResourcePolicy
+	for i := range policies {
+		if matchesNodeSelector(nodeLabels, policies[i].Spec.NodeSelector) {
+			matchingPolicies = append(matchingPolicies, &policies[i])
+		}
 	}
+	_, matches := resolvePolicies(logger, nil, "", matchingPolicies, allDeviceAttrs, devices, nil)
+	return matches
+}
 
-	allocatableDevices := r.deviceStateManager.GetAllocatableDevices()
+// resolvePolicies builds the policy device map of allocatableDevices and
+// records the policy matching each device and the DeviceAttributes of each of
+// its attributes. It also records the devices each policy matched, lost to or
+// merged into a config taking precedence, and its invalid selectors, in
+// results when not nil, and reports them with Warning events on recorder when
+// not nil. Policies are applied by precedence, see sortPolicies: the first
+// config selecting a device advertises it, the following ones are ignored
+// unless their policy is in Merge overlap mode.
+func resolvePolicies(
+	logger klog.Logger,
+	recorder record.EventRecorder,
+	nodeName string,
+	policies []*sriovdrav1alpha1.SriovResourcePolicy,
+	allDeviceAttrs []sriovdrav1alpha1.DeviceAttributes,
+	allocatableDevices drasriovtypes.AllocatableDevices,
+	results policyResults,
+) (map[string]map[resourceapi.QualifiedName]resourceapi.DeviceAttribute, map[string]drasriovtypes.DevicePolicyMatch) {
+	policyDevices := make(map[string]map[resourceapi.QualifiedName]resourceapi.DeviceAttribute)
+	matches := make(map[string]drasriovtypes.DevicePolicyMatch)
 
-	sort.Slice(policies, func(i, j int) bool {
-		return policies[i].Name < policies[j].Name
-	})
+	sortPolicies(policies)
 	for _, policy := range policies {
-		r.log.V(2).Info("Processing policy",
+		logger.V(2).Info("Processing policy",
 			"policyName", policy.Name,
+			"priority", policy.Spec.Priority,
+			"overlapMode", policy.Spec.OverlapMode,
 			"configCount", len(policy.Spec.Configs),
 			"totalDevices", len(allocatableDevices))
+		result := results.of(policy.Name)
+		merge := policy.Spec.OverlapMode == sriovdrav1alpha1.OverlapModeMerge
 
 		for _, config := range policy.Spec.Configs {
-			resolvedAttrs := r.resolveDeviceAttributes(config.DeviceAttributesSelector, allDeviceAttrs)
+			resolvedAttrs, attrSources, err := resolveDeviceAttributes(config.DeviceAttributesSelector, allDeviceAttrs)
+			if err != nil {
+				logger.Error(err, "Invalid DeviceAttributesSelector", "policyName", policy.Name)
+				events.Warning(recorder, events.ReasonInvalidSelector,
+					fmt.Sprintf("invalid deviceAttributesSelector: %v", err), events.PolicyReference(policy))
+			}
+			for _, filter := range config.ResourceFilters {
+				if err := resourceFilterError(filter); err != nil {
+					events.Warning(recorder, events.ReasonInvalidSelector,
+						fmt.Sprintf("invalid resourceFilters: %v", err), events.PolicyReference(policy))
+				}
+			}
+			if reserved := reservedAttributeKeys(resolvedAttrs); len(reserved) > 0 {
+				events.Warning(recorder, events.ReasonReservedAttributeConflict,
+					fmt.Sprintf("attributes %s collide with attributes discovered by the driver and are ignored", strings.Join(reserved, ", ")),
+					events.PolicyReference(policy))
+			}
+			selectors, err := compileSelectors(config.Selectors)
+			if err != nil {
+				events.Warning(recorder, events.ReasonInvalidSelector,
+					fmt.Sprintf("invalid selectors: %v", err), events.PolicyReference(policy))
+				continue
+			}
 
+			// the error of the first device by name, so that the reported
+			// error does not change between reconciles
+			var selectorErrDevice string
+			var selectorErr error
 			for deviceName, device := range allocatableDevices {
-				if _, exists := policyDevices[deviceName]; exists {
+				if !deviceMatchesFilters(device, config.ResourceFilters) {
+					continue
+				}
+				if selected, err := deviceMatchesSelectors(device, selectors); !selected {
+					if err != nil && (selectorErr == nil || deviceName < selectorErrDevice) {
+						selectorErrDevice, selectorErr = deviceName, err
+					}
 					continue
 				}
 
-				if r.deviceMatchesFilters(device, config.ResourceFilters) {
+				owner, exists := matches[deviceName]
+				if !exists {
 					attrs := make(map[resourceapi.QualifiedName]resourceapi.DeviceAttribute, len(resolvedAttrs))
 					for k, v := range resolvedAttrs {
 						attrs[k] = v
 					}
 					policyDevices[deviceName] = attrs
-					r.log.V(2).Info("Device matches config filter",
+					matches[deviceName] = drasriovtypes.DevicePolicyMatch{Policy: policy.Name, AttributeSources: attrSources}
+					result.matched = append(result.matched, deviceName)
+					logger.V(2).Info("Device matches config filter",
 						"deviceName", deviceName,
 						"policyName", policy.Name,
 						"device", device,
 						"attributes", attrs)
+					continue
+				}
+
+				if !merge {
+					result.shadow(deviceName, owner.Policy)
+					continue
+				}
+				matches[deviceName] = mergeDeviceAttributes(policyDevices[deviceName], owner, policy.Name, resolvedAttrs, attrSources)
+				if owner.Policy != policy.Name {
+					result.merge(deviceName, owner.Policy)
+				}
+				logger.V(2).Info("Device attributes merged",
+					"deviceName", deviceName,
+					"policyName", policy.Name,
+					"advertisingPolicy", owner.Policy)
+			}
+			if selectorErr != nil {
+				message := fmt.Sprintf("selectors failed on device %s: %v", selectorErrDevice, selectorErr)
+				events.Warning(recorder, events.ReasonInvalidSelector, message, events.PolicyReference(policy))
+				if result.selectorErr == "" {
+					result.selectorErr = message
 				}
 			}
 		}
+		reportOverlaps(recorder, nodeName, policy, result)
 	}
 
-	r.log.Info("Policy devices resolved",
-		"matchingDevices", len(policyDevices),
-		"totalDevices", len(allocatableDevices))
-	r.log.V(2).Info("Policy devices details", "policyDevices", policyDevices)
+	logger.V(2).Info("Policy devices details", "policyDevices", policyDevices)
 
-	return policyDevices
+	return policyDevices, matches
+}
+
+// sortPolicies orders policies by precedence: highest priority first, then by
+// name.
+func sortPolicies(policies []*sriovdrav1alpha1.SriovResourcePolicy) {
+	sort.Slice(policies, func(i, j int) bool {
+		if policies[i].Spec.Priority != policies[j].Spec.Priority {
+			return policies[i].Spec.Priority > policies[j].Spec.Priority
+		}
+		return policies[i].Name < policies[j].Name
+	})
+}
+
+// mergeDeviceAttributes adds the attributes of a config of policy in Merge
+// overlap mode to the attributes of a device matched earlier by owner. The
+// attributes already set are kept.
+func mergeDeviceAttributes(
+	attrs map[resourceapi.QualifiedName]resourceapi.DeviceAttribute,
+	owner drasriovtypes.DevicePolicyMatch,
+	policy string,
+	resolvedAttrs map[resourceapi.QualifiedName]resourceapi.DeviceAttribute,
+	attrSources map[resourceapi.QualifiedName]string,
+) drasriovtypes.DevicePolicyMatch {
+	sources := make(map[resourceapi.QualifiedName]string, len(owner.AttributeSources)+len(attrSources))
+	for k, v := range owner.AttributeSources {
+		sources[k] = v
+	}
+	for k, v := range resolvedAttrs {
+		if _, exists := attrs[k]; exists {
+			continue
+		}
+		attrs[k] = v
+		sources[k] = attrSources[k]
+	}
+	owner.AttributeSources = sources
+	if policy != owner.Policy && !stringSliceContains(owner.MergedPolicies, policy) {
+		owner.MergedPolicies = append(append([]string(nil), owner.MergedPolicies...), policy)
+	}
+	return owner
+}
+
+// reportOverlaps warns about the devices policy selects on node nodeName that
+// keep the attributes of another policy taking precedence. The devices a
+// policy in Merge overlap mode adds attributes to, and the devices left to an
+// earlier config of the same policy, are expected and only recorded in the
+// status of the policy.
+func reportOverlaps(recorder record.EventRecorder, nodeName string, policy *sriovdrav1alpha1.SriovResourcePolicy, result *policyResult) {
+	shadowed := make(map[string]string, len(result.shadowed))
+	for device, owner := range result.shadowed {
+		if owner != policy.Name {
+			shadowed[device] = owner
+		}
+	}
+	if len(shadowed) > 0 {
+		events.Warning(recorder, events.ReasonPolicyOverlap,
+			fmt.Sprintf("%d devices on node %s keep the attributes of policies taking precedence and ignore this policy: %s",
+				len(shadowed), nodeName, overlapSummary(shadowed)),
+			events.PolicyReference(policy))
+	}
+}
+
+// maxOverlapDevices is the number of devices listed in an overlap event.
+const maxOverlapDevices = 5
+
+// overlapSummary lists the first devices of overlaps with their policy.
+func overlapSummary(overlaps map[string]string) string {
+	devices := make([]string, 0, len(overlaps))
+	for name := range overlaps {
+		devices = append(devices, name)
+	}
+	sort.Strings(devices)
+	var summary []string
+	for i, name := range devices {
+		if i == maxOverlapDevices {
+			summary = append(summary, "...")
+			break
+		}
+		summary = append(summary, fmt.Sprintf("%s (%s)", name, overlaps[name]))
+	}
+	return strings.Join(summary, ", ")
 }
 
 // resolveDeviceAttributes finds all DeviceAttributes objects matching the
 // given label selector and merges their attributes. When multiple objects
 // match and define the same key, the value from the alphabetically last
-// object name wins (deterministic).
-func (r *SriovResourcePolicyReconciler) resolveDeviceAttributes(
+// object name wins (deterministic). It also returns the name of the object
+// each attribute comes from, and the error of an invalid selector.
+func resolveDeviceAttributes(
 	selector *metav1.LabelSelector,
 	allDeviceAttrs []sriovdrav1alpha1.DeviceAttributes,
-) map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
+) (map[resourceapi.QualifiedName]resourceapi.DeviceAttribute, map[resourceapi.QualifiedName]string, error) {
 	if selector == nil {
-		return nil
+		return nil, nil, nil
 	}
 
 	sel, err := metav1.LabelSelectorAsSelector(selector)
 	if err != nil {
-		r.log.Error(err, "Invalid DeviceAttributesSelector")
-		return nil
+		return nil, nil, err
 	}
 
 	// Collect matching DeviceAttributes, sort by name for determinism
@@ -202,18 +462,21 @@ func (r *SriovResourcePolicyReconciler) resolveDeviceAttributes(
 	})
 
 	merged := make(map[resourceapi.QualifiedName]resourceapi.DeviceAttribute)
+	sources := make(map[resourceapi.QualifiedName]string)
 	for _, da := range matched {
 		for key, val := range da.Spec.Attributes {
 			merged[key] = val
+			sources[key] = da.Name
 		}
 	}
 
-	return merged
+	return merged, sources, nil
 }
 
 // matchesNodeSelector checks if node labels match the given NodeSelector.
-// A nil selector matches all nodes.
-func (r *SriovResourcePolicyReconciler) matchesNodeSelector(nodeLabels map[string]string, nodeSelector *corev1.NodeSelector) bool {
+// A nil selector matches all nodes, the terms that cannot be parsed match
+// none, see nodeSelectorError.
+func matchesNodeSelector(nodeLabels map[string]string, nodeSelector *corev1.NodeSelector) bool {
 	if nodeSelector == nil || len(nodeSelector.NodeSelectorTerms) == 0 {
 		return true
 	}
@@ -223,7 +486,6 @@ func (r *SriovResourcePolicyReconciler) matchesNodeSelector(nodeLabels map[strin
 		ls := nodeSelectorTermToLabelSelector(term)
 		selector, err := metav1.LabelSelectorAsSelector(ls)
 		if err != nil {
-			r.log.V(2).Info("Failed to parse NodeSelectorTerm", "error", err)
 			continue
 		}
 		if selector.Matches(labels.Set(nodeLabels)) {
@@ -233,6 +495,83 @@ func (r *SriovResourcePolicyReconciler) matchesNodeSelector(nodeLabels map[strin
 	return false
 }
 
+// policyErrors returns the errors of the selectors and filters of policy that
+// cannot be parsed. They only depend on the spec, unlike the devices the
+// policy selects on each node.
+func policyErrors(policy *sriovdrav1alpha1.SriovResourcePolicy) []string {
+	var errs []string
+	if err := nodeSelectorError(policy.Spec.NodeSelector); err != nil {
+		errs = append(errs, fmt.Sprintf("invalid nodeSelector: %v", err))
+	}
+	for _, config := range policy.Spec.Configs {
+		if config.DeviceAttributesSelector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(config.DeviceAttributesSelector); err != nil {
+				errs = append(errs, fmt.Sprintf("invalid deviceAttributesSelector: %v", err))
+			}
+		}
+		for _, filter := range config.ResourceFilters {
+			if err := resourceFilterError(filter); err != nil {
+				errs = append(errs, fmt.Sprintf("invalid resourceFilters: %v", err))
+			}
+		}
+		if _, err := compileSelectors(config.Selectors); err != nil {
+			errs = append(errs, fmt.Sprintf("invalid selectors: %v", err))
+		}
+	}
+	return errs
+}
+
+// nodeExists returns a function checking if a node exists. The nodes are
+// listed on the first call, all nodes are reported to exist when they cannot
+// be listed so that no status entry is dropped by mistake.
+func (r *SriovResourcePolicyReconciler) nodeExists(ctx context.Context) func(string) bool {
+	var names sets.Set[string]
+	listed := false
+	return func(name string) bool {
+		if !listed {
+			listed = true
+			nodes := &metav1.PartialObjectMetadataList{}
+			nodes.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("NodeList"))
+			if err := r.nodeReader.List(ctx, nodes); err != nil {
+				r.log.Error(err, "Failed to list nodes, keeping the status of all nodes")
+			} else {
+				names = sets.New[string]()
+				for _, node := range nodes.Items {
+					names.Insert(node.Name)
+				}
+			}
+		}
+		return names == nil || names.Has(name)
+	}
+}
+
+// nodeSelectorError returns the error of the first NodeSelectorTerm failing to
+// parse, such a term never matches.
+func nodeSelectorError(nodeSelector *corev1.NodeSelector) error {
+	if nodeSelector == nil {
+		return nil
+	}
+	for _, term := range nodeSelector.NodeSelectorTerms {
+		if _, err := metav1.LabelSelectorAsSelector(nodeSelectorTermToLabelSelector(term)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reservedAttributeKeys returns the sorted keys of attrs colliding with the
+// attributes discovered by the driver.
+func reservedAttributeKeys(attrs map[resourceapi.QualifiedName]resourceapi.DeviceAttribute) []string {
+	var reserved []string
+	for key := range attrs {
+		if consts.ReservedAttributes[key] {
+			reserved = append(reserved, string(key))
+		}
+	}
+	sort.Strings(reserved)
+	return reserved
+}
+
 func nodeSelectorTermToLabelSelector(term corev1.NodeSelectorTerm) *metav1.LabelSelector {
 	var exprs []metav1.LabelSelectorRequirement
 	for _, req := range term.MatchExpressions {
@@ -247,13 +586,13 @@ func nodeSelectorTermToLabelSelector(term corev1.NodeSelectorTerm) *metav1.Label
 
 // deviceMatchesFilters checks if a device matches any of the provided resource filters.
 // Empty filters list matches all devices.
-func (r *SriovResourcePolicyReconciler) deviceMatchesFilters(device resourceapi.Device, filters []sriovdrav1alpha1.ResourceFilter) bool {
+func deviceMatchesFilters(device resourceapi.Device, filters []sriovdrav1alpha1.ResourceFilter) bool {
 	if len(filters) == 0 {
 		return true
 	}
 
 	for _, filter := range filters {
-		if r.deviceMatchesFilter(device, filter) {
+		if deviceMatchesFilter(device, filter) {
 			return true
 		}
 	}
@@ -262,7 +601,7 @@ func (r *SriovResourcePolicyReconciler) deviceMatchesFilters(device resourceapi.
 }
 
 // deviceMatchesFilter checks if a device matches a specific resource filter
-func (r *SriovResourcePolicyReconciler) deviceMatchesFilter(device resourceapi.Device, filter sriovdrav1alpha1.ResourceFilter) bool {
+func deviceMatchesFilter(device resourceapi.Device, filter sriovdrav1alpha1.ResourceFilter) bool {
 	if len(filter.Vendors) > 0 {
 		vendorAttr, exists := device.Attributes[consts.AttributeVendorID]
 		if !exists || vendorAttr.StringValue == nil {
@@ -298,7 +637,45 @@ func (r *SriovResourcePolicyReconciler) deviceMatchesFilter(device resourceapi.D
 		if !exists || pfAttr.StringValue == nil {
 			return false
 		}
-		if !stringSliceContains(filter.PfNames, *pfAttr.StringValue) {
+		if !pfNameMatches(filter.PfNames, *pfAttr.StringValue, device.Attributes[consts.AttributeVFID].IntValue) {
+			return false
+		}
+	}
+
+	if len(filter.VfIndices) > 0 {
+		vfIDAttr, exists := device.Attributes[consts.AttributeVFID]
+		if !exists || vfIDAttr.IntValue == nil {
+			return false
+		}
+		if !vfIndexMatches(filter.VfIndices, *vfIDAttr.IntValue) {
+			return false
+		}
+	}
+
+	if len(filter.NumaNodes) > 0 {
+		numaAttr, exists := device.Attributes[consts.AttributeNUMANode]
+		if !exists || numaAttr.IntValue == nil {
+			return false
+		}
+		if !slices.Contains(filter.NumaNodes, *numaAttr.IntValue) {
+			return false
+		}
+	}
+
+	if filter.RdmaCapable != nil {
+		rdmaAttr, exists := device.Attributes[consts.AttributeRDMACapable]
+		rdmaCapable := exists && rdmaAttr.BoolValue != nil && *rdmaAttr.BoolValue
+		if rdmaCapable != *filter.RdmaCapable {
+			return false
+		}
+	}
+
+	if filter.EswitchMode != "" {
+		eswitchAttr, exists := device.Attributes[consts.AttributeEswitchMode]
+		if !exists || eswitchAttr.StringValue == nil {
+			return false
+		}
+		if !strings.EqualFold(filter.EswitchMode, *eswitchAttr.StringValue) {
 			return false
 		}
 	}
@@ -323,10 +700,8 @@ func (r *SriovResourcePolicyReconciler) deviceMatchesFilter(device resourceapi.D
 		}
 	}
 
-	// TODO: Implement driver checking if needed
-	if len(filter.Drivers) > 0 {
-		r.log.V(3).Info("Driver filtering not yet implemented", "deviceName", device.Name)
-	}
+	// TODO: Implement driver checking if needed, filter.Drivers is ignored
+	// until then
 
 	return true
 }
@@ -412,11 +787,18 @@ func (r *SriovResourcePolicyReconciler) SetupWithManager(mgr ctrl.Manager) error
 
 	nodeMetadata := &metav1.PartialObjectMetadata{}
 	nodeMetadata.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Node"))
+	// the node metadata are watched below, listing them from the cache does
+	// not reach the API server
+	r.nodeReader = mgr.GetCache()
+
+	// the drivers of all nodes write the status of the policies, only spec
+	// changes trigger a sync
+	specChanged := builder.WithPredicates(predicate.GenerationChangedPredicate{})
 
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&sriovdrav1alpha1.SriovResourcePolicy{}).
+		For(&sriovdrav1alpha1.SriovResourcePolicy{}, specChanged).
 		Watches(nodeMetadata, nodeEventHandler).
-		Watches(&sriovdrav1alpha1.SriovResourcePolicy{}, delayedEventHandler).
+		Watches(&sriovdrav1alpha1.SriovResourcePolicy{}, delayedEventHandler, specChanged).
 		Watches(&sriovdrav1alpha1.DeviceAttributes{}, delayedEventHandler).
 		WithEventFilter(namespacePredicate).
 		WatchesRawSource(source.Channel(eventChan, &handler.EnqueueRequestForObject{})).
@@ -2,16 +2,28 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	corev1 "k8s.io/api/core/v1"
 	resourceapi "k8s.io/api/resource/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	dracel "k8s.io/dynamic-resource-allocation/cel"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlclientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 
 	sriovdrav1alpha1 "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/sriovdra/v1alpha1"
 	sriovconsts "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/flags"
 	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 )
 
@@ -27,20 +39,18 @@ func (l *localFakeState) UpdatePolicyDevices(_ context.Context, _ map[string]map
 }
 
 var _ = Describe("matchesNodeSelector", func() {
-	var r *SriovResourcePolicyReconciler
 	var nodeLabels map[string]string
 
 	BeforeEach(func() {
-		r = &SriovResourcePolicyReconciler{}
 		nodeLabels = map[string]string{"role": "dpdk", "zone": "a"}
 	})
 
 	It("nil selector matches all nodes", func() {
-		Expect(r.matchesNodeSelector(nodeLabels, nil)).To(BeTrue())
+		Expect(matchesNodeSelector(nodeLabels, nil)).To(BeTrue())
 	})
 
 	It("empty terms matches all nodes", func() {
-		Expect(r.matchesNodeSelector(nodeLabels, &corev1.NodeSelector{})).To(BeTrue())
+		Expect(matchesNodeSelector(nodeLabels, &corev1.NodeSelector{})).To(BeTrue())
 	})
 
 	It("matches when label In expression is satisfied", func() {
@@ -53,7 +63,7 @@ var _ = Describe("matchesNodeSelector", func() {
 				}},
 			}},
 		}
-		Expect(r.matchesNodeSelector(nodeLabels, sel)).To(BeTrue())
+		Expect(matchesNodeSelector(nodeLabels, sel)).To(BeTrue())
 	})
 
 	It("does not match when label value differs", func() {
@@ -66,7 +76,7 @@ var _ = Describe("matchesNodeSelector", func() {
 				}},
 			}},
 		}
-		Expect(r.matchesNodeSelector(nodeLabels, sel)).To(BeFalse())
+		Expect(matchesNodeSelector(nodeLabels, sel)).To(BeFalse())
 	})
 
 	It("ORs multiple NodeSelectorTerms", func() {
@@ -80,7 +90,7 @@ var _ = Describe("matchesNodeSelector", func() {
 				}}},
 			},
 		}
-		Expect(r.matchesNodeSelector(nodeLabels, sel)).To(BeTrue())
+		Expect(matchesNodeSelector(nodeLabels, sel)).To(BeTrue())
 	})
 })
 
@@ -93,7 +103,6 @@ var _ = Describe("stringSliceContains", func() {
 
 var _ = Describe("deviceMatchesFilter", func() {
 	It("matches valid filters and rejects mismatches", func() {
-		r := &SriovResourcePolicyReconciler{}
 		vendor := "8086"
 		dev := "154c"
 		pf := "eth0"
@@ -114,7 +123,7 @@ var _ = Describe("deviceMatchesFilter", func() {
 			},
 		}
 
-		Expect(r.deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{})).To(BeTrue())
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{})).To(BeTrue())
 
 		f := sriovdrav1alpha1.ResourceFilter{
 			Vendors:        []string{"8086"},
@@ -124,39 +133,204 @@ var _ = Describe("deviceMatchesFilter", func() {
 			PfPciAddresses: []string{"0000:01:00.0"},
 			LinkType:       sriovconsts.LinkTypeEthernet,
 		}
-		Expect(r.deviceMatchesFilter(d, f)).To(BeTrue())
+		Expect(deviceMatchesFilter(d, f)).To(BeTrue())
 
-		Expect(r.deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{Vendors: []string{"1234"}})).To(BeFalse())
-		Expect(r.deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{Devices: []string{"9999"}})).To(BeFalse())
-		Expect(r.deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{PciAddresses: []string{"0000:00:00.2"}})).To(BeFalse())
-		Expect(r.deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{PfNames: []string{"eth9"}})).To(BeFalse())
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{Vendors: []string{"1234"}})).To(BeFalse())
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{Devices: []string{"9999"}})).To(BeFalse())
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{PciAddresses: []string{"0000:00:00.2"}})).To(BeFalse())
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{PfNames: []string{"eth9"}})).To(BeFalse())
 		// Test with a different parent PCI address
-		Expect(r.deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{PfPciAddresses: []string{"0000:00:ff.f"}})).To(BeFalse())
-		Expect(r.deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{LinkType: sriovconsts.LinkTypeInfiniband})).To(BeFalse())
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{PfPciAddresses: []string{"0000:00:ff.f"}})).To(BeFalse())
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{LinkType: sriovconsts.LinkTypeInfiniband})).To(BeFalse())
 
 		// Multiple filters set: all must match
-		Expect(r.deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{
 			Vendors:  []string{"8086"},
 			LinkType: sriovconsts.LinkTypeEthernet,
 		})).To(BeTrue())
-		Expect(r.deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{
 			Vendors:  []string{"8086"},
 			LinkType: sriovconsts.LinkTypeInfiniband,
 		})).To(BeFalse())
-		Expect(r.deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{
 			Vendors:  []string{"1234"},
 			LinkType: sriovconsts.LinkTypeEthernet,
 		})).To(BeFalse())
 	})
 })
 
-var _ = Describe("deviceMatchesFilters linkType", func() {
-	var r *SriovResourcePolicyReconciler
+var _ = Describe("deviceMatchesFilter extended selectors", func() {
+	var d resourceapi.Device
 
 	BeforeEach(func() {
-		r = &SriovResourcePolicyReconciler{}
+		pf := "eth0"
+		vfID := int64(5)
+		numaNode := int64(1)
+		rdma := true
+		eswitchMode := sriovconsts.EswitchModeSwitchdev
+		d = resourceapi.Device{
+			Name: "devA",
+			Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				sriovconsts.AttributePFName:      {StringValue: &pf},
+				sriovconsts.AttributeVFID:        {IntValue: &vfID},
+				sriovconsts.AttributeNUMANode:    {IntValue: &numaNode},
+				sriovconsts.AttributeRDMACapable: {BoolValue: &rdma},
+				sriovconsts.AttributeEswitchMode: {StringValue: &eswitchMode},
+			},
+		}
+	})
+
+	It("matches VF index ranges of a PF name", func() {
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{PfNames: []string{"eth0#0-7"}})).To(BeTrue())
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{PfNames: []string{"eth0#0-3,5"}})).To(BeTrue())
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{PfNames: []string{"eth0#6-7"}})).To(BeFalse())
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{PfNames: []string{"eth1#0-7"}})).To(BeFalse())
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{PfNames: []string{"eth0#6-7", "eth0#4-5"}})).To(BeTrue())
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{PfNames: []string{"eth0#7-0"}})).To(BeFalse())
+	})
+
+	It("matches VF indices", func() {
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{VfIndices: []string{"0-7"}})).To(BeTrue())
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{VfIndices: []string{"1", "5"}})).To(BeTrue())
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{VfIndices: []string{"0-4,6"}})).To(BeFalse())
+	})
+
+	It("matches NUMA node, RDMA capability and eswitch mode", func() {
+		rdma, noRdma := true, false
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{NumaNodes: []int64{0, 1}})).To(BeTrue())
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{NumaNodes: []int64{0}})).To(BeFalse())
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{RdmaCapable: &rdma})).To(BeTrue())
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{RdmaCapable: &noRdma})).To(BeFalse())
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{EswitchMode: sriovconsts.EswitchModeSwitchdev})).To(BeTrue())
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{EswitchMode: sriovconsts.EswitchModeLegacy})).To(BeFalse())
+	})
+
+	It("treats missing attributes as not matching, and as not RDMA capable", func() {
+		d.Attributes = nil
+		noRdma := false
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{VfIndices: []string{"0-7"}})).To(BeFalse())
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{NumaNodes: []int64{0}})).To(BeFalse())
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{EswitchMode: sriovconsts.EswitchModeLegacy})).To(BeFalse())
+		Expect(deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{RdmaCapable: &noRdma})).To(BeTrue())
+	})
+
+	It("reports invalid VF index ranges", func() {
+		Expect(resourceFilterError(sriovdrav1alpha1.ResourceFilter{PfNames: []string{"eth0", "eth1#0-7,9"}, VfIndices: []string{"3"}})).To(Succeed())
+		Expect(resourceFilterError(sriovdrav1alpha1.ResourceFilter{PfNames: []string{"eth0#7-0"}})).To(MatchError(ContainSubstring("eth0#7-0")))
+		Expect(resourceFilterError(sriovdrav1alpha1.ResourceFilter{VfIndices: []string{"1-"}})).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("CEL selectors", func() {
+	var d resourceapi.Device
+
+	BeforeEach(func() {
+		vendor := "8086"
+		vfID := int64(3)
+		d = resourceapi.Device{
+			Name: "devA",
+			Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				sriovconsts.AttributeVendorID: {StringValue: &vendor},
+				sriovconsts.AttributeVFID:     {IntValue: &vfID},
+			},
+		}
+	})
+
+	selectors := func(expressions ...string) []sriovdrav1alpha1.DeviceSelector {
+		var selectors []sriovdrav1alpha1.DeviceSelector
+		for _, expression := range expressions {
+			selectors = append(selectors, sriovdrav1alpha1.DeviceSelector{
+				CEL: &sriovdrav1alpha1.CELDeviceSelector{Expression: expression},
+			})
+		}
+		return selectors
+	}
+
+	It("matches devices matching all the expressions", func() {
+		compiled, err := compileSelectors(selectors(
+			`device.attributes["sriovnetwork.k8snetworkplumbingwg.io"].vendor == "8086"`,
+			`device.attributes["sriovnetwork.k8snetworkplumbingwg.io"].vfID < 4`,
+		))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deviceMatchesSelectors(d, compiled)).To(BeTrue())
+
+		compiled, err = compileSelectors(selectors(
+			`device.attributes["sriovnetwork.k8snetworkplumbingwg.io"].vendor == "8086"`,
+			`device.attributes["sriovnetwork.k8snetworkplumbingwg.io"].vfID >= 4`,
+		))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deviceMatchesSelectors(d, compiled)).To(BeFalse())
+	})
+
+	It("reports compilation errors", func() {
+		_, err := compileSelectors(selectors(`device.attributes[`))
+		Expect(err).To(MatchError(HavePrefix("selector 0:")))
+		_, err = compileSelectors(selectors(`device.driver`))
+		Expect(err).To(HaveOccurred())
 	})
 
+	It("rejects expressions exceeding the cost limit also once compiled", func() {
+		expensive := `device.attributes["a"].all(w, device.attributes["a"].all(x, ` +
+			`device.attributes["a"].all(y, device.attributes["a"].all(z, w == z))))`
+		for range 2 {
+			_, err := compileSelectors(selectors(expensive))
+			Expect(err).To(MatchError(ContainSubstring("exceeds the limit")))
+		}
+	})
+
+	It("reuses the compiled expressions", func() {
+		expression := `device.attributes["sriovnetwork.k8snetworkplumbingwg.io"].vfID == 1`
+		compiled, err := compileSelectors(selectors(expression))
+		Expect(err).ToNot(HaveOccurred())
+		cached, ok := compiledExpressions.Get(expression)
+		Expect(ok).To(BeTrue())
+		Expect(cached.(dracel.CompilationResult).Program).To(BeIdenticalTo(compiled[0].Program))
+	})
+
+	It("reports evaluation errors", func() {
+		compiled, err := compileSelectors(selectors(`device.attributes["sriovnetwork.k8snetworkplumbingwg.io"].missing == "x"`))
+		Expect(err).ToNot(HaveOccurred())
+		matches, err := deviceMatchesSelectors(d, compiled)
+		Expect(err).To(HaveOccurred())
+		Expect(matches).To(BeFalse())
+	})
+
+	It("records selector errors on the policy and selects no devices", func() {
+		alloc := drasriovtypes.AllocatableDevices{"devA": d}
+		r := &SriovResourcePolicyReconciler{deviceStateManager: &localFakeState{alloc: alloc}}
+		policies := []*sriovdrav1alpha1.SriovResourcePolicy{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "p1"},
+				Spec: sriovdrav1alpha1.SriovResourcePolicySpec{
+					Configs: []sriovdrav1alpha1.Config{{Selectors: selectors(`device.attributes[`)}},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "p2"},
+				Spec: sriovdrav1alpha1.SriovResourcePolicySpec{
+					Configs: []sriovdrav1alpha1.Config{{Selectors: selectors(`device.attributes["sriovnetwork.k8snetworkplumbingwg.io"].missing`)}},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "p3"},
+				Spec: sriovdrav1alpha1.SriovResourcePolicySpec{
+					Configs: []sriovdrav1alpha1.Config{{Selectors: selectors(`device.attributes["sriovnetwork.k8snetworkplumbingwg.io"].vfID == 3`)}},
+				},
+			},
+		}
+		results := policyResults{}
+		_, matches := resolvePolicies(r.log, r.recorder, r.nodeName, policies, nil, alloc, results)
+
+		Expect(matches).To(HaveKeyWithValue("devA", HaveField("Policy", "p3")))
+		Expect(policyErrors(policies[0])).To(ConsistOf(HavePrefix("invalid selectors")))
+		Expect(policyErrors(policies[1])).To(BeEmpty())
+		Expect(results.of("p2").nodeStatus("node-a").Error).To(HavePrefix("selectors failed on device devA"))
+		Expect(policyErrors(policies[2])).To(BeEmpty())
+		Expect(results.of("p3").nodeStatus("node-a").Error).To(BeEmpty())
+	})
+})
+
+var _ = Describe("deviceMatchesFilters linkType", func() {
 	makeDevice := func(lt string) resourceapi.Device {
 		return resourceapi.Device{
 			Name: "dev-lt",
@@ -172,37 +346,37 @@ var _ = Describe("deviceMatchesFilters linkType", func() {
 
 	It("matches ethernet device with 'eth' (lowercase)", func() {
 		d := makeDevice(sriovconsts.LinkTypeEthernet)
-		Expect(r.deviceMatchesFilters(d, filters("eth"))).To(BeTrue())
+		Expect(deviceMatchesFilters(d, filters("eth"))).To(BeTrue())
 	})
 
 	It("matches ethernet device with 'ETH' (uppercase)", func() {
 		d := makeDevice(sriovconsts.LinkTypeEthernet)
-		Expect(r.deviceMatchesFilters(d, filters("ETH"))).To(BeTrue())
+		Expect(deviceMatchesFilters(d, filters("ETH"))).To(BeTrue())
 	})
 
 	It("matches ethernet device with 'Eth' (mixed case)", func() {
 		d := makeDevice(sriovconsts.LinkTypeEthernet)
-		Expect(r.deviceMatchesFilters(d, filters("Eth"))).To(BeTrue())
+		Expect(deviceMatchesFilters(d, filters("Eth"))).To(BeTrue())
 	})
 
 	It("matches infiniband device with 'ib' (lowercase)", func() {
 		d := makeDevice(sriovconsts.LinkTypeInfiniband)
-		Expect(r.deviceMatchesFilters(d, filters("ib"))).To(BeTrue())
+		Expect(deviceMatchesFilters(d, filters("ib"))).To(BeTrue())
 	})
 
 	It("matches infiniband device with 'IB' (uppercase)", func() {
 		d := makeDevice(sriovconsts.LinkTypeInfiniband)
-		Expect(r.deviceMatchesFilters(d, filters("IB"))).To(BeTrue())
+		Expect(deviceMatchesFilters(d, filters("IB"))).To(BeTrue())
 	})
 
 	It("does not match ethernet device with 'ib' filter", func() {
 		d := makeDevice(sriovconsts.LinkTypeEthernet)
-		Expect(r.deviceMatchesFilters(d, filters("ib"))).To(BeFalse())
+		Expect(deviceMatchesFilters(d, filters("ib"))).To(BeFalse())
 	})
 
 	It("does not match infiniband device with 'eth' filter", func() {
 		d := makeDevice(sriovconsts.LinkTypeInfiniband)
-		Expect(r.deviceMatchesFilters(d, filters("eth"))).To(BeFalse())
+		Expect(deviceMatchesFilters(d, filters("eth"))).To(BeFalse())
 	})
 
 	It("does not match when device has no linkType attribute", func() {
@@ -210,7 +384,7 @@ var _ = Describe("deviceMatchesFilters linkType", func() {
 			Name:       "dev-no-lt",
 			Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{},
 		}
-		Expect(r.deviceMatchesFilters(d, filters("eth"))).To(BeFalse())
+		Expect(deviceMatchesFilters(d, filters("eth"))).To(BeFalse())
 	})
 })
 
@@ -293,4 +467,386 @@ var _ = Describe("getPolicyDeviceMap", func() {
 		Expect(m["devA"]).To(HaveKey(resourceapi.QualifiedName("sriovnetwork.k8snetworkplumbingwg.io/resourceName")))
 		Expect(*m["devA"][resourceapi.QualifiedName("sriovnetwork.k8snetworkplumbingwg.io/resourceName")].StringValue).To(Equal("my-resource"))
 	})
+
+	It("records the policy and DeviceAttributes each device attribute comes from", func() {
+		vendor := "8086"
+		alloc := drasriovtypes.AllocatableDevices{
+			"devA": resourceapi.Device{
+				Name: "devA",
+				Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+					sriovconsts.AttributeVendorID: {StringValue: &vendor},
+				},
+			},
+		}
+		r := &SriovResourcePolicyReconciler{deviceStateManager: &localFakeState{alloc: alloc}}
+
+		zoneA, zoneB, rack := "a", "b", "r1"
+		deviceAttrs := []sriovdrav1alpha1.DeviceAttributes{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "da2", Labels: map[string]string{"pool": "test"}},
+				Spec: sriovdrav1alpha1.DeviceAttributesSpec{
+					Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						"example.com/zone": {StringValue: &zoneB},
+					},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "da1", Labels: map[string]string{"pool": "test"}},
+				Spec: sriovdrav1alpha1.DeviceAttributesSpec{
+					Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						"example.com/zone": {StringValue: &zoneA},
+						"example.com/rack": {StringValue: &rack},
+					},
+				},
+			},
+		}
+		policies := []*sriovdrav1alpha1.SriovResourcePolicy{{
+			ObjectMeta: metav1.ObjectMeta{Name: "p1"},
+			Spec: sriovdrav1alpha1.SriovResourcePolicySpec{
+				Configs: []sriovdrav1alpha1.Config{{
+					DeviceAttributesSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "test"}},
+				}},
+			},
+		}}
+
+		_, matches := resolvePolicies(r.log, r.recorder, r.nodeName, policies, deviceAttrs, alloc, nil)
+		Expect(matches).To(Equal(map[string]drasriovtypes.DevicePolicyMatch{
+			"devA": {
+				Policy: "p1",
+				AttributeSources: map[resourceapi.QualifiedName]string{
+					"example.com/zone": "da2",
+					"example.com/rack": "da1",
+				},
+			},
+		}))
+	})
+
+	It("emits Warning events for invalid selectors and reserved attributes", func() {
+		vendor := "8086"
+		alloc := drasriovtypes.AllocatableDevices{
+			"devA": resourceapi.Device{
+				Name: "devA",
+				Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+					sriovconsts.AttributeVendorID: {StringValue: &vendor},
+				},
+			},
+		}
+		recorder := record.NewFakeRecorder(10)
+		r := &SriovResourcePolicyReconciler{deviceStateManager: &localFakeState{alloc: alloc}}
+		r.SetEventRecorder(recorder)
+
+		deviceAttrs := []sriovdrav1alpha1.DeviceAttributes{{
+			ObjectMeta: metav1.ObjectMeta{Name: "da1", Labels: map[string]string{"pool": "test"}},
+			Spec: sriovdrav1alpha1.DeviceAttributesSpec{
+				Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+					sriovconsts.AttributeVendorID: {StringValue: &vendor},
+				},
+			},
+		}}
+		policies := []*sriovdrav1alpha1.SriovResourcePolicy{{
+			ObjectMeta: metav1.ObjectMeta{Name: "p1"},
+			Spec: sriovdrav1alpha1.SriovResourcePolicySpec{
+				Configs: []sriovdrav1alpha1.Config{
+					{DeviceAttributesSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "test"}}},
+					{DeviceAttributesSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "pool", Operator: "Bogus"}}}},
+				},
+			},
+		}}
+
+		m := r.getPolicyDeviceMap(policies, deviceAttrs)
+		Expect(m).To(HaveLen(1))
+		Expect(recorder.Events).To(HaveLen(2))
+		Expect(<-recorder.Events).To(HavePrefix("Warning ReservedAttributeConflict"))
+		Expect(<-recorder.Events).To(HavePrefix("Warning InvalidSelector"))
+	})
+})
+
+var _ = Describe("policy status", func() {
+	var (
+		alloc    drasriovtypes.AllocatableDevices
+		r        *SriovResourcePolicyReconciler
+		policies []*sriovdrav1alpha1.SriovResourcePolicy
+	)
+
+	BeforeEach(func() {
+		vendor := "8086"
+		alloc = drasriovtypes.AllocatableDevices{
+			"devA": resourceapi.Device{
+				Name: "devA",
+				Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+					sriovconsts.AttributeVendorID: {StringValue: &vendor},
+				},
+			},
+			"devB": resourceapi.Device{Name: "devB"},
+		}
+		r = &SriovResourcePolicyReconciler{nodeName: "node-a", deviceStateManager: &localFakeState{alloc: alloc}}
+		policies = []*sriovdrav1alpha1.SriovResourcePolicy{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "p2", Generation: 3},
+				Spec: sriovdrav1alpha1.SriovResourcePolicySpec{
+					Configs: []sriovdrav1alpha1.Config{{
+						DeviceAttributesSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "pool", Operator: "Bogus"}}},
+					}},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "p1"},
+				Spec: sriovdrav1alpha1.SriovResourcePolicySpec{
+					Configs: []sriovdrav1alpha1.Config{
+						{ResourceFilters: []sriovdrav1alpha1.ResourceFilter{{Vendors: []string{"8086"}}}},
+					},
+				},
+			},
+		}
+	})
+
+	It("records the devices matched and lost to a policy taking precedence", func() {
+		p2, p1 := policies[0], policies[1]
+		results := policyResults{}
+		resolvePolicies(r.log, r.recorder, r.nodeName, policies, nil, alloc, results)
+
+		Expect(results.of("p1").nodeStatus("node-a")).To(Equal(sriovdrav1alpha1.PolicyNodeStatus{
+			NodeName:       "node-a",
+			MatchedDevices: 1,
+			Matched:        []string{"devA"},
+		}))
+		Expect(results.of("p2").nodeStatus("node-a")).To(Equal(sriovdrav1alpha1.PolicyNodeStatus{
+			NodeName:        "node-a",
+			MatchedDevices:  1,
+			ShadowedDevices: 1,
+			Matched:         []string{"devB"},
+			Shadowed:        []sriovdrav1alpha1.OverlappingDevice{{Name: "devA", Policy: "p1"}},
+		}))
+		Expect(results.of("p2").shadowed).To(Equal(map[string]string{"devA": "p1"}))
+		Expect(policyErrors(p1)).To(BeEmpty())
+		Expect(policyErrors(p2)).To(ConsistOf(HavePrefix("invalid deviceAttributesSelector")))
+	})
+
+	It("lists the first devices of each result and flags the truncated lists", func() {
+		result := &policyResult{}
+		for i := 0; i < sriovdrav1alpha1.MaxPolicyStatusDevices+2; i++ {
+			result.matched = append(result.matched, fmt.Sprintf("dev%02d", i))
+		}
+		result.shadow("devX", "p1")
+
+		status := result.nodeStatus("node-a")
+		Expect(status.MatchedDevices).To(Equal(int32(sriovdrav1alpha1.MaxPolicyStatusDevices + 2)))
+		Expect(status.Matched).To(HaveLen(sriovdrav1alpha1.MaxPolicyStatusDevices))
+		Expect(status.Matched[0]).To(Equal("dev00"))
+		Expect(status.Shadowed).To(Equal([]sriovdrav1alpha1.OverlappingDevice{{Name: "devX", Policy: "p1"}}))
+		Expect(status.Truncated).To(BeTrue())
+
+		result.matched = result.matched[:1]
+		Expect(result.nodeStatus("node-a").Truncated).To(BeFalse())
+	})
+
+	It("records invalid VF index ranges as errors of the policy", func() {
+		p1 := policies[1]
+		p1.Spec.Configs[0].ResourceFilters[0].VfIndices = []string{"7-0"}
+		results := policyResults{}
+		resolvePolicies(r.log, r.recorder, r.nodeName, policies, nil, alloc, results)
+
+		Expect(policyErrors(p1)).To(ConsistOf(HavePrefix("invalid resourceFilters")))
+		Expect(results.of("p1").matched).To(BeEmpty())
+	})
+
+	It("reports the errors of a policy on nodes it does not apply to", func() {
+		policies[0].Spec.NodeSelector = &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+			MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"b"}}},
+		}}}
+		results := policyResults{}
+		results.of("p2").errors = policyErrors(policies[0])
+
+		status := r.policyStatus(policies[0], results.of("p2"), func(string) bool { return true })
+		Expect(status.Nodes).To(BeEmpty())
+		Expect(status.Conditions).To(ConsistOf(And(
+			HaveField("Type", sriovdrav1alpha1.PolicyValid),
+			HaveField("Status", metav1.ConditionFalse),
+			HaveField("Message", HavePrefix("invalid deviceAttributesSelector")),
+		)))
+	})
+
+	It("replaces the entry of the node and keeps the entries of other nodes", func() {
+		policy := policies[0]
+		results := policyResults{}
+		results.of("p2").applies = true
+		results.of("p2").errors = policyErrors(policy)
+		resolvePolicies(r.log, r.recorder, r.nodeName, policies, nil, alloc, results)
+
+		policy.Status.Nodes = []sriovdrav1alpha1.PolicyNodeStatus{
+			{NodeName: "node-a", MatchedDevices: 5},
+			{NodeName: "node-b", MatchedDevices: 7},
+		}
+		allNodes := func(string) bool { return true }
+		status := r.policyStatus(policy, results.of("p2"), allNodes)
+		Expect(status.Nodes).To(HaveLen(2))
+		Expect(status.Nodes[0].MatchedDevices).To(Equal(int32(1)))
+		Expect(status.Nodes[1]).To(Equal(policy.Status.Nodes[1]))
+		Expect(status.Conditions).To(ConsistOf(And(
+			HaveField("Type", sriovdrav1alpha1.PolicyValid),
+			HaveField("Status", metav1.ConditionFalse),
+			HaveField("ObservedGeneration", int64(3)),
+		)))
+
+		// the node is dropped once the policy no longer applies to it
+		results.of("p2").applies = false
+		status = r.policyStatus(policy, results.of("p2"), allNodes)
+		Expect(status.Nodes).To(Equal(policy.Status.Nodes[1:]))
+	})
+
+	It("retries the status updates conflicting with other nodes and returns the conflict once they run out", func() {
+		backoff := policyStatusBackoff
+		DeferCleanup(func() { policyStatusBackoff = backoff })
+		policyStatusBackoff = wait.Backoff{Steps: 3, Duration: time.Millisecond}
+
+		policy := policies[1]
+		updates := 0
+		r.Client = ctrlclientfake.NewClientBuilder().
+			WithScheme(flags.Scheme).
+			WithObjects(policy).
+			WithStatusSubresource(policy).
+			WithInterceptorFuncs(interceptor.Funcs{
+				SubResourceUpdate: func(_ context.Context, _ client.Client, _ string, obj client.Object, _ ...client.SubResourceUpdateOption) error {
+					updates++
+					return apierrors.NewConflict(schema.GroupResource{Resource: "sriovresourcepolicies"}, obj.GetName(), errors.New("modified"))
+				},
+			}).
+			Build()
+		results := policyResults{}
+		results.of("p1").applies = true
+
+		err := r.updatePolicyStatus(context.Background(), policy, results.of("p1"), func(string) bool { return true })
+		Expect(apierrors.IsConflict(err)).To(BeTrue())
+		Expect(updates).To(Equal(3))
+	})
+
+	It("drops the entries of deleted nodes", func() {
+		policy := policies[0]
+		results := policyResults{}
+		results.of("p2").applies = true
+		policy.Status.Nodes = []sriovdrav1alpha1.PolicyNodeStatus{
+			{NodeName: "node-b", MatchedDevices: 7},
+			{NodeName: "node-c", MatchedDevices: 3},
+		}
+
+		status := r.policyStatus(policy, results.of("p2"), func(name string) bool { return name != "node-c" })
+		Expect(status.Nodes).To(HaveLen(2))
+		Expect(status.Nodes[0].NodeName).To(Equal("node-a"))
+		Expect(status.Nodes[1].NodeName).To(Equal("node-b"))
+	})
+})
+
+var _ = Describe("policy priority and overlaps", func() {
+	var (
+		alloc       drasriovtypes.AllocatableDevices
+		deviceAttrs []sriovdrav1alpha1.DeviceAttributes
+		recorder    *record.FakeRecorder
+		r           *SriovResourcePolicyReconciler
+	)
+
+	policy := func(name string, priority int32, mode sriovdrav1alpha1.OverlapMode, pool string) *sriovdrav1alpha1.SriovResourcePolicy {
+		return &sriovdrav1alpha1.SriovResourcePolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: sriovdrav1alpha1.SriovResourcePolicySpec{
+				Priority:    priority,
+				OverlapMode: mode,
+				Configs: []sriovdrav1alpha1.Config{{
+					DeviceAttributesSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": pool}},
+				}},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		alloc = drasriovtypes.AllocatableDevices{"devA": resourceapi.Device{Name: "devA"}}
+		zoneA, zoneB, rack := "a", "b", "r1"
+		deviceAttrs = []sriovdrav1alpha1.DeviceAttributes{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "da-a", Labels: map[string]string{"pool": "a"}},
+				Spec: sriovdrav1alpha1.DeviceAttributesSpec{
+					Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						"example.com/zone": {StringValue: &zoneA},
+						"example.com/rack": {StringValue: &rack},
+					},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "da-b", Labels: map[string]string{"pool": "b"}},
+				Spec: sriovdrav1alpha1.DeviceAttributesSpec{
+					Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						"example.com/zone": {StringValue: &zoneB},
+					},
+				},
+			},
+		}
+		recorder = record.NewFakeRecorder(10)
+		r = &SriovResourcePolicyReconciler{nodeName: "node-a", deviceStateManager: &localFakeState{alloc: alloc}}
+		r.SetEventRecorder(recorder)
+	})
+
+	It("advertises a device through the policy with the highest priority", func() {
+		policies := []*sriovdrav1alpha1.SriovResourcePolicy{
+			policy("p-a", 0, "", "a"),
+			policy("p-b", 10, "", "b"),
+		}
+		results := policyResults{}
+		policyDevices, matches := resolvePolicies(r.log, r.recorder, r.nodeName, policies, deviceAttrs, alloc, results)
+
+		Expect(policyDevices["devA"]).To(HaveLen(1))
+		Expect(*policyDevices["devA"]["example.com/zone"].StringValue).To(Equal("b"))
+		Expect(matches["devA"].Policy).To(Equal("p-b"))
+		Expect(results.of("p-a").shadowed).To(Equal(map[string]string{"devA": "p-b"}))
+		Expect(results.of("p-a").nodeStatus("node-a").ShadowedDevices).To(Equal(int32(1)))
+		Expect(recorder.Events).To(HaveLen(1))
+		Expect(<-recorder.Events).To(And(HavePrefix("Warning PolicyOverlap"), ContainSubstring("devA (p-b)")))
+	})
+
+	It("merges the attributes the policies taking precedence do not set", func() {
+		policies := []*sriovdrav1alpha1.SriovResourcePolicy{
+			policy("p-a", 0, sriovdrav1alpha1.OverlapModeMerge, "a"),
+			policy("p-b", 10, "", "b"),
+		}
+		results := policyResults{}
+		policyDevices, matches := resolvePolicies(r.log, r.recorder, r.nodeName, policies, deviceAttrs, alloc, results)
+
+		Expect(policyDevices["devA"]).To(HaveLen(2))
+		Expect(*policyDevices["devA"]["example.com/zone"].StringValue).To(Equal("b"))
+		Expect(*policyDevices["devA"]["example.com/rack"].StringValue).To(Equal("r1"))
+		Expect(matches["devA"]).To(Equal(drasriovtypes.DevicePolicyMatch{
+			Policy: "p-b",
+			AttributeSources: map[resourceapi.QualifiedName]string{
+				"example.com/zone": "da-b",
+				"example.com/rack": "da-a",
+			},
+			MergedPolicies: []string{"p-a"},
+		}))
+		nodeStatus := results.of("p-a").nodeStatus("node-a")
+		Expect(nodeStatus.ShadowedDevices).To(BeZero())
+		Expect(nodeStatus.MergedDevices).To(Equal(int32(1)))
+		Expect(results.of("p-a").merged).To(Equal(map[string]string{"devA": "p-b"}))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("does not warn about the devices an earlier config of the same policy takes", func() {
+		p := policy("p-a", 0, "", "a")
+		p.Spec.Configs = append(p.Spec.Configs, sriovdrav1alpha1.Config{
+			DeviceAttributesSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "b"}},
+		})
+		results := policyResults{}
+		policyDevices, matches := resolvePolicies(r.log, r.recorder, r.nodeName, []*sriovdrav1alpha1.SriovResourcePolicy{p}, deviceAttrs, alloc, results)
+
+		Expect(*policyDevices["devA"]["example.com/zone"].StringValue).To(Equal("a"))
+		Expect(matches["devA"].Policy).To(Equal("p-a"))
+		Expect(results.of("p-a").shadowed).To(Equal(map[string]string{"devA": "p-a"}))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("orders policies of equal priority by name", func() {
+		policies := []*sriovdrav1alpha1.SriovResourcePolicy{
+			policy("p-b", 5, "", "b"),
+			policy("p-a", 5, "", "a"),
+		}
+		_, matches := resolvePolicies(r.log, r.recorder, r.nodeName, policies, deviceAttrs, alloc, nil)
+		Expect(matches["devA"].Policy).To(Equal("p-a"))
+	})
 })
@@ -163,22 +163,42 @@ func (s *Manager) getDeviceInfoStore() DeviceInfoStore {
 	return NewDeviceInfoStore()
 }
 
-// syncDeviceInfoFilesForPreparedDevicesIfNeeded writes device-info files only when MULTUS mode is enabled.
+// syncDeviceInfoFilesForPreparedDevicesIfNeeded writes device-info files only for the devices of MULTUS claims.
 func (s *Manager) syncDeviceInfoFilesForPreparedDevicesIfNeeded(ctx context.Context, preparedDevices drasriovtypes.PreparedDevices) error {
-	if !s.isMultusMode() {
-		klog.FromContext(ctx).V(4).Info("Skipping device-info file write because configuration mode is not MULTUS",
+	multusDevices := s.multusDevices(preparedDevices)
+	if len(multusDevices) == 0 {
+		klog.FromContext(ctx).V(4).Info("Skipping device-info file write because no device is in MULTUS configuration mode",
 			"configurationMode", s.configurationMode)
 		return nil
 	}
-	return s.syncDeviceInfoFilesForPreparedDevices(ctx, preparedDevices)
+	return s.syncDeviceInfoFilesForPreparedDevices(ctx, multusDevices)
 }
 
-// cleanDeviceInfoFilesForPreparedDevicesIfNeeded removes device-info files only when MULTUS mode is enabled.
+// cleanDeviceInfoFilesForPreparedDevicesIfNeeded removes device-info files only for the devices of MULTUS claims.
 func (s *Manager) cleanDeviceInfoFilesForPreparedDevicesIfNeeded(ctx context.Context, preparedDevices drasriovtypes.PreparedDevices) error {
-	if !s.isMultusMode() {
-		klog.FromContext(ctx).V(4).Info("Skipping device-info file cleanup because configuration mode is not MULTUS",
+	multusDevices := s.multusDevices(preparedDevices)
+	if len(multusDevices) == 0 {
+		klog.FromContext(ctx).V(4).Info("Skipping device-info file cleanup because no device is in MULTUS configuration mode",
 			"configurationMode", s.configurationMode)
 		return nil
 	}
-	return s.cleanDeviceInfoFilesForPreparedDevices(ctx, preparedDevices)
+	return s.cleanDeviceInfoFilesForPreparedDevices(ctx, multusDevices)
+}
+
+// multusDevices returns the prepared devices attached by Multus, nil devices
+// are kept so they are reported.
+func (s *Manager) multusDevices(preparedDevices drasriovtypes.PreparedDevices) drasriovtypes.PreparedDevices {
+	if s.isMultusMode() {
+		return preparedDevices
+	}
+	if !s.isHybridMode() {
+		return nil
+	}
+	multusDevices := drasriovtypes.PreparedDevices{}
+	for _, preparedDevice := range preparedDevices {
+		if preparedDevice == nil || s.isMultusDevice(preparedDevice) {
+			multusDevices = append(multusDevices, preparedDevice)
+		}
+	}
+	return multusDevices
 }
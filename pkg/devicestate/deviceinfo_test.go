@@ -406,6 +406,85 @@ var _ = Describe("DeviceInfo compatibility", Serial, func() {
 		Expect(fakeUtils.saveCalls).To(BeEmpty())
 	})
 
+	It("writes device-info only for MULTUS claims in HYBRID mode", func() {
+		fakeUtils := &fakeDeviceInfoUtils{}
+		cdiHandler, err := cdi.NewHandler(GinkgoT().TempDir())
+		Expect(err).NotTo(HaveOccurred())
+		manager := &Manager{
+			cdi:                    cdiHandler,
+			deviceInfoStore:        fakeUtils,
+			defaultInterfacePrefix: "vfnet",
+			configurationMode:      string(consts.ConfigurationModeHybrid),
+			allocatable: drasriovtypes.AllocatableDevices{
+				"device1": {
+					Name: "device1",
+					Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						consts.AttributePciAddress:         {StringValue: ptr.To("0000:01:00.1")},
+						consts.AttributeMultusResourceName: {StringValue: ptr.To("intel.com/sriov")},
+						consts.AttributeMultusDeviceID:     {StringValue: ptr.To("0000:01:00.1")},
+					},
+				},
+			},
+		}
+
+		mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", gomock.Any()).Return("", nil)
+		mockHost.EXPECT().GetRDMADevicesForPCI("0000:01:00.1").Return([]string{})
+
+		// no NetworkAttachmentDefinition is resolved for MULTUS claims, the
+		// manager has no client to fetch one
+		encodedConfig := []byte(`{"apiVersion":"sriovnetwork.k8snetworkplumbingwg.io/v1alpha1","kind":"VfConfig","netAttachDefName":"test-net","configurationMode":"MULTUS"}`)
+		claim := &resourceapi.ResourceClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-claim",
+				Namespace: "test-ns",
+				UID:       "claim-uid",
+			},
+			Status: resourceapi.ResourceClaimStatus{
+				Allocation: &resourceapi.AllocationResult{
+					Devices: resourceapi.DeviceAllocationResult{
+						Results: []resourceapi.DeviceRequestAllocationResult{
+							{Driver: consts.DriverName, Device: "device1", Request: "req1", Pool: "pool1"},
+						},
+						Config: []resourceapi.DeviceAllocationConfiguration{
+							{
+								Source:   resourceapi.AllocationConfigSourceClaim,
+								Requests: []string{"req1"},
+								DeviceConfiguration: resourceapi.DeviceConfiguration{
+									Opaque: &resourceapi.OpaqueDeviceConfiguration{
+										Driver:     consts.DriverName,
+										Parameters: runtime.RawExtension{Raw: encodedConfig},
+									},
+								},
+							},
+						},
+					},
+				},
+				ReservedFor: []resourceapi.ResourceClaimConsumerReference{
+					{UID: "pod-uid"},
+				},
+			},
+		}
+
+		ifNameIndex := 0
+		preparedDevices, err := manager.PrepareDevicesForClaim(context.Background(), &ifNameIndex, claim)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(preparedDevices).To(HaveLen(1))
+		Expect(preparedDevices[0].NetAttachDefConfig).To(BeEmpty())
+		Expect(preparedDevices[0].IfName).To(BeEmpty())
+		Expect(preparedDevices[0].MultusResourceName).To(Equal("intel.com/sriov"))
+		Expect(fakeUtils.saveCalls).To(HaveLen(1))
+
+		// devices of STANDALONE claims are left to the driver
+		standalone := &drasriovtypes.PreparedDevice{
+			PciAddress:         "0000:01:00.2",
+			MultusResourceName: "intel.com/sriov",
+			MultusDeviceID:     "0000:01:00.2",
+			Config:             &configapi.VfConfig{},
+		}
+		Expect(manager.syncDeviceInfoFilesForPreparedDevicesIfNeeded(context.Background(), drasriovtypes.PreparedDevices{standalone})).To(Succeed())
+		Expect(fakeUtils.saveCalls).To(HaveLen(1))
+	})
+
 	It("cleans device-info files during Unprepare", func() {
 		fakeUtils := &fakeDeviceInfoUtils{}
 		cdiHandler, err := cdi.NewHandler(GinkgoT().TempDir())
@@ -9,6 +9,7 @@ import (
 	"k8s.io/klog/v2"
 	"k8s.io/utils/ptr"
 
+	sriovdrav1alpha1 "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/sriovdra/v1alpha1"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
@@ -26,9 +27,37 @@ type PFInfo struct {
 	NumaNode    string
 }
 
+// ExcludedDevice is a network device left out by the discovery.
+type ExcludedDevice struct {
+	PciAddress string
+	Reason     string
+	Message    string
+}
+
+// Discovery is the result of an SR-IOV device discovery.
+type Discovery struct {
+	// Devices are the discovered VFs, keyed by device name.
+	Devices types.AllocatableDevices
+	// PhysicalFunctions are the PFs the VFs were looked up on.
+	PhysicalFunctions []PFInfo
+	// Excluded are the network devices that were skipped.
+	Excluded []ExcludedDevice
+}
+
 func DiscoverSriovDevices() (types.AllocatableDevices, error) {
+	discovery, err := Discover()
+	if err != nil {
+		return nil, err
+	}
+	return discovery.Devices, nil
+}
+
+// Discover discovers the SR-IOV devices of the host along with their PFs and
+// the network devices that were skipped.
+func Discover() (*Discovery, error) {
 	logger := klog.LoggerWithName(klog.Background(), "DiscoverSriovDevices")
 	pfList := []PFInfo{}
+	excluded := []ExcludedDevice{}
 	resourceList := types.AllocatableDevices{}
 
 	logger.Info("Starting SR-IOV device discovery")
@@ -70,6 +99,11 @@ func DiscoverSriovDevices() (types.AllocatableDevices, error) {
 		pfNetName := host.GetHelpers().TryGetPFInterfaceName(device.Address)
 		if pfNetName == "" {
 			logger.Error(nil, "Unable to get interface name for device, skipping", "address", device.Address)
+			excluded = append(excluded, ExcludedDevice{
+				PciAddress: device.Address,
+				Reason:     sriovdrav1alpha1.ExclusionReasonNoNetworkInterface,
+				Message:    "unable to get the network interface name of the device",
+			})
 			continue
 		}
 
@@ -218,5 +252,9 @@ func DiscoverSriovDevices() (types.AllocatableDevices, error) {
 	}
 
 	logger.Info("SR-IOV device discovery completed", "totalDevices", len(resourceList))
-	return resourceList, nil
+	return &Discovery{
+		Devices:           resourceList,
+		PhysicalFunctions: pfList,
+		Excluded:          excluded,
+	}, nil
 }
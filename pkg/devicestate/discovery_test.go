@@ -10,6 +10,7 @@ import (
 	"go.uber.org/mock/gomock"
 	"k8s.io/utils/ptr"
 
+	sriovdrav1alpha1 "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/sriovdra/v1alpha1"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
 	mock_host "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host/mock"
@@ -452,6 +453,47 @@ var _ = Describe("DiscoverSriovDevices", func() {
 			Expect(devices).To(HaveLen(0))
 		})
 
+		It("should report the PFs and the devices without interface name", func() {
+			pciInfo := &pci.Info{
+				Devices: []*pci.Device{
+					{
+						Address: "0000:01:00.0",
+						Class:   &pcidb.Class{ID: "02"},
+						Vendor:  &pcidb.Vendor{ID: "8086"},
+						Product: &pcidb.Product{ID: "1572"},
+					},
+					{
+						Address: "0000:02:00.0",
+						Class:   &pcidb.Class{ID: "02"},
+						Vendor:  &pcidb.Vendor{ID: "8086"},
+						Product: &pcidb.Product{ID: "1572"},
+					},
+				},
+			}
+
+			mockHost.EXPECT().PCI().Return(pciInfo, nil)
+			mockHost.EXPECT().IsSriovVF("0000:01:00.0").Return(false)
+			mockHost.EXPECT().TryGetPFInterfaceName("0000:01:00.0").Return("eth0")
+			mockHost.EXPECT().GetNicSriovMode("0000:01:00.0").Return(consts.EswitchModeLegacy)
+			mockHost.EXPECT().GetNumaNode("0000:01:00.0").Return("0", nil)
+			mockHost.EXPECT().GetPCIeRoot("0000:01:00.0").Return("pci0000:00", nil)
+			mockHost.EXPECT().GetLinkType("0000:01:00.0").Return(consts.LinkTypeEthernet, nil)
+			mockHost.EXPECT().GetVFList("0000:01:00.0").Return([]host.VFInfo{{PciAddress: "0000:01:00.1", DeviceID: "154c"}}, nil)
+			mockHost.EXPECT().VerifyRDMACapability("0000:01:00.1").Return(false)
+			mockHost.EXPECT().IsSriovVF("0000:02:00.0").Return(false)
+			mockHost.EXPECT().TryGetPFInterfaceName("0000:02:00.0").Return("")
+
+			discovery, err := Discover()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(discovery.Devices).To(HaveKey("0000-01-00-1"))
+			Expect(discovery.PhysicalFunctions).To(HaveLen(1))
+			Expect(discovery.PhysicalFunctions[0].NetName).To(Equal("eth0"))
+			Expect(discovery.PhysicalFunctions[0].EswitchMode).To(Equal(consts.EswitchModeLegacy))
+			Expect(discovery.Excluded).To(HaveLen(1))
+			Expect(discovery.Excluded[0].PciAddress).To(Equal("0000:02:00.0"))
+			Expect(discovery.Excluded[0].Reason).To(Equal(sriovdrav1alpha1.ExclusionReasonNoNetworkInterface))
+		})
+
 		It("should skip devices with invalid class ID", func() {
 			pciInfo := &pci.Info{
 				Devices: []*pci.Device{
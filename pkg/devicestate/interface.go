@@ -14,7 +14,8 @@ import (
 
 // DeviceState defines the minimal interface used by the controller for device state operations.
 type DeviceState interface {
-	// GetAllocatableDevices returns the full discovered device set.
+	// GetAllocatableDevices returns the full discovered device set, without
+	// the attributes applied by the policies.
 	GetAllocatableDevices() drasriovtypes.AllocatableDevices
 	// UpdatePolicyDevices updates the set of advertised devices and their policy-applied attributes.
 	// Keys in policyDevices are device names matched by policies (these will be advertised).
@@ -8,8 +8,10 @@ import (
 	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
 
 	resourceapi "k8s.io/api/resource/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/dynamic-resource-allocation/kubeletplugin"
@@ -24,8 +26,10 @@ import (
 	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cdi"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/events"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/flags"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/tracing"
 	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 )
 
@@ -36,12 +40,17 @@ type Manager struct {
 	deviceInfoStore        DeviceInfoStore
 	defaultInterfacePrefix string
 	allocatable            drasriovtypes.AllocatableDevices
+	physicalFunctions      []PFInfo
+	excludedDevices        []ExcludedDevice
 	republishCallback      func(context.Context) error
 	// policyAttrKeys tracks attribute keys set by policy per device, so they
 	// can be cleared without touching discovery attributes. Presence of a
 	// device key also indicates that the device is advertised (policy-matched).
 	policyAttrKeys    map[string]map[resourceapi.QualifiedName]bool
 	configurationMode string
+	// mu guards the attributes and advertisement of the devices updated by
+	// the policy controller.
+	mu sync.RWMutex
 }
 
 // NewManager creates a new device-state manager and initializes allocatable SR-IOV devices.
@@ -61,7 +70,7 @@ func NewManager(config *drasriovtypes.Config, cdi *cdi.Handler, deviceInfoStore
 		return nil, err
 	}
 
-	allocatable, err := DiscoverSriovDevices()
+	discovery, err := Discover()
 	if err != nil {
 		return nil, fmt.Errorf("error enumerating all possible devices: %v", err)
 	}
@@ -75,16 +84,41 @@ func NewManager(config *drasriovtypes.Config, cdi *cdi.Handler, deviceInfoStore
 		defaultInterfacePrefix: config.Flags.DefaultInterfacePrefix,
 		cdi:                    cdi,
 		deviceInfoStore:        deviceInfoStore,
-		allocatable:            allocatable,
+		allocatable:            discovery.Devices,
+		physicalFunctions:      discovery.PhysicalFunctions,
+		excludedDevices:        discovery.Excluded,
 		configurationMode:      configurationMode,
 	}
 
 	return state, nil
 }
 
-// GetAllocatableDevices returns the allocatable devices
+// GetAllocatableDevices returns a copy of the allocatable devices with their
+// discovery attributes only. The policy controller matches the policies
+// against them, so the attributes the policies applied cannot change which
+// policies match.
 func (s *Manager) GetAllocatableDevices() drasriovtypes.AllocatableDevices {
-	return s.allocatable
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	devices := make(drasriovtypes.AllocatableDevices, len(s.allocatable))
+	for name, device := range s.allocatable {
+		device := *device.DeepCopy()
+		for key := range s.policyAttrKeys[name] {
+			delete(device.Attributes, key)
+		}
+		devices[name] = device
+	}
+	return devices
+}
+
+// PhysicalFunctions returns the PFs found by the device discovery.
+func (s *Manager) PhysicalFunctions() []PFInfo {
+	return s.physicalFunctions
+}
+
+// ExcludedDevices returns the network devices skipped by the device discovery.
+func (s *Manager) ExcludedDevices() []ExcludedDevice {
+	return s.excludedDevices
 }
 
 // normalizeConfigurationMode validates the configured mode and applies defaulting.
@@ -96,21 +130,22 @@ func normalizeConfigurationMode(mode string) (string, error) {
 		return string(consts.ConfigurationModeStandalone), nil
 	case consts.ConfigurationModeMultus:
 		return string(consts.ConfigurationModeMultus), nil
+	case consts.ConfigurationModeHybrid:
+		return string(consts.ConfigurationModeHybrid), nil
 	default:
-		return "", fmt.Errorf("unsupported configuration mode %q, expected %q or %q", mode, consts.ConfigurationModeStandalone, consts.ConfigurationModeMultus)
+		return "", fmt.Errorf("unsupported configuration mode %q, expected %q, %q or %q", mode, consts.ConfigurationModeStandalone, consts.ConfigurationModeMultus, consts.ConfigurationModeHybrid)
 	}
 }
 
 // GetAllocatableDeviceByName returns a discovered allocatable device and whether it exists.
 func (s *Manager) GetAllocatableDeviceByName(deviceName string) (resourceapi.Device, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	device, exists := s.allocatable[deviceName]
-	return device, exists
-}
-
-// isStandaloneMode reports whether the manager is running in STANDALONE mode.
-func (s *Manager) isStandaloneMode() bool {
-	mode := consts.ConfigurationMode(s.configurationMode)
-	return mode == "" || mode == consts.ConfigurationModeStandalone
+	if !exists {
+		return device, false
+	}
+	return *device.DeepCopy(), true
 }
 
 // isMultusMode reports whether the manager is running in MULTUS mode.
@@ -118,9 +153,48 @@ func (s *Manager) isMultusMode() bool {
 	return consts.ConfigurationMode(s.configurationMode) == consts.ConfigurationModeMultus
 }
 
+// isHybridMode reports whether the manager is running in HYBRID mode.
+func (s *Manager) isHybridMode() bool {
+	return consts.ConfigurationMode(s.configurationMode) == consts.ConfigurationModeHybrid
+}
+
+// claimConfigurationMode returns the configuration mode of a claim config:
+// the mode selected by the config in HYBRID mode, STANDALONE by default, and
+// the node mode otherwise. Configs selecting another mode than the node one
+// are rejected outside of HYBRID mode.
+func (s *Manager) claimConfigurationMode(config *configapi.VfConfig) (consts.ConfigurationMode, error) {
+	if !s.isHybridMode() {
+		mode := consts.ConfigurationMode(s.configurationMode)
+		if mode == "" {
+			mode = consts.ConfigurationModeStandalone
+		}
+		if config != nil && config.ConfigurationMode != "" && consts.ConfigurationMode(config.ConfigurationMode) != mode {
+			return "", fmt.Errorf("configuration mode %q requires the node to run in %q mode, it runs in %q mode", config.ConfigurationMode, consts.ConfigurationModeHybrid, mode)
+		}
+		return mode, nil
+	}
+	if config != nil && config.IsMultusConfigurationMode() {
+		return consts.ConfigurationModeMultus, nil
+	}
+	return consts.ConfigurationModeStandalone, nil
+}
+
+// isMultusDevice reports whether Multus attaches a prepared device.
+func (s *Manager) isMultusDevice(device *drasriovtypes.PreparedDevice) bool {
+	mode, err := s.claimConfigurationMode(device.Config)
+	return err == nil && mode == consts.ConfigurationModeMultus
+}
+
 // PrepareDevicesForClaim prepares the devices for a given claim
 // It will return the prepared devices for the claim
 func (s *Manager) PrepareDevicesForClaim(ctx context.Context, ifNameIndex *int, claim *resourceapi.ResourceClaim) (drasriovtypes.PreparedDevices, error) {
+	ctx, span := tracing.Start(ctx, "PrepareDevicesForClaim", tracing.ClaimAttributes(claim.Namespace, claim.Name, claim.UID)...)
+	preparedDevices, err := s.prepareDevicesForClaim(ctx, ifNameIndex, claim)
+	tracing.End(span, err)
+	return preparedDevices, err
+}
+
+func (s *Manager) prepareDevicesForClaim(ctx context.Context, ifNameIndex *int, claim *resourceapi.ResourceClaim) (drasriovtypes.PreparedDevices, error) {
 	logger := klog.FromContext(ctx).WithName("PrepareDevicesForClaim")
 
 	resultsConfig, err := getMapOfOpaqueDeviceConfigForDevice(configapi.Decoder, claim.Status.Allocation.Devices.Config)
@@ -132,7 +206,7 @@ func (s *Manager) PrepareDevicesForClaim(ctx context.Context, ifNameIndex *int,
 	preparedDevices, err := s.prepareDevices(ctx, ifNameIndex, claim, resultsConfig)
 	if err != nil {
 		logger.Error(err, "Prepare failed", "claim", *claim)
-		return nil, fmt.Errorf("prepare failed: %v", err)
+		return nil, fmt.Errorf("prepare failed: %w", err)
 	}
 	if len(preparedDevices) == 0 {
 		logger.Error(fmt.Errorf("no prepared devices found for claim"), "Prepare failed", "claim", *claim)
@@ -150,7 +224,10 @@ func (s *Manager) PrepareDevicesForClaim(ctx context.Context, ifNameIndex *int,
 		return nil, errors.Join(rollbackErrs...)
 	}
 
-	if err = s.cdi.CreateClaimSpecFile(preparedDevices); err != nil {
+	_, span := tracing.Start(ctx, "CreateClaimSpecFile", tracing.AttributeDeviceCount.Int(len(preparedDevices)))
+	err = s.cdi.CreateClaimSpecFile(preparedDevices)
+	tracing.End(span, err)
+	if err != nil {
 		rollbackErrs := []error{fmt.Errorf("unable to create CDI spec file for claim: %v", err)}
 		if cleanupErr := s.cleanDeviceInfoFilesForPreparedDevicesIfNeeded(ctx, preparedDevices); cleanupErr != nil {
 			rollbackErrs = append(rollbackErrs, fmt.Errorf("cleanup after CDI spec failure failed: %w", cleanupErr))
@@ -182,13 +259,15 @@ func (s *Manager) prepareDevices(ctx context.Context, ifNameIndex *int,
 		// make changes if needed
 		config.Normalize()
 
-		preparedDevice, err := s.applyConfigOnDevice(ctx, ifNameIndex, claim, config, &result)
+		deviceCtx, span := tracing.Start(ctx, "PrepareDevice", tracing.AttributeDeviceName.String(result.Device))
+		preparedDevice, err := s.applyConfigOnDevice(deviceCtx, ifNameIndex, claim, config, &result)
+		tracing.End(span, err)
 		if err != nil {
 			logger.Error(err, "error applying config on device", "config", config, "result", result)
 			if rollbackErr := s.unprepareDevices(preparedDevices); rollbackErr != nil {
-				return nil, fmt.Errorf("error applying config on device: %v; rollback failed: %v", err, rollbackErr)
+				return nil, fmt.Errorf("error applying config on device: %w; rollback failed: %v", err, rollbackErr)
 			}
-			return nil, fmt.Errorf("error applying config on device: %v", err)
+			return nil, fmt.Errorf("error applying config on device: %w", err)
 		}
 
 		rawConfig, err := json.Marshal(config)
@@ -213,14 +292,18 @@ func (s *Manager) prepareDevices(ctx context.Context, ifNameIndex *int,
 func (s *Manager) applyConfigOnDevice(ctx context.Context, ifNameIndex *int, claim *resourceapi.ResourceClaim, config *configapi.VfConfig, result *resourceapi.DeviceRequestAllocationResult) (*drasriovtypes.PreparedDevice, error) {
 	logger := klog.FromContext(ctx).WithName("applyConfigOnDevice")
 	logger.V(3).Info("Applying config on device", "config", config, "result", result)
-	deviceInfo, exist := s.allocatable[result.Device]
+	deviceInfo, exist := s.GetAllocatableDeviceByName(result.Device)
 	if !exist {
 		return nil, fmt.Errorf("device %s not found in allocatable devices", result.Device)
 	}
+	mode, err := s.claimConfigurationMode(config)
+	if err != nil {
+		return nil, err
+	}
 	// if in multus mode, we try to get the multus resource name and device ID from the device attributes
 	var multusResourceName string
 	var multusDeviceID string
-	if s.isMultusMode() {
+	if mode == consts.ConfigurationModeMultus {
 		var hasMultusDeviceInfo bool
 		multusResourceName, multusDeviceID, hasMultusDeviceInfo = extractMultusDeviceInfoAttrs(logger, deviceInfo.Attributes)
 		if !hasMultusDeviceInfo {
@@ -230,17 +313,24 @@ func (s *Manager) applyConfigOnDevice(ctx context.Context, ifNameIndex *int, cla
 	}
 
 	var netAttachDefRawConfig string
-	var err error
 	pciAddress := *deviceInfo.Attributes[consts.AttributePciAddress].StringValue
-	// if in standalone mode, we get the net attach def raw config and add the deviceID (PCI address) to it
-	if s.isStandaloneMode() {
-		netAttachDefNamespace := claim.GetNamespace()
-		if config.NetAttachDefNamespace != "" {
-			netAttachDefNamespace = config.NetAttachDefNamespace
-		}
-		netAttachDefRawConfig, err = s.getNetAttachDefRawConfig(ctx, netAttachDefNamespace, config.NetAttachDefName)
-		if err != nil {
-			return nil, fmt.Errorf("error getting net attach def raw config: %w", err)
+	// if in standalone mode, we get the inline or net attach def raw config and add the deviceID (PCI address) to it,
+	// devices attached with netlink don't use any CNI config
+	if mode == consts.ConfigurationModeStandalone && !config.IsNetlinkAttachMode() {
+		switch {
+		case config.HasCNIConfig():
+			netAttachDefRawConfig = string(config.CNIConfig.Raw)
+		case config.NetAttachDefName == "":
+			return nil, fmt.Errorf("no net attach def name set")
+		default:
+			netAttachDefNamespace := claim.GetNamespace()
+			if config.NetAttachDefNamespace != "" {
+				netAttachDefNamespace = config.NetAttachDefNamespace
+			}
+			netAttachDefRawConfig, err = s.getNetAttachDefRawConfig(ctx, netAttachDefNamespace, config.NetAttachDefName)
+			if err != nil {
+				return nil, fmt.Errorf("error getting net attach def raw config: %w", err)
+			}
 		}
 		// add to sriov-cni compatible netconf the deviceID (PCI address)
 		netAttachDefRawConfig, err = drasriovtypes.AddDeviceIDToNetConf(netAttachDefRawConfig, pciAddress)
@@ -249,15 +339,15 @@ func (s *Manager) applyConfigOnDevice(ctx context.Context, ifNameIndex *int, cla
 		}
 	}
 	// Bind device to driver if specified in config
-	originalDriver, err := host.GetHelpers().BindDeviceDriver(pciAddress, config)
+	originalDriver, err := bindDeviceDriver(ctx, pciAddress, config)
 	if err != nil {
-		return nil, fmt.Errorf("error binding device %s to driver: %w", pciAddress, err)
+		return nil, events.WithReason(events.ReasonDriverBindFailed, fmt.Errorf("error binding device %s to driver: %w", pciAddress, err))
 	}
 	restoreDriverOnError := func(cause error) error {
 		if config.Driver == "" {
 			return cause
 		}
-		if restoreErr := host.GetHelpers().RestoreDeviceDriver(pciAddress, originalDriver); restoreErr != nil {
+		if restoreErr := restoreDeviceDriver(pciAddress, originalDriver); restoreErr != nil {
 			return fmt.Errorf("%w; additionally failed to restore original driver for device %s: %v", cause, pciAddress, restoreErr)
 		}
 		return cause
@@ -335,7 +425,7 @@ func (s *Manager) applyConfigOnDevice(ctx context.Context, ifNameIndex *int, cla
 	ifName := config.IfName
 	// if the device name is not set, we use the default interface prefix
 	// and the interface index, we also bump the index.
-	if s.isStandaloneMode() && ifName == "" {
+	if mode == consts.ConfigurationModeStandalone && ifName == "" {
 		ifName = fmt.Sprintf("%s%d", s.defaultInterfacePrefix, *ifNameIndex)
 		*ifNameIndex++
 	}
@@ -364,6 +454,7 @@ func (s *Manager) applyConfigOnDevice(ctx context.Context, ifNameIndex *int, cla
 		MultusResourceName: multusResourceName,
 		DeviceAttributes:   metadataAttributes,
 		PodUID:             string(claim.Status.ReservedFor[0].UID),
+		PodName:            claim.Status.ReservedFor[0].Name,
 		Config:             config,
 		OriginalDriver:     originalDriver,
 	}
@@ -387,7 +478,7 @@ func (s *Manager) handleRDMADevice(ctx context.Context, deviceInfo resourceapi.D
 
 	if len(rdmaDevices) == 0 {
 		logger.V(2).Info("No RDMA devices found for PCI address", "device", pciAddress)
-		return nil, nil, fmt.Errorf("no RDMA devices found for PCI address %s", pciAddress)
+		return nil, nil, events.WithReason(events.ReasonRDMADeviceMissing, fmt.Errorf("no RDMA devices found for PCI address %s", pciAddress))
 	}
 
 	if len(rdmaDevices) > 1 {
@@ -409,7 +500,7 @@ func (s *Manager) handleRDMADevice(ctx context.Context, deviceInfo resourceapi.D
 	if len(charDevices) == 0 {
 		logger.V(2).Info("No RDMA character devices found",
 			"device", pciAddress, "rdmaDevice", rdmaDevice)
-		return nil, nil, fmt.Errorf("no RDMA character devices found for RDMA device %s (PCI: %s)", rdmaDevice, pciAddress)
+		return nil, nil, events.WithReason(events.ReasonRDMADeviceMissing, fmt.Errorf("no RDMA character devices found for RDMA device %s (PCI: %s)", rdmaDevice, pciAddress))
 	}
 
 	// Use RDMA device name in env var key to support multiple RDMA devices
@@ -455,7 +546,11 @@ func (s *Manager) getNetAttachDefRawConfig(ctx context.Context, namespace string
 		Namespace: namespace,
 	}, netAttachDef)
 	if err != nil {
-		return "", fmt.Errorf("error getting net attach def for net attach def %s/%s: %w", namespace, netAttachDefName, err)
+		err = fmt.Errorf("error getting net attach def for net attach def %s/%s: %w", namespace, netAttachDefName, err)
+		if apierrors.IsNotFound(err) {
+			return "", events.WithReason(events.ReasonNADNotFound, err)
+		}
+		return "", err
 	}
 	return netAttachDef.Spec.Config, nil
 }
@@ -504,7 +599,7 @@ func (s *Manager) unprepareDevices(preparedDevices drasriovtypes.PreparedDevices
 		}
 		// Restore original driver if a driver change was made
 		if preparedDevice.Config.Driver != "" {
-			if err := host.GetHelpers().RestoreDeviceDriver(preparedDevice.PciAddress, preparedDevice.OriginalDriver); err != nil {
+			if err := restoreDeviceDriver(preparedDevice.PciAddress, preparedDevice.OriginalDriver); err != nil {
 				logger.Error(err, "Failed to restore original driver for device", "device", preparedDevice.PciAddress, "originalDriver", preparedDevice.OriginalDriver)
 				return fmt.Errorf("failed to restore original driver for device %s: %w", preparedDevice.PciAddress, err)
 			}
@@ -514,12 +609,30 @@ func (s *Manager) unprepareDevices(preparedDevices drasriovtypes.PreparedDevices
 	return nil
 }
 
-// GetAdvertisedDevices returns only devices that are matched by a policy.
+// SnapshotDevices returns a copy of the discovered devices and the names of
+// the advertised ones.
+func (s *Manager) SnapshotDevices() (drasriovtypes.AllocatableDevices, map[string]bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	devices := make(drasriovtypes.AllocatableDevices, len(s.allocatable))
+	for name, device := range s.allocatable {
+		devices[name] = *device.DeepCopy()
+	}
+	advertised := make(map[string]bool, len(s.policyAttrKeys))
+	for name := range s.policyAttrKeys {
+		advertised[name] = true
+	}
+	return devices, advertised
+}
+
+// GetAdvertisedDevices returns a copy of the devices matched by a policy.
 func (s *Manager) GetAdvertisedDevices() drasriovtypes.AllocatableDevices {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	result := make(drasriovtypes.AllocatableDevices, len(s.policyAttrKeys))
 	for name := range s.policyAttrKeys {
 		if device, exists := s.allocatable[name]; exists {
-			result[name] = device
+			result[name] = *device.DeepCopy()
 		}
 	}
 	return result
@@ -533,6 +646,27 @@ func (s *Manager) UpdatePolicyDevices(ctx context.Context, policyDevices map[str
 	logger := klog.FromContext(ctx).WithName("UpdatePolicyDevices")
 	logger.V(2).Info("Updating policy devices", "policyDeviceCount", len(policyDevices))
 
+	if !s.applyPolicyDevices(logger, policyDevices) {
+		logger.V(2).Info("No changes to policy devices")
+		return nil
+	}
+
+	if s.republishCallback != nil {
+		if err := s.republishCallback(ctx); err != nil {
+			logger.Error(err, "Failed to republish resources after policy update")
+			return fmt.Errorf("failed to republish resources: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// applyPolicyDevices applies the policy attributes to the matched devices and
+// clears them from the others. It reports whether anything changed.
+func (s *Manager) applyPolicyDevices(logger klog.Logger, policyDevices map[string]map[resourceapi.QualifiedName]resourceapi.DeviceAttribute) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	changesMade := false
 
 	// Clear policy attributes from devices no longer in the policy set
@@ -605,20 +739,10 @@ func (s *Manager) UpdatePolicyDevices(ctx context.Context, policyDevices map[str
 		s.policyAttrKeys[deviceName] = newKeys
 	}
 
-	if !changesMade {
-		logger.V(2).Info("No changes to policy devices")
-		return nil
-	}
-
-	logger.Info("Policy devices updated", "totalDevices", len(s.allocatable), "advertisedDevices", len(s.policyAttrKeys))
-	if s.republishCallback != nil {
-		if err := s.republishCallback(ctx); err != nil {
-			logger.Error(err, "Failed to republish resources after policy update")
-			return fmt.Errorf("failed to republish resources: %w", err)
-		}
+	if changesMade {
+		logger.Info("Policy devices updated", "totalDevices", len(s.allocatable), "advertisedDevices", len(s.policyAttrKeys))
 	}
-
-	return nil
+	return changesMade
 }
 
 // clearPolicyAttributes removes all policy-set attributes from a device.
@@ -84,6 +84,73 @@ var _ = Describe("Manager", Serial, func() {
 			Expect(result).To(HaveKey("device1"))
 			Expect(result).To(HaveKey("device2"))
 		})
+
+		It("should return copies not updated by the policy controller", func() {
+			vendor := "8086"
+			m := &Manager{
+				allocatable: drasriovtypes.AllocatableDevices{
+					"device1": resourceapi.Device{Name: "device1", Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						consts.AttributeVendorID: {StringValue: &vendor},
+					}},
+				},
+			}
+
+			result := m.GetAllocatableDevices()
+			advertised := m.GetAdvertisedDevices()
+			Expect(m.UpdatePolicyDevices(context.Background(), map[string]map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				"device1": {"example.com/pool": {StringValue: ptr.To("pool-a")}},
+			})).To(Succeed())
+
+			Expect(result["device1"].Attributes).To(HaveLen(1))
+			Expect(advertised).To(BeEmpty())
+			Expect(m.GetAdvertisedDevices()["device1"].Attributes).To(HaveLen(2))
+
+			delete(result["device1"].Attributes, consts.AttributeVendorID)
+			Expect(m.allocatable["device1"].Attributes).To(HaveKey(resourceapi.QualifiedName(consts.AttributeVendorID)))
+		})
+
+		It("should leave out the attributes applied by the policies", func() {
+			vendor := "8086"
+			m := &Manager{
+				allocatable: drasriovtypes.AllocatableDevices{
+					"device1": resourceapi.Device{Name: "device1", Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						consts.AttributeVendorID: {StringValue: &vendor},
+					}},
+				},
+			}
+			Expect(m.UpdatePolicyDevices(context.Background(), map[string]map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				"device1": {"example.com/pool": {StringValue: ptr.To("pool-a")}},
+			})).To(Succeed())
+
+			Expect(m.GetAllocatableDevices()["device1"].Attributes).To(Equal(map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				consts.AttributeVendorID: {StringValue: &vendor},
+			}))
+			Expect(m.GetAdvertisedDevices()["device1"].Attributes).To(HaveKey(resourceapi.QualifiedName("example.com/pool")))
+		})
+
+		It("should be safe to call while the policy devices are updated", func() {
+			m := &Manager{
+				allocatable: drasriovtypes.AllocatableDevices{
+					"device1": resourceapi.Device{Name: "device1"},
+				},
+			}
+
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				for i := range 100 {
+					Expect(m.UpdatePolicyDevices(context.Background(), map[string]map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						"device1": {"example.com/index": {IntValue: ptr.To(int64(i))}},
+					})).To(Succeed())
+				}
+			}()
+			for range 100 {
+				device := m.GetAllocatableDevices()["device1"]
+				Expect(device.Attributes).NotTo(HaveKey(resourceapi.QualifiedName("example.com/unset")))
+			}
+			Eventually(done).Should(BeClosed())
+		})
 	})
 
 	Context("GetAllocatableDeviceByName", func() {
@@ -135,6 +202,41 @@ var _ = Describe("Manager", Serial, func() {
 			Expect(err).NotTo(HaveOccurred())
 			Expect(mode).To(Equal(string(consts.ConfigurationModeMultus)))
 		})
+
+		It("accepts explicit HYBRID mode", func() {
+			mode, err := normalizeConfigurationMode(string(consts.ConfigurationModeHybrid))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mode).To(Equal(string(consts.ConfigurationModeHybrid)))
+		})
+	})
+
+	Context("claimConfigurationMode", func() {
+		It("selects the claim mode in HYBRID mode, STANDALONE by default", func() {
+			manager := &Manager{configurationMode: string(consts.ConfigurationModeHybrid)}
+
+			mode, err := manager.claimConfigurationMode(&configapi.VfConfig{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mode).To(Equal(consts.ConfigurationModeStandalone))
+
+			mode, err = manager.claimConfigurationMode(&configapi.VfConfig{ConfigurationMode: string(consts.ConfigurationModeMultus)})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mode).To(Equal(consts.ConfigurationModeMultus))
+		})
+
+		It("uses the node mode outside of HYBRID mode", func() {
+			manager := &Manager{configurationMode: string(consts.ConfigurationModeMultus)}
+
+			mode, err := manager.claimConfigurationMode(&configapi.VfConfig{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mode).To(Equal(consts.ConfigurationModeMultus))
+		})
+
+		It("rejects claims selecting another mode outside of HYBRID mode", func() {
+			manager := &Manager{configurationMode: string(consts.ConfigurationModeStandalone)}
+
+			_, err := manager.claimConfigurationMode(&configapi.VfConfig{ConfigurationMode: string(consts.ConfigurationModeMultus)})
+			Expect(err).To(MatchError(ContainSubstring("requires the node to run in \"HYBRID\" mode")))
+		})
 	})
 
 	Context("getNetAttachDefRawConfig", func() {
@@ -359,6 +461,62 @@ var _ = Describe("Manager", Serial, func() {
 			Expect(err.Error()).To(ContainSubstring("error creating map of opaque device config"))
 		})
 
+		It("should return config validation errors from PrepareDevicesForClaim", func() {
+			cdiHandler, err := cdi.NewHandler(GinkgoT().TempDir())
+			Expect(err).NotTo(HaveOccurred())
+
+			m := &Manager{
+				cdi:         cdiHandler,
+				allocatable: drasriovtypes.AllocatableDevices{},
+			}
+
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-claim",
+					Namespace: "test-ns",
+					UID:       "claim-uid",
+				},
+				Status: resourceapi.ResourceClaimStatus{
+					Allocation: &resourceapi.AllocationResult{
+						Devices: resourceapi.DeviceAllocationResult{
+							Results: []resourceapi.DeviceRequestAllocationResult{
+								{
+									Driver:  consts.DriverName,
+									Device:  "device1",
+									Request: "req1",
+									Pool:    "pool1",
+								},
+							},
+							Config: []resourceapi.DeviceAllocationConfiguration{
+								{
+									Source:   resourceapi.AllocationConfigSourceClaim,
+									Requests: []string{"req1"},
+									DeviceConfiguration: resourceapi.DeviceConfiguration{
+										Opaque: &resourceapi.OpaqueDeviceConfiguration{
+											Driver: consts.DriverName,
+											Parameters: runtime.RawExtension{
+												Raw: []byte(`{
+													"apiVersion": "` + consts.GroupName + `/v1alpha1",
+													"kind": "VfConfig",
+													"netAttachDefName": "net",
+													"cniConfig": {"cniVersion": "1.0.0", "type": "sriov"}
+												}`),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+
+			ifNameIndex := 0
+			_, err = m.PrepareDevicesForClaim(context.Background(), &ifNameIndex, claim)
+			Expect(err).To(MatchError(ContainSubstring("mutually exclusive")))
+			Expect(claim.Status.Devices).To(BeEmpty())
+		})
+
 		It("should return standalone net-attach-def lookup errors from PrepareDevicesForClaim", func() {
 			cdiHandler, err := cdi.NewHandler(GinkgoT().TempDir())
 			Expect(err).NotTo(HaveOccurred())
@@ -950,6 +1108,131 @@ var _ = Describe("Manager", Serial, func() {
 			Expect(preparedDevice.IfName).To(Equal("net0"))
 		})
 
+		It("should use inline CNI config without a NetworkAttachmentDefinition", func() {
+			m := newTestManagerWithK8sClient()
+			m.defaultInterfacePrefix = "net"
+			m.allocatable = drasriovtypes.AllocatableDevices{
+				"device1": resourceapi.Device{
+					Name: "device1",
+					Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						consts.AttributePciAddress: {
+							StringValue: ptr.To("0000:01:00.1"),
+						},
+					},
+				},
+			}
+
+			config := &configapi.VfConfig{
+				CNIConfig: &runtime.RawExtension{Raw: []byte(`{"cniVersion":"1.0.0","name":"inline-net","type":"sriov"}`)},
+			}
+
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-claim",
+					Namespace: "test-ns",
+					UID:       "claim-uid",
+				},
+				Status: resourceapi.ResourceClaimStatus{
+					ReservedFor: []resourceapi.ResourceClaimConsumerReference{
+						{UID: "pod-uid"},
+					},
+				},
+			}
+
+			result := &resourceapi.DeviceRequestAllocationResult{
+				Device:  "device1",
+				Request: "req1",
+				Pool:    "pool1",
+			}
+
+			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", config).Return("", nil)
+
+			ifNameIndex := 0
+			preparedDevice, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, result)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(preparedDevice).NotTo(BeNil())
+			Expect(preparedDevice.NetAttachDefConfig).To(MatchJSON(`{"cniVersion":"1.0.0","name":"inline-net","type":"sriov","deviceID":"0000:01:00.1"}`))
+		})
+
+		It("should return error without a network in STANDALONE mode", func() {
+			m := newTestManagerWithK8sClient()
+			m.allocatable = drasriovtypes.AllocatableDevices{
+				"device1": resourceapi.Device{
+					Name: "device1",
+					Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						consts.AttributePciAddress: {
+							StringValue: ptr.To("0000:01:00.1"),
+						},
+					},
+				},
+			}
+
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-claim",
+					Namespace: "test-ns",
+					UID:       "claim-uid",
+				},
+			}
+			result := &resourceapi.DeviceRequestAllocationResult{
+				Device:  "device1",
+				Request: "req1",
+				Pool:    "pool1",
+			}
+
+			ifNameIndex := 0
+			_, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, configapi.DefaultVfConfig(), result)
+			Expect(err).To(MatchError("no net attach def name set"))
+		})
+
+		It("should not look up a NetworkAttachmentDefinition in netlink attach mode", func() {
+			m := newTestManagerWithK8sClient()
+			m.defaultInterfacePrefix = "net"
+			m.allocatable = drasriovtypes.AllocatableDevices{
+				"device1": resourceapi.Device{
+					Name: "device1",
+					Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						consts.AttributePciAddress: {
+							StringValue: ptr.To("0000:01:00.1"),
+						},
+					},
+				},
+			}
+
+			config := &configapi.VfConfig{
+				AttachMode: configapi.AttachModeNetlink,
+				Addresses:  []string{"192.168.1.10/24"},
+			}
+
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-claim",
+					Namespace: "test-ns",
+					UID:       "claim-uid",
+				},
+				Status: resourceapi.ResourceClaimStatus{
+					ReservedFor: []resourceapi.ResourceClaimConsumerReference{
+						{UID: "pod-uid"},
+					},
+				},
+			}
+
+			result := &resourceapi.DeviceRequestAllocationResult{
+				Device:  "device1",
+				Request: "req1",
+				Pool:    "pool1",
+			}
+
+			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", config).Return("", nil)
+
+			ifNameIndex := 0
+			preparedDevice, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, result)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(preparedDevice).NotTo(BeNil())
+			Expect(preparedDevice.NetAttachDefConfig).To(BeEmpty())
+			Expect(preparedDevice.IfName).To(Equal("net0"))
+		})
+
 		It("restores the original driver when VFIO file lookup fails", func() {
 			m := &Manager{
 				allocatable: drasriovtypes.AllocatableDevices{
@@ -1063,6 +1346,28 @@ var _ = Describe("Manager", Serial, func() {
 			Expect(advertised).To(HaveKey("devA"))
 		})
 
+		It("SnapshotDevices returns a copy of all devices and the advertised names", func() {
+			vendor := "8086"
+			s := &Manager{
+				allocatable: map[string]resourceapi.Device{
+					"devA": {Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						consts.AttributeVendorID: {StringValue: &vendor},
+					}},
+					"devB": {},
+				},
+				policyAttrKeys: map[string]map[resourceapi.QualifiedName]bool{
+					"devA": {},
+				},
+			}
+
+			devices, advertised := s.SnapshotDevices()
+			Expect(devices).To(HaveLen(2))
+			Expect(advertised).To(Equal(map[string]bool{"devA": true}))
+
+			delete(devices["devA"].Attributes, consts.AttributeVendorID)
+			Expect(s.allocatable["devA"].Attributes).To(HaveKey(resourceapi.QualifiedName(consts.AttributeVendorID)))
+		})
+
 		It("should trigger republish callback when changes are made", func() {
 			callbackCalled := false
 			callback := func(ctx context.Context) error {
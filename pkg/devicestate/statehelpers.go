@@ -1,6 +1,7 @@
 package devicestate
 
 import (
+	"context"
 	"fmt"
 
 	resourceapi "k8s.io/api/resource/v1"
@@ -9,6 +10,9 @@ import (
 
 	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/metrics"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/tracing"
 )
 
 // GetOpaqueDeviceConfigs returns an ordered list of the configs contained in possibleConfigs for this driver.
@@ -80,6 +84,35 @@ func getMapOfOpaqueDeviceConfigForDevice(
 		}
 	}
 
+	// the settings depend on each other and may be set by different
+	// configs, so they are validated once merged
+	for request, resultConfig := range resultConfigs {
+		if err := resultConfig.Validate(); err != nil {
+			return nil, fmt.Errorf("error validating config parameters for request %q: %w", request, err)
+		}
+	}
+
 	klog.V(3).InfoS("Result configs", "resultConfigs", resultConfigs)
 	return resultConfigs, nil
 }
+
+// bindDeviceDriver binds a device to the driver of its config, counts and
+// traces the operation, it returns the driver the device was bound to before.
+func bindDeviceDriver(ctx context.Context, pciAddress string, config *configapi.VfConfig) (string, error) {
+	_, span := tracing.Start(ctx, "BindDeviceDriver",
+		tracing.AttributePciAddress.String(pciAddress), tracing.AttributeDriver.String(config.Driver))
+	originalDriver, err := host.GetHelpers().BindDeviceDriver(pciAddress, config)
+	tracing.End(span, err)
+	if config.Driver != "" {
+		metrics.DriverOperation(metrics.DriverBind, config.Driver, err)
+	}
+	return originalDriver, err
+}
+
+// restoreDeviceDriver binds a device back to its original driver and counts
+// the operation.
+func restoreDeviceDriver(pciAddress, originalDriver string) error {
+	err := host.GetHelpers().RestoreDeviceDriver(pciAddress, originalDriver)
+	metrics.DriverOperation(metrics.DriverUnbind, originalDriver, err)
+	return err
+}
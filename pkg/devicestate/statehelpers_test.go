@@ -480,6 +480,57 @@ var _ = Describe("getMapOfOpaqueDeviceConfigForDevice", func() {
 			Expect(err.Error()).To(ContainSubstring("error decoding config parameters"))
 		})
 
+		It("should return error for invalid inline CNI config", func() {
+			configs := []resourceapi.DeviceAllocationConfiguration{
+				{
+					Source:   resourceapi.AllocationConfigSourceClaim,
+					Requests: []string{"request1"},
+					DeviceConfiguration: resourceapi.DeviceConfiguration{
+						Opaque: &resourceapi.OpaqueDeviceConfiguration{
+							Driver: consts.DriverName,
+							Parameters: runtime.RawExtension{
+								Raw: []byte(`{
+									"apiVersion": "` + consts.GroupName + `/v1alpha1",
+									"kind": "VfConfig",
+									"cniConfig": {"cniVersion": "1.0.0", "name": "net"}
+								}`),
+							},
+						},
+					},
+				},
+			}
+
+			_, err := getMapOfOpaqueDeviceConfigForDevice(decoder, configs)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("error validating config parameters"))
+		})
+
+		It("should return error for netlink settings outside netlink attach mode", func() {
+			configs := []resourceapi.DeviceAllocationConfiguration{
+				{
+					Source:   resourceapi.AllocationConfigSourceClaim,
+					Requests: []string{"request1"},
+					DeviceConfiguration: resourceapi.DeviceConfiguration{
+						Opaque: &resourceapi.OpaqueDeviceConfiguration{
+							Driver: consts.DriverName,
+							Parameters: runtime.RawExtension{
+								Raw: []byte(`{
+									"apiVersion": "` + consts.GroupName + `/v1alpha1",
+									"kind": "VfConfig",
+									"netAttachDefName": "net",
+									"mtu": 9000
+								}`),
+							},
+						},
+					},
+				},
+			}
+
+			_, err := getMapOfOpaqueDeviceConfigForDevice(decoder, configs)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("error validating config parameters"))
+		})
+
 		It("should return empty result when no configs match driver", func() {
 			configs := []resourceapi.DeviceAllocationConfiguration{
 				{
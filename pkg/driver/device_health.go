@@ -0,0 +1,139 @@
+package driver
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"k8s.io/klog/v2"
+	drahealthv1alpha1 "k8s.io/kubelet/pkg/apis/dra-health/v1alpha1"
+)
+
+// deviceHealthKey identifies a device within the DRA health service.
+type deviceHealthKey struct {
+	pool   string
+	device string
+}
+
+// deviceHealthTracker keeps the last reported health of prepared devices and
+// fans out changes to the kubelet health watchers.
+type deviceHealthTracker struct {
+	mu       sync.Mutex
+	devices  map[deviceHealthKey]*drahealthv1alpha1.DeviceHealth
+	watchers map[chan struct{}]struct{}
+}
+
+func (t *deviceHealthTracker) set(key deviceHealthKey, health *drahealthv1alpha1.DeviceHealth) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.devices == nil {
+		t.devices = make(map[deviceHealthKey]*drahealthv1alpha1.DeviceHealth)
+	}
+	t.devices[key] = health
+	t.notifyLocked()
+}
+
+func (t *deviceHealthTracker) forget(key deviceHealthKey) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, found := t.devices[key]; !found {
+		return
+	}
+	delete(t.devices, key)
+	t.notifyLocked()
+}
+
+func (t *deviceHealthTracker) snapshot() []*drahealthv1alpha1.DeviceHealth {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	devices := make([]*drahealthv1alpha1.DeviceHealth, 0, len(t.devices))
+	for _, health := range t.devices {
+		devices = append(devices, health)
+	}
+	return devices
+}
+
+func (t *deviceHealthTracker) watch() chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.watchers == nil {
+		t.watchers = make(map[chan struct{}]struct{})
+	}
+	ch := make(chan struct{}, 1)
+	t.watchers[ch] = struct{}{}
+	return ch
+}
+
+func (t *deviceHealthTracker) unwatch(ch chan struct{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.watchers, ch)
+}
+
+// notifyLocked wakes up all watchers without blocking, a pending notification
+// already guarantees that the watcher sends the latest snapshot.
+func (t *deviceHealthTracker) notifyLocked() {
+	for ch := range t.watchers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// UpdateDeviceHealth records the health of a prepared device and streams it to
+// the kubelet through the DRAResourceHealth service.
+func (d *Driver) UpdateDeviceHealth(ctx context.Context, poolName, deviceName string, healthy bool, message string) {
+	status := drahealthv1alpha1.HealthStatus_HEALTHY
+	if !healthy {
+		status = drahealthv1alpha1.HealthStatus_UNHEALTHY
+	}
+	klog.FromContext(ctx).V(3).Info("Updating device health", "poolName", poolName, "deviceName", deviceName, "health", status.String(), "message", message)
+
+	d.deviceHealth.set(deviceHealthKey{pool: poolName, device: deviceName}, &drahealthv1alpha1.DeviceHealth{
+		Device: &drahealthv1alpha1.DeviceIdentifier{
+			PoolName:   poolName,
+			DeviceName: deviceName,
+		},
+		Health:                    status,
+		LastUpdatedTime:           time.Now().Unix(),
+		HealthCheckTimeoutSeconds: d.healthCheckTimeoutSeconds(),
+		Message:                   message,
+	})
+}
+
+// healthCheckTimeoutSeconds tells the kubelet how long a reported status stays
+// valid. Statuses are refreshed on every CNI CHECK round, so allow a few
+// missed rounds before the kubelet falls back to unknown.
+func (d *Driver) healthCheckTimeoutSeconds() int64 {
+	if d.config == nil || d.config.Flags == nil || d.config.Flags.CNICheckInterval <= 0 {
+		return 0
+	}
+	return int64((3 * d.config.Flags.CNICheckInterval).Seconds())
+}
+
+// NodeWatchResources implements [drahealthv1alpha1.DRAResourceHealthServer].
+// It sends the current health of all tracked devices and then a new snapshot
+// every time a device health changes.
+func (d *Driver) NodeWatchResources(_ *drahealthv1alpha1.NodeWatchResourcesRequest, stream grpc.ServerStreamingServer[drahealthv1alpha1.NodeWatchResourcesResponse]) error {
+	ctx := stream.Context()
+	logger := klog.FromContext(ctx).WithName("NodeWatchResources")
+	logger.V(2).Info("Kubelet started watching device health")
+
+	changed := d.deviceHealth.watch()
+	defer d.deviceHealth.unwatch(changed)
+
+	for {
+		if err := stream.Send(&drahealthv1alpha1.NodeWatchResourcesResponse{Devices: d.deviceHealth.snapshot()}); err != nil {
+			logger.Error(err, "Failed to send device health")
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			logger.V(2).Info("Kubelet stopped watching device health")
+			return nil
+		case <-changed:
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package driver
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"google.golang.org/grpc"
+	drahealthv1alpha1 "k8s.io/kubelet/pkg/apis/dra-health/v1alpha1"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// fakeHealthStream collects the responses sent by NodeWatchResources.
+type fakeHealthStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	responses chan *drahealthv1alpha1.NodeWatchResourcesResponse
+}
+
+func (s *fakeHealthStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeHealthStream) Send(resp *drahealthv1alpha1.NodeWatchResourcesResponse) error {
+	s.responses <- resp
+	return nil
+}
+
+var _ = Describe("Device health", func() {
+	var (
+		d      *Driver
+		ctx    context.Context
+		cancel context.CancelFunc
+		stream *fakeHealthStream
+		done   chan error
+		// stopped is closed when the watch started by the spec returned, nil
+		// when the spec started none.
+		stopped chan struct{}
+	)
+
+	BeforeEach(func() {
+		d = &Driver{config: &types.Config{Flags: &types.Flags{CNICheckInterval: 10 * time.Second}}}
+		ctx, cancel = context.WithCancel(context.Background())
+		stream = &fakeHealthStream{ctx: ctx, responses: make(chan *drahealthv1alpha1.NodeWatchResourcesResponse, 10)}
+		done = make(chan error, 1)
+		stopped = nil
+	})
+
+	AfterEach(func() {
+		cancel()
+		if stopped != nil {
+			Eventually(stopped).Should(BeClosed())
+		}
+	})
+
+	startWatch := func() {
+		stopped = make(chan struct{})
+		go func() {
+			defer close(stopped)
+			done <- d.NodeWatchResources(&drahealthv1alpha1.NodeWatchResourcesRequest{}, stream)
+		}()
+	}
+
+	It("sends the current snapshot when the kubelet starts watching", func() {
+		d.UpdateDeviceHealth(ctx, "pool-a", "dev-a", true, "")
+		startWatch()
+
+		var resp *drahealthv1alpha1.NodeWatchResourcesResponse
+		Eventually(stream.responses).Should(Receive(&resp))
+		Expect(resp.Devices).To(HaveLen(1))
+		Expect(resp.Devices[0].Device.PoolName).To(Equal("pool-a"))
+		Expect(resp.Devices[0].Device.DeviceName).To(Equal("dev-a"))
+		Expect(resp.Devices[0].Health).To(Equal(drahealthv1alpha1.HealthStatus_HEALTHY))
+		Expect(resp.Devices[0].HealthCheckTimeoutSeconds).To(Equal(int64(30)))
+	})
+
+	It("streams health changes and forgotten devices", func() {
+		startWatch()
+		var resp *drahealthv1alpha1.NodeWatchResourcesResponse
+		Eventually(stream.responses).Should(Receive(&resp))
+		Expect(resp.Devices).To(BeEmpty())
+
+		d.UpdateDeviceHealth(ctx, "pool-a", "dev-a", false, "link down")
+		Eventually(stream.responses).Should(Receive(&resp))
+		Expect(resp.Devices).To(HaveLen(1))
+		Expect(resp.Devices[0].Health).To(Equal(drahealthv1alpha1.HealthStatus_UNHEALTHY))
+		Expect(resp.Devices[0].Message).To(Equal("link down"))
+
+		d.deviceHealth.forget(deviceHealthKey{pool: "pool-a", device: "dev-a"})
+		Eventually(stream.responses).Should(Receive(&resp))
+		Expect(resp.Devices).To(BeEmpty())
+	})
+
+	It("returns when the stream is closed", func() {
+		startWatch()
+		Eventually(stream.responses).Should(Receive())
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+	})
+})
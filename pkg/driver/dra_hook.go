@@ -4,17 +4,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	corev1 "k8s.io/api/core/v1"
 	resourceapi "k8s.io/api/resource/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/dynamic-resource-allocation/kubeletplugin"
 	"k8s.io/klog/v2"
 
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/events"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/metrics"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/tracing"
 )
 
 func (d *Driver) PrepareResourceClaims(ctx context.Context, claims []*resourceapi.ResourceClaim) (map[k8stypes.UID]kubeletplugin.PrepareResult, error) {
@@ -24,6 +31,8 @@ func (d *Driver) PrepareResourceClaims(ctx context.Context, claims []*resourceap
 	}
 	logger := klog.FromContext(ctx).WithName("PrepareResourceClaims")
 	logger.V(3).Info("claims", "claims", claims)
+	defer metrics.ObserveClaimOperation(metrics.OperationPrepare, time.Now())
+	defer d.updatePreparedDeviceMetrics()
 
 	// we share this between all the claims so we can enumerate network interfaces
 	ifNameIndex := 0
@@ -31,10 +40,13 @@ func (d *Driver) PrepareResourceClaims(ctx context.Context, claims []*resourceap
 	for _, claim := range claims {
 		logger.V(1).Info("Preparing claim", "claim", claim.UID)
 		logger.V(3).Info("Claim", "claim", claim)
-		result[claim.UID] = d.prepareResourceClaim(ctx, &ifNameIndex, claim)
+		claimCtx, span := tracing.Start(ctx, "PrepareResourceClaim", claimSpanAttributes(claim)...)
+		result[claim.UID] = d.prepareResourceClaim(claimCtx, &ifNameIndex, claim)
+		tracing.End(span, result[claim.UID].Err)
 		logger.V(1).Info("Prepared claim", "claim", claim.UID, "result", result[claim.UID])
 		if result[claim.UID].Err != nil {
 			logger.Error(result[claim.UID].Err, "failed to prepare resource claim", "claim", claim)
+			d.emitPrepareFailed(claim, result[claim.UID].Err)
 		}
 	}
 
@@ -52,6 +64,7 @@ func (d *Driver) PrepareResourceClaims(ctx context.Context, claims []*resourceap
 	preparedDevices, exists := d.podManager.GetDevicesByPodUID(podUID)
 	if !exists && len(claims) > 0 {
 		logger.Error(fmt.Errorf("no prepared devices found for pod %s", podUID), "Error preparing devices for claim")
+		metrics.ClaimOperationFailed(metrics.OperationPrepare, metrics.ReasonMissingPodDevice)
 		return result, fmt.Errorf("no prepared devices found for pod %s", podUID)
 	}
 	// create a global spec file for the pod level environment variables
@@ -61,6 +74,7 @@ func (d *Driver) PrepareResourceClaims(ctx context.Context, claims []*resourceap
 		if !exist {
 			baseErr := fmt.Errorf("device not found for device name %s", preparedDevice.Device.DeviceName)
 			logger.Error(baseErr, "Error preparing devices for claim")
+			metrics.ClaimOperationFailed(metrics.OperationPrepare, metrics.ReasonDeviceNotFound)
 			if cleanupErr := d.rollbackPreparedClaims(ctx, claims); cleanupErr != nil {
 				return result, errors.Join(baseErr, fmt.Errorf("cleanup failed after prepare error: %w", cleanupErr))
 			}
@@ -69,10 +83,13 @@ func (d *Driver) PrepareResourceClaims(ctx context.Context, claims []*resourceap
 		pciAddresses = append(pciAddresses, *device.Attributes[consts.AttributePciAddress].StringValue)
 	}
 
+	_, span := tracing.Start(ctx, "CreateGlobalPodSpecFile", tracing.AttributePodUID.String(string(podUID)))
 	err := d.cdi.CreateGlobalPodSpecFile(string(podUID), pciAddresses)
+	tracing.End(span, err)
 	if err != nil {
 		logger.Error(err, "Error creating global spec file for pod", "pod", podUID)
 		baseErr := fmt.Errorf("error creating global spec file for pod: %w", err)
+		metrics.ClaimOperationFailed(metrics.OperationPrepare, metrics.ReasonCDISpec)
 		if cleanupErr := d.rollbackPreparedClaims(ctx, claims); cleanupErr != nil {
 			return result, errors.Join(baseErr, fmt.Errorf("cleanup failed after global spec error: %w", cleanupErr))
 		}
@@ -112,19 +129,15 @@ func (d *Driver) prepareResourceClaim(ctx context.Context, ifNameIndex *int, cla
 	// Get pod info from claim
 	if len(claim.Status.ReservedFor) == 0 {
 		logger.Error(fmt.Errorf("no pod info found for claim %s/%s/%s", claim.Namespace, claim.Name, claim.UID), "Error preparing devices for claim")
-		return kubeletplugin.PrepareResult{
-			Err: fmt.Errorf("no pod info found for claim %s/%s/%s", claim.Namespace, claim.Name, claim.UID),
-		}
+		return prepareFailed(metrics.ReasonInvalidClaim, fmt.Errorf("no pod info found for claim %s/%s/%s", claim.Namespace, claim.Name, claim.UID))
 	} else if len(claim.Status.ReservedFor) > 1 {
 		logger.Error(fmt.Errorf("multiple pods found for claim %s/%s/%s not supported", claim.Namespace, claim.Name, claim.UID), "Error preparing devices for claim")
-		return kubeletplugin.PrepareResult{
-			Err: fmt.Errorf("multiple pods found for claim %s/%s/%s not supported", claim.Namespace, claim.Name, claim.UID),
-		}
+		return prepareFailed(metrics.ReasonInvalidClaim, fmt.Errorf("multiple pods found for claim %s/%s/%s not supported", claim.Namespace, claim.Name, claim.UID))
 	}
 
 	if claim.Status.Allocation == nil {
 		logger.Error(fmt.Errorf("claim not yet allocated"), "Prepare failed", "claim", claim.UID)
-		return kubeletplugin.PrepareResult{Err: fmt.Errorf("claim not yet allocated")}
+		return prepareFailed(metrics.ReasonInvalidClaim, fmt.Errorf("claim not yet allocated"))
 	}
 
 	// get the pod UID
@@ -144,9 +157,7 @@ func (d *Driver) prepareResourceClaim(ctx context.Context, ifNameIndex *int, cla
 	preparedDevices, err := d.deviceStateManager.PrepareDevicesForClaim(ctx, ifNameIndex, claim)
 	if err != nil {
 		logger.Error(err, "Error preparing devices for claim", "claim", claim.UID)
-		return kubeletplugin.PrepareResult{
-			Err: fmt.Errorf("error preparing devices for claim %v: %w", claim.UID, err),
-		}
+		return prepareFailed(metrics.ReasonDevicePrepare, fmt.Errorf("error preparing devices for claim %v: %w", claim.UID, err))
 	}
 
 	var prepared []kubeletplugin.Device
@@ -158,13 +169,9 @@ func (d *Driver) prepareResourceClaim(ctx context.Context, ifNameIndex *int, cla
 	if err != nil {
 		logger.Error(err, "Error setting prepared devices for pod into pod manager", "pod", podUID)
 		if cleanupErr := d.deviceStateManager.Unprepare(string(claim.UID), preparedDevices); cleanupErr != nil {
-			return kubeletplugin.PrepareResult{
-				Err: fmt.Errorf("error setting prepared devices for pod %s into pod manager: %w; cleanup failed: %v", podUID, err, cleanupErr),
-			}
-		}
-		return kubeletplugin.PrepareResult{
-			Err: fmt.Errorf("error setting prepared devices for pod %s into pod manager: %w", podUID, err),
+			return prepareFailed(metrics.ReasonCheckpoint, fmt.Errorf("error setting prepared devices for pod %s into pod manager: %w; cleanup failed: %v", podUID, err, cleanupErr))
 		}
+		return prepareFailed(metrics.ReasonCheckpoint, fmt.Errorf("error setting prepared devices for pod %s into pod manager: %w", podUID, err))
 	}
 
 	// Store original devices list to preserve across conflict retries
@@ -209,9 +216,17 @@ func (d *Driver) UnprepareResourceClaims(ctx context.Context, claims []kubeletpl
 	logger.V(1).Info("UnprepareResourceClaims is called", "number of claims", len(claims))
 	logger.V(3).Info("claims", "claims", claims)
 	result := make(map[k8stypes.UID]error)
+	defer metrics.ObserveClaimOperation(metrics.OperationUnprepare, time.Now())
+	defer d.updatePreparedDeviceMetrics()
 
 	for _, claim := range claims {
-		result[claim.UID] = d.unprepareResourceClaim(ctx, claim)
+		claimCtx, span := tracing.Start(ctx, "UnprepareResourceClaim", tracing.ClaimAttributes(claim.Namespace, claim.Name, claim.UID)...)
+		result[claim.UID] = d.unprepareResourceClaim(claimCtx, claim)
+		tracing.End(span, result[claim.UID])
+		if result[claim.UID] != nil {
+			events.Warning(d.eventRecorder(), events.Reason(result[claim.UID], events.ReasonUnprepareFailed), result[claim.UID].Error(),
+				events.ClaimReference(claim.Namespace, claim.Name, claim.UID))
+		}
 	}
 
 	logger.V(3).Info("Unprepared claims", "result", result)
@@ -228,19 +243,70 @@ func (d *Driver) unprepareResourceClaim(ctx context.Context, claim kubeletplugin
 		return nil
 	}
 
+	// without NRI no event reports the stopped sandbox before the claim is
+	// unprepared, detach the networks still attached first
+	if detacher := d.getNetworkDetacher(); detacher != nil {
+		if err := detacher.DetachNetworks(ctx, preparedDevices); err != nil {
+			metrics.ClaimOperationFailed(metrics.OperationUnprepare, metrics.ReasonNetworkDetach)
+			return fmt.Errorf("error detaching networks for claim %v: %w", claim.UID, err)
+		}
+	}
+
 	if err := d.deviceStateManager.Unprepare(string(claim.UID), preparedDevices); err != nil {
+		metrics.ClaimOperationFailed(metrics.OperationUnprepare, metrics.ReasonDeviceUnprepare)
 		return fmt.Errorf("error unpreparing devices for claim %v: %w", claim.UID, err)
 	}
+	for _, preparedDevice := range preparedDevices {
+		d.deviceHealth.forget(deviceHealthKey{pool: preparedDevice.Device.PoolName, device: preparedDevice.Device.DeviceName})
+	}
 
 	// delete the claim from the pod manager
 	err := d.podManager.DeleteClaim(claim)
 	if err != nil {
 		logger.Error(err, "Error deleting claim from pod manager", "claim", claim.UID)
+		metrics.ClaimOperationFailed(metrics.OperationUnprepare, metrics.ReasonCheckpoint)
 		return fmt.Errorf("error deleting claim %s from pod manager: %w", claim.UID, err)
 	}
 	return nil
 }
 
+// prepareFailed counts a claim failing to prepare and returns its result.
+func prepareFailed(reason string, err error) kubeletplugin.PrepareResult {
+	metrics.ClaimOperationFailed(metrics.OperationPrepare, reason)
+	return kubeletplugin.PrepareResult{Err: err}
+}
+
+// claimSpanAttributes returns the span attributes of a claim and of the pod
+// it is reserved for.
+func claimSpanAttributes(claim *resourceapi.ResourceClaim) []attribute.KeyValue {
+	attrs := tracing.ClaimAttributes(claim.Namespace, claim.Name, claim.UID)
+	if len(claim.Status.ReservedFor) > 0 {
+		attrs = append(attrs,
+			tracing.AttributePodUID.String(string(claim.Status.ReservedFor[0].UID)),
+			tracing.AttributePodName.String(claim.Status.ReservedFor[0].Name))
+	}
+	return attrs
+}
+
+// emitPrepareFailed emits a Warning event on a claim failing to prepare and on
+// the pod it is reserved for.
+func (d *Driver) emitPrepareFailed(claim *resourceapi.ResourceClaim, err error) {
+	var pod *corev1.ObjectReference
+	if len(claim.Status.ReservedFor) > 0 {
+		pod = events.PodReference(claim.Namespace, claim.Status.ReservedFor[0].Name, claim.Status.ReservedFor[0].UID)
+	}
+	events.Warning(d.eventRecorder(), events.Reason(err, events.ReasonPrepareFailed), err.Error(),
+		events.ClaimReference(claim.Namespace, claim.Name, claim.UID), pod)
+}
+
+// eventRecorder returns the recorder of the Warning events, nil without one.
+func (d *Driver) eventRecorder() record.EventRecorder {
+	if d.config == nil {
+		return nil
+	}
+	return d.config.EventRecorder
+}
+
 func (d *Driver) HandleError(ctx context.Context, err error, msg string) {
 	utilruntime.HandleErrorWithContext(ctx, err, msg)
 	if !errors.Is(err, kubeletplugin.ErrRecoverable) && d.cancelCtx != nil {
@@ -20,8 +20,7 @@ import (
 	"context"
 	"fmt"
 	"maps"
-	"os"
-	"path"
+	"sync"
 	"time"
 
 	resourceapi "k8s.io/api/resource/v1"
@@ -31,11 +30,14 @@ import (
 	"k8s.io/dynamic-resource-allocation/kubeletplugin"
 	"k8s.io/dynamic-resource-allocation/resourceslice"
 	"k8s.io/klog/v2"
+	drahealthv1alpha1 "k8s.io/kubelet/pkg/apis/dra-health/v1alpha1"
 
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cdi"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/devicestate"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/metrics"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/tracing"
 	sriovdratype "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 )
 
@@ -45,9 +47,12 @@ var (
 	enableDeviceMetadataOption = kubeletplugin.EnableDeviceMetadata
 	cdiDirectoryOption         = kubeletplugin.CDIDirectory
 	metadataVersionsOption     = kubeletplugin.MetadataVersions
+	rollingUpdateOption        = kubeletplugin.RollingUpdate
 )
 
 type Driver struct {
+	drahealthv1alpha1.UnimplementedDRAResourceHealthServer
+
 	client             coreclientset.Interface
 	helper             *kubeletplugin.Helper
 	deviceStateManager *devicestate.Manager
@@ -56,6 +61,30 @@ type Driver struct {
 	cancelCtx          func(error)
 	config             *sriovdratype.Config
 	cdi                *cdi.Handler
+	deviceHealth       deviceHealthTracker
+	readiness          readinessChecks
+
+	// mu guards networkDetacher and lastPublish.
+	mu sync.Mutex
+	// networkDetacher detaches the networks still attached when a claim is
+	// unprepared, set when networks are attached from CDI hooks.
+	networkDetacher sriovdratype.NetworkDetacher
+	// lastPublish is the result of the last ResourceSlice publication.
+	lastPublish sriovdratype.PublishStatus
+}
+
+// SetNetworkDetacher sets the detacher run for attached devices when a claim
+// is unprepared.
+func (d *Driver) SetNetworkDetacher(detacher sriovdratype.NetworkDetacher) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.networkDetacher = detacher
+}
+
+func (d *Driver) getNetworkDetacher() sriovdratype.NetworkDetacher {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.networkDetacher
 }
 
 func buildPluginOptions(config *sriovdratype.Config) []kubeletplugin.Option {
@@ -66,6 +95,9 @@ func buildPluginOptions(config *sriovdratype.Config) []kubeletplugin.Option {
 		kubeletplugin.RegistrarDirectoryPath(config.Flags.KubeletRegistrarDirectoryPath),
 		kubeletplugin.PluginDataDirectoryPath(config.DriverPluginPath()),
 	}
+	if config.Flags.PodUID != "" {
+		pluginOpts = append(pluginOpts, rollingUpdateOption(k8stypes.UID(config.Flags.PodUID)))
+	}
 	if config.Flags.EnableDeviceMetadata {
 		pluginOpts = append(
 			pluginOpts,
@@ -74,6 +106,9 @@ func buildPluginOptions(config *sriovdratype.Config) []kubeletplugin.Option {
 			metadataVersionsOption(metadatav1alpha1.SchemeGroupVersion),
 		)
 	}
+	if config.Flags.TracingEndpoint != "" {
+		pluginOpts = append(pluginOpts, kubeletplugin.GRPCInterceptor(tracing.GRPCInterceptor()))
+	}
 	return pluginOpts
 }
 
@@ -102,7 +137,9 @@ func Start(ctx context.Context, config *sriovdratype.Config, deviceStateManager
 		return nil, err
 	}
 
-	driver.healthcheck, err = startHealthcheck(ctx, config)
+	driver.AddReadinessCheck("publish", driver.checkPublish)
+	driver.AddReadinessCheck("checkpoint", func(context.Context) error { return podManager.CheckpointWritable() })
+	driver.healthcheck, err = startHealthcheck(ctx, config, &driver.readiness)
 	if err != nil {
 		return nil, fmt.Errorf("start healthcheck: %w", err)
 	}
@@ -111,6 +148,7 @@ func Start(ctx context.Context, config *sriovdratype.Config, deviceStateManager
 	if err = driver.PublishResources(ctx); err != nil {
 		return nil, fmt.Errorf("failed to publish resources: %w", err)
 	}
+	driver.updatePreparedDeviceMetrics()
 	return driver, nil
 }
 
@@ -141,24 +179,14 @@ func waitForRegistration(ctx context.Context, helper *kubeletplugin.Helper) erro
 	}
 }
 
-// Shutdown shuts down the driver
+// Shutdown shuts down the driver. Stopping the kubelet plugin removes its
+// sockets, the sockets of another instance running during a rolling update
+// are left in place.
 func (d *Driver) Shutdown(logger klog.Logger) error {
 	if d.healthcheck != nil {
 		d.healthcheck.Stop(logger)
 	}
 	d.helper.Stop()
-
-	// remove the socket files
-	// TODO: this is not needed after https://github.com/kubernetes/kubernetes/pull/133934 is merged
-	err := os.Remove(path.Join(d.config.Flags.KubeletRegistrarDirectoryPath, consts.DriverName+"-reg.sock"))
-	if err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("error removing socket file: %w", err)
-	}
-	err = os.Remove(path.Join(d.config.DriverPluginPath(), "dra.sock"))
-	if err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("error removing socket file: %w", err)
-	}
-
 	return nil
 }
 
@@ -182,12 +210,48 @@ func (d *Driver) PublishResources(ctx context.Context) error {
 		},
 	}
 
-	if err := d.helper.PublishResources(ctx, resources); err != nil {
+	err := d.helper.PublishResources(ctx, resources)
+	metrics.ResourceSlicePublished(err)
+	d.mu.Lock()
+	d.lastPublish = sriovdratype.PublishStatus{Time: time.Now(), Err: err}
+	d.mu.Unlock()
+	if err != nil {
 		return err
 	}
+	metrics.SetDevices(metrics.DevicesDiscovered, devicesByPF(d.deviceStateManager.GetAllocatableDevices()))
+	metrics.SetDevices(metrics.DevicesAdvertised, devicesByPF(advertised))
 	return nil
 }
 
+// updatePreparedDeviceMetrics refreshes the number of prepared devices per PF.
+func (d *Driver) updatePreparedDeviceMetrics() {
+	if d.podManager == nil || d.deviceStateManager == nil {
+		return
+	}
+	prepared := sriovdratype.AllocatableDevices{}
+	for _, devices := range d.podManager.ListDevicesByPodUID() {
+		for _, preparedDevice := range devices {
+			if device, exists := d.deviceStateManager.GetAllocatableDeviceByName(preparedDevice.Device.DeviceName); exists {
+				prepared[preparedDevice.Device.DeviceName] = device
+			}
+		}
+	}
+	metrics.SetDevices(metrics.DevicesPrepared, devicesByPF(prepared))
+}
+
+// devicesByPF counts devices by the name of their PF.
+func devicesByPF(devices sriovdratype.AllocatableDevices) map[string]int {
+	counts := map[string]int{}
+	for _, device := range devices {
+		pfName := ""
+		if attr, exists := device.Attributes[consts.AttributePFName]; exists && attr.StringValue != nil {
+			pfName = *attr.StringValue
+		}
+		counts[pfName]++
+	}
+	return counts
+}
+
 // UpdateRequestMetadata refreshes per-request metadata files for a prepared claim.
 func (d *Driver) UpdateRequestMetadata(
 	ctx context.Context,
@@ -6,14 +6,18 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 
 	resourceapi "k8s.io/api/resource/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	metadatav1alpha1 "k8s.io/dynamic-resource-allocation/api/metadata/v1alpha1"
 	"k8s.io/dynamic-resource-allocation/kubeletplugin"
 
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/events"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/metrics"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 )
@@ -91,6 +95,58 @@ var _ = Describe("Driver", func() {
 			Expect(res.Err).To(HaveOccurred())
 			Expect(res.Err.Error()).To(ContainSubstring("claim not yet allocated"))
 		})
+
+		It("counts claims failing to prepare by reason", func() {
+			failures := metrics.ClaimOperationFailures.WithLabelValues(metrics.OperationPrepare, metrics.ReasonInvalidClaim)
+			before := testutil.ToFloat64(failures)
+
+			d := &Driver{}
+			claim := &resourceapi.ResourceClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "rc", UID: k8stypes.UID("rc-uid")}}
+			res := d.prepareResourceClaim(context.Background(), new(int), claim)
+			Expect(res.Err).To(HaveOccurred())
+			Expect(testutil.ToFloat64(failures)).To(Equal(before + 1))
+		})
+
+		It("emits a Warning event on the claim and pod failing to prepare", func() {
+			recorder := record.NewFakeRecorder(10)
+			d := &Driver{config: &types.Config{EventRecorder: recorder}}
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "rc", UID: k8stypes.UID("rc-uid")},
+				Status: resourceapi.ResourceClaimStatus{
+					ReservedFor: []resourceapi.ResourceClaimConsumerReference{{Resource: "pods", Name: "pod", UID: "pod-uid"}},
+				},
+			}
+			err := fmt.Errorf("prepare failed: %w", events.WithReason(events.ReasonNADNotFound, fmt.Errorf("net attach def not found")))
+
+			d.emitPrepareFailed(claim, err)
+			Expect(recorder.Events).To(HaveLen(2))
+			Expect(<-recorder.Events).To(Equal("Warning NADNotFound prepare failed: net attach def not found"))
+		})
+	})
+
+	Context("unprepareResourceClaim", func() {
+		It("keeps the claim prepared when its networks fail to detach", func() {
+			flags := &types.Flags{KubeletPluginsDirectoryPath: GinkgoT().TempDir()}
+			pm, err := podmanager.NewPodManager(&types.Config{Flags: flags})
+			Expect(err).ToNot(HaveOccurred())
+
+			claim := kubeletplugin.NamespacedObject{
+				NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "rc1"},
+				UID:            k8stypes.UID("rc-uid"),
+			}
+			devices := types.PreparedDevices{{ClaimNamespacedName: claim, PodUID: "pod-uid", SandboxID: "sandbox-id"}}
+			Expect(pm.Set(k8stypes.UID("pod-uid"), claim.UID, devices)).To(Succeed())
+
+			detacher := &fakeNetworkDetacher{err: fmt.Errorf("CNI DEL failed")}
+			d := &Driver{podManager: pm}
+			d.SetNetworkDetacher(detacher)
+
+			err = d.unprepareResourceClaim(context.Background(), claim)
+			Expect(err).To(MatchError(ContainSubstring("CNI DEL failed")))
+			Expect(detacher.devices).To(Equal(devices))
+			_, found := pm.GetByClaim(claim)
+			Expect(found).To(BeTrue())
+		})
 	})
 
 	Context("HandleError", func() {
@@ -196,5 +252,34 @@ var _ = Describe("Driver", func() {
 			Expect(cdiCalled).To(BeFalse())
 			Expect(metadataVersionCalled).To(BeFalse())
 		})
+
+		It("enables rolling updates with the pod UID", func() {
+			oldRollingUpdateOption := rollingUpdateOption
+			DeferCleanup(func() { rollingUpdateOption = oldRollingUpdateOption })
+
+			var uid k8stypes.UID
+			rollingUpdateOption = func(podUID k8stypes.UID) kubeletplugin.Option {
+				uid = podUID
+				return oldRollingUpdateOption(podUID)
+			}
+
+			cfg := &types.Config{Flags: &types.Flags{NodeName: "node-a"}}
+			Expect(buildPluginOptions(cfg)).To(HaveLen(5))
+			Expect(uid).To(BeEmpty())
+
+			cfg.Flags.PodUID = "pod-uid"
+			Expect(buildPluginOptions(cfg)).To(HaveLen(6))
+			Expect(uid).To(Equal(k8stypes.UID("pod-uid")))
+		})
 	})
 })
+
+type fakeNetworkDetacher struct {
+	devices types.PreparedDevices
+	err     error
+}
+
+func (f *fakeNetworkDetacher) DetachNetworks(_ context.Context, devices types.PreparedDevices) error {
+	f.devices = devices
+	return f.err
+}
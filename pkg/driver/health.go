@@ -18,12 +18,15 @@ package driver
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"net/url"
-	"path"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -35,39 +38,39 @@ import (
 	registerapi "k8s.io/kubelet/pkg/apis/pluginregistration/v1"
 
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
 
-	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+// Health service names. The liveness service checks the kubelet plugin, the
+// readiness service also checks the components of the driver.
+const (
+	livenessService  = "liveness"
+	readinessService = "readiness"
 )
 
 type Healthcheck struct {
 	grpc_health_v1.UnimplementedHealthServer
 
-	server *grpc.Server
-	wg     sync.WaitGroup
+	server     *grpc.Server
+	httpServer *http.Server
+	wg         sync.WaitGroup
 
 	regClient registerapi.RegistrationClient
 	draClient drapb.DRAPluginClient
+	readiness *readinessChecks
 }
 
-func startHealthcheck(ctx context.Context, config *types.Config) (*Healthcheck, error) {
+func startHealthcheck(ctx context.Context, config *types.Config, readiness *readinessChecks) (*Healthcheck, error) {
 	log := klog.FromContext(ctx)
 
 	port := config.Flags.HealthcheckPort
-	if port < 0 {
+	httpPort := config.Flags.HealthcheckHTTPPort
+	if port < 0 && httpPort < 0 {
 		return nil, nil
 	}
 
-	addr := net.JoinHostPort("", strconv.Itoa(port))
-	lis, err := net.Listen("tcp", addr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to listen for healthcheck service at %s: %w", addr, err)
-	}
-
 	regSockPath := (&url.URL{
 		Scheme: "unix",
-		// TODO: this needs to adapt when seamless upgrades
-		// are enabled and the filename includes a uid.
-		Path: path.Join(config.Flags.KubeletRegistrarDirectoryPath, consts.DriverName+"-reg.sock"),
+		Path:   config.RegistrarSocketPath(),
 	}).String()
 	log.Info("connecting to registration socket", "path", regSockPath)
 	regConn, err := grpc.NewClient(
@@ -80,7 +83,7 @@ func startHealthcheck(ctx context.Context, config *types.Config) (*Healthcheck,
 
 	draSockPath := (&url.URL{
 		Scheme: "unix",
-		Path:   path.Join(config.DriverPluginPath(), "dra.sock"),
+		Path:   config.DRASocketPath(),
 	}).String()
 	log.Info("connecting to DRA socket", "path", draSockPath)
 	draConn, err := grpc.NewClient(
@@ -91,22 +94,56 @@ func startHealthcheck(ctx context.Context, config *types.Config) (*Healthcheck,
 		return nil, fmt.Errorf("connect to DRA socket: %w", err)
 	}
 
-	server := grpc.NewServer()
 	healthcheck := &Healthcheck{
-		server:    server,
 		regClient: registerapi.NewRegistrationClient(regConn),
 		draClient: drapb.NewDRAPluginClient(draConn),
+		readiness: readiness,
 	}
-	grpc_health_v1.RegisterHealthServer(server, healthcheck)
 
-	healthcheck.wg.Add(1)
-	go func() {
-		defer healthcheck.wg.Done()
-		log.Info("starting healthcheck service", "addr", lis.Addr().String())
-		if err := server.Serve(lis); err != nil {
-			log.Error(err, "failed to serve healthcheck service", "addr", addr)
+	if port >= 0 {
+		addr := net.JoinHostPort("", strconv.Itoa(port))
+		lis, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen for healthcheck service at %s: %w", addr, err)
 		}
-	}()
+		healthcheck.server = grpc.NewServer()
+		grpc_health_v1.RegisterHealthServer(healthcheck.server, healthcheck)
+
+		healthcheck.wg.Add(1)
+		go func() {
+			defer healthcheck.wg.Done()
+			log.Info("starting healthcheck service", "addr", lis.Addr().String())
+			if err := healthcheck.server.Serve(lis); err != nil {
+				log.Error(err, "failed to serve healthcheck service", "addr", addr)
+			}
+		}()
+	}
+
+	if httpPort >= 0 {
+		addr := net.JoinHostPort("", strconv.Itoa(httpPort))
+		lis, err := net.Listen("tcp", addr)
+		if err != nil {
+			healthcheck.Stop(log)
+			return nil, fmt.Errorf("failed to listen for HTTP healthcheck service at %s: %w", addr, err)
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/livez", healthcheck.serveHTTP(livenessService))
+		mux.HandleFunc("/readyz", healthcheck.serveHTTP(readinessService))
+		healthcheck.httpServer = &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+			BaseContext:       func(net.Listener) context.Context { return ctx },
+		}
+
+		healthcheck.wg.Add(1)
+		go func() {
+			defer healthcheck.wg.Done()
+			log.Info("starting HTTP healthcheck service", "addr", lis.Addr().String())
+			if err := healthcheck.httpServer.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error(err, "failed to serve HTTP healthcheck service", "addr", addr)
+			}
+		}()
+	}
 
 	return healthcheck, nil
 }
@@ -116,14 +153,16 @@ func (h *Healthcheck) Stop(logger klog.Logger) {
 		logger.Info("stopping healthcheck service")
 		h.server.GracefulStop()
 	}
+	if h.httpServer != nil {
+		logger.Info("stopping HTTP healthcheck service")
+		_ = h.httpServer.Close()
+	}
 	h.wg.Wait()
 }
 
 // Check implements [grpc_health_v1.HealthServer].
 func (h *Healthcheck) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
-	log := klog.FromContext(ctx)
-
-	knownServices := map[string]struct{}{"": {}, "liveness": {}}
+	knownServices := map[string]struct{}{"": {}, livenessService: {}, readinessService: {}}
 	if _, known := knownServices[req.GetService()]; !known {
 		return nil, status.Error(codes.NotFound, "unknown service")
 	}
@@ -131,21 +170,80 @@ func (h *Healthcheck) Check(ctx context.Context, req *grpc_health_v1.HealthCheck
 	status := &grpc_health_v1.HealthCheckResponse{
 		Status: grpc_health_v1.HealthCheckResponse_NOT_SERVING,
 	}
+	if healthy(h.check(ctx, req.GetService())) {
+		status.Status = grpc_health_v1.HealthCheckResponse_SERVING
+	}
+	return status, nil
+}
+
+// check runs the checks of a health service. Every service checks the kubelet
+// plugin, the readiness service adds the checks of the driver components.
+func (h *Healthcheck) check(ctx context.Context, service string) []componentStatus {
+	log := klog.FromContext(ctx)
+
+	statuses := []componentStatus{{name: "kubelet-plugin", err: h.checkKubeletPlugin(ctx)}}
+	if service == readinessService && h.readiness != nil {
+		statuses = append(statuses, h.readiness.run(ctx)...)
+	}
+	for _, component := range statuses {
+		if component.err != nil {
+			log.Error(component.err, "health check failed", "service", service, "component", component.name)
+		}
+	}
+	return statuses
+}
+
+// checkKubeletPlugin calls the registration and DRA services of the plugin.
+func (h *Healthcheck) checkKubeletPlugin(ctx context.Context) error {
+	log := klog.FromContext(ctx)
 
 	info, err := h.regClient.GetInfo(ctx, &registerapi.InfoRequest{})
 	if err != nil {
-		log.Error(err, "failed to call GetInfo")
-		return status, nil
+		return fmt.Errorf("failed to call GetInfo: %w", err)
 	}
 	log.V(5).Info("Successfully invoked GetInfo", "info", info)
 
 	_, err = h.draClient.NodePrepareResources(ctx, &drapb.NodePrepareResourcesRequest{})
 	if err != nil {
-		log.Error(err, "failed to call NodePrepareResources")
-		return status, nil
+		return fmt.Errorf("failed to call NodePrepareResources: %w", err)
 	}
 	log.V(5).Info("Successfully invoked NodePrepareResources")
+	return nil
+}
 
-	status.Status = grpc_health_v1.HealthCheckResponse_SERVING
-	return status, nil
+// serveHTTP mirrors a health service over HTTP for kubelet httpGet probes. It
+// answers 200 when healthy and 503 otherwise, with the status of every
+// checked component.
+func (h *Healthcheck) serveHTTP(service string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		statuses := h.check(r.Context(), service)
+
+		var body strings.Builder
+		for _, component := range statuses {
+			if component.err != nil {
+				fmt.Fprintf(&body, "[-]%s failed: %v\n", component.name, component.err)
+			} else {
+				fmt.Fprintf(&body, "[+]%s ok\n", component.name)
+			}
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if healthy(statuses) {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(&body, "%s check passed\n", service)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintf(&body, "%s check failed\n", service)
+		}
+		_, _ = w.Write([]byte(body.String()))
+	}
+}
+
+func healthy(statuses []componentStatus) bool {
+	for _, component := range statuses {
+		if component.err != nil {
+			return false
+		}
+	}
+	return true
 }
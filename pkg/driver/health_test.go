@@ -0,0 +1,102 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	drapb "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	registerapi "k8s.io/kubelet/pkg/apis/pluginregistration/v1"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+type fakeRegistrationClient struct {
+	registerapi.RegistrationClient
+	err error
+}
+
+func (f *fakeRegistrationClient) GetInfo(context.Context, *registerapi.InfoRequest, ...grpc.CallOption) (*registerapi.PluginInfo, error) {
+	return &registerapi.PluginInfo{}, f.err
+}
+
+type fakeDRAPluginClient struct {
+	drapb.DRAPluginClient
+}
+
+func (f *fakeDRAPluginClient) NodePrepareResources(context.Context, *drapb.NodePrepareResourcesRequest, ...grpc.CallOption) (*drapb.NodePrepareResourcesResponse, error) {
+	return &drapb.NodePrepareResourcesResponse{}, nil
+}
+
+var _ = Describe("Healthcheck", func() {
+	var (
+		ctx       context.Context
+		readiness *readinessChecks
+		h         *Healthcheck
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		readiness = &readinessChecks{}
+		h = &Healthcheck{
+			regClient: &fakeRegistrationClient{},
+			draClient: &fakeDRAPluginClient{},
+			readiness: readiness,
+		}
+	})
+
+	check := func(service string) grpc_health_v1.HealthCheckResponse_ServingStatus {
+		resp, err := h.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: service})
+		Expect(err).ToNot(HaveOccurred())
+		return resp.GetStatus()
+	}
+
+	It("reports readiness from the component checks", func() {
+		readiness.add("publish", func(context.Context) error { return nil })
+		Expect(check(readinessService)).To(Equal(grpc_health_v1.HealthCheckResponse_SERVING))
+
+		readiness.add("nri", func(context.Context) error { return errors.New("not connected") })
+		Expect(check(readinessService)).To(Equal(grpc_health_v1.HealthCheckResponse_NOT_SERVING))
+		Expect(check(livenessService)).To(Equal(grpc_health_v1.HealthCheckResponse_SERVING))
+	})
+
+	It("reports the kubelet plugin failure on every service", func() {
+		h.regClient = &fakeRegistrationClient{err: errors.New("unavailable")}
+		Expect(check("")).To(Equal(grpc_health_v1.HealthCheckResponse_NOT_SERVING))
+		Expect(check(readinessService)).To(Equal(grpc_health_v1.HealthCheckResponse_NOT_SERVING))
+	})
+
+	It("rejects unknown services", func() {
+		_, err := h.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: "unknown"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("mirrors the readiness service over HTTP", func() {
+		readiness.add("checkpoint", func(context.Context) error { return nil })
+		readiness.add("controller-cache", func(context.Context) error { return errors.New("cache is not synced") })
+
+		rec := httptest.NewRecorder()
+		h.serveHTTP(readinessService)(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		Expect(rec.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(rec.Body.String()).To(Equal("[+]kubelet-plugin ok\n[+]checkpoint ok\n[-]controller-cache failed: cache is not synced\nreadiness check failed\n"))
+
+		rec = httptest.NewRecorder()
+		h.serveHTTP(livenessService)(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+	})
+})
+
+var _ = Describe("Driver readiness checks", func() {
+	It("reports the last ResourceSlice publication failure", func() {
+		d := &Driver{}
+		Expect(d.checkPublish(context.Background())).To(Succeed())
+
+		d.lastPublish = types.PublishStatus{Err: errors.New("forbidden")}
+		Expect(d.checkPublish(context.Background())).To(MatchError(ContainSubstring("forbidden")))
+	})
+})
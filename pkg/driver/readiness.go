@@ -0,0 +1,74 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	sriovdratype "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// ReadinessCheck reports an error while a component of the driver is not ready.
+type ReadinessCheck func(ctx context.Context) error
+
+// componentStatus is the result of the readiness check of a component.
+type componentStatus struct {
+	name string
+	err  error
+}
+
+// readinessChecks holds the component checks of the readiness service. They
+// are added by the components as they start, after the health service.
+type readinessChecks struct {
+	mu     sync.RWMutex
+	checks map[string]ReadinessCheck
+}
+
+// add sets the check of a component, replacing the previous one.
+func (r *readinessChecks) add(name string, check ReadinessCheck) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.checks == nil {
+		r.checks = make(map[string]ReadinessCheck)
+	}
+	r.checks[name] = check
+}
+
+// run runs the checks of all components, sorted by name.
+func (r *readinessChecks) run(ctx context.Context) []componentStatus {
+	r.mu.RLock()
+	statuses := make([]componentStatus, 0, len(r.checks))
+	checks := make(map[string]ReadinessCheck, len(r.checks))
+	for name, check := range r.checks {
+		statuses = append(statuses, componentStatus{name: name})
+		checks[name] = check
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].name < statuses[j].name })
+	for i := range statuses {
+		statuses[i].err = checks[statuses[i].name](ctx)
+	}
+	return statuses
+}
+
+// AddReadinessCheck adds the check of a component to the readiness service.
+func (d *Driver) AddReadinessCheck(name string, check ReadinessCheck) {
+	d.readiness.add(name, check)
+}
+
+// checkPublish reports the error of the last ResourceSlice publication.
+func (d *Driver) checkPublish(_ context.Context) error {
+	if err := d.PublishStatus().Err; err != nil {
+		return fmt.Errorf("last ResourceSlice publication failed: %w", err)
+	}
+	return nil
+}
+
+// PublishStatus returns the result of the last ResourceSlice publication.
+func (d *Driver) PublishStatus() sriovdratype.PublishStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.lastPublish
+}
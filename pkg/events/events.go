@@ -0,0 +1,125 @@
+// Package events reports the failures of the driver as Kubernetes Warning
+// events on the objects users look at: the ResourceClaim and Pod of a device
+// and the SriovResourcePolicy selecting it.
+package events
+
+import (
+	"context"
+	"errors"
+
+	corev1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	sriovdrav1alpha1 "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/sriovdra/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/flags"
+)
+
+// Reasons of the Warning events on ResourceClaims and Pods.
+const (
+	ReasonPrepareFailed     = "PrepareFailed"
+	ReasonUnprepareFailed   = "UnprepareFailed"
+	ReasonDriverBindFailed  = "DriverBindFailed"
+	ReasonNADNotFound       = "NADNotFound"
+	ReasonRDMADeviceMissing = "RDMADeviceMissing"
+	ReasonCNIAddFailed      = "CNIAddFailed"
+	ReasonCNIDelFailed      = "CNIDelFailed"
+)
+
+// Reasons of the Warning events on SriovResourcePolicies.
+const (
+	ReasonInvalidSelector           = "InvalidSelector"
+	ReasonReservedAttributeConflict = "ReservedAttributeConflict"
+	ReasonPolicyUpdateFailed        = "PolicyUpdateFailed"
+	ReasonPolicyOverlap             = "PolicyOverlap"
+)
+
+// NewRecorder starts a broadcaster writing the events of the driver running on
+// nodeName to the API server. The returned function stops it.
+func NewRecorder(ctx context.Context, client kubernetes.Interface, nodeName string) (record.EventRecorder, func()) {
+	broadcaster := record.NewBroadcaster(record.WithContext(ctx))
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	recorder := broadcaster.NewRecorder(flags.Scheme, corev1.EventSource{Component: consts.DriverName, Host: nodeName})
+	return recorder, broadcaster.Shutdown
+}
+
+// Warning emits a Warning event on every object. Nothing is emitted without a
+// recorder, nil objects are skipped.
+func Warning(recorder record.EventRecorder, reason, message string, objects ...*corev1.ObjectReference) {
+	if recorder == nil {
+		return
+	}
+	for _, object := range objects {
+		if object != nil {
+			recorder.Event(object, corev1.EventTypeWarning, reason, message)
+		}
+	}
+}
+
+// ClaimReference returns the reference of a ResourceClaim.
+func ClaimReference(namespace, name string, uid k8stypes.UID) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion: resourceapi.SchemeGroupVersion.String(),
+		Kind:       "ResourceClaim",
+		Namespace:  namespace,
+		Name:       name,
+		UID:        uid,
+	}
+}
+
+// PodReference returns the reference of a Pod, nil without a name.
+func PodReference(namespace, name string, uid k8stypes.UID) *corev1.ObjectReference {
+	if name == "" {
+		return nil
+	}
+	return &corev1.ObjectReference{
+		APIVersion: corev1.SchemeGroupVersion.String(),
+		Kind:       "Pod",
+		Namespace:  namespace,
+		Name:       name,
+		UID:        uid,
+	}
+}
+
+// PolicyReference returns the reference of a SriovResourcePolicy.
+func PolicyReference(policy *sriovdrav1alpha1.SriovResourcePolicy) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion: sriovdrav1alpha1.GroupVersion.String(),
+		Kind:       "SriovResourcePolicy",
+		Namespace:  policy.Namespace,
+		Name:       policy.Name,
+		UID:        policy.UID,
+	}
+}
+
+// reasonError annotates an error with the reason of the event reporting it.
+type reasonError struct {
+	reason string
+	err    error
+}
+
+func (e *reasonError) Error() string { return e.err.Error() }
+
+func (e *reasonError) Unwrap() error { return e.err }
+
+// WithReason annotates err with the reason of the event reporting it, the
+// error message is unchanged.
+func WithReason(reason string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &reasonError{reason: reason, err: err}
+}
+
+// Reason returns the reason err was annotated with, or fallback.
+func Reason(err error, fallback string) string {
+	var re *reasonError
+	if errors.As(err, &re) {
+		return re.reason
+	}
+	return fallback
+}
@@ -0,0 +1,67 @@
+package events_test
+
+import (
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	sriovdrav1alpha1 "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/sriovdra/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/events"
+)
+
+var _ = Describe("Events", func() {
+	Context("WithReason", func() {
+		It("keeps the reason through wrapping", func() {
+			err := events.WithReason(events.ReasonNADNotFound, errors.New("not found"))
+			wrapped := fmt.Errorf("prepare failed: %w", err)
+			Expect(wrapped.Error()).To(Equal("prepare failed: not found"))
+			Expect(events.Reason(wrapped, events.ReasonPrepareFailed)).To(Equal(events.ReasonNADNotFound))
+		})
+
+		It("returns the fallback without a reason", func() {
+			Expect(events.Reason(errors.New("failed"), events.ReasonPrepareFailed)).To(Equal(events.ReasonPrepareFailed))
+		})
+
+		It("keeps a nil error nil", func() {
+			Expect(events.WithReason(events.ReasonNADNotFound, nil)).To(BeNil())
+		})
+	})
+
+	Context("Warning", func() {
+		It("emits an event on every object", func() {
+			recorder := record.NewFakeRecorder(10)
+			events.Warning(recorder, events.ReasonCNIAddFailed, "failed",
+				events.ClaimReference("default", "claim", "claim-uid"),
+				events.PodReference("default", "pod", "pod-uid"))
+			Expect(recorder.Events).To(HaveLen(2))
+			Expect(<-recorder.Events).To(Equal("Warning CNIAddFailed failed"))
+		})
+
+		It("skips pods without a name", func() {
+			recorder := record.NewFakeRecorder(10)
+			events.Warning(recorder, events.ReasonPrepareFailed, "failed",
+				events.ClaimReference("default", "claim", "claim-uid"),
+				events.PodReference("default", "", ""))
+			Expect(recorder.Events).To(HaveLen(1))
+		})
+
+		It("does nothing without a recorder", func() {
+			Expect(func() {
+				events.Warning(nil, events.ReasonPrepareFailed, "failed", events.ClaimReference("default", "claim", "claim-uid"))
+			}).NotTo(Panic())
+		})
+	})
+
+	It("references SriovResourcePolicies", func() {
+		policy := &sriovdrav1alpha1.SriovResourcePolicy{ObjectMeta: metav1.ObjectMeta{Name: "policy", Namespace: "ns", UID: "uid"}}
+		ref := events.PolicyReference(policy)
+		Expect(ref.Kind).To(Equal("SriovResourcePolicy"))
+		Expect(ref.APIVersion).To(Equal(sriovdrav1alpha1.GroupVersion.String()))
+		Expect(ref.Name).To(Equal("policy"))
+		Expect(ref.Namespace).To(Equal("ns"))
+	})
+})
@@ -73,6 +73,18 @@ type VFInfo struct {
 	DeviceID   string
 }
 
+// VFStats holds the traffic statistics of a Virtual Function reported by its PF
+type VFStats struct {
+	RxBytes   uint64
+	TxBytes   uint64
+	RxPackets uint64
+	TxPackets uint64
+	RxDropped uint64
+	TxDropped uint64
+	Broadcast uint64
+	Multicast uint64
+}
+
 // Interface defines the unified interface for all host system operations.
 // This interface allows for easy mocking in unit tests by implementing mock versions
 // of all the host-related methods.
@@ -91,6 +103,11 @@ type Interface interface {
 	TryGetPFInterfaceName(pciAddr string) string
 	GetNicSriovMode(pciAddr string) string
 	GetLinkType(pciAddr string) (string, error)
+	GetVFStats(pfName string) (map[int]VFStats, error)
+	GetNetDevicesFromPci(pciAddr string) ([]string, error)
+	// NewNetlinkHandle returns a netlink handle in the network namespace at
+	// netnsPath, or in the network namespace of the driver when it is empty.
+	NewNetlinkHandle(netnsPath string) (NetlinkHandle, error)
 
 	// Topology functions
 	GetNumaNode(pciAddress string) (string, error)
@@ -297,6 +314,41 @@ func (h *Host) GetNicSriovMode(pciAddr string) string {
 	return mode
 }
 
+// GetVFStats returns the statistics of the VFs of a PF by VF index, read
+// through netlink from the IFLA_VF_STATS attribute of the PF link.
+func (h *Host) GetVFStats(pfName string) (map[int]VFStats, error) {
+	vfs, err := h.netlinkProvider.GetLinkVfInfos(pfName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get VF infos of PF %s: %w", pfName, err)
+	}
+	stats := make(map[int]VFStats, len(vfs))
+	for _, vf := range vfs {
+		stats[vf.ID] = VFStats{
+			RxBytes:   vf.RxBytes,
+			TxBytes:   vf.TxBytes,
+			RxPackets: vf.RxPackets,
+			TxPackets: vf.TxPackets,
+			RxDropped: vf.RxDropped,
+			TxDropped: vf.TxDropped,
+			Broadcast: vf.Broadcast,
+			Multicast: vf.Multicast,
+		}
+	}
+	return stats, nil
+}
+
+// GetNetDevicesFromPci returns the names of the netdevs of a PCI device.
+func (h *Host) GetNetDevicesFromPci(pciAddr string) ([]string, error) {
+	return h.sriovnetProvider.GetNetDevicesFromPci(pciAddr)
+}
+
+// NewNetlinkHandle returns a netlink handle in the network namespace at
+// netnsPath, or in the network namespace of the driver when it is empty. The
+// handle must be closed.
+func (h *Host) NewNetlinkHandle(netnsPath string) (NetlinkHandle, error) {
+	return h.netlinkProvider.NewHandle(netnsPath)
+}
+
 // GetLinkType returns the link type for a given network interface
 // Common types: ethernet (type 1), infiniband (type 32)
 func (h *Host) GetLinkType(pciAddr string) (string, error) {
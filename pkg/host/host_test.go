@@ -9,6 +9,7 @@ import (
 	"go.uber.org/mock/gomock"
 
 	"github.com/k8snetworkplumbingwg/sriovnet"
+	"github.com/vishvananda/netlink"
 
 	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
@@ -235,6 +236,30 @@ var _ = Describe("Host", func() {
 			})
 		})
 
+		Context("GetVFStats", func() {
+			It("should return the statistics by VF index", func() {
+				fakeNetlink := &host.FakeNetlinkProvider{VfInfos: []netlink.VfInfo{
+					{ID: 0, RxBytes: 100, TxBytes: 200, RxPackets: 1, TxPackets: 2, RxDropped: 3, TxDropped: 4, Broadcast: 5, Multicast: 6},
+					{ID: 3, RxBytes: 10},
+				}}
+				hStats := host.NewHostForTest(fakeNetlink)
+
+				stats, err := hStats.GetVFStats("eth0")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(stats).To(HaveLen(2))
+				Expect(stats[0]).To(Equal(host.VFStats{RxBytes: 100, TxBytes: 200, RxPackets: 1, TxPackets: 2, RxDropped: 3, TxDropped: 4, Broadcast: 5, Multicast: 6}))
+				Expect(stats[3].RxBytes).To(Equal(uint64(10)))
+			})
+
+			It("should return an error when the PF link cannot be read", func() {
+				hStats := host.NewHostForTest(&host.FakeNetlinkProvider{VfInfosError: fmt.Errorf("link not found")})
+
+				_, err := hStats.GetVFStats("eth0")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("link not found"))
+			})
+		})
+
 		Context("GetLinkType", func() {
 			It("should return 'ethernet' for type ArphrdEther", func() {
 				fs.Dirs = []string{"sys/class/net/eth0"}
@@ -143,6 +143,21 @@ func (mr *MockInterfaceMockRecorder) GetLinkType(pciAddr any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLinkType", reflect.TypeOf((*MockInterface)(nil).GetLinkType), pciAddr)
 }
 
+// GetNetDevicesFromPci mocks base method.
+func (m *MockInterface) GetNetDevicesFromPci(pciAddr string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNetDevicesFromPci", pciAddr)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNetDevicesFromPci indicates an expected call of GetNetDevicesFromPci.
+func (mr *MockInterfaceMockRecorder) GetNetDevicesFromPci(pciAddr any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNetDevicesFromPci", reflect.TypeOf((*MockInterface)(nil).GetNetDevicesFromPci), pciAddr)
+}
+
 // GetNicSriovMode mocks base method.
 func (m *MockInterface) GetNicSriovMode(pciAddr string) string {
 	m.ctrl.T.Helper()
@@ -247,6 +262,21 @@ func (mr *MockInterfaceMockRecorder) GetVFList(pfPciAddress any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVFList", reflect.TypeOf((*MockInterface)(nil).GetVFList), pfPciAddress)
 }
 
+// GetVFStats mocks base method.
+func (m *MockInterface) GetVFStats(pfName string) (map[int]host.VFStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVFStats", pfName)
+	ret0, _ := ret[0].(map[int]host.VFStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVFStats indicates an expected call of GetVFStats.
+func (mr *MockInterfaceMockRecorder) GetVFStats(pfName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVFStats", reflect.TypeOf((*MockInterface)(nil).GetVFStats), pfName)
+}
+
 // IsDpdkDriver mocks base method.
 func (m *MockInterface) IsDpdkDriver(driver string) bool {
 	m.ctrl.T.Helper()
@@ -317,6 +347,21 @@ func (mr *MockInterfaceMockRecorder) LoadKernelModule(moduleName any) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadKernelModule", reflect.TypeOf((*MockInterface)(nil).LoadKernelModule), moduleName)
 }
 
+// NewNetlinkHandle mocks base method.
+func (m *MockInterface) NewNetlinkHandle(netnsPath string) (host.NetlinkHandle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewNetlinkHandle", netnsPath)
+	ret0, _ := ret[0].(host.NetlinkHandle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NewNetlinkHandle indicates an expected call of NewNetlinkHandle.
+func (mr *MockInterfaceMockRecorder) NewNetlinkHandle(netnsPath any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewNetlinkHandle", reflect.TypeOf((*MockInterface)(nil).NewNetlinkHandle), netnsPath)
+}
+
 // PCI mocks base method.
 func (m *MockInterface) PCI() (*ghw.PCIInfo, error) {
 	m.ctrl.T.Helper()
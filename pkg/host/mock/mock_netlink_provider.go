@@ -0,0 +1,294 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: netlink_provider.go
+//
+// Generated by this command:
+//
+//	mockgen -destination mock/mock_netlink_provider.go -source netlink_provider.go
+//
+
+// Package mock_host is a generated GoMock package.
+package mock_host
+
+import (
+	net "net"
+	reflect "reflect"
+
+	host "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	netlink "github.com/vishvananda/netlink"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockNetlinkProvider is a mock of NetlinkProvider interface.
+type MockNetlinkProvider struct {
+	ctrl     *gomock.Controller
+	recorder *MockNetlinkProviderMockRecorder
+	isgomock struct{}
+}
+
+// MockNetlinkProviderMockRecorder is the mock recorder for MockNetlinkProvider.
+type MockNetlinkProviderMockRecorder struct {
+	mock *MockNetlinkProvider
+}
+
+// NewMockNetlinkProvider creates a new mock instance.
+func NewMockNetlinkProvider(ctrl *gomock.Controller) *MockNetlinkProvider {
+	mock := &MockNetlinkProvider{ctrl: ctrl}
+	mock.recorder = &MockNetlinkProviderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNetlinkProvider) EXPECT() *MockNetlinkProviderMockRecorder {
+	return m.recorder
+}
+
+// GetDevLinkDeviceEswitchMode mocks base method.
+func (m *MockNetlinkProvider) GetDevLinkDeviceEswitchMode(pciAddr string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDevLinkDeviceEswitchMode", pciAddr)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDevLinkDeviceEswitchMode indicates an expected call of GetDevLinkDeviceEswitchMode.
+func (mr *MockNetlinkProviderMockRecorder) GetDevLinkDeviceEswitchMode(pciAddr any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDevLinkDeviceEswitchMode", reflect.TypeOf((*MockNetlinkProvider)(nil).GetDevLinkDeviceEswitchMode), pciAddr)
+}
+
+// GetLinkVfInfos mocks base method.
+func (m *MockNetlinkProvider) GetLinkVfInfos(linkName string) ([]netlink.VfInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLinkVfInfos", linkName)
+	ret0, _ := ret[0].([]netlink.VfInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLinkVfInfos indicates an expected call of GetLinkVfInfos.
+func (mr *MockNetlinkProviderMockRecorder) GetLinkVfInfos(linkName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLinkVfInfos", reflect.TypeOf((*MockNetlinkProvider)(nil).GetLinkVfInfos), linkName)
+}
+
+// NewHandle mocks base method.
+func (m *MockNetlinkProvider) NewHandle(netnsPath string) (host.NetlinkHandle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewHandle", netnsPath)
+	ret0, _ := ret[0].(host.NetlinkHandle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NewHandle indicates an expected call of NewHandle.
+func (mr *MockNetlinkProviderMockRecorder) NewHandle(netnsPath any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewHandle", reflect.TypeOf((*MockNetlinkProvider)(nil).NewHandle), netnsPath)
+}
+
+// MockNetlinkHandle is a mock of NetlinkHandle interface.
+type MockNetlinkHandle struct {
+	ctrl     *gomock.Controller
+	recorder *MockNetlinkHandleMockRecorder
+	isgomock struct{}
+}
+
+// MockNetlinkHandleMockRecorder is the mock recorder for MockNetlinkHandle.
+type MockNetlinkHandleMockRecorder struct {
+	mock *MockNetlinkHandle
+}
+
+// NewMockNetlinkHandle creates a new mock instance.
+func NewMockNetlinkHandle(ctrl *gomock.Controller) *MockNetlinkHandle {
+	mock := &MockNetlinkHandle{ctrl: ctrl}
+	mock.recorder = &MockNetlinkHandleMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNetlinkHandle) EXPECT() *MockNetlinkHandleMockRecorder {
+	return m.recorder
+}
+
+// AddrAdd mocks base method.
+func (m *MockNetlinkHandle) AddrAdd(link netlink.Link, addr *netlink.Addr) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddrAdd", link, addr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddrAdd indicates an expected call of AddrAdd.
+func (mr *MockNetlinkHandleMockRecorder) AddrAdd(link, addr any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddrAdd", reflect.TypeOf((*MockNetlinkHandle)(nil).AddrAdd), link, addr)
+}
+
+// Close mocks base method.
+func (m *MockNetlinkHandle) Close() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Close")
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockNetlinkHandleMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockNetlinkHandle)(nil).Close))
+}
+
+// LinkByIndex mocks base method.
+func (m *MockNetlinkHandle) LinkByIndex(index int) (netlink.Link, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkByIndex", index)
+	ret0, _ := ret[0].(netlink.Link)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LinkByIndex indicates an expected call of LinkByIndex.
+func (mr *MockNetlinkHandleMockRecorder) LinkByIndex(index any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkByIndex", reflect.TypeOf((*MockNetlinkHandle)(nil).LinkByIndex), index)
+}
+
+// LinkByName mocks base method.
+func (m *MockNetlinkHandle) LinkByName(name string) (netlink.Link, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkByName", name)
+	ret0, _ := ret[0].(netlink.Link)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LinkByName indicates an expected call of LinkByName.
+func (mr *MockNetlinkHandleMockRecorder) LinkByName(name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkByName", reflect.TypeOf((*MockNetlinkHandle)(nil).LinkByName), name)
+}
+
+// LinkSetDown mocks base method.
+func (m *MockNetlinkHandle) LinkSetDown(link netlink.Link) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkSetDown", link)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LinkSetDown indicates an expected call of LinkSetDown.
+func (mr *MockNetlinkHandleMockRecorder) LinkSetDown(link any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkSetDown", reflect.TypeOf((*MockNetlinkHandle)(nil).LinkSetDown), link)
+}
+
+// LinkSetHardwareAddr mocks base method.
+func (m *MockNetlinkHandle) LinkSetHardwareAddr(link netlink.Link, hwaddr net.HardwareAddr) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkSetHardwareAddr", link, hwaddr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LinkSetHardwareAddr indicates an expected call of LinkSetHardwareAddr.
+func (mr *MockNetlinkHandleMockRecorder) LinkSetHardwareAddr(link, hwaddr any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkSetHardwareAddr", reflect.TypeOf((*MockNetlinkHandle)(nil).LinkSetHardwareAddr), link, hwaddr)
+}
+
+// LinkSetMTU mocks base method.
+func (m *MockNetlinkHandle) LinkSetMTU(link netlink.Link, mtu int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkSetMTU", link, mtu)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LinkSetMTU indicates an expected call of LinkSetMTU.
+func (mr *MockNetlinkHandleMockRecorder) LinkSetMTU(link, mtu any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkSetMTU", reflect.TypeOf((*MockNetlinkHandle)(nil).LinkSetMTU), link, mtu)
+}
+
+// LinkSetName mocks base method.
+func (m *MockNetlinkHandle) LinkSetName(link netlink.Link, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkSetName", link, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LinkSetName indicates an expected call of LinkSetName.
+func (mr *MockNetlinkHandleMockRecorder) LinkSetName(link, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkSetName", reflect.TypeOf((*MockNetlinkHandle)(nil).LinkSetName), link, name)
+}
+
+// LinkSetNsFd mocks base method.
+func (m *MockNetlinkHandle) LinkSetNsFd(link netlink.Link, fd int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkSetNsFd", link, fd)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LinkSetNsFd indicates an expected call of LinkSetNsFd.
+func (mr *MockNetlinkHandleMockRecorder) LinkSetNsFd(link, fd any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkSetNsFd", reflect.TypeOf((*MockNetlinkHandle)(nil).LinkSetNsFd), link, fd)
+}
+
+// LinkSetNsPid mocks base method.
+func (m *MockNetlinkHandle) LinkSetNsPid(link netlink.Link, nspid int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkSetNsPid", link, nspid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LinkSetNsPid indicates an expected call of LinkSetNsPid.
+func (mr *MockNetlinkHandleMockRecorder) LinkSetNsPid(link, nspid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkSetNsPid", reflect.TypeOf((*MockNetlinkHandle)(nil).LinkSetNsPid), link, nspid)
+}
+
+// LinkSetUp mocks base method.
+func (m *MockNetlinkHandle) LinkSetUp(link netlink.Link) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkSetUp", link)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LinkSetUp indicates an expected call of LinkSetUp.
+func (mr *MockNetlinkHandleMockRecorder) LinkSetUp(link any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkSetUp", reflect.TypeOf((*MockNetlinkHandle)(nil).LinkSetUp), link)
+}
+
+// NsFd mocks base method.
+func (m *MockNetlinkHandle) NsFd() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NsFd")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// NsFd indicates an expected call of NsFd.
+func (mr *MockNetlinkHandleMockRecorder) NsFd() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NsFd", reflect.TypeOf((*MockNetlinkHandle)(nil).NsFd))
+}
+
+// RouteAdd mocks base method.
+func (m *MockNetlinkHandle) RouteAdd(route *netlink.Route) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RouteAdd", route)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RouteAdd indicates an expected call of RouteAdd.
+func (mr *MockNetlinkHandleMockRecorder) RouteAdd(route any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RouteAdd", reflect.TypeOf((*MockNetlinkHandle)(nil).RouteAdd), route)
+}
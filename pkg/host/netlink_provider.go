@@ -1,14 +1,47 @@
 package host
 
 import (
+	"fmt"
+	"net"
+
 	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
 )
 
 // NetlinkProvider wraps netlink library calls to allow mocking in unit tests.
+//
+//go:generate mockgen -destination mock/mock_netlink_provider.go -source netlink_provider.go
 type NetlinkProvider interface {
 	// GetDevLinkDeviceEswitchMode returns the eswitch mode ("legacy" or
 	// "switchdev") for the given PF PCI address via devlink.
 	GetDevLinkDeviceEswitchMode(pciAddr string) (string, error)
+	// GetLinkVfInfos returns the VF information of a PF link, including the
+	// IFLA_VF_STATS statistics of each VF.
+	GetLinkVfInfos(linkName string) ([]netlink.VfInfo, error)
+	// NewHandle returns a netlink handle in the network namespace at
+	// netnsPath, or in the network namespace of the driver when it is empty.
+	NewHandle(netnsPath string) (NetlinkHandle, error)
+}
+
+// NetlinkHandle sends the netlink requests configuring the links of a network
+// namespace.
+type NetlinkHandle interface {
+	// NsFd returns the file descriptor of the network namespace of the
+	// handle, to move links into it.
+	NsFd() int
+	LinkByName(name string) (netlink.Link, error)
+	LinkByIndex(index int) (netlink.Link, error)
+	LinkSetUp(link netlink.Link) error
+	LinkSetDown(link netlink.Link) error
+	LinkSetName(link netlink.Link, name string) error
+	LinkSetMTU(link netlink.Link, mtu int) error
+	LinkSetHardwareAddr(link netlink.Link, hwaddr net.HardwareAddr) error
+	LinkSetNsFd(link netlink.Link, fd int) error
+	LinkSetNsPid(link netlink.Link, nspid int) error
+	AddrAdd(link netlink.Link, addr *netlink.Addr) error
+	RouteAdd(route *netlink.Route) error
+	// Close releases the handle and its network namespace.
+	Close()
 }
 
 type defaultNetlinkProvider struct{}
@@ -22,3 +55,47 @@ func (defaultNetlinkProvider) GetDevLinkDeviceEswitchMode(pciAddr string) (strin
 	}
 	return dev.Attrs.Eswitch.Mode, nil
 }
+
+func (defaultNetlinkProvider) GetLinkVfInfos(linkName string) ([]netlink.VfInfo, error) {
+	link, err := netlink.LinkByName(linkName)
+	if err != nil {
+		return nil, err
+	}
+	return link.Attrs().Vfs, nil
+}
+
+func (defaultNetlinkProvider) NewHandle(netnsPath string) (NetlinkHandle, error) {
+	var ns netns.NsHandle
+	var err error
+	if netnsPath == "" {
+		ns, err = netns.Get()
+	} else {
+		ns, err = netns.GetFromPath(netnsPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open network namespace %s: %w", netnsPath, err)
+	}
+	handle, err := netlink.NewHandleAt(ns)
+	if err != nil {
+		_ = ns.Close()
+		return nil, fmt.Errorf("failed to get netlink handle for network namespace %s: %w", netnsPath, err)
+	}
+	return &netlinkHandle{Handle: handle, ns: ns}, nil
+}
+
+// netlinkHandle is a netlink handle owning its network namespace.
+type netlinkHandle struct {
+	*netlink.Handle
+	ns netns.NsHandle
+}
+
+var _ NetlinkHandle = &netlinkHandle{}
+
+func (h *netlinkHandle) NsFd() int {
+	return int(h.ns)
+}
+
+func (h *netlinkHandle) Close() {
+	h.Handle.Close()
+	_ = h.ns.Close()
+}
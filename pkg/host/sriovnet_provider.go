@@ -7,6 +7,8 @@ type SriovnetProvider interface {
 	// GetUplinkRepresentor returns the PF uplink netdev name for a given PCI
 	// address (PF or VF).
 	GetUplinkRepresentor(pciAddr string) (string, error)
+	// GetNetDevicesFromPci returns the netdev names of a PCI device.
+	GetNetDevicesFromPci(pciAddr string) ([]string, error)
 }
 
 type defaultSriovnetProvider struct{}
@@ -16,3 +18,7 @@ var _ SriovnetProvider = &defaultSriovnetProvider{}
 func (defaultSriovnetProvider) GetUplinkRepresentor(pciAddr string) (string, error) {
 	return sriovnet.GetUplinkRepresentor(pciAddr)
 }
+
+func (defaultSriovnetProvider) GetNetDevicesFromPci(pciAddr string) ([]string, error) {
+	return sriovnet.GetNetDevicesFromPci(pciAddr)
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path"
 
+	"github.com/vishvananda/netlink"
 	"k8s.io/klog/v2"
 )
 
@@ -33,24 +34,43 @@ func NewHostForTest(netlinkProvider NetlinkProvider, sriovnetProvider ...Sriovne
 type FakeNetlinkProvider struct {
 	EswitchMode  string
 	EswitchError error
+	VfInfos      []netlink.VfInfo
+	VfInfosError error
+	Handle       NetlinkHandle
+	HandleError  error
 }
 
 func (f *FakeNetlinkProvider) GetDevLinkDeviceEswitchMode(_ string) (string, error) {
 	return f.EswitchMode, f.EswitchError
 }
 
+func (f *FakeNetlinkProvider) GetLinkVfInfos(_ string) ([]netlink.VfInfo, error) {
+	return f.VfInfos, f.VfInfosError
+}
+
+func (f *FakeNetlinkProvider) NewHandle(_ string) (NetlinkHandle, error) {
+	return f.Handle, f.HandleError
+}
+
 // FakeSriovnetProvider is a configurable SriovnetProvider for use in unit tests.
 type FakeSriovnetProvider struct {
 	// UplinkName is returned by GetUplinkRepresentor on success.
 	UplinkName string
 	// UplinkError, when non-nil, is returned instead of UplinkName.
 	UplinkError error
+	// NetDevs is returned by GetNetDevicesFromPci with NetDevsError.
+	NetDevs      []string
+	NetDevsError error
 }
 
 func (f *FakeSriovnetProvider) GetUplinkRepresentor(_ string) (string, error) {
 	return f.UplinkName, f.UplinkError
 }
 
+func (f *FakeSriovnetProvider) GetNetDevicesFromPci(_ string) ([]string, error) {
+	return f.NetDevs, f.NetDevsError
+}
+
 // FakeFilesystem allows to setup isolated fake files structure used for the tests.
 type FakeFilesystem struct {
 	RootDir  string
@@ -0,0 +1,206 @@
+// Package introspection serves a read-only view of the node plugin state on a
+// local unix socket: the discovered devices and the policy advertising them,
+// the claims prepared for each pod and the result of the last publish.
+package introspection
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/nodeview"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/unixsocket"
+)
+
+// Paths served by the introspection server.
+const (
+	PathDevices = "/devices"
+	PathClaims  = "/claims"
+	PathPublish = "/publish"
+)
+
+// Device is a discovered device.
+type Device struct {
+	Name       string `json:"name"`
+	Advertised bool   `json:"advertised"`
+	// Policy is the SriovResourcePolicy advertising the device.
+	Policy     string                                                    `json:"policy,omitempty"`
+	Attributes map[resourceapi.QualifiedName]resourceapi.DeviceAttribute `json:"attributes,omitempty"`
+	// AttributeSources is the DeviceAttributes each policy attribute comes from.
+	AttributeSources map[resourceapi.QualifiedName]string `json:"attributeSources,omitempty"`
+	// MergedPolicies are the policies in Merge overlap mode adding attributes.
+	MergedPolicies []string `json:"mergedPolicies,omitempty"`
+}
+
+// Pod is a pod with prepared claims.
+type Pod struct {
+	UID    k8stypes.UID `json:"uid"`
+	Name   string       `json:"name,omitempty"`
+	Claims []Claim      `json:"claims"`
+}
+
+// Claim is a claim prepared for a pod.
+type Claim struct {
+	UID       k8stypes.UID     `json:"uid"`
+	Namespace string           `json:"namespace"`
+	Name      string           `json:"name"`
+	Devices   []PreparedDevice `json:"devices"`
+}
+
+// PreparedDevice is a device prepared for a claim.
+type PreparedDevice struct {
+	Device         string                         `json:"device"`
+	Pool           string                         `json:"pool"`
+	Requests       []string                       `json:"requests,omitempty"`
+	PciAddress     string                         `json:"pciAddress"`
+	IfName         string                         `json:"ifName,omitempty"`
+	Driver         string                         `json:"driver,omitempty"`
+	OriginalDriver string                         `json:"originalDriver,omitempty"`
+	CDIDeviceIDs   []string                       `json:"cdiDeviceIDs,omitempty"`
+	SandboxID      string                         `json:"sandboxID,omitempty"`
+	NetworkData    *resourceapi.NetworkDeviceData `json:"networkData,omitempty"`
+	Phase          drasriovtypes.DevicePhase      `json:"phase,omitempty"`
+	PreparedTime   *metav1.Time                   `json:"preparedTime,omitempty"`
+	PhaseTime      *metav1.Time                   `json:"phaseTime,omitempty"`
+}
+
+// PublishStatus is the result of the last ResourceSlice publish.
+type PublishStatus struct {
+	LastPublishTime *time.Time `json:"lastPublishTime,omitempty"`
+	Error           string     `json:"error,omitempty"`
+}
+
+// Server serves the node plugin state on a unix socket.
+type Server struct {
+	*unixsocket.Server
+	sources nodeview.Sources
+}
+
+// NewServer creates a server for the state of sources on socketPath. Nil
+// sources are served as empty.
+func NewServer(socketPath string, sources nodeview.Sources) *Server {
+	s := &Server{sources: sources}
+	mux := http.NewServeMux()
+	mux.HandleFunc(PathDevices, s.serveDevices)
+	mux.HandleFunc(PathClaims, s.serveClaims)
+	mux.HandleFunc(PathPublish, s.servePublish)
+	s.Server = unixsocket.NewServer("introspection", socketPath, mux)
+	return s
+}
+
+// Devices returns the discovered devices sorted by name.
+func (s *Server) Devices() []Device {
+	devices := []Device{}
+	for _, device := range s.sources.ListDevices() {
+		d := Device{
+			Name:       device.Name,
+			Advertised: device.Advertised,
+			Attributes: device.Device.Attributes,
+		}
+		if device.Match != nil {
+			d.Policy = device.Match.Policy
+			d.AttributeSources = device.Match.AttributeSources
+			d.MergedPolicies = device.Match.MergedPolicies
+		}
+		devices = append(devices, d)
+	}
+	return devices
+}
+
+// Pods returns the pods with prepared claims sorted by UID.
+func (s *Server) Pods() []Pod {
+	byPod := s.sources.PreparedDevices()
+
+	pods := make([]Pod, 0, len(byPod))
+	for podUID, preparedDevices := range byPod {
+		pod := Pod{UID: podUID, Claims: []Claim{}}
+		claims := make(map[k8stypes.UID]int)
+		for _, preparedDevice := range preparedDevices {
+			if pod.Name == "" {
+				pod.Name = preparedDevice.PodName
+			}
+			claimRef := preparedDevice.ClaimNamespacedName
+			idx, ok := claims[claimRef.UID]
+			if !ok {
+				idx = len(pod.Claims)
+				claims[claimRef.UID] = idx
+				pod.Claims = append(pod.Claims, Claim{
+					UID:       claimRef.UID,
+					Namespace: claimRef.Namespace,
+					Name:      claimRef.Name,
+				})
+			}
+			pod.Claims[idx].Devices = append(pod.Claims[idx].Devices, preparedDeviceView(preparedDevice))
+		}
+		sort.Slice(pod.Claims, func(i, j int) bool { return pod.Claims[i].UID < pod.Claims[j].UID })
+		pods = append(pods, pod)
+	}
+	sort.Slice(pods, func(i, j int) bool { return pods[i].UID < pods[j].UID })
+	return pods
+}
+
+// Publish returns the result of the last ResourceSlice publish.
+func (s *Server) Publish() PublishStatus {
+	status := s.sources.PublishStatus()
+	resp := PublishStatus{}
+	if !status.Time.IsZero() {
+		resp.LastPublishTime = &status.Time
+	}
+	if status.Err != nil {
+		resp.Error = status.Err.Error()
+	}
+	return resp
+}
+
+func preparedDeviceView(preparedDevice *drasriovtypes.PreparedDevice) PreparedDevice {
+	d := PreparedDevice{
+		Device:         preparedDevice.Device.GetDeviceName(),
+		Pool:           preparedDevice.Device.GetPoolName(),
+		Requests:       preparedDevice.Device.GetRequestNames(),
+		PciAddress:     preparedDevice.PciAddress,
+		IfName:         preparedDevice.IfName,
+		OriginalDriver: preparedDevice.OriginalDriver,
+		CDIDeviceIDs:   preparedDevice.Device.GetCdiDeviceIds(),
+		SandboxID:      preparedDevice.SandboxID,
+		NetworkData:    preparedDevice.NetworkDeviceData,
+		Phase:          preparedDevice.Phase,
+		PreparedTime:   preparedDevice.PreparedTime,
+		PhaseTime:      preparedDevice.PhaseTime,
+	}
+	if preparedDevice.Config != nil {
+		d.Driver = preparedDevice.Config.Driver
+	}
+	return d
+}
+
+func (s *Server) serveDevices(w http.ResponseWriter, r *http.Request) {
+	serveJSON(w, r, func() any { return s.Devices() })
+}
+
+func (s *Server) serveClaims(w http.ResponseWriter, r *http.Request) {
+	serveJSON(w, r, func() any { return s.Pods() })
+}
+
+func (s *Server) servePublish(w http.ResponseWriter, r *http.Request) {
+	serveJSON(w, r, func() any { return s.Publish() })
+}
+
+func serveJSON(w http.ResponseWriter, r *http.Request, body func() any) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(body()); err != nil {
+		klog.FromContext(r.Context()).WithName("introspection").Error(err, "Failed to write introspection response", "path", r.URL.Path)
+	}
+}
@@ -0,0 +1,252 @@
+package introspection_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	resourceapi "k8s.io/api/resource/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	"k8s.io/utils/ptr"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/devicestate"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/introspection"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/nodeview"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+type fakeDevices struct {
+	devices    types.AllocatableDevices
+	advertised map[string]bool
+}
+
+func (f *fakeDevices) SnapshotDevices() (types.AllocatableDevices, map[string]bool) {
+	return f.devices, f.advertised
+}
+
+func (f *fakeDevices) PhysicalFunctions() []devicestate.PFInfo {
+	return nil
+}
+
+func (f *fakeDevices) ExcludedDevices() []devicestate.ExcludedDevice {
+	return nil
+}
+
+type fakePolicies map[string]types.DevicePolicyMatch
+
+func (f fakePolicies) DevicePolicyMatches() map[string]types.DevicePolicyMatch {
+	return f
+}
+
+type fakeClaims map[k8stypes.UID]types.PreparedDevices
+
+func (f fakeClaims) ListDevicesByPodUID() map[k8stypes.UID]types.PreparedDevices {
+	return f
+}
+
+type fakePublish types.PublishStatus
+
+func (f fakePublish) PublishStatus() types.PublishStatus {
+	return types.PublishStatus(f)
+}
+
+var _ = Describe("Introspection server", func() {
+	var (
+		sources nodeview.Sources
+		server  *introspection.Server
+	)
+
+	BeforeEach(func() {
+		sources = nodeview.Sources{
+			Devices: &fakeDevices{
+				devices: types.AllocatableDevices{
+					"0000-08-00-2": {Name: "0000-08-00-2"},
+					"0000-08-00-1": {
+						Name: "0000-08-00-1",
+						Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+							"vendor": {StringValue: ptr.To("8086")},
+							"zone":   {StringValue: ptr.To("a")},
+						},
+					},
+				},
+				advertised: map[string]bool{"0000-08-00-1": true},
+			},
+			Policies: fakePolicies{
+				"0000-08-00-1": {Policy: "policy-a", AttributeSources: map[resourceapi.QualifiedName]string{"zone": "attrs-a"}},
+			},
+			Claims: fakeClaims{
+				"pod-uid": {
+					{
+						Device: drapbv1.Device{
+							RequestNames: []string{"vf"},
+							PoolName:     "node-a",
+							DeviceName:   "0000-08-00-1",
+							CdiDeviceIds: []string{"k8s.sriovnetwork.k8snetworkplumbingwg.io/vf=claim-uid-0000-08-00-1"},
+						},
+						ClaimNamespacedName: kubeletplugin.NamespacedObject{
+							NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "claim"},
+							UID:            "claim-uid",
+						},
+						Config:            &configapi.VfConfig{Driver: "vfio-pci"},
+						PciAddress:        "0000:08:00.1",
+						IfName:            "net1",
+						PodUID:            "pod-uid",
+						PodName:           "pod",
+						OriginalDriver:    "iavf",
+						SandboxID:         "sandbox-id",
+						NetworkDeviceData: &resourceapi.NetworkDeviceData{InterfaceName: "net1", IPs: []string{"10.0.0.2/24"}},
+					},
+				},
+			},
+			Publish: fakePublish{Time: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), Err: errors.New("forbidden")},
+		}
+	})
+
+	JustBeforeEach(func() {
+		server = introspection.NewServer("", sources)
+	})
+
+	It("lists the devices with the policy advertising them", func() {
+		Expect(server.Devices()).To(Equal([]introspection.Device{
+			{
+				Name:       "0000-08-00-1",
+				Advertised: true,
+				Policy:     "policy-a",
+				Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+					"vendor": {StringValue: ptr.To("8086")},
+					"zone":   {StringValue: ptr.To("a")},
+				},
+				AttributeSources: map[resourceapi.QualifiedName]string{"zone": "attrs-a"},
+			},
+			{Name: "0000-08-00-2"},
+		}))
+	})
+
+	It("groups the prepared devices by pod and claim", func() {
+		Expect(server.Pods()).To(Equal([]introspection.Pod{{
+			UID:  "pod-uid",
+			Name: "pod",
+			Claims: []introspection.Claim{{
+				UID:       "claim-uid",
+				Namespace: "default",
+				Name:      "claim",
+				Devices: []introspection.PreparedDevice{{
+					Device:         "0000-08-00-1",
+					Pool:           "node-a",
+					Requests:       []string{"vf"},
+					PciAddress:     "0000:08:00.1",
+					IfName:         "net1",
+					Driver:         "vfio-pci",
+					OriginalDriver: "iavf",
+					CDIDeviceIDs:   []string{"k8s.sriovnetwork.k8snetworkplumbingwg.io/vf=claim-uid-0000-08-00-1"},
+					SandboxID:      "sandbox-id",
+					NetworkData:    &resourceapi.NetworkDeviceData{InterfaceName: "net1", IPs: []string{"10.0.0.2/24"}},
+				}},
+			}},
+		}}))
+	})
+
+	It("reports the last publish", func() {
+		status := server.Publish()
+		Expect(status.LastPublishTime).NotTo(BeNil())
+		Expect(status.LastPublishTime.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))).To(BeTrue())
+		Expect(status.Error).To(Equal("forbidden"))
+	})
+
+	Context("without sources", func() {
+		BeforeEach(func() {
+			sources = nodeview.Sources{}
+		})
+
+		It("serves empty state", func() {
+			Expect(server.Devices()).To(BeEmpty())
+			Expect(server.Pods()).To(BeEmpty())
+			Expect(server.Publish()).To(Equal(introspection.PublishStatus{}))
+		})
+	})
+
+	Context("on a unix socket", func() {
+		var (
+			client     *http.Client
+			socketPath string
+		)
+
+		JustBeforeEach(func() {
+			// unix socket paths are limited in length, keep it short
+			dir, err := os.MkdirTemp("", "introspection")
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(os.RemoveAll, dir)
+			socketPath = filepath.Join(dir, "introspection.sock")
+
+			server = introspection.NewServer(socketPath, sources)
+			Expect(server.Start(context.Background())).To(Succeed())
+			DeferCleanup(server.Stop)
+
+			info, err := os.Stat(socketPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o600)))
+
+			client = &http.Client{
+				Transport: &http.Transport{
+					DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+						var dialer net.Dialer
+						return dialer.DialContext(ctx, "unix", socketPath)
+					},
+				},
+			}
+		})
+
+		It("serves the state as JSON", func() {
+			resp, err := client.Get("http://introspection" + introspection.PathDevices)
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Header.Get("Content-Type")).To(Equal("application/json"))
+
+			var devices []introspection.Device
+			Expect(json.NewDecoder(resp.Body).Decode(&devices)).To(Succeed())
+			Expect(devices).To(HaveLen(2))
+			Expect(devices[0].Policy).To(Equal("policy-a"))
+
+			resp, err = client.Get("http://introspection" + introspection.PathPublish)
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+			var status introspection.PublishStatus
+			Expect(json.NewDecoder(resp.Body).Decode(&status)).To(Succeed())
+			Expect(status.Error).To(Equal("forbidden"))
+		})
+
+		It("rejects other methods than GET", func() {
+			resp, err := client.Post("http://introspection"+introspection.PathClaims, "application/json", nil)
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+		})
+
+		It("leaves the socket of the driver instance replacing it", func() {
+			next := introspection.NewServer(socketPath, sources)
+			Expect(next.Start(context.Background())).To(Succeed())
+			DeferCleanup(next.Stop)
+
+			server.Stop()
+			Expect(socketPath).To(BeAnExistingFile())
+			resp, err := client.Get("http://introspection" + introspection.PathPublish)
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			next.Stop()
+			Expect(socketPath).NotTo(BeAnExistingFile())
+		})
+	})
+})
@@ -0,0 +1,357 @@
+// Package inventory gathers the cluster-wide view of the SR-IOV devices
+// published by the driver, the claims they are allocated to and the
+// inconsistencies between them, for the kubectl sriov-dra plugin.
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sriovdrav1alpha1 "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/sriovdra/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/controller"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// Options selects what is gathered.
+type Options struct {
+	// PolicyNamespace is the namespace of the SriovResourcePolicy and
+	// DeviceAttributes objects, the namespace of the driver.
+	PolicyNamespace string
+	// NodeName restricts the report to the devices and claims of a node.
+	NodeName string
+}
+
+// Report is the cluster-wide view of the driver devices and claims.
+type Report struct {
+	Devices []Device `json:"devices"`
+	Claims  []Claim  `json:"claims"`
+	Issues  []Issue  `json:"issues"`
+}
+
+// Device is a device published in a ResourceSlice of the driver.
+type Device struct {
+	Node       string `json:"node"`
+	Pool       string `json:"pool"`
+	PF         string `json:"pf,omitempty"`
+	Name       string `json:"name"`
+	PciAddress string `json:"pciAddress,omitempty"`
+	// Policy is the SriovResourcePolicy selecting the device.
+	Policy string `json:"policy,omitempty"`
+	// DeviceAttributes are the DeviceAttributes the policy attributes of the
+	// device come from.
+	DeviceAttributes []string `json:"deviceAttributes,omitempty"`
+	// Claims are the namespace/name of the claims the device is allocated to.
+	Claims []string `json:"claims,omitempty"`
+}
+
+// Claim is a ResourceClaim with devices of the driver allocated.
+type Claim struct {
+	Namespace string       `json:"namespace"`
+	Name      string       `json:"name"`
+	UID       k8stypes.UID `json:"uid"`
+	// Pods are the names of the pods the claim is reserved for.
+	Pods    []string      `json:"pods,omitempty"`
+	Devices []ClaimDevice `json:"devices"`
+}
+
+// ClaimDevice is a device allocated to a claim.
+type ClaimDevice struct {
+	Request    string `json:"request"`
+	Node       string `json:"node,omitempty"`
+	Pool       string `json:"pool"`
+	Device     string `json:"device"`
+	PF         string `json:"pf,omitempty"`
+	PciAddress string `json:"pciAddress,omitempty"`
+	// NetworkData is the network data the driver reported in the claim
+	// status, nil while the device is not prepared.
+	NetworkData *resourceapi.NetworkDeviceData `json:"networkData,omitempty"`
+}
+
+// Issue is an inconsistency found between the objects.
+type Issue struct {
+	// Object is the kind and namespace/name of the object concerned.
+	Object  string `json:"object"`
+	Message string `json:"message"`
+}
+
+type deviceKey struct {
+	pool   string
+	device string
+}
+
+// Collect gathers the report from the cluster.
+func Collect(ctx context.Context, c client.Reader, opts Options) (*Report, error) {
+	slices := &resourceapi.ResourceSliceList{}
+	if err := c.List(ctx, slices); err != nil {
+		return nil, fmt.Errorf("failed to list ResourceSlices: %w", err)
+	}
+	nodes := &corev1.NodeList{}
+	if err := c.List(ctx, nodes); err != nil {
+		return nil, fmt.Errorf("failed to list Nodes: %w", err)
+	}
+	policies := &sriovdrav1alpha1.SriovResourcePolicyList{}
+	if err := c.List(ctx, policies, client.InNamespace(opts.PolicyNamespace)); err != nil {
+		return nil, fmt.Errorf("failed to list SriovResourcePolicies: %w", err)
+	}
+	deviceAttrs := &sriovdrav1alpha1.DeviceAttributesList{}
+	if err := c.List(ctx, deviceAttrs, client.InNamespace(opts.PolicyNamespace)); err != nil {
+		return nil, fmt.Errorf("failed to list DeviceAttributes: %w", err)
+	}
+	claims := &resourceapi.ResourceClaimList{}
+	if err := c.List(ctx, claims); err != nil {
+		return nil, fmt.Errorf("failed to list ResourceClaims: %w", err)
+	}
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods); err != nil {
+		return nil, fmt.Errorf("failed to list Pods: %w", err)
+	}
+
+	nodeLabels := make(map[string]map[string]string, len(nodes.Items))
+	for _, node := range nodes.Items {
+		nodeLabels[node.Name] = node.Labels
+	}
+	podUIDs := make(map[k8stypes.NamespacedName]k8stypes.UID, len(pods.Items))
+	for _, pod := range pods.Items {
+		podUIDs[k8stypes.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}] = pod.UID
+	}
+
+	report := &Report{Devices: []Device{}, Claims: []Claim{}, Issues: []Issue{}}
+
+	// devices of the driver per node, matched against the policies the
+	// way the node plugin of the node does
+	nodeDevices := make(map[string]drasriovtypes.AllocatableDevices)
+	devicePools := make(map[string]map[string]string)
+	for _, slice := range slices.Items {
+		if slice.Spec.Driver != consts.DriverName || slice.Spec.NodeName == nil {
+			continue
+		}
+		node := *slice.Spec.NodeName
+		if opts.NodeName != "" && node != opts.NodeName {
+			continue
+		}
+		if nodeDevices[node] == nil {
+			nodeDevices[node] = make(drasriovtypes.AllocatableDevices)
+			devicePools[node] = make(map[string]string)
+		}
+		for _, device := range slice.Spec.Devices {
+			nodeDevices[node][device.Name] = discoveredDevice(device)
+			devicePools[node][device.Name] = slice.Spec.Pool.Name
+		}
+	}
+
+	devices := make(map[deviceKey]*Device)
+	for node, allocatable := range nodeDevices {
+		if _, ok := nodeLabels[node]; !ok {
+			report.Issues = append(report.Issues, Issue{
+				Object:  "Node/" + node,
+				Message: "node publishing ResourceSlices does not exist",
+			})
+		}
+		matches := controller.MatchDevicePolicies(klog.FromContext(ctx), nodeLabels[node], policies.Items, deviceAttrs.Items, allocatable)
+		for name, device := range allocatable {
+			d := &Device{
+				Node:       node,
+				Pool:       devicePools[node][name],
+				PF:         stringAttribute(device, consts.AttributePFName),
+				Name:       name,
+				PciAddress: stringAttribute(device, consts.AttributePciAddress),
+			}
+			if match, ok := matches[name]; ok {
+				d.Policy = match.Policy
+				d.DeviceAttributes = attributeSourceNames(match.AttributeSources)
+			} else {
+				report.Issues = append(report.Issues, Issue{
+					Object:  fmt.Sprintf("Device/%s/%s", d.Pool, name),
+					Message: "published device is not selected by any SriovResourcePolicy",
+				})
+			}
+			devices[deviceKey{pool: d.Pool, device: name}] = d
+		}
+	}
+
+	for i := range claims.Items {
+		claim := &claims.Items[i]
+		view, issues := collectClaim(claim, devices, podUIDs, opts)
+		report.Issues = append(report.Issues, issues...)
+		if view != nil {
+			report.Claims = append(report.Claims, *view)
+		}
+	}
+
+	for _, device := range devices {
+		sort.Strings(device.Claims)
+		if len(device.Claims) > 1 {
+			report.Issues = append(report.Issues, Issue{
+				Object:  fmt.Sprintf("Device/%s/%s", device.Pool, device.Name),
+				Message: fmt.Sprintf("device is allocated to several claims: %v", device.Claims),
+			})
+		}
+		report.Devices = append(report.Devices, *device)
+	}
+
+	sort.Slice(report.Devices, func(i, j int) bool {
+		a, b := report.Devices[i], report.Devices[j]
+		if a.Node != b.Node {
+			return a.Node < b.Node
+		}
+		if a.PF != b.PF {
+			return a.PF < b.PF
+		}
+		return a.Name < b.Name
+	})
+	sort.Slice(report.Claims, func(i, j int) bool {
+		a, b := report.Claims[i], report.Claims[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	sort.Slice(report.Issues, func(i, j int) bool {
+		a, b := report.Issues[i], report.Issues[j]
+		if a.Object != b.Object {
+			return a.Object < b.Object
+		}
+		return a.Message < b.Message
+	})
+	return report, nil
+}
+
+// collectClaim returns the claim view and the issues of a claim, nil when no
+// device of the driver is allocated to it. podUIDs are the UIDs of the pods
+// of the cluster.
+func collectClaim(claim *resourceapi.ResourceClaim, devices map[deviceKey]*Device, podUIDs map[k8stypes.NamespacedName]k8stypes.UID, opts Options) (*Claim, []Issue) {
+	object := fmt.Sprintf("ResourceClaim/%s/%s", claim.Namespace, claim.Name)
+	if claim.Status.Allocation == nil {
+		return nil, nil
+	}
+
+	statuses := make(map[deviceKey]*resourceapi.AllocatedDeviceStatus)
+	for i := range claim.Status.Devices {
+		status := &claim.Status.Devices[i]
+		if status.Driver == consts.DriverName {
+			statuses[deviceKey{pool: status.Pool, device: status.Device}] = status
+		}
+	}
+
+	view := &Claim{
+		Namespace: claim.Namespace,
+		Name:      claim.Name,
+		UID:       claim.UID,
+	}
+	var issues []Issue
+	onNode := opts.NodeName == ""
+	for _, result := range claim.Status.Allocation.Devices.Results {
+		if result.Driver != consts.DriverName {
+			continue
+		}
+		key := deviceKey{pool: result.Pool, device: result.Device}
+		claimDevice := ClaimDevice{
+			Request: result.Request,
+			Pool:    result.Pool,
+			Device:  result.Device,
+		}
+		if status, ok := statuses[key]; ok {
+			claimDevice.NetworkData = status.NetworkData
+			delete(statuses, key)
+		}
+		if device, ok := devices[key]; ok {
+			claimDevice.Node = device.Node
+			claimDevice.PF = device.PF
+			claimDevice.PciAddress = device.PciAddress
+			device.Claims = append(device.Claims, claim.Namespace+"/"+claim.Name)
+			onNode = true
+		} else if opts.NodeName == "" {
+			issues = append(issues, Issue{
+				Object:  object,
+				Message: fmt.Sprintf("allocated device %s/%s is not published in any ResourceSlice", result.Pool, result.Device),
+			})
+		}
+		view.Devices = append(view.Devices, claimDevice)
+	}
+	if len(view.Devices) == 0 || !onNode {
+		return nil, nil
+	}
+
+	for key := range statuses {
+		issues = append(issues, Issue{
+			Object:  object,
+			Message: fmt.Sprintf("status reports device %s/%s which is not allocated to the claim", key.pool, key.device),
+		})
+	}
+
+	prepared := false
+	for _, device := range view.Devices {
+		if device.NetworkData != nil {
+			prepared = true
+		}
+	}
+	if prepared && len(claim.Status.ReservedFor) == 0 {
+		issues = append(issues, Issue{
+			Object:  object,
+			Message: "claim has prepared devices but is not reserved for any pod",
+		})
+	}
+
+	for _, consumer := range claim.Status.ReservedFor {
+		if consumer.APIGroup != "" || consumer.Resource != "pods" {
+			continue
+		}
+		view.Pods = append(view.Pods, consumer.Name)
+		uid, exists := podUIDs[k8stypes.NamespacedName{Namespace: claim.Namespace, Name: consumer.Name}]
+		switch {
+		case !exists:
+			issues = append(issues, Issue{
+				Object:  object,
+				Message: fmt.Sprintf("claim is reserved for pod %s which does not exist", consumer.Name),
+			})
+		case uid != consumer.UID:
+			issues = append(issues, Issue{
+				Object:  object,
+				Message: fmt.Sprintf("claim is reserved for pod %s with UID %s, the pod has UID %s", consumer.Name, consumer.UID, uid),
+			})
+		}
+	}
+	return view, issues
+}
+
+// discoveredDevice returns a copy of a published device with only the
+// attributes discovered by the driver, the attributes applied by the
+// policies are left out as the node plugin does not select devices by them.
+func discoveredDevice(device resourceapi.Device) resourceapi.Device {
+	attrs := make(map[resourceapi.QualifiedName]resourceapi.DeviceAttribute, len(device.Attributes))
+	for key, attr := range device.Attributes {
+		if consts.ReservedAttributes[key] {
+			attrs[key] = attr
+		}
+	}
+	device.Attributes = attrs
+	return device
+}
+
+func stringAttribute(device resourceapi.Device, name resourceapi.QualifiedName) string {
+	if attr, ok := device.Attributes[name]; ok && attr.StringValue != nil {
+		return *attr.StringValue
+	}
+	return ""
+}
+
+func attributeSourceNames(sources map[resourceapi.QualifiedName]string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, name := range sources {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,213 @@
+package inventory_test
+
+import (
+	"bytes"
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	ctrlclientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovdrav1alpha1 "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/sriovdra/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/flags"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/inventory"
+)
+
+const namespace = "dra-driver-sriov"
+
+func vfDevice(name, pf, pciAddress, vendor string) resourceapi.Device {
+	return resourceapi.Device{
+		Name: name,
+		Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+			consts.AttributePFName:     {StringValue: ptr.To(pf)},
+			consts.AttributePciAddress: {StringValue: ptr.To(pciAddress)},
+			consts.AttributeVendorID:   {StringValue: ptr.To(vendor)},
+		},
+	}
+}
+
+func allocatedClaim(name string, devices ...string) *resourceapi.ResourceClaim {
+	claim := &resourceapi.ResourceClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, UID: k8stypes.UID("uid-" + name)},
+		Status: resourceapi.ResourceClaimStatus{
+			Allocation: &resourceapi.AllocationResult{},
+		},
+	}
+	for _, device := range devices {
+		claim.Status.Allocation.Devices.Results = append(claim.Status.Allocation.Devices.Results, resourceapi.DeviceRequestAllocationResult{
+			Request: "vf",
+			Driver:  consts.DriverName,
+			Pool:    "node-a",
+			Device:  device,
+		})
+	}
+	return claim
+}
+
+var _ = Describe("Collect", func() {
+	var objects []runtime.Object
+
+	BeforeEach(func() {
+		objects = []runtime.Object{
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a", Labels: map[string]string{"sriov": "true"}}},
+			&resourceapi.ResourceSlice{
+				ObjectMeta: metav1.ObjectMeta{Name: "node-a-slice"},
+				Spec: resourceapi.ResourceSliceSpec{
+					Driver:   consts.DriverName,
+					NodeName: ptr.To("node-a"),
+					Pool:     resourceapi.ResourcePool{Name: "node-a", ResourceSliceCount: 1},
+					Devices: []resourceapi.Device{
+						vfDevice("0000-08-00-2", "ens1f0", "0000:08:00.2", "8086"),
+						vfDevice("0000-08-00-1", "ens1f0", "0000:08:00.1", "8086"),
+						vfDevice("0000-09-00-1", "ens1f1", "0000:09:00.1", "15b3"),
+					},
+				},
+			},
+			&resourceapi.ResourceSlice{
+				ObjectMeta: metav1.ObjectMeta{Name: "other-driver"},
+				Spec: resourceapi.ResourceSliceSpec{
+					Driver:   "gpu.example.com",
+					NodeName: ptr.To("node-a"),
+					Pool:     resourceapi.ResourcePool{Name: "node-a", ResourceSliceCount: 1},
+					Devices:  []resourceapi.Device{{Name: "gpu-0"}},
+				},
+			},
+			&sriovdrav1alpha1.SriovResourcePolicy{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "intel"},
+				Spec: sriovdrav1alpha1.SriovResourcePolicySpec{
+					NodeSelector: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+						MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "sriov", Operator: corev1.NodeSelectorOpIn, Values: []string{"true"}}},
+					}}},
+					Configs: []sriovdrav1alpha1.Config{{
+						DeviceAttributesSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "intel"}},
+						ResourceFilters:          []sriovdrav1alpha1.ResourceFilter{{Vendors: []string{"8086"}}},
+					}},
+				},
+			},
+			&sriovdrav1alpha1.DeviceAttributes{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "intel-attrs", Labels: map[string]string{"pool": "intel"}},
+				Spec: sriovdrav1alpha1.DeviceAttributesSpec{
+					Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						consts.AttributeResourceName: {StringValue: ptr.To("intel")},
+					},
+				},
+			},
+		}
+	})
+
+	collect := func(opts inventory.Options) *inventory.Report {
+		c := ctrlclientfake.NewClientBuilder().WithScheme(flags.Scheme).WithRuntimeObjects(objects...).Build()
+		opts.PolicyNamespace = namespace
+		report, err := inventory.Collect(context.Background(), c, opts)
+		Expect(err).NotTo(HaveOccurred())
+		return report
+	}
+
+	It("lists the devices of the driver per node and PF with their policy", func() {
+		report := collect(inventory.Options{})
+		Expect(report.Devices).To(Equal([]inventory.Device{
+			{Node: "node-a", Pool: "node-a", PF: "ens1f0", Name: "0000-08-00-1", PciAddress: "0000:08:00.1", Policy: "intel", DeviceAttributes: []string{"intel-attrs"}},
+			{Node: "node-a", Pool: "node-a", PF: "ens1f0", Name: "0000-08-00-2", PciAddress: "0000:08:00.2", Policy: "intel", DeviceAttributes: []string{"intel-attrs"}},
+			{Node: "node-a", Pool: "node-a", PF: "ens1f1", Name: "0000-09-00-1", PciAddress: "0000:09:00.1"},
+		}))
+		Expect(report.Issues).To(ConsistOf(inventory.Issue{
+			Object:  "Device/node-a/0000-09-00-1",
+			Message: "published device is not selected by any SriovResourcePolicy",
+		}))
+	})
+
+	It("matches the policies against the discovered attributes of the devices only", func() {
+		slice := objects[1].(*resourceapi.ResourceSlice)
+		slice.Spec.Devices[2].Attributes[consts.AttributeResourceName] = resourceapi.DeviceAttribute{StringValue: ptr.To("mellanox")}
+		objects = append(objects, &sriovdrav1alpha1.SriovResourcePolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "mellanox"},
+			Spec: sriovdrav1alpha1.SriovResourcePolicySpec{
+				Configs: []sriovdrav1alpha1.Config{{
+					Selectors: []sriovdrav1alpha1.DeviceSelector{{CEL: &sriovdrav1alpha1.CELDeviceSelector{
+						Expression: `device.attributes["` + consts.DriverName + `"].resourceName == "mellanox"`,
+					}}},
+				}},
+			},
+		})
+
+		report := collect(inventory.Options{})
+		Expect(report.Devices).To(ContainElement(inventory.Device{
+			Node: "node-a", Pool: "node-a", PF: "ens1f1", Name: "0000-09-00-1", PciAddress: "0000:09:00.1",
+		}))
+	})
+
+	It("maps the claims to their pods, VFs and network data", func() {
+		claim := allocatedClaim("claim-a", "0000-08-00-1")
+		claim.Status.ReservedFor = []resourceapi.ResourceClaimConsumerReference{{Resource: "pods", Name: "pod-a", UID: "pod-uid"}}
+		claim.Status.Devices = []resourceapi.AllocatedDeviceStatus{{
+			Driver:      consts.DriverName,
+			Pool:        "node-a",
+			Device:      "0000-08-00-1",
+			NetworkData: &resourceapi.NetworkDeviceData{InterfaceName: "net1", IPs: []string{"10.0.0.2/24"}},
+		}}
+		objects = append(objects, claim, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod-a", UID: "pod-uid"}})
+
+		report := collect(inventory.Options{})
+		Expect(report.Claims).To(Equal([]inventory.Claim{{
+			Namespace: "default",
+			Name:      "claim-a",
+			UID:       "uid-claim-a",
+			Pods:      []string{"pod-a"},
+			Devices: []inventory.ClaimDevice{{
+				Request:     "vf",
+				Node:        "node-a",
+				Pool:        "node-a",
+				Device:      "0000-08-00-1",
+				PF:          "ens1f0",
+				PciAddress:  "0000:08:00.1",
+				NetworkData: &resourceapi.NetworkDeviceData{InterfaceName: "net1", IPs: []string{"10.0.0.2/24"}},
+			}},
+		}}))
+		Expect(report.Devices[0].Claims).To(Equal([]string{"default/claim-a"}))
+
+		var out bytes.Buffer
+		Expect(inventory.PrintClaims(&out, report.Claims)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("10.0.0.2/24"))
+	})
+
+	It("flags inconsistencies between claims, devices and pods", func() {
+		unreserved := allocatedClaim("unreserved", "0000-08-00-1")
+		unreserved.Status.Devices = []resourceapi.AllocatedDeviceStatus{{
+			Driver:      consts.DriverName,
+			Pool:        "node-a",
+			Device:      "0000-08-00-1",
+			NetworkData: &resourceapi.NetworkDeviceData{InterfaceName: "net1"},
+		}}
+		orphaned := allocatedClaim("orphaned", "0000-08-00-1")
+		orphaned.Status.ReservedFor = []resourceapi.ResourceClaimConsumerReference{{Resource: "pods", Name: "gone", UID: "gone-uid"}}
+		stale := allocatedClaim("stale", "0000-0a-00-1")
+		objects = append(objects, unreserved, orphaned, stale, &resourceapi.ResourceClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pending"},
+		})
+
+		report := collect(inventory.Options{})
+		Expect(report.Issues).To(ContainElements(
+			inventory.Issue{Object: "Device/node-a/0000-08-00-1", Message: "device is allocated to several claims: [default/orphaned default/unreserved]"},
+			inventory.Issue{Object: "ResourceClaim/default/orphaned", Message: "claim is reserved for pod gone which does not exist"},
+			inventory.Issue{Object: "ResourceClaim/default/stale", Message: "allocated device node-a/0000-0a-00-1 is not published in any ResourceSlice"},
+			inventory.Issue{Object: "ResourceClaim/default/unreserved", Message: "claim has prepared devices but is not reserved for any pod"},
+		))
+		Expect(report.Claims).To(HaveLen(3))
+	})
+
+	It("restricts the report to a node", func() {
+		objects = append(objects, allocatedClaim("claim-a", "0000-08-00-1"))
+
+		report := collect(inventory.Options{NodeName: "node-b"})
+		Expect(report.Devices).To(BeEmpty())
+		Expect(report.Claims).To(BeEmpty())
+		Expect(report.Issues).To(BeEmpty())
+	})
+})
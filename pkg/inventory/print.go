@@ -0,0 +1,64 @@
+package inventory
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// none is printed for empty columns, like kubectl does.
+const none = "<none>"
+
+// PrintDevices prints the devices as a table.
+func PrintDevices(w io.Writer, devices []Device) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tPF\tDEVICE\tPCI ADDRESS\tPOLICY\tDEVICE ATTRIBUTES\tCLAIMS")
+	for _, d := range devices {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			d.Node, orNone(d.PF), d.Name, orNone(d.PciAddress), orNone(d.Policy),
+			join(d.DeviceAttributes), join(d.Claims))
+	}
+	return tw.Flush()
+}
+
+// PrintClaims prints one line per device allocated to a claim.
+func PrintClaims(w io.Writer, claims []Claim) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tCLAIM\tPODS\tREQUEST\tNODE\tPF\tDEVICE\tPCI ADDRESS\tINTERFACE\tIPS\tMAC")
+	for _, c := range claims {
+		for _, d := range c.Devices {
+			ifName, ips, mac := none, none, none
+			if d.NetworkData != nil {
+				ifName = orNone(d.NetworkData.InterfaceName)
+				ips = join(d.NetworkData.IPs)
+				mac = orNone(d.NetworkData.HardwareAddress)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				c.Namespace, c.Name, join(c.Pods), d.Request, orNone(d.Node), orNone(d.PF), d.Device,
+				orNone(d.PciAddress), ifName, ips, mac)
+		}
+	}
+	return tw.Flush()
+}
+
+// PrintIssues prints the issues as a table.
+func PrintIssues(w io.Writer, issues []Issue) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(tw, "OBJECT\tISSUE")
+	for _, i := range issues {
+		fmt.Fprintf(tw, "%s\t%s\n", i.Object, i.Message)
+	}
+	return tw.Flush()
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return none
+	}
+	return s
+}
+
+func join(values []string) string {
+	return orNone(strings.Join(values, ","))
+}
@@ -0,0 +1,177 @@
+// Package metrics defines the Prometheus metrics of the node plugin. They are
+// registered to the controller-runtime registry and served on /metrics by the
+// controller manager.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "dra_driver_sriov"
+
+// Claim operations.
+const (
+	OperationPrepare   = "prepare"
+	OperationUnprepare = "unprepare"
+)
+
+// Reasons of claim operation failures.
+const (
+	ReasonInvalidClaim     = "invalid_claim"
+	ReasonDeviceNotFound   = "device_not_found"
+	ReasonDevicePrepare    = "device_prepare"
+	ReasonDeviceUnprepare  = "device_unprepare"
+	ReasonNetworkDetach    = "network_detach"
+	ReasonCheckpoint       = "checkpoint"
+	ReasonCDISpec          = "cdi_spec"
+	ReasonMissingPodDevice = "missing_pod_devices"
+)
+
+// CNI operations.
+const (
+	CNIAdd = "add"
+	CNIDel = "del"
+)
+
+// Driver operations.
+const (
+	DriverBind   = "bind"
+	DriverUnbind = "unbind"
+)
+
+// Device states counted per PF.
+const (
+	DevicesDiscovered = "discovered"
+	DevicesAdvertised = "advertised"
+	DevicesPrepared   = "prepared"
+)
+
+const (
+	resultSuccess = "success"
+	resultError   = "error"
+)
+
+var (
+	// ClaimOperationDuration is the latency of the PrepareResourceClaims and
+	// UnprepareResourceClaims calls of the kubelet.
+	ClaimOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "claim_operation_duration_seconds",
+		Help:      "Latency of the PrepareResourceClaims and UnprepareResourceClaims calls.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"operation"})
+
+	// ClaimOperationFailures counts the claims failing to prepare or unprepare.
+	ClaimOperationFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "claim_operation_failures_total",
+		Help:      "Number of claims failing to prepare or unprepare, by reason.",
+	}, []string{"operation", "reason"})
+
+	// CNIOperationDuration is the duration of the CNI ADD and DEL operations.
+	CNIOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "cni_operation_duration_seconds",
+		Help:      "Duration of the CNI ADD and DEL operations.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"operation", "result"})
+
+	// DriverOperations counts the driver bind and unbind operations of the
+	// virtual functions.
+	DriverOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "driver_operations_total",
+		Help:      "Number of virtual function driver bind and unbind operations.",
+	}, []string{"operation", "driver", "result"})
+
+	// Devices is the number of devices of each PF by state.
+	Devices = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "devices",
+		Help:      "Number of discovered, advertised and prepared devices per PF.",
+	}, []string{"pf", "state"})
+
+	// ClaimStatusQueueDepth is the number of claims whose network status is
+	// still to be written, including the ones waiting for a retry.
+	ClaimStatusQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "claim_status_queue_depth",
+		Help:      "Number of claims with a network status update waiting to be written.",
+	})
+
+	// ResourceSlicePublishes counts the ResourceSlice publications.
+	ResourceSlicePublishes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "resourceslice_publish_total",
+		Help:      "Number of ResourceSlice publications.",
+	})
+
+	// ResourceSlicePublishErrors counts the failed ResourceSlice publications.
+	ResourceSlicePublishErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "resourceslice_publish_errors_total",
+		Help:      "Number of failed ResourceSlice publications.",
+	})
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(
+		ClaimOperationDuration,
+		ClaimOperationFailures,
+		CNIOperationDuration,
+		DriverOperations,
+		Devices,
+		ClaimStatusQueueDepth,
+		ResourceSlicePublishes,
+		ResourceSlicePublishErrors,
+	)
+}
+
+// ObserveClaimOperation records the latency of a claim operation started at start.
+func ObserveClaimOperation(operation string, start time.Time) {
+	ClaimOperationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// ClaimOperationFailed counts a claim failing a claim operation for reason.
+func ClaimOperationFailed(operation, reason string) {
+	ClaimOperationFailures.WithLabelValues(operation, reason).Inc()
+}
+
+// ObserveCNIOperation records the duration of a CNI operation started at start.
+func ObserveCNIOperation(operation string, start time.Time, err error) {
+	CNIOperationDuration.WithLabelValues(operation, result(err)).Observe(time.Since(start).Seconds())
+}
+
+// DriverOperation counts a bind or unbind of a virtual function to driver.
+func DriverOperation(operation, driver string, err error) {
+	if driver == "" {
+		driver = "default"
+	}
+	DriverOperations.WithLabelValues(operation, driver, result(err)).Inc()
+}
+
+// SetDevices replaces the device counts per PF of a state.
+func SetDevices(state string, countsByPF map[string]int) {
+	Devices.DeletePartialMatch(prometheus.Labels{"state": state})
+	for pf, count := range countsByPF {
+		Devices.WithLabelValues(pf, state).Set(float64(count))
+	}
+}
+
+// ResourceSlicePublished counts a ResourceSlice publication and its failure.
+func ResourceSlicePublished(err error) {
+	ResourceSlicePublishes.Inc()
+	if err != nil {
+		ResourceSlicePublishErrors.Inc()
+	}
+}
+
+func result(err error) string {
+	if err != nil {
+		return resultError
+	}
+	return resultSuccess
+}
@@ -0,0 +1,50 @@
+package metrics_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/metrics"
+)
+
+var _ = Describe("Metrics", func() {
+	It("replaces the device counts of a state", func() {
+		metrics.SetDevices(metrics.DevicesDiscovered, map[string]int{"eth0": 4, "eth1": 2})
+		metrics.SetDevices(metrics.DevicesAdvertised, map[string]int{"eth0": 1})
+		metrics.SetDevices(metrics.DevicesDiscovered, map[string]int{"eth0": 3})
+
+		Expect(testutil.ToFloat64(metrics.Devices.WithLabelValues("eth0", metrics.DevicesDiscovered))).To(Equal(3.0))
+		Expect(testutil.ToFloat64(metrics.Devices.WithLabelValues("eth0", metrics.DevicesAdvertised))).To(Equal(1.0))
+		Expect(testutil.CollectAndCount(metrics.Devices)).To(Equal(2))
+	})
+
+	It("labels driver operations by result and defaults the driver name", func() {
+		metrics.DriverOperation(metrics.DriverUnbind, "", nil)
+		metrics.DriverOperation(metrics.DriverBind, "vfio-pci", errors.New("bind failed"))
+
+		Expect(testutil.ToFloat64(metrics.DriverOperations.WithLabelValues(metrics.DriverUnbind, "default", "success"))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(metrics.DriverOperations.WithLabelValues(metrics.DriverBind, "vfio-pci", "error"))).To(Equal(1.0))
+	})
+
+	It("counts ResourceSlice publications and their errors", func() {
+		publishes := testutil.ToFloat64(metrics.ResourceSlicePublishes)
+		publishErrors := testutil.ToFloat64(metrics.ResourceSlicePublishErrors)
+
+		metrics.ResourceSlicePublished(nil)
+		metrics.ResourceSlicePublished(errors.New("publish failed"))
+
+		Expect(testutil.ToFloat64(metrics.ResourceSlicePublishes)).To(Equal(publishes + 2))
+		Expect(testutil.ToFloat64(metrics.ResourceSlicePublishErrors)).To(Equal(publishErrors + 1))
+	})
+
+	It("observes CNI operations by result", func() {
+		metrics.ObserveCNIOperation(metrics.CNIAdd, time.Now(), nil)
+		metrics.ObserveCNIOperation(metrics.CNIDel, time.Now(), errors.New("del failed"))
+
+		Expect(testutil.CollectAndCount(metrics.CNIOperationDuration)).To(Equal(2))
+	})
+})
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var vfLabels = []string{"pci_address", "pf", "namespace", "pod", "claim"}
+
+// vfStat describes a VF statistic and reads it from the stats of the VF.
+type vfStat struct {
+	desc  *prometheus.Desc
+	value func(stats host.VFStats) uint64
+}
+
+var vfStats = []vfStat{
+	newVFStat("rx_bytes_total", "Bytes received by the VF.", func(s host.VFStats) uint64 { return s.RxBytes }),
+	newVFStat("tx_bytes_total", "Bytes transmitted by the VF.", func(s host.VFStats) uint64 { return s.TxBytes }),
+	newVFStat("rx_packets_total", "Packets received by the VF.", func(s host.VFStats) uint64 { return s.RxPackets }),
+	newVFStat("tx_packets_total", "Packets transmitted by the VF.", func(s host.VFStats) uint64 { return s.TxPackets }),
+	newVFStat("rx_dropped_total", "Received packets dropped by the VF.", func(s host.VFStats) uint64 { return s.RxDropped }),
+	newVFStat("tx_dropped_total", "Transmitted packets dropped by the VF.", func(s host.VFStats) uint64 { return s.TxDropped }),
+	newVFStat("broadcast_packets_total", "Broadcast packets received by the VF.", func(s host.VFStats) uint64 { return s.Broadcast }),
+	newVFStat("multicast_packets_total", "Multicast packets received by the VF.", func(s host.VFStats) uint64 { return s.Multicast }),
+}
+
+func newVFStat(name, help string, value func(stats host.VFStats) uint64) vfStat {
+	return vfStat{
+		desc:  prometheus.NewDesc(prometheus.BuildFQName(namespace, "vf", name), help, vfLabels, nil),
+		value: value,
+	}
+}
+
+// PreparedDeviceLister lists the prepared devices of the node.
+type PreparedDeviceLister interface {
+	ListDevicesByPodUID() map[k8stypes.UID]types.PreparedDevices
+}
+
+// VFStatsCollector exports the statistics the PFs report for the prepared VFs,
+// labeled with the pod and claim using them. The statistics are read through
+// netlink on every scrape, the PF still sees the VFs moved to pod network
+// namespaces.
+type VFStatsCollector struct {
+	devices PreparedDeviceLister
+}
+
+var _ prometheus.Collector = &VFStatsCollector{}
+
+// NewVFStatsCollector creates a collector for the VFs of the prepared devices.
+func NewVFStatsCollector(devices PreparedDeviceLister) *VFStatsCollector {
+	return &VFStatsCollector{devices: devices}
+}
+
+// RegisterVFStatsCollector registers a collector for the VFs of the prepared
+// devices to the metrics served on /metrics.
+func RegisterVFStatsCollector(devices PreparedDeviceLister) error {
+	return crmetrics.Registry.Register(NewVFStatsCollector(devices))
+}
+
+// Describe implements prometheus.Collector.
+func (c *VFStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, stat := range vfStats {
+		ch <- stat.desc
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *VFStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	logger := klog.FromContext(context.Background()).WithName("VFStatsCollector")
+
+	devicesByPF := map[string]types.PreparedDevices{}
+	for _, devices := range c.devices.ListDevicesByPodUID() {
+		for _, device := range devices {
+			pfName, _, ok := vfOfDevice(device)
+			if !ok {
+				continue
+			}
+			devicesByPF[pfName] = append(devicesByPF[pfName], device)
+		}
+	}
+
+	for pfName, devices := range devicesByPF {
+		statsByVF, err := host.GetHelpers().GetVFStats(pfName)
+		if err != nil {
+			logger.V(2).Info("Failed to read VF statistics", "pf", pfName, "error", err.Error())
+			continue
+		}
+		for _, device := range devices {
+			_, vfID, _ := vfOfDevice(device)
+			stats, found := statsByVF[vfID]
+			if !found {
+				continue
+			}
+			labels := []string{
+				device.PciAddress,
+				pfName,
+				device.ClaimNamespacedName.Namespace,
+				device.PodName,
+				device.ClaimNamespacedName.Name,
+			}
+			for _, stat := range vfStats {
+				ch <- prometheus.MustNewConstMetric(stat.desc, prometheus.CounterValue, float64(stat.value(stats)), labels...)
+			}
+		}
+	}
+}
+
+// vfOfDevice returns the PF name and the VF index of a prepared device.
+func vfOfDevice(device *types.PreparedDevice) (string, int, bool) {
+	pfName, found := device.DeviceAttributes[consts.AttributePFName]
+	if !found || pfName.StringValue == nil || *pfName.StringValue == "" {
+		return "", 0, false
+	}
+	vfID, found := device.DeviceAttributes[consts.AttributeVFID]
+	if !found || vfID.IntValue == nil {
+		return "", 0, false
+	}
+	return *pfName.StringValue, int(*vfID.IntValue), true
+}
@@ -0,0 +1,99 @@
+package metrics_test
+
+import (
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/mock/gomock"
+	resourceapi "k8s.io/api/resource/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	"k8s.io/utils/ptr"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	mock_host "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host/mock"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/metrics"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+type fakeDeviceLister map[k8stypes.UID]types.PreparedDevices
+
+func (f fakeDeviceLister) ListDevicesByPodUID() map[k8stypes.UID]types.PreparedDevices {
+	return f
+}
+
+func preparedVF(pciAddress, pfName string, vfID int64, pod, claim string) *types.PreparedDevice {
+	return &types.PreparedDevice{
+		PciAddress: pciAddress,
+		PodName:    pod,
+		ClaimNamespacedName: kubeletplugin.NamespacedObject{
+			NamespacedName: k8stypes.NamespacedName{Namespace: "tenant-a", Name: claim},
+		},
+		DeviceAttributes: map[string]resourceapi.DeviceAttribute{
+			consts.AttributePFName: {StringValue: ptr.To(pfName)},
+			consts.AttributeVFID:   {IntValue: ptr.To(vfID)},
+		},
+	}
+}
+
+var _ = Describe("VFStatsCollector", Serial, func() {
+	var (
+		mockCtrl    *gomock.Controller
+		mockHost    *mock_host.MockInterface
+		origHelpers host.Interface
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockHost = mock_host.NewMockInterface(mockCtrl)
+		_ = host.GetHelpers()
+		origHelpers = host.Helpers
+		host.Helpers = mockHost
+	})
+
+	AfterEach(func() {
+		host.Helpers = origHelpers
+		mockCtrl.Finish()
+	})
+
+	It("exports the statistics of prepared VFs with pod and claim labels", func() {
+		lister := fakeDeviceLister{
+			"pod-uid": {
+				preparedVF("0000:01:00.2", "eth0", 0, "pod-a", "claim-a"),
+				preparedVF("0000:01:00.3", "eth0", 1, "pod-a", "claim-b"),
+			},
+		}
+		mockHost.EXPECT().GetVFStats("eth0").Return(map[int]host.VFStats{
+			0: {RxBytes: 1000, TxBytes: 2000, RxDropped: 3},
+			1: {RxBytes: 10},
+		}, nil)
+
+		expected := `
+# HELP dra_driver_sriov_vf_rx_bytes_total Bytes received by the VF.
+# TYPE dra_driver_sriov_vf_rx_bytes_total counter
+dra_driver_sriov_vf_rx_bytes_total{claim="claim-a",namespace="tenant-a",pci_address="0000:01:00.2",pf="eth0",pod="pod-a"} 1000
+dra_driver_sriov_vf_rx_bytes_total{claim="claim-b",namespace="tenant-a",pci_address="0000:01:00.3",pf="eth0",pod="pod-a"} 10
+# HELP dra_driver_sriov_vf_rx_dropped_total Received packets dropped by the VF.
+# TYPE dra_driver_sriov_vf_rx_dropped_total counter
+dra_driver_sriov_vf_rx_dropped_total{claim="claim-a",namespace="tenant-a",pci_address="0000:01:00.2",pf="eth0",pod="pod-a"} 3
+dra_driver_sriov_vf_rx_dropped_total{claim="claim-b",namespace="tenant-a",pci_address="0000:01:00.3",pf="eth0",pod="pod-a"} 0
+`
+		Expect(testutil.CollectAndCompare(metrics.NewVFStatsCollector(lister), strings.NewReader(expected),
+			"dra_driver_sriov_vf_rx_bytes_total", "dra_driver_sriov_vf_rx_dropped_total")).To(Succeed())
+	})
+
+	It("skips PFs whose statistics cannot be read and devices without VF attributes", func() {
+		noVF := preparedVF("0000:02:00.2", "eth1", 0, "pod-b", "claim-c")
+		delete(noVF.DeviceAttributes, consts.AttributeVFID)
+		lister := fakeDeviceLister{
+			"pod-uid": {preparedVF("0000:01:00.2", "eth0", 0, "pod-a", "claim-a"), noVF},
+		}
+		mockHost.EXPECT().GetVFStats("eth0").Return(nil, errors.New("link not found"))
+
+		Expect(testutil.CollectAndCount(metrics.NewVFStatsCollector(lister))).To(Equal(0))
+	})
+})
@@ -0,0 +1,13 @@
+package nodestate_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestNodeState(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "NodeState Suite")
+}
@@ -0,0 +1,271 @@
+// Package nodestate writes the SriovDraNodeState of the node: the discovered
+// PFs and VFs, the policy advertising each VF, the devices left out with the
+// reason, the prepared allocations and the health of discovery and publish.
+package nodestate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sriovdrav1alpha1 "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/sriovdra/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/nodeview"
+)
+
+// syncInterval is how often the state is rebuilt, it is only written when it
+// changed.
+const syncInterval = 10 * time.Second
+
+// Writer keeps the SriovDraNodeState of a node up to date.
+type Writer struct {
+	client    client.Client
+	nodeName  string
+	namespace string
+	sources   nodeview.Sources
+
+	mu sync.Mutex
+	// written is the status last written, nil until the state was written.
+	written *sriovdrav1alpha1.SriovDraNodeStateStatus
+}
+
+// NewWriter creates a writer for the SriovDraNodeState named after nodeName
+// in namespace. Nil sources are reported as empty.
+func NewWriter(c client.Client, nodeName, namespace string, sources nodeview.Sources) *Writer {
+	return &Writer{
+		client:    c,
+		nodeName:  nodeName,
+		namespace: namespace,
+		sources:   sources,
+	}
+}
+
+// Start syncs the state periodically until ctx is done.
+func (w *Writer) Start(ctx context.Context) {
+	logger := klog.FromContext(ctx).WithName("nodestate")
+	go wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := w.Sync(ctx); err != nil {
+			logger.Error(err, "Failed to update SriovDraNodeState", "node", w.nodeName)
+		}
+	}, syncInterval)
+}
+
+// Sync writes the current state unless it is the state last written.
+func (w *Writer) Sync(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	status := w.Status()
+	if w.written != nil && equality.Semantic.DeepEqual(withConditions(status, w.written.Conditions), *w.written) {
+		return nil
+	}
+
+	nodeState := &sriovdrav1alpha1.SriovDraNodeState{}
+	err := w.client.Get(ctx, client.ObjectKey{Namespace: w.namespace, Name: w.nodeName}, nodeState)
+	if apierrors.IsNotFound(err) {
+		nodeState, err = w.create(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	status = withConditions(status, nodeState.Status.Conditions)
+	if !equality.Semantic.DeepEqual(status, nodeState.Status) {
+		nodeState.Status = status
+		if err := w.client.Status().Update(ctx, nodeState); err != nil {
+			return fmt.Errorf("failed to update SriovDraNodeState status: %w", err)
+		}
+	}
+	w.written = nodeState.Status.DeepCopy()
+	return nil
+}
+
+// create creates the state owned by the node so that it is removed with it.
+func (w *Writer) create(ctx context.Context) (*sriovdrav1alpha1.SriovDraNodeState, error) {
+	nodeState := &sriovdrav1alpha1.SriovDraNodeState{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: w.namespace,
+			Name:      w.nodeName,
+		},
+	}
+	node := &corev1.Node{}
+	if err := w.client.Get(ctx, client.ObjectKey{Name: w.nodeName}, node); err != nil {
+		return nil, fmt.Errorf("failed to get node %s: %w", w.nodeName, err)
+	}
+	nodeState.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: "v1",
+		Kind:       "Node",
+		Name:       node.Name,
+		UID:        node.UID,
+	}}
+	if err := w.client.Create(ctx, nodeState); err != nil {
+		return nil, fmt.Errorf("failed to create SriovDraNodeState: %w", err)
+	}
+	return nodeState, nil
+}
+
+// Status builds the state of the node. The conditions carry no transition
+// time, it is set when the status is written.
+func (w *Writer) Status() sriovdrav1alpha1.SriovDraNodeStateStatus {
+	status := sriovdrav1alpha1.SriovDraNodeStateStatus{}
+	w.addDevices(&status)
+	w.addAllocations(&status)
+	vfs := 0
+	for _, pf := range status.PhysicalFunctions {
+		vfs += len(pf.VirtualFunctions)
+	}
+	status.Conditions = []metav1.Condition{
+		discoveredCondition(vfs, len(status.PhysicalFunctions)),
+		w.publishedCondition(),
+	}
+	return status
+}
+
+func (w *Writer) addDevices(status *sriovdrav1alpha1.SriovDraNodeStateStatus) {
+	vfsByPF := make(map[string][]sriovdrav1alpha1.VirtualFunctionStatus)
+	for _, device := range w.sources.ListDevices() {
+		vf := sriovdrav1alpha1.VirtualFunctionStatus{
+			Name:       device.Name,
+			PciAddress: stringAttribute(device.Device, consts.AttributePciAddress),
+			Attributes: device.Device.Attributes,
+			Advertised: device.Advertised,
+		}
+		if device.Match != nil {
+			vf.Policy = device.Match.Policy
+		}
+		if !vf.Advertised {
+			status.ExcludedDevices = append(status.ExcludedDevices, sriovdrav1alpha1.ExcludedDevice{
+				Name:       device.Name,
+				PciAddress: vf.PciAddress,
+				Reason:     sriovdrav1alpha1.ExclusionReasonNotSelected,
+				Message:    "no SriovResourcePolicy selects the device",
+			})
+		}
+		pfPciAddress := stringAttribute(device.Device, consts.AttributePfPciAddress)
+		vfsByPF[pfPciAddress] = append(vfsByPF[pfPciAddress], vf)
+	}
+
+	for _, pf := range w.sources.PhysicalFunctions() {
+		vfs := vfsByPF[pf.PciAddress]
+		status.PhysicalFunctions = append(status.PhysicalFunctions, sriovdrav1alpha1.PhysicalFunctionStatus{
+			Name:             pf.NetName,
+			PciAddress:       pf.PciAddress,
+			VendorID:         pf.VendorID,
+			DeviceID:         pf.DeviceID,
+			EswitchMode:      pf.EswitchMode,
+			LinkType:         pf.LinkType,
+			NumaNode:         pf.NumaNode,
+			VirtualFunctions: vfs,
+		})
+	}
+	sort.Slice(status.PhysicalFunctions, func(i, j int) bool {
+		return status.PhysicalFunctions[i].PciAddress < status.PhysicalFunctions[j].PciAddress
+	})
+
+	for _, excluded := range w.sources.ExcludedDevices() {
+		status.ExcludedDevices = append(status.ExcludedDevices, sriovdrav1alpha1.ExcludedDevice{
+			PciAddress: excluded.PciAddress,
+			Reason:     excluded.Reason,
+			Message:    excluded.Message,
+		})
+	}
+	sort.Slice(status.ExcludedDevices, func(i, j int) bool {
+		return status.ExcludedDevices[i].PciAddress < status.ExcludedDevices[j].PciAddress
+	})
+}
+
+func (w *Writer) addAllocations(status *sriovdrav1alpha1.SriovDraNodeStateStatus) {
+	for podUID, preparedDevices := range w.sources.PreparedDevices() {
+		for _, preparedDevice := range preparedDevices {
+			claimRef := preparedDevice.ClaimNamespacedName
+			status.Allocations = append(status.Allocations, sriovdrav1alpha1.PreparedAllocation{
+				Device:         preparedDevice.Device.GetDeviceName(),
+				PciAddress:     preparedDevice.PciAddress,
+				ClaimNamespace: claimRef.Namespace,
+				ClaimName:      claimRef.Name,
+				ClaimUID:       claimRef.UID,
+				PodUID:         podUID,
+				PodName:        preparedDevice.PodName,
+				Phase:          string(preparedDevice.Phase),
+			})
+		}
+	}
+	sort.Slice(status.Allocations, func(i, j int) bool {
+		a, b := status.Allocations[i], status.Allocations[j]
+		if a.ClaimUID != b.ClaimUID {
+			return a.ClaimUID < b.ClaimUID
+		}
+		return a.Device < b.Device
+	})
+}
+
+func discoveredCondition(vfs, pfs int) metav1.Condition {
+	if vfs == 0 {
+		return metav1.Condition{
+			Type:    sriovdrav1alpha1.NodeStateDevicesDiscovered,
+			Status:  metav1.ConditionFalse,
+			Reason:  "NoDevices",
+			Message: fmt.Sprintf("no SR-IOV virtual functions found on %d physical functions", pfs),
+		}
+	}
+	return metav1.Condition{
+		Type:    sriovdrav1alpha1.NodeStateDevicesDiscovered,
+		Status:  metav1.ConditionTrue,
+		Reason:  "DevicesFound",
+		Message: fmt.Sprintf("found %d SR-IOV virtual functions on %d physical functions", vfs, pfs),
+	}
+}
+
+func (w *Writer) publishedCondition() metav1.Condition {
+	condition := metav1.Condition{
+		Type:    sriovdrav1alpha1.NodeStateResourcesPublished,
+		Status:  metav1.ConditionUnknown,
+		Reason:  "NotPublished",
+		Message: "resources have not been published yet",
+	}
+	publish := w.sources.PublishStatus()
+	switch {
+	case publish.Err != nil:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "PublishFailed"
+		condition.Message = publish.Err.Error()
+	case !publish.Time.IsZero():
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "Published"
+		condition.Message = "resources are published in the ResourceSlice"
+	}
+	return condition
+}
+
+// withConditions returns status with the conditions merged into current, the
+// transition time of a condition is kept while its status does not change.
+func withConditions(status sriovdrav1alpha1.SriovDraNodeStateStatus, current []metav1.Condition) sriovdrav1alpha1.SriovDraNodeStateStatus {
+	conditions := make([]metav1.Condition, 0, len(current))
+	for _, condition := range current {
+		conditions = append(conditions, *condition.DeepCopy())
+	}
+	for _, condition := range status.Conditions {
+		meta.SetStatusCondition(&conditions, condition)
+	}
+	status.Conditions = conditions
+	return status
+}
+
+func stringAttribute(device resourceapi.Device, name resourceapi.QualifiedName) string {
+	if attribute, ok := device.Attributes[name]; ok && attribute.StringValue != nil {
+		return *attribute.StringValue
+	}
+	return ""
+}
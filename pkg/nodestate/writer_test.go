@@ -0,0 +1,218 @@
+package nodestate_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlclientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovdrav1alpha1 "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/sriovdra/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/devicestate"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/flags"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/nodestate"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/nodeview"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+type fakeDevices struct {
+	devices    types.AllocatableDevices
+	advertised map[string]bool
+	pfs        []devicestate.PFInfo
+	excluded   []devicestate.ExcludedDevice
+}
+
+func (f *fakeDevices) SnapshotDevices() (types.AllocatableDevices, map[string]bool) {
+	return f.devices, f.advertised
+}
+
+func (f *fakeDevices) PhysicalFunctions() []devicestate.PFInfo {
+	return f.pfs
+}
+
+func (f *fakeDevices) ExcludedDevices() []devicestate.ExcludedDevice {
+	return f.excluded
+}
+
+type fakePolicies map[string]types.DevicePolicyMatch
+
+func (f fakePolicies) DevicePolicyMatches() map[string]types.DevicePolicyMatch {
+	return f
+}
+
+type fakeClaims map[k8stypes.UID]types.PreparedDevices
+
+func (f fakeClaims) ListDevicesByPodUID() map[k8stypes.UID]types.PreparedDevices {
+	return f
+}
+
+type fakePublish struct {
+	status types.PublishStatus
+}
+
+func (f *fakePublish) PublishStatus() types.PublishStatus {
+	return f.status
+}
+
+func vf(pciAddress, pfPciAddress string) resourceapi.Device {
+	return resourceapi.Device{
+		Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+			consts.AttributePciAddress:   {StringValue: ptr.To(pciAddress)},
+			consts.AttributePfPciAddress: {StringValue: ptr.To(pfPciAddress)},
+		},
+	}
+}
+
+var _ = Describe("SriovDraNodeState writer", func() {
+	const (
+		nodeName  = "node-a"
+		namespace = "dra-driver-sriov"
+	)
+
+	var (
+		ctx       context.Context
+		k8sClient client.Client
+		devices   *fakeDevices
+		publish   *fakePublish
+		writer    *nodestate.Writer
+	)
+
+	getNodeState := func() *sriovdrav1alpha1.SriovDraNodeState {
+		nodeState := &sriovdrav1alpha1.SriovDraNodeState{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: nodeName}, nodeState)).To(Succeed())
+		return nodeState
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName, UID: "node-uid"}}
+		k8sClient = ctrlclientfake.NewClientBuilder().
+			WithScheme(flags.Scheme).
+			WithObjects(node).
+			WithStatusSubresource(&sriovdrav1alpha1.SriovDraNodeState{}).
+			Build()
+
+		devices = &fakeDevices{
+			devices: types.AllocatableDevices{
+				"0000-08-00-2": vf("0000:08:00.2", "0000:08:00.0"),
+				"0000-08-00-1": vf("0000:08:00.1", "0000:08:00.0"),
+			},
+			advertised: map[string]bool{"0000-08-00-1": true},
+			pfs: []devicestate.PFInfo{
+				{PciAddress: "0000:08:00.0", NetName: "eth0", VendorID: "8086", EswitchMode: consts.EswitchModeLegacy, NumaNode: "0"},
+			},
+			excluded: []devicestate.ExcludedDevice{
+				{PciAddress: "0000:09:00.0", Reason: sriovdrav1alpha1.ExclusionReasonNoNetworkInterface},
+			},
+		}
+		publish = &fakePublish{status: types.PublishStatus{Time: time.Now()}}
+		writer = nodestate.NewWriter(k8sClient, nodeName, namespace, nodeview.Sources{
+			Devices:  devices,
+			Policies: fakePolicies{"0000-08-00-1": {Policy: "policy-a"}},
+			Claims: fakeClaims{
+				"pod-uid": {
+					{
+						Device: drapbv1.Device{
+							DeviceName: "0000-08-00-1",
+							PoolName:   nodeName,
+						},
+						ClaimNamespacedName: kubeletplugin.NamespacedObject{
+							NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "claim-a"},
+							UID:            "claim-uid",
+						},
+						PodName:    "pod-a",
+						PciAddress: "0000:08:00.1",
+						Phase:      types.DevicePhaseAttached,
+					},
+				},
+			},
+			Publish: publish,
+		})
+	})
+
+	It("creates the state owned by the node", func() {
+		Expect(writer.Sync(ctx)).To(Succeed())
+
+		nodeState := getNodeState()
+		Expect(nodeState.OwnerReferences).To(ConsistOf(HaveField("UID", k8stypes.UID("node-uid"))))
+
+		status := nodeState.Status
+		Expect(status.PhysicalFunctions).To(HaveLen(1))
+		pf := status.PhysicalFunctions[0]
+		Expect(pf.Name).To(Equal("eth0"))
+		Expect(pf.EswitchMode).To(Equal(consts.EswitchModeLegacy))
+		Expect(pf.VirtualFunctions).To(HaveLen(2))
+		Expect(pf.VirtualFunctions[0].Name).To(Equal("0000-08-00-1"))
+		Expect(pf.VirtualFunctions[0].Advertised).To(BeTrue())
+		Expect(pf.VirtualFunctions[0].Policy).To(Equal("policy-a"))
+		Expect(pf.VirtualFunctions[1].Advertised).To(BeFalse())
+		Expect(pf.VirtualFunctions[1].Policy).To(BeEmpty())
+
+		Expect(status.ExcludedDevices).To(HaveLen(2))
+		Expect(status.ExcludedDevices[0].Name).To(Equal("0000-08-00-2"))
+		Expect(status.ExcludedDevices[0].Reason).To(Equal(sriovdrav1alpha1.ExclusionReasonNotSelected))
+		Expect(status.ExcludedDevices[1].PciAddress).To(Equal("0000:09:00.0"))
+		Expect(status.ExcludedDevices[1].Reason).To(Equal(sriovdrav1alpha1.ExclusionReasonNoNetworkInterface))
+
+		Expect(status.Allocations).To(ConsistOf(sriovdrav1alpha1.PreparedAllocation{
+			Device:         "0000-08-00-1",
+			PciAddress:     "0000:08:00.1",
+			ClaimNamespace: "default",
+			ClaimName:      "claim-a",
+			ClaimUID:       "claim-uid",
+			PodUID:         "pod-uid",
+			PodName:        "pod-a",
+			Phase:          string(types.DevicePhaseAttached),
+		}))
+
+		Expect(status.Conditions).To(ConsistOf(
+			And(HaveField("Type", sriovdrav1alpha1.NodeStateDevicesDiscovered), HaveField("Status", metav1.ConditionTrue)),
+			And(HaveField("Type", sriovdrav1alpha1.NodeStateResourcesPublished), HaveField("Status", metav1.ConditionTrue)),
+		))
+	})
+
+	It("updates the state when it changes", func() {
+		Expect(writer.Sync(ctx)).To(Succeed())
+		resourceVersion := getNodeState().ResourceVersion
+
+		Expect(writer.Sync(ctx)).To(Succeed())
+		Expect(getNodeState().ResourceVersion).To(Equal(resourceVersion))
+
+		publish.status = types.PublishStatus{Time: time.Now(), Err: errors.New("slice rejected")}
+		devices.advertised["0000-08-00-2"] = true
+		Expect(writer.Sync(ctx)).To(Succeed())
+
+		nodeState := getNodeState()
+		Expect(nodeState.ResourceVersion).NotTo(Equal(resourceVersion))
+		Expect(nodeState.Status.PhysicalFunctions[0].VirtualFunctions[1].Advertised).To(BeTrue())
+		Expect(nodeState.Status.ExcludedDevices).To(HaveLen(1))
+		Expect(nodeState.Status.Conditions).To(ContainElement(And(
+			HaveField("Type", sriovdrav1alpha1.NodeStateResourcesPublished),
+			HaveField("Status", metav1.ConditionFalse),
+			HaveField("Message", "slice rejected"),
+		)))
+	})
+
+	It("reports a node without SR-IOV devices", func() {
+		devices.devices = types.AllocatableDevices{}
+		devices.advertised = map[string]bool{}
+		devices.pfs = nil
+		Expect(writer.Sync(ctx)).To(Succeed())
+
+		Expect(getNodeState().Status.Conditions).To(ContainElement(And(
+			HaveField("Type", sriovdrav1alpha1.NodeStateDevicesDiscovered),
+			HaveField("Status", metav1.ConditionFalse),
+		)))
+	})
+})
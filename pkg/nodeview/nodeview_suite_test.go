@@ -0,0 +1,13 @@
+package nodeview_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestNodeView(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Node View Suite")
+}
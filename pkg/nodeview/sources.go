@@ -0,0 +1,117 @@
+// Package nodeview reads the state of the node plugin that the introspection
+// server serves and the SriovDraNodeState writer reports: the discovered
+// devices and the policy advertising them, the prepared claims and the result
+// of the last publish.
+package nodeview
+
+import (
+	"sort"
+
+	resourceapi "k8s.io/api/resource/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/devicestate"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// DeviceSource lists copies of the devices discovered on the node.
+type DeviceSource interface {
+	SnapshotDevices() (drasriovtypes.AllocatableDevices, map[string]bool)
+	PhysicalFunctions() []devicestate.PFInfo
+	ExcludedDevices() []devicestate.ExcludedDevice
+}
+
+// PolicySource tells the policy and DeviceAttributes of each advertised device.
+type PolicySource interface {
+	DevicePolicyMatches() map[string]drasriovtypes.DevicePolicyMatch
+}
+
+// ClaimSource lists the prepared devices of each pod. The devices are copies
+// read while the driver keeps updating the prepared claims.
+type ClaimSource interface {
+	ListDevicesByPodUID() map[k8stypes.UID]drasriovtypes.PreparedDevices
+}
+
+// PublishSource tells the result of the last ResourceSlice publish.
+type PublishSource interface {
+	PublishStatus() drasriovtypes.PublishStatus
+}
+
+// Sources are the components the state of the node is read from. Nil sources
+// are read as empty.
+type Sources struct {
+	Devices  DeviceSource
+	Policies PolicySource
+	Claims   ClaimSource
+	Publish  PublishSource
+}
+
+// Device is a discovered device.
+type Device struct {
+	Name       string
+	Device     resourceapi.Device
+	Advertised bool
+	// Match is the policy advertising the device, nil when the device is not
+	// advertised.
+	Match *drasriovtypes.DevicePolicyMatch
+}
+
+// ListDevices returns the discovered devices sorted by name.
+func (s Sources) ListDevices() []Device {
+	if s.Devices == nil {
+		return nil
+	}
+	allocatable, advertised := s.Devices.SnapshotDevices()
+	var matches map[string]drasriovtypes.DevicePolicyMatch
+	if s.Policies != nil {
+		matches = s.Policies.DevicePolicyMatches()
+	}
+
+	devices := make([]Device, 0, len(allocatable))
+	for name, device := range allocatable {
+		d := Device{
+			Name:       name,
+			Device:     device,
+			Advertised: advertised[name],
+		}
+		if match, ok := matches[name]; ok && d.Advertised {
+			d.Match = &match
+		}
+		devices = append(devices, d)
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].Name < devices[j].Name })
+	return devices
+}
+
+// PhysicalFunctions returns the PFs found by the device discovery.
+func (s Sources) PhysicalFunctions() []devicestate.PFInfo {
+	if s.Devices == nil {
+		return nil
+	}
+	return s.Devices.PhysicalFunctions()
+}
+
+// ExcludedDevices returns the network devices skipped by the device discovery.
+func (s Sources) ExcludedDevices() []devicestate.ExcludedDevice {
+	if s.Devices == nil {
+		return nil
+	}
+	return s.Devices.ExcludedDevices()
+}
+
+// PreparedDevices returns the prepared devices of each pod.
+func (s Sources) PreparedDevices() map[k8stypes.UID]drasriovtypes.PreparedDevices {
+	if s.Claims == nil {
+		return nil
+	}
+	return s.Claims.ListDevicesByPodUID()
+}
+
+// PublishStatus returns the result of the last ResourceSlice publish, zero
+// until the resources are published.
+func (s Sources) PublishStatus() drasriovtypes.PublishStatus {
+	if s.Publish == nil {
+		return drasriovtypes.PublishStatus{}
+	}
+	return s.Publish.PublishStatus()
+}
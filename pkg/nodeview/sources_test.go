@@ -0,0 +1,74 @@
+package nodeview_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	resourceapi "k8s.io/api/resource/v1"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/devicestate"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/nodeview"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+type fakeDevices struct {
+	devices    types.AllocatableDevices
+	advertised map[string]bool
+}
+
+func (f *fakeDevices) SnapshotDevices() (types.AllocatableDevices, map[string]bool) {
+	return f.devices, f.advertised
+}
+
+func (f *fakeDevices) PhysicalFunctions() []devicestate.PFInfo {
+	return []devicestate.PFInfo{{PciAddress: "0000:08:00.0"}}
+}
+
+func (f *fakeDevices) ExcludedDevices() []devicestate.ExcludedDevice {
+	return nil
+}
+
+type fakePolicies map[string]types.DevicePolicyMatch
+
+func (f fakePolicies) DevicePolicyMatches() map[string]types.DevicePolicyMatch {
+	return f
+}
+
+var _ = Describe("Sources", func() {
+	It("lists the devices by name with the policy of the advertised ones", func() {
+		sources := nodeview.Sources{
+			Devices: &fakeDevices{
+				devices: types.AllocatableDevices{
+					"0000-08-00-2": {Name: "0000-08-00-2"},
+					"0000-08-00-1": {Name: "0000-08-00-1"},
+				},
+				advertised: map[string]bool{"0000-08-00-1": true},
+			},
+			Policies: fakePolicies{
+				"0000-08-00-1": {Policy: "policy-a"},
+				// a stale match of a device no longer advertised
+				"0000-08-00-2": {Policy: "policy-b"},
+			},
+		}
+
+		Expect(sources.ListDevices()).To(Equal([]nodeview.Device{
+			{
+				Name:       "0000-08-00-1",
+				Device:     resourceapi.Device{Name: "0000-08-00-1"},
+				Advertised: true,
+				Match:      &types.DevicePolicyMatch{Policy: "policy-a"},
+			},
+			{Name: "0000-08-00-2", Device: resourceapi.Device{Name: "0000-08-00-2"}},
+		}))
+		Expect(sources.PhysicalFunctions()).To(HaveLen(1))
+	})
+
+	It("reads nil sources as empty", func() {
+		sources := nodeview.Sources{}
+
+		Expect(sources.ListDevices()).To(BeEmpty())
+		Expect(sources.PhysicalFunctions()).To(BeEmpty())
+		Expect(sources.ExcludedDevices()).To(BeEmpty())
+		Expect(sources.PreparedDevices()).To(BeEmpty())
+		Expect(sources.PublishStatus()).To(Equal(types.PublishStatus{}))
+	})
+})
@@ -0,0 +1,220 @@
+package nri
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/containerd/nri/pkg/api"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cdihook"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cni"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/tracing"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// stoppedSandboxInterval is the period of the sweep detaching the devices of
+// stopped sandboxes when networks are attached from CDI hooks, as no hook runs
+// once the sandbox itself is gone.
+const stoppedSandboxInterval = 30 * time.Second
+
+// NewCDIHookPlugin creates the plugin attaching the networks from the CDI
+// hooks served by the driver, for runtimes without NRI. It is not registered
+// to NRI, its workers are started with StartWorkers.
+func NewCDIHookPlugin(config *types.Config, podManager *podmanager.PodManager, cniRuntime cni.Interface, metadataUpdater types.MetadataUpdater, healthUpdater types.DeviceHealthUpdater) *Plugin {
+	return newPlugin(config, podManager, cniRuntime, metadataUpdater, healthUpdater)
+}
+
+// AttachDevice runs the CNI ADD operation for a device of a claim on the
+// createRuntime CDI hook of a container. Every container of the pod runs the
+// hook, the device is only attached once per sandbox.
+func (p *Plugin) AttachDevice(ctx context.Context, req *cdihook.Request) error {
+	ctx, span := tracing.Start(ctx, "AttachDevice",
+		append(tracing.PodAttributes(req.PodNamespace, req.PodName, k8stypes.UID(req.PodUID)),
+			tracing.AttributeClaimUID.String(req.ClaimUID),
+			tracing.AttributeDeviceName.String(req.DeviceName),
+			tracing.AttributeSandboxID.String(req.SandboxID))...)
+	err := p.attachDevice(ctx, req)
+	tracing.End(span, err)
+	return err
+}
+
+func (p *Plugin) attachDevice(ctx context.Context, req *cdihook.Request) error {
+	logger := klog.FromContext(ctx).WithName("CDI hook AttachDevice")
+	p.hookMu.Lock()
+	defer p.hookMu.Unlock()
+
+	device, err := p.hookDevice(req)
+	if err != nil {
+		return err
+	}
+	if device.Config != nil && device.Config.IsMultusConfigurationMode() {
+		logger.V(2).Info("Device is attached by Multus", "deviceName", req.DeviceName, "pod.UID", req.PodUID)
+		return nil
+	}
+	if device.SandboxID == req.SandboxID {
+		logger.V(2).Info("Device already attached to pod sandbox", "deviceName", req.DeviceName, "pod.UID", req.PodUID, "sandboxID", req.SandboxID)
+		return nil
+	}
+
+	podUID := k8stypes.UID(req.PodUID)
+	pod := &api.PodSandbox{
+		Id:        req.SandboxID,
+		Uid:       req.PodUID,
+		Name:      req.PodName,
+		Namespace: req.PodNamespace,
+		Linux: &api.LinuxPodSandbox{
+			Namespaces: []*api.LinuxNamespace{{Type: "network", Path: req.NetworkNamespace}},
+		},
+	}
+	devices := types.PreparedDevices{device}
+
+	// the pod sandbox was recreated, release the device from the previous one
+	networkDevicesData := types.NetworkDataChanStructList{}
+	if device.SandboxID != "" {
+		networkDevicesData = p.detachStaleDevices(ctx, podUID, devices)
+		if device.SandboxID != "" {
+			return fmt.Errorf("failed to detach device %s from stopped sandbox %s", req.DeviceName, device.SandboxID)
+		}
+	}
+
+	logger.Info("Attaching network", "deviceName", req.DeviceName, "pod.UID", req.PodUID, "pod.Name", req.PodName, "pod.Namespace", req.PodNamespace, "sandboxID", req.SandboxID)
+	if err := p.podManager.UpdatePreparedDevicesSandbox(devices, req.SandboxID, req.NetworkNamespace); err != nil {
+		return fmt.Errorf("failed to record pod sandbox for prepared devices: %w", err)
+	}
+	attached, err := p.attachNetworks(ctx, pod, req.NetworkNamespace, devices)
+	if err != nil {
+		if persistErr := p.enqueueNetworkDeviceData(networkDevicesData); persistErr != nil {
+			logger.Error(persistErr, "Failed to persist network data of the detached devices", "pod.UID", req.PodUID)
+		}
+		return err
+	}
+	if err := p.updateRequestMetadataBeforeSandboxStart(ctx, attached); err != nil {
+		logger.Error(err, "Failed to update request metadata before container start", "pod.UID", req.PodUID)
+		return fmt.Errorf("failed to update request metadata before container start: %w", err)
+	}
+
+	p.trackSandbox(pod)
+	return p.enqueueNetworkDeviceData(append(networkDevicesData, attached...))
+}
+
+// DetachDevice runs on the poststop CDI hook of a container. Containers also
+// stop when they restart, so the device is only detached once the network
+// namespace of its sandbox is gone. Devices of sandboxes stopping later are
+// detached by the stopped sandbox sweep or when their claim is unprepared.
+func (p *Plugin) DetachDevice(ctx context.Context, req *cdihook.Request) error {
+	p.hookMu.Lock()
+	defer p.hookMu.Unlock()
+
+	device, err := p.hookDevice(req)
+	if err != nil {
+		klog.FromContext(ctx).WithName("CDI hook DetachDevice").V(2).Info("Skipping detach", "reason", err.Error())
+		return nil
+	}
+	if device.SandboxID == "" || networkNamespaceExists(device.NetworkNamespace) {
+		return nil
+	}
+	return p.detachDevices(ctx, k8stypes.UID(req.PodUID), types.PreparedDevices{device})
+}
+
+// DetachNetworks runs the CNI DEL operation for the devices still attached to
+// a pod sandbox. The driver calls it when unpreparing a claim so no network
+// leaks when the sandbox stopped without a hook reporting it.
+func (p *Plugin) DetachNetworks(ctx context.Context, devices types.PreparedDevices) error {
+	p.hookMu.Lock()
+	defer p.hookMu.Unlock()
+
+	devicesByPod := map[k8stypes.UID]types.PreparedDevices{}
+	for _, device := range devices {
+		if device.SandboxID != "" {
+			podUID := k8stypes.UID(device.PodUID)
+			devicesByPod[podUID] = append(devicesByPod[podUID], device)
+		}
+	}
+	for podUID, attached := range devicesByPod {
+		if err := p.detachDevices(ctx, podUID, attached); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartWorkers starts the claim status worker and the periodic operations
+// without registering to NRI, for networks attached from CDI hooks.
+func (p *Plugin) StartWorkers(ctx context.Context) {
+	p.enqueuePreparedNetworkDeviceData(ctx)
+	go p.runClaimStatusWorker(ctx)
+	if p.checkInterval > 0 {
+		go p.checkNetworksRunner(ctx)
+	}
+	go wait.UntilWithContext(ctx, p.detachStoppedSandboxes, stoppedSandboxInterval)
+}
+
+// StopWorkers stops the claim status worker.
+func (p *Plugin) StopWorkers() {
+	p.claimStatusQueue.ShutDown()
+}
+
+// detachStoppedSandboxes detaches the devices whose sandbox network namespace
+// no longer exists.
+func (p *Plugin) detachStoppedSandboxes(ctx context.Context) {
+	p.hookMu.Lock()
+	defer p.hookMu.Unlock()
+
+	for podUID, devices := range p.podManager.ListDevicesByPodUID() {
+		stale := types.PreparedDevices{}
+		for _, device := range devices {
+			if device.SandboxID != "" && !networkNamespaceExists(device.NetworkNamespace) {
+				stale = append(stale, device)
+			}
+		}
+		if len(stale) > 0 {
+			_ = p.detachDevices(ctx, podUID, stale)
+		}
+	}
+}
+
+// detachDevices detaches devices of a pod from their stopped sandbox and
+// reports the ones failing to detach.
+func (p *Plugin) detachDevices(ctx context.Context, podUID k8stypes.UID, devices types.PreparedDevices) error {
+	networkDevicesData := p.detachStaleDevices(ctx, podUID, devices)
+	if !p.hasAttachedDevices(podUID) {
+		p.untrackSandbox(&api.PodSandbox{Uid: string(podUID)}, devices)
+	}
+	if err := p.enqueueNetworkDeviceData(networkDevicesData); err != nil {
+		klog.FromContext(ctx).Error(err, "Failed to persist network data of the detached devices", "pod.UID", podUID)
+	}
+	if len(networkDevicesData) < len(devices) {
+		return fmt.Errorf("failed to detach %d of %d devices of pod %s", len(devices)-len(networkDevicesData), len(devices), podUID)
+	}
+	return nil
+}
+
+// hasAttachedDevices reports whether a device of the pod is still attached to a sandbox.
+func (p *Plugin) hasAttachedDevices(podUID k8stypes.UID) bool {
+	devices, _ := p.podManager.GetDevicesByPodUID(podUID)
+	for _, device := range devices {
+		if device.SandboxID != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hookDevice returns the prepared device a hook request is for.
+func (p *Plugin) hookDevice(req *cdihook.Request) (*types.PreparedDevice, error) {
+	devices, found := p.podManager.GetDevicesByPodUID(k8stypes.UID(req.PodUID))
+	if !found {
+		return nil, fmt.Errorf("no prepared devices found for pod %s", req.PodUID)
+	}
+	for _, device := range devices {
+		if string(device.ClaimNamespacedName.UID) == req.ClaimUID && device.Device.DeviceName == req.DeviceName {
+			return device, nil
+		}
+	}
+	return nil, fmt.Errorf("device %s of claim %s is not prepared for pod %s", req.DeviceName, req.ClaimUID, req.PodUID)
+}
@@ -0,0 +1,168 @@
+package nri
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/containerd/nri/pkg/api"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	resourceapi "k8s.io/api/resource/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cdihook"
+	cnimock "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cni/mock"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("CDI hook network attachment", func() {
+	var (
+		ctx       context.Context
+		mockCNI   *cnimock.MockInterface
+		pm        *podmanager.PodManager
+		plugin    *Plugin
+		netnsPath string
+		req       *cdihook.Request
+		pod       *api.PodSandbox
+		prepared  types.PreparedDevices
+	)
+
+	// stored returns the prepared device as tracked by the PodManager.
+	stored := func() *types.PreparedDevice {
+		devices, found := pm.Get(k8stypes.UID("pod-uid"), prepared[0].ClaimNamespacedName.UID)
+		Expect(found).To(BeTrue())
+		return devices[0]
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		ctrl := gomock.NewController(GinkgoT())
+		mockCNI = cnimock.NewMockInterface(ctrl)
+
+		tmpDir := GinkgoT().TempDir()
+		cfg := &types.Config{Flags: &types.Flags{KubeletPluginsDirectoryPath: tmpDir}}
+		var err error
+		pm, err = podmanager.NewPodManager(cfg)
+		Expect(err).NotTo(HaveOccurred())
+
+		// a regular file stands in for the network namespace of a running sandbox
+		netnsPath = filepath.Join(tmpDir, "netns")
+		Expect(os.WriteFile(netnsPath, nil, 0o600)).To(Succeed())
+
+		claimUID := k8stypes.UID("claim-uid")
+		prepared = types.PreparedDevices{
+			{
+				ClaimNamespacedName: kubeletplugin.NamespacedObject{
+					NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "claim"},
+					UID:            claimUID,
+				},
+				Device: drapbv1.Device{PoolName: "pool", DeviceName: "dev"},
+				IfName: "net1",
+				PodUID: "pod-uid",
+			},
+		}
+		Expect(pm.Set(k8stypes.UID("pod-uid"), claimUID, prepared)).To(Succeed())
+
+		req = &cdihook.Request{
+			Action:           cdihook.ActionAdd,
+			ClaimUID:         string(claimUID),
+			DeviceName:       "dev",
+			PodUID:           "pod-uid",
+			PodName:          "pod-name",
+			PodNamespace:     "default",
+			SandboxID:        "sandbox-2",
+			NetworkNamespace: netnsPath,
+		}
+		pod = &api.PodSandbox{
+			Id:        "sandbox-2",
+			Name:      "pod-name",
+			Namespace: "default",
+			Uid:       "pod-uid",
+			Linux: &api.LinuxPodSandbox{
+				Namespaces: []*api.LinuxNamespace{{Type: "network", Path: netnsPath}},
+			},
+		}
+
+		plugin = &Plugin{
+			podManager:       pm,
+			cniRuntime:       mockCNI,
+			claimStatusQueue: newClaimStatusQueue(),
+		}
+	})
+
+	It("attaches the device once per sandbox", func() {
+		networkData := &resourceapi.NetworkDeviceData{InterfaceName: "net1"}
+		mockCNI.EXPECT().AttachNetwork(gomock.Any(), pod, netnsPath, sameDevice(prepared[0])).Return(networkData, nil, nil).Times(1)
+
+		Expect(plugin.AttachDevice(ctx, req)).To(Succeed())
+		// a second container of the pod runs the hook again
+		Expect(plugin.AttachDevice(ctx, req)).To(Succeed())
+
+		Expect(stored().SandboxID).To(Equal("sandbox-2"))
+		Expect(stored().NetworkNamespace).To(Equal(netnsPath))
+		queued := pendingNetworkData(plugin)
+		Expect(queued).To(HaveLen(1))
+		Expect(queued[0].NetworkDeviceData).To(Equal(networkData))
+		Expect(plugin.runningSandboxes()).To(ConsistOf(pod))
+	})
+
+	It("detaches the previous sandbox before attaching the new one", func() {
+		Expect(pm.UpdatePreparedDevicesSandbox(prepared, "sandbox-1", "")).To(Succeed())
+		gomock.InOrder(
+			mockCNI.EXPECT().DetachNetwork(gomock.Any(), &api.PodSandbox{Id: "sandbox-1", Uid: "pod-uid"}, "", sameDevice(prepared[0])).Return(nil),
+			mockCNI.EXPECT().AttachNetwork(gomock.Any(), pod, netnsPath, sameDevice(prepared[0])).Return(&resourceapi.NetworkDeviceData{}, nil, nil),
+		)
+
+		Expect(plugin.AttachDevice(ctx, req)).To(Succeed())
+		Expect(stored().SandboxID).To(Equal("sandbox-2"))
+	})
+
+	It("fails for a device not prepared for the pod", func() {
+		req.DeviceName = "other"
+		Expect(plugin.AttachDevice(ctx, req)).To(MatchError(ContainSubstring("is not prepared")))
+	})
+
+	It("keeps the device attached on poststop while the sandbox runs", func() {
+		Expect(pm.UpdatePreparedDevicesSandbox(prepared, "sandbox-2", netnsPath)).To(Succeed())
+		req.Action = cdihook.ActionDel
+
+		Expect(plugin.DetachDevice(ctx, req)).To(Succeed())
+		Expect(stored().SandboxID).To(Equal("sandbox-2"))
+	})
+
+	It("detaches the device on poststop once the sandbox is gone", func() {
+		goneNetns := filepath.Join(GinkgoT().TempDir(), "gone")
+		Expect(pm.UpdatePreparedDevicesSandbox(prepared, "sandbox-2", goneNetns)).To(Succeed())
+		mockCNI.EXPECT().DetachNetwork(gomock.Any(), &api.PodSandbox{Id: "sandbox-2", Uid: "pod-uid"}, "", sameDevice(prepared[0])).Return(nil)
+		req.Action = cdihook.ActionDel
+
+		Expect(plugin.DetachDevice(ctx, req)).To(Succeed())
+		Expect(stored().SandboxID).To(BeEmpty())
+		queued := pendingNetworkData(plugin)
+		Expect(queued).To(HaveLen(1))
+		Expect(queued[0].NetworkDeviceData).To(BeNil())
+	})
+
+	It("detaches attached devices when the claim is unprepared", func() {
+		Expect(pm.UpdatePreparedDevicesSandbox(prepared, "sandbox-2", netnsPath)).To(Succeed())
+		mockCNI.EXPECT().DetachNetwork(gomock.Any(), &api.PodSandbox{Id: "sandbox-2", Uid: "pod-uid"}, netnsPath, sameDevice(prepared[0])).Return(errors.New("boom"))
+
+		Expect(plugin.DetachNetworks(ctx, prepared)).To(MatchError(ContainSubstring("failed to detach 1 of 1 devices")))
+		Expect(stored().SandboxID).To(Equal("sandbox-2"))
+	})
+
+	It("detaches devices of stopped sandboxes in the periodic sweep", func() {
+		goneNetns := filepath.Join(GinkgoT().TempDir(), "gone")
+		Expect(pm.UpdatePreparedDevicesSandbox(prepared, "sandbox-2", goneNetns)).To(Succeed())
+		mockCNI.EXPECT().DetachNetwork(gomock.Any(), &api.PodSandbox{Id: "sandbox-2", Uid: "pod-uid"}, "", sameDevice(prepared[0])).Return(nil)
+
+		plugin.detachStoppedSandboxes(ctx)
+		Expect(stored().SandboxID).To(BeEmpty())
+	})
+})
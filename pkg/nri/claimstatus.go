@@ -0,0 +1,260 @@
+package nri
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	resourceapi "k8s.io/api/resource/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/metrics"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/tracing"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+const (
+	claimStatusQueueName = "claim_network_status"
+
+	claimStatusRetryBaseDelay = 100 * time.Millisecond
+	claimStatusRetryMaxDelay  = 5 * time.Minute
+)
+
+// claimNetworkUpdate holds the network data of a claim that still has to be
+// written to the claim status. Updates for the same device are merged so only
+// the latest one is written.
+type claimNetworkUpdate struct {
+	claim   kubeletplugin.NamespacedObject
+	devices map[claimStatusDeviceKey]*types.NetworkDataChanStruct
+}
+
+func newClaimStatusQueue() workqueue.TypedRateLimitingInterface[k8stypes.UID] {
+	return workqueue.NewTypedRateLimitingQueueWithConfig(
+		workqueue.NewTypedItemExponentialFailureRateLimiter[k8stypes.UID](claimStatusRetryBaseDelay, claimStatusRetryMaxDelay),
+		workqueue.TypedRateLimitingQueueConfig[k8stypes.UID]{Name: claimStatusQueueName},
+	)
+}
+
+// enqueueNetworkDeviceData persists the network data of the devices to the
+// checkpoint, then merges it into the pending claim updates and queues the
+// affected claims. The claim status is only written once the driver cannot
+// lose the data on restart, nothing is queued when persisting fails.
+func (p *Plugin) enqueueNetworkDeviceData(networkDataList types.NetworkDataChanStructList) error {
+	persisted := types.NetworkDataChanStructList{}
+	for _, item := range networkDataList {
+		if item != nil && item.PreparedDevice != nil {
+			persisted = append(persisted, item)
+		}
+	}
+	if len(persisted) == 0 {
+		return nil
+	}
+	if err := p.podManager.UpdatePreparedDevicesNetworkData(persisted); err != nil {
+		return fmt.Errorf("failed to persist device network data: %w", err)
+	}
+	p.queueNetworkDeviceData(persisted)
+	return nil
+}
+
+// queueNetworkDeviceData merges the persisted network data into the pending
+// claim updates and queues the affected claims. It never blocks.
+func (p *Plugin) queueNetworkDeviceData(networkDataList types.NetworkDataChanStructList) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.pendingClaimUpdates == nil {
+		p.pendingClaimUpdates = make(map[k8stypes.UID]*claimNetworkUpdate)
+	}
+
+	for _, item := range networkDataList {
+		if item == nil || item.PreparedDevice == nil {
+			continue
+		}
+		claim := item.PreparedDevice.ClaimNamespacedName
+		update, found := p.pendingClaimUpdates[claim.UID]
+		if !found {
+			update = &claimNetworkUpdate{
+				claim:   claim,
+				devices: make(map[claimStatusDeviceKey]*types.NetworkDataChanStruct),
+			}
+			p.pendingClaimUpdates[claim.UID] = update
+		}
+		update.devices[deviceStatusKey(item.PreparedDevice)] = item
+		p.claimStatusQueue.Add(claim.UID)
+	}
+	metrics.ClaimStatusQueueDepth.Set(float64(len(p.pendingClaimUpdates)))
+}
+
+// enqueuePreparedNetworkDeviceData rebuilds the claim updates from the network
+// data stored in the checkpoint, so updates that were pending when the driver
+// stopped are not lost.
+func (p *Plugin) enqueuePreparedNetworkDeviceData(ctx context.Context) {
+	logger := klog.FromContext(ctx).WithName("enqueuePreparedNetworkDeviceData")
+	networkDataList := types.NetworkDataChanStructList{}
+	for _, devices := range p.podManager.ListDevicesByPodUID() {
+		for _, device := range devices {
+			if device.NetworkDeviceData == nil {
+				continue
+			}
+			networkDataList = append(networkDataList, &types.NetworkDataChanStruct{
+				PreparedDevice:    device,
+				NetworkDeviceData: device.NetworkDeviceData,
+				CNIConfig:         cniConfigMap(ctx, device),
+			})
+		}
+	}
+	logger.V(2).Info("Rebuilt claim network updates from checkpoint", "devices", len(networkDataList))
+	p.queueNetworkDeviceData(networkDataList)
+}
+
+// cniConfigMap parses the CNI configuration of a device for the claim status.
+func cniConfigMap(ctx context.Context, device *types.PreparedDevice) map[string]interface{} {
+	configMap := map[string]interface{}{}
+	if device.NetAttachDefConfig == "" {
+		return configMap
+	}
+	if err := json.Unmarshal([]byte(device.NetAttachDefConfig), &configMap); err != nil {
+		klog.FromContext(ctx).V(2).Info("Failed to unmarshal NetAttachDefConfig, proceeding with empty CNIConfig", "error", err.Error())
+		return map[string]interface{}{}
+	}
+	return configMap
+}
+
+// runClaimStatusWorker processes the claim status queue until it is shut down.
+func (p *Plugin) runClaimStatusWorker(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		p.claimStatusQueue.ShutDown()
+	}()
+	for p.processNextClaimUpdate(ctx) {
+	}
+}
+
+// processNextClaimUpdate writes the pending update of the next queued claim.
+// Failed updates are retried with backoff until they succeed. It returns false
+// once the queue is shut down.
+func (p *Plugin) processNextClaimUpdate(ctx context.Context) bool {
+	logger := klog.FromContext(ctx).WithName("processNextClaimUpdate")
+	claimUID, shutdown := p.claimStatusQueue.Get()
+	if shutdown {
+		return false
+	}
+	defer p.claimStatusQueue.Done(claimUID)
+
+	update := p.pendingClaimUpdate(claimUID)
+	if update == nil {
+		p.claimStatusQueue.Forget(claimUID)
+		return true
+	}
+
+	if err := p.updateNetworkDeviceData(ctx, update); err != nil {
+		logger.Error(err, "Failed to update claim network data, retrying", "claim", claimUID, "retries", p.claimStatusQueue.NumRequeues(claimUID))
+		p.claimStatusQueue.AddRateLimited(claimUID)
+		return true
+	}
+
+	p.completeClaimUpdate(claimUID, update)
+	p.claimStatusQueue.Forget(claimUID)
+	return true
+}
+
+// pendingClaimUpdate returns a copy of the pending update of a claim.
+func (p *Plugin) pendingClaimUpdate(claimUID k8stypes.UID) *claimNetworkUpdate {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pending, found := p.pendingClaimUpdates[claimUID]
+	if !found {
+		return nil
+	}
+	update := &claimNetworkUpdate{
+		claim:   pending.claim,
+		devices: make(map[claimStatusDeviceKey]*types.NetworkDataChanStruct, len(pending.devices)),
+	}
+	for key, item := range pending.devices {
+		update.devices[key] = item
+	}
+	return update
+}
+
+// completeClaimUpdate drops the written device updates, keeping the ones merged
+// while the claim was being updated.
+func (p *Plugin) completeClaimUpdate(claimUID k8stypes.UID, update *claimNetworkUpdate) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pending, found := p.pendingClaimUpdates[claimUID]
+	if !found {
+		return
+	}
+	for key, item := range update.devices {
+		if pending.devices[key] == item {
+			delete(pending.devices, key)
+		}
+	}
+	if len(pending.devices) == 0 {
+		delete(p.pendingClaimUpdates, claimUID)
+	}
+	metrics.ClaimStatusQueueDepth.Set(float64(len(p.pendingClaimUpdates)))
+}
+
+// updateNetworkDeviceData writes the network data of the claim devices, already
+// persisted to the checkpoint, to the claim status and to the network-status
+// annotation of the pods.
+func (p *Plugin) updateNetworkDeviceData(ctx context.Context, update *claimNetworkUpdate) error {
+	var links []trace.SpanContext
+	for _, item := range update.devices {
+		links = append(links, item.SpanContext)
+	}
+	ctx, span := tracing.StartLinked(ctx, "UpdateClaimNetworkStatus", links,
+		tracing.ClaimAttributes(update.claim.Namespace, update.claim.Name, update.claim.UID)...)
+	err := p.writeNetworkDeviceData(ctx, update)
+	tracing.End(span, err)
+	return err
+}
+
+func (p *Plugin) writeNetworkDeviceData(ctx context.Context, update *claimNetworkUpdate) error {
+	logger := klog.FromContext(ctx).WithName("updateNetworkDeviceData")
+	logger.V(2).Info("Updating network device data", "claim", update.claim.UID, "devices", len(update.devices))
+
+	claim := &resourceapi.ResourceClaim{}
+	err := p.k8sClient.Client.Get(ctx, client.ObjectKey{
+		Name:      update.claim.Name,
+		Namespace: update.claim.Namespace,
+	}, claim)
+	if apierrors.IsNotFound(err) {
+		logger.V(2).Info("Claim no longer exists, dropping network data update", "claim", update.claim.UID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get claim %s/%s: %w", update.claim.Namespace, update.claim.Name, err)
+	}
+	if update.claim.UID != "" && claim.UID != update.claim.UID {
+		logger.V(2).Info("Claim was recreated, dropping network data update", "claim", update.claim.UID, "currentClaim", claim.UID)
+		return nil
+	}
+
+	statusDeviceIndex := p.buildClaimStatusDeviceIndex(claim)
+	hasClaimStatusUpdates := false
+	for _, item := range update.devices {
+		if p.updateClaimDeviceStatus(claim, statusDeviceIndex, item) {
+			hasClaimStatusUpdates = true
+		}
+	}
+	if hasClaimStatusUpdates {
+		// conflicts are retried by the queue with the latest claim
+		if _, err := p.k8sClient.ResourceV1().ResourceClaims(claim.Namespace).UpdateStatus(ctx, claim, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update claim network data: %w", err)
+		}
+		logger.V(2).Info("Successfully updated claim network data", "claim", claim.UID)
+	} else {
+		logger.V(2).Info("No claim status updates generated for claim", "claim", claim.UID)
+	}
+
+	return p.updatePodNetworkStatus(ctx, claim, update)
+}
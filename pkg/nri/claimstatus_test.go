@@ -0,0 +1,215 @@
+package nri
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	resourceapi "k8s.io/api/resource/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	ctrlclientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/flags"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/metrics"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// pendingNetworkData returns the network data of all pending claim updates.
+func pendingNetworkData(p *Plugin) types.NetworkDataChanStructList {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	networkDataList := types.NetworkDataChanStructList{}
+	for _, update := range p.pendingClaimUpdates {
+		for _, item := range update.devices {
+			networkDataList = append(networkDataList, item)
+		}
+	}
+	return networkDataList
+}
+
+var _ = Describe("NRI claim status queue", func() {
+	var (
+		ctx        context.Context
+		cfg        *types.Config
+		pm         *podmanager.PodManager
+		plugin     *Plugin
+		fakeClient *k8sfake.Clientset
+		prepared   types.PreparedDevices
+		claimUID   k8stypes.UID
+	)
+
+	getClaim := func() *resourceapi.ResourceClaim {
+		claim, err := fakeClient.ResourceV1().ResourceClaims("default").Get(ctx, "claim-a", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		return claim
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		cfg = &types.Config{Flags: &types.Flags{KubeletPluginsDirectoryPath: GinkgoT().TempDir()}}
+		var err error
+		pm, err = podmanager.NewPodManager(cfg)
+		Expect(err).NotTo(HaveOccurred())
+
+		claimUID = k8stypes.UID("claim-a-uid")
+		prepared = types.PreparedDevices{
+			{
+				ClaimNamespacedName: kubeletplugin.NamespacedObject{
+					NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "claim-a"},
+					UID:            claimUID,
+				},
+				Device: drapbv1.Device{PoolName: "pool-a", DeviceName: "dev-a"},
+			},
+		}
+		Expect(pm.Set(k8stypes.UID("pod-a-uid"), claimUID, prepared)).To(Succeed())
+
+		claim := &resourceapi.ResourceClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "claim-a", Namespace: "default", UID: claimUID},
+			Status: resourceapi.ResourceClaimStatus{
+				Devices: []resourceapi.AllocatedDeviceStatus{
+					{Driver: consts.DriverName, Pool: "pool-a", Device: "dev-a"},
+				},
+			},
+		}
+		fakeClient = k8sfake.NewSimpleClientset(claim.DeepCopy())
+		plugin = &Plugin{
+			podManager:       pm,
+			claimStatusQueue: newClaimStatusQueue(),
+			k8sClient: flags.ClientSets{
+				Interface: fakeClient,
+				Client:    ctrlclientfake.NewClientBuilder().WithScheme(flags.Scheme).WithRuntimeObjects(claim.DeepCopy()).Build(),
+			},
+		}
+	})
+
+	AfterEach(func() {
+		plugin.claimStatusQueue.ShutDown()
+	})
+
+	It("merges updates of the same device into one claim update", func() {
+		plugin.enqueueNetworkDeviceData(types.NetworkDataChanStructList{
+			{PreparedDevice: prepared[0], NetworkDeviceData: &resourceapi.NetworkDeviceData{InterfaceName: "old"}},
+		})
+		plugin.enqueueNetworkDeviceData(types.NetworkDataChanStructList{
+			{PreparedDevice: prepared[0], NetworkDeviceData: &resourceapi.NetworkDeviceData{InterfaceName: "new"}, CNIResult: map[string]interface{}{}},
+		})
+		Expect(plugin.claimStatusQueue.Len()).To(Equal(1))
+
+		Expect(plugin.processNextClaimUpdate(ctx)).To(BeTrue())
+
+		Expect(getClaim().Status.Devices[0].NetworkData.InterfaceName).To(Equal("new"))
+		Expect(pendingNetworkData(plugin)).To(BeEmpty())
+	})
+
+	It("reports the depth of the queue as a metric", func() {
+		plugin.enqueueNetworkDeviceData(types.NetworkDataChanStructList{
+			{PreparedDevice: prepared[0], NetworkDeviceData: &resourceapi.NetworkDeviceData{InterfaceName: "net1"}},
+		})
+
+		Expect(testutil.ToFloat64(metrics.ClaimStatusQueueDepth)).To(Equal(1.0))
+		Expect(plugin.processNextClaimUpdate(ctx)).To(BeTrue())
+		Expect(testutil.ToFloat64(metrics.ClaimStatusQueueDepth)).To(Equal(0.0))
+	})
+
+	It("keeps failed updates pending and retries them", func() {
+		fakeClient.PrependReactor("update", "resourceclaims", func(k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, errors.New("apiserver unavailable")
+		})
+		plugin.enqueueNetworkDeviceData(types.NetworkDataChanStructList{
+			{PreparedDevice: prepared[0], NetworkDeviceData: &resourceapi.NetworkDeviceData{InterfaceName: "net1"}, CNIResult: map[string]interface{}{}},
+		})
+
+		Expect(plugin.processNextClaimUpdate(ctx)).To(BeTrue())
+
+		Expect(pendingNetworkData(plugin)).To(HaveLen(1))
+		Expect(plugin.claimStatusQueue.NumRequeues(claimUID)).To(Equal(1))
+	})
+
+	It("persists the network data before the claim status is written", func() {
+		networkData := &resourceapi.NetworkDeviceData{InterfaceName: "net1"}
+		Expect(plugin.enqueueNetworkDeviceData(types.NetworkDataChanStructList{
+			{PreparedDevice: prepared[0], NetworkDeviceData: networkData},
+		})).To(Succeed())
+
+		restarted, err := podmanager.NewPodManager(cfg)
+		Expect(err).NotTo(HaveOccurred())
+		persisted, found := restarted.Get(k8stypes.UID("pod-a-uid"), claimUID)
+		Expect(found).To(BeTrue())
+		Expect(persisted[0].NetworkDeviceData).To(Equal(networkData))
+		Expect(getClaim().Status.Devices[0].NetworkData).To(BeNil())
+	})
+
+	It("retries conflicting claim updates from the queue", func() {
+		conflicts := 0
+		fakeClient.PrependReactor("update", "resourceclaims", func(k8stesting.Action) (bool, runtime.Object, error) {
+			if conflicts > 0 {
+				return false, nil, nil
+			}
+			conflicts++
+			return true, nil, apierrors.NewConflict(resourceapi.Resource("resourceclaims"), "claim-a", errors.New("modified"))
+		})
+		Expect(plugin.enqueueNetworkDeviceData(types.NetworkDataChanStructList{
+			{PreparedDevice: prepared[0], NetworkDeviceData: &resourceapi.NetworkDeviceData{InterfaceName: "net1"}},
+		})).To(Succeed())
+
+		Expect(plugin.processNextClaimUpdate(ctx)).To(BeTrue())
+		Expect(plugin.claimStatusQueue.NumRequeues(claimUID)).To(Equal(1))
+		Expect(pendingNetworkData(plugin)).To(HaveLen(1))
+
+		Expect(plugin.processNextClaimUpdate(ctx)).To(BeTrue())
+		Expect(getClaim().Status.Devices[0].NetworkData.InterfaceName).To(Equal("net1"))
+		Expect(pendingNetworkData(plugin)).To(BeEmpty())
+	})
+
+	It("drops updates of deleted claims", func() {
+		plugin.k8sClient.Client = ctrlclientfake.NewClientBuilder().WithScheme(flags.Scheme).Build()
+		plugin.enqueueNetworkDeviceData(types.NetworkDataChanStructList{
+			{PreparedDevice: prepared[0], NetworkDeviceData: &resourceapi.NetworkDeviceData{InterfaceName: "net1"}},
+		})
+
+		Expect(plugin.processNextClaimUpdate(ctx)).To(BeTrue())
+
+		Expect(pendingNetworkData(plugin)).To(BeEmpty())
+		Expect(plugin.claimStatusQueue.NumRequeues(claimUID)).To(BeZero())
+	})
+
+	It("rebuilds pending updates from the checkpoint", func() {
+		Expect(pm.UpdatePreparedDeviceNetworkData(prepared[0], &resourceapi.NetworkDeviceData{InterfaceName: "net1"})).To(Succeed())
+
+		restarted, err := podmanager.NewPodManager(cfg)
+		Expect(err).NotTo(HaveOccurred())
+		plugin.podManager = restarted
+		plugin.enqueuePreparedNetworkDeviceData(ctx)
+
+		Expect(pendingNetworkData(plugin)).To(HaveLen(1))
+		Expect(plugin.processNextClaimUpdate(ctx)).To(BeTrue())
+		Expect(getClaim().Status.Devices[0].NetworkData.InterfaceName).To(Equal("net1"))
+	})
+
+	It("does not rewrite a claim that already reports the rebuilt network data", func() {
+		networkData := &resourceapi.NetworkDeviceData{InterfaceName: "net1"}
+		claim := getClaim()
+		claim.Status.Devices[0].NetworkData = networkData
+		_, err := fakeClient.ResourceV1().ResourceClaims("default").UpdateStatus(ctx, claim, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		plugin.k8sClient.Client = ctrlclientfake.NewClientBuilder().WithScheme(flags.Scheme).WithRuntimeObjects(claim.DeepCopy()).Build()
+		Expect(pm.UpdatePreparedDeviceNetworkData(prepared[0], networkData)).To(Succeed())
+		actions := len(fakeClient.Actions())
+
+		plugin.enqueuePreparedNetworkDeviceData(ctx)
+		Expect(plugin.processNextClaimUpdate(ctx)).To(BeTrue())
+
+		Expect(fakeClient.Actions()).To(HaveLen(actions))
+	})
+})
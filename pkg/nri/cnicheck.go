@@ -0,0 +1,170 @@
+package nri
+
+import (
+	"context"
+	"time"
+
+	"github.com/containerd/nri/pkg/api"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// trackSandbox remembers a pod sandbox with attached networks so the periodic
+// CNI CHECK can run against it.
+func (p *Plugin) trackSandbox(pod *api.PodSandbox) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.sandboxes == nil {
+		p.sandboxes = make(map[string]*api.PodSandbox)
+	}
+	p.sandboxes[pod.Uid] = pod
+}
+
+// untrackSandbox forgets a stopped pod sandbox and the CHECK results of its devices.
+func (p *Plugin) untrackSandbox(pod *api.PodSandbox, devices types.PreparedDevices) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.sandboxes, pod.Uid)
+	for _, device := range devices {
+		delete(p.networkHealth, deviceStatusKey(device))
+	}
+}
+
+func (p *Plugin) runningSandboxes() []*api.PodSandbox {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sandboxes := make([]*api.PodSandbox, 0, len(p.sandboxes))
+	for _, pod := range p.sandboxes {
+		sandboxes = append(sandboxes, pod)
+	}
+	return sandboxes
+}
+
+// recordNetworkHealth stores the CHECK result of a device and reports whether
+// it differs from the previous one.
+func (p *Plugin) recordNetworkHealth(key claimStatusDeviceKey, message string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.networkHealth == nil {
+		p.networkHealth = make(map[claimStatusDeviceKey]string)
+	}
+	previous, found := p.networkHealth[key]
+	p.networkHealth[key] = message
+	return !found || previous != message
+}
+
+func deviceStatusKey(device *types.PreparedDevice) claimStatusDeviceKey {
+	return claimStatusDeviceKey{
+		driver: consts.DriverName,
+		pool:   device.Device.PoolName,
+		device: device.Device.DeviceName,
+	}
+}
+
+// checkNetworksRunner periodically runs CNI CHECK for the networks attached to
+// running pods.
+func (p *Plugin) checkNetworksRunner(ctx context.Context) {
+	ticker := time.NewTicker(p.checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.checkNetworks(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkNetworks runs CNI CHECK for every device of every tracked sandbox and
+// reports the result as device health and as a claim device condition.
+func (p *Plugin) checkNetworks(ctx context.Context) {
+	logger := klog.FromContext(ctx).WithName("checkNetworks")
+	for _, pod := range p.runningSandboxes() {
+		devices, found := p.podNetworkDevices(k8stypes.UID(pod.Uid))
+		if !found {
+			logger.V(2).Info("No prepared devices left for pod, stop checking it", "pod.UID", pod.Uid)
+			p.untrackSandbox(pod, nil)
+			continue
+		}
+		networkNamespace := getNetworkNamespace(pod)
+		if networkNamespace == "" {
+			continue
+		}
+
+		for _, device := range devices {
+			message := ""
+			if err := p.cniRuntime.CheckNetwork(ctx, pod, networkNamespace, device); err != nil {
+				logger.Error(err, "CNI CHECK failed", "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace)
+				message = err.Error()
+			}
+			p.reportNetworkHealth(ctx, device, message)
+		}
+	}
+}
+
+// reportNetworkHealth publishes the CHECK result of a device. The device health
+// is refreshed on every round, the claim is only updated when the result changes.
+func (p *Plugin) reportNetworkHealth(ctx context.Context, device *types.PreparedDevice, message string) {
+	logger := klog.FromContext(ctx).WithName("reportNetworkHealth")
+	healthy := message == ""
+	if p.healthUpdater != nil {
+		p.healthUpdater.UpdateDeviceHealth(ctx, device.Device.PoolName, device.Device.DeviceName, healthy, message)
+	}
+
+	if !p.recordNetworkHealth(deviceStatusKey(device), message) {
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:    consts.DeviceConditionNetworkReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  consts.DeviceReasonCNICheckSucceeded,
+		Message: "CNI CHECK succeeded",
+	}
+	if !healthy {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = consts.DeviceReasonCNICheckFailed
+		condition.Message = message
+	}
+	if err := p.updateClaimDeviceConditionWithRetry(ctx, device, condition); err != nil {
+		logger.Error(err, "Failed to update claim device condition", "claim", device.ClaimNamespacedName.UID, "deviceName", device.Device.DeviceName)
+	}
+}
+
+// updateClaimDeviceConditionWithRetry sets a condition on the claim status
+// entries of a device, refreshing the claim on every attempt.
+func (p *Plugin) updateClaimDeviceConditionWithRetry(ctx context.Context, device *types.PreparedDevice, condition metav1.Condition) error {
+	logger := klog.FromContext(ctx).WithName("updateClaimDeviceConditionWithRetry")
+	claimName := device.ClaimNamespacedName
+	key := deviceStatusKey(device)
+	return wait.ExponentialBackoffWithContext(ctx, consts.Backoff, func(ctx context.Context) (bool, error) {
+		claim, err := p.k8sClient.ResourceV1().ResourceClaims(claimName.Namespace).Get(ctx, claimName.Name, metav1.GetOptions{})
+		if err != nil {
+			logger.V(2).Info("Failed to fetch claim", "claim", claimName.UID, "error", err.Error())
+			return false, nil
+		}
+
+		changed := false
+		for _, idx := range p.buildClaimStatusDeviceIndex(claim)[key] {
+			if meta.SetStatusCondition(&claim.Status.Devices[idx].Conditions, condition) {
+				changed = true
+			}
+		}
+		if !changed {
+			return true, nil
+		}
+
+		if _, err := p.k8sClient.ResourceV1().ResourceClaims(claim.Namespace).UpdateStatus(ctx, claim, metav1.UpdateOptions{}); err != nil {
+			logger.V(2).Info("Retrying claim device condition update", "claim", claim.UID, "error", err.Error())
+			return false, nil
+		}
+		return true, nil
+	})
+}
@@ -0,0 +1,169 @@
+package nri
+
+import (
+	"context"
+	"errors"
+
+	"github.com/containerd/nri/pkg/api"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+
+	cnimock "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cni/mock"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/flags"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+type healthReport struct {
+	poolName   string
+	deviceName string
+	healthy    bool
+	message    string
+}
+
+type fakeHealthUpdater struct {
+	reports []healthReport
+}
+
+func (f *fakeHealthUpdater) UpdateDeviceHealth(_ context.Context, poolName, deviceName string, healthy bool, message string) {
+	f.reports = append(f.reports, healthReport{poolName: poolName, deviceName: deviceName, healthy: healthy, message: message})
+}
+
+var _ = Describe("NRI CNI CHECK", func() {
+	var (
+		ctx           context.Context
+		mockCNI       *cnimock.MockInterface
+		healthUpdater *fakeHealthUpdater
+		plugin        *Plugin
+		pod           *api.PodSandbox
+		prepared      types.PreparedDevices
+	)
+
+	getNetworkReadyCondition := func() *metav1.Condition {
+		claim, err := plugin.k8sClient.ResourceV1().ResourceClaims("default").Get(ctx, "claim-a", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(claim.Status.Devices).To(HaveLen(1))
+		return meta.FindStatusCondition(claim.Status.Devices[0].Conditions, consts.DeviceConditionNetworkReady)
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		ctrl := gomock.NewController(GinkgoT())
+		mockCNI = cnimock.NewMockInterface(ctrl)
+		healthUpdater = &fakeHealthUpdater{}
+
+		cfg := &types.Config{
+			Flags: &types.Flags{
+				KubeletPluginsDirectoryPath: GinkgoT().TempDir(),
+			},
+		}
+		pm, err := podmanager.NewPodManager(cfg)
+		Expect(err).NotTo(HaveOccurred())
+
+		pod = &api.PodSandbox{
+			Id:        "sandbox-id",
+			Name:      "pod-name",
+			Namespace: "default",
+			Uid:       "pod-a-uid",
+			Linux: &api.LinuxPodSandbox{
+				Namespaces: []*api.LinuxNamespace{{Type: "network", Path: "/proc/123/ns/net"}},
+			},
+		}
+
+		claimUID := k8stypes.UID("claim-a-uid")
+		prepared = types.PreparedDevices{
+			{
+				ClaimNamespacedName: kubeletplugin.NamespacedObject{
+					NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "claim-a"},
+					UID:            claimUID,
+				},
+				Device: drapbv1.Device{
+					PoolName:   "pool-a",
+					DeviceName: "dev-a",
+				},
+				IfName: "net1",
+			},
+		}
+		Expect(pm.Set(k8stypes.UID(pod.Uid), claimUID, prepared)).To(Succeed())
+
+		claim := &resourceapi.ResourceClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "claim-a", Namespace: "default", UID: claimUID},
+			Status: resourceapi.ResourceClaimStatus{
+				Devices: []resourceapi.AllocatedDeviceStatus{
+					{Driver: consts.DriverName, Pool: "pool-a", Device: "dev-a"},
+				},
+			},
+		}
+
+		plugin = &Plugin{
+			podManager:    pm,
+			cniRuntime:    mockCNI,
+			healthUpdater: healthUpdater,
+			k8sClient: flags.ClientSets{
+				Interface: k8sfake.NewSimpleClientset(claim),
+			},
+		}
+		plugin.trackSandbox(pod)
+	})
+
+	It("reports healthy devices and sets the NetworkReady condition", func() {
+		mockCNI.EXPECT().CheckNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).Return(nil)
+
+		plugin.checkNetworks(ctx)
+
+		Expect(healthUpdater.reports).To(Equal([]healthReport{{poolName: "pool-a", deviceName: "dev-a", healthy: true}}))
+		condition := getNetworkReadyCondition()
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(consts.DeviceReasonCNICheckSucceeded))
+	})
+
+	It("reports failed checks as unhealthy and updates the condition on transition", func() {
+		gomock.InOrder(
+			mockCNI.EXPECT().CheckNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).Return(nil),
+			mockCNI.EXPECT().CheckNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).Return(errors.New("link down")),
+		)
+
+		plugin.checkNetworks(ctx)
+		plugin.checkNetworks(ctx)
+
+		Expect(healthUpdater.reports).To(HaveLen(2))
+		Expect(healthUpdater.reports[1].healthy).To(BeFalse())
+		Expect(healthUpdater.reports[1].message).To(Equal("link down"))
+		condition := getNetworkReadyCondition()
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(consts.DeviceReasonCNICheckFailed))
+		Expect(condition.Message).To(Equal("link down"))
+	})
+
+	It("does not update the claim when the result is unchanged", func() {
+		mockCNI.EXPECT().CheckNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).Return(nil).Times(2)
+
+		plugin.checkNetworks(ctx)
+		fakeClient := plugin.k8sClient.Interface.(*k8sfake.Clientset)
+		actions := len(fakeClient.Actions())
+		plugin.checkNetworks(ctx)
+
+		Expect(fakeClient.Actions()).To(HaveLen(actions))
+		Expect(healthUpdater.reports).To(HaveLen(2))
+	})
+
+	It("stops checking sandboxes that were stopped", func() {
+		mockCNI.EXPECT().DetachNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).Return(nil)
+
+		Expect(plugin.StopPodSandbox(ctx, pod)).To(Succeed())
+		plugin.checkNetworks(ctx)
+
+		Expect(healthUpdater.reports).To(BeEmpty())
+	})
+})
@@ -0,0 +1,97 @@
+package nri
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/containerd/nri/pkg/stub"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.etcd.io/etcd/client/pkg/v3/fileutil"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+type fakeStub struct {
+	stub.Stub
+	started atomic.Bool
+	stopped atomic.Bool
+}
+
+func (f *fakeStub) Start(context.Context) error {
+	f.started.Store(true)
+	return nil
+}
+
+func (f *fakeStub) Stop() {
+	f.stopped.Store(true)
+}
+
+var _ = Describe("NRI plugin handover", func() {
+	var (
+		plugin   *Plugin
+		fake     *fakeStub
+		lockPath string
+		ctx      context.Context
+	)
+
+	BeforeEach(func() {
+		tempDir, err := os.MkdirTemp("", "nri-handover-test-*")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, tempDir)
+
+		cfg := &types.Config{Flags: &types.Flags{KubeletPluginsDirectoryPath: tempDir, PodUID: "new-pod"}}
+		Expect(os.MkdirAll(cfg.DriverPluginPath(), 0o750)).To(Succeed())
+		podManager, err := podmanager.NewPodManager(cfg)
+		Expect(err).NotTo(HaveOccurred())
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+
+		fake = &fakeStub{}
+		lockPath = filepath.Join(cfg.DriverPluginPath(), consts.NRIPluginLockFile)
+		plugin = &Plugin{
+			stub:             fake,
+			podManager:       podManager,
+			claimStatusQueue: newClaimStatusQueue(),
+			lockPath:         lockPath,
+			cancelMainCtx:    func(err error) { Fail(err.Error()) },
+		}
+	})
+
+	It("starts right away when no other driver instance holds the NRI lock", func() {
+		Expect(plugin.Start(ctx)).To(Succeed())
+		Expect(fake.started.Load()).To(BeTrue())
+		Expect(plugin.Connected(ctx)).To(Succeed())
+
+		_, err := fileutil.TryLockFile(lockPath, os.O_RDWR|os.O_CREATE, 0o600)
+		Expect(err).To(MatchError(fileutil.ErrLocked))
+
+		plugin.Stop()
+		Expect(fake.stopped.Load()).To(BeTrue())
+		next, err := fileutil.TryLockFile(lockPath, os.O_RDWR|os.O_CREATE, 0o600)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(next.Close()).To(Succeed())
+	})
+
+	It("waits in standby until the previous driver instance releases the NRI lock", func() {
+		previous, err := fileutil.TryLockFile(lockPath, os.O_RDWR|os.O_CREATE, 0o600)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(plugin.Start(ctx)).To(Succeed())
+		Expect(plugin.Connected(ctx)).To(Succeed())
+		Consistently(fake.started.Load).WithTimeout(200 * time.Millisecond).Should(BeFalse())
+
+		Expect(previous.Close()).To(Succeed())
+		Eventually(fake.started.Load).WithTimeout(3 * time.Second).Should(BeTrue())
+		Eventually(plugin.standby.Load).Should(BeFalse())
+
+		plugin.Stop()
+	})
+})
@@ -0,0 +1,200 @@
+package nri
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+
+	cni100 "github.com/containernetworking/cni/pkg/types/100"
+	nettypes "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	netattdefclientutils "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/utils"
+	resourceapi "k8s.io/api/resource/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// updatePodNetworkStatus writes the network data of the claim devices to the
+// Multus network-status annotation of the pods they are attached to, so tools
+// reading the annotation also see the interfaces attached in STANDALONE mode.
+func (p *Plugin) updatePodNetworkStatus(ctx context.Context, claim *resourceapi.ResourceClaim, update *claimNetworkUpdate) error {
+	devicesByPod := map[k8stypes.UID][]*types.NetworkDataChanStruct{}
+	for _, item := range update.devices {
+		if item.PreparedDevice.IfName == "" {
+			continue
+		}
+		podUID := k8stypes.UID(item.PreparedDevice.PodUID)
+		devicesByPod[podUID] = append(devicesByPod[podUID], item)
+	}
+
+	for _, consumer := range claim.Status.ReservedFor {
+		if consumer.APIGroup != "" || consumer.Resource != "pods" {
+			continue
+		}
+		items := devicesByPod[consumer.UID]
+		if len(items) == 0 {
+			continue
+		}
+		if err := p.patchPodNetworkStatus(ctx, claim.Namespace, consumer.Name, consumer.UID, items); err != nil {
+			return fmt.Errorf("failed to update network status of pod %s/%s: %w", claim.Namespace, consumer.Name, err)
+		}
+	}
+	return nil
+}
+
+// patchPodNetworkStatus merges the network status of the devices into the
+// network-status annotation of a pod. Entries of other networks, like the ones
+// written by Multus, are kept.
+func (p *Plugin) patchPodNetworkStatus(ctx context.Context, namespace, name string, podUID k8stypes.UID, items []*types.NetworkDataChanStruct) error {
+	logger := klog.FromContext(ctx).WithName("patchPodNetworkStatus")
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		pod, err := p.k8sClient.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			logger.V(2).Info("Pod no longer exists, skipping network status update", "pod", namespace+"/"+name)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if pod.UID != podUID {
+			logger.V(2).Info("Pod was recreated, skipping network status update", "pod", namespace+"/"+name, "pod.UID", podUID)
+			return nil
+		}
+
+		existing := []nettypes.NetworkStatus{}
+		if raw := pod.Annotations[nettypes.NetworkStatusAnnot]; raw != "" {
+			if err := json.Unmarshal([]byte(raw), &existing); err != nil {
+				// never overwrite an annotation we cannot merge into
+				logger.Error(err, "Failed to parse pod network status, skipping network status update", "pod", namespace+"/"+name)
+				return nil
+			}
+		}
+		statuses, err := mergeNetworkStatus(existing, items)
+		if err != nil {
+			return err
+		}
+		if apiequality.Semantic.DeepEqual(existing, statuses) {
+			return nil
+		}
+
+		rawStatuses, err := json.Marshal(statuses)
+		if err != nil {
+			return fmt.Errorf("failed to marshal network status: %w", err)
+		}
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"resourceVersion": pod.ResourceVersion,
+				"annotations": map[string]string{
+					nettypes.NetworkStatusAnnot: string(rawStatuses),
+				},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to marshal network status patch: %w", err)
+		}
+		_, err = p.k8sClient.CoreV1().Pods(namespace).Patch(ctx, name, k8stypes.MergePatchType, patch, metav1.PatchOptions{})
+		return err
+	})
+}
+
+// mergeNetworkStatus replaces the entries of the device interfaces in the
+// network status, entries are matched by interface name. Detached devices
+// have their entry removed. Devices without a CNI result, rebuilt from the
+// checkpoint, keep their existing entry which may hold more details.
+func mergeNetworkStatus(existing []nettypes.NetworkStatus, items []*types.NetworkDataChanStruct) ([]nettypes.NetworkStatus, error) {
+	byInterface := make(map[string]*types.NetworkDataChanStruct, len(items))
+	for _, item := range items {
+		byInterface[item.PreparedDevice.IfName] = item
+	}
+
+	statuses := []nettypes.NetworkStatus{}
+	kept := map[string]bool{}
+	for _, status := range existing {
+		item, found := byInterface[status.Interface]
+		if !found || (item.CNIResult == nil && item.NetworkDeviceData != nil) {
+			statuses = append(statuses, status)
+			kept[status.Interface] = found
+		}
+	}
+
+	for _, item := range items {
+		if item.NetworkDeviceData == nil || kept[item.PreparedDevice.IfName] {
+			continue
+		}
+		status, err := networkStatusForDevice(item)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, *status)
+	}
+	return statuses, nil
+}
+
+// networkStatusForDevice builds the network status entry of an attached
+// device from its CNI result, or from its network data when no result is
+// available.
+func networkStatusForDevice(item *types.NetworkDataChanStruct) (*nettypes.NetworkStatus, error) {
+	device := item.PreparedDevice
+	deviceInfo := &nettypes.DeviceInfo{
+		Type:    nettypes.DeviceInfoTypePCI,
+		Version: nettypes.DeviceInfoVersion,
+		Pci: &nettypes.PciDevice{
+			PciAddress: device.PciAddress,
+		},
+	}
+
+	if item.CNIResult != nil {
+		raw, err := json.Marshal(item.CNIResult)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal CNI result of device %s: %w", device.Device.DeviceName, err)
+		}
+		result, err := cni100.NewResult(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CNI result of device %s: %w", device.Device.DeviceName, err)
+		}
+		status, err := netattdefclientutils.CreateNetworkStatus(result, networkStatusName(device), false, deviceInfo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create network status of device %s: %w", device.Device.DeviceName, err)
+		}
+		if status.Interface == "" {
+			status.Interface = device.IfName
+		}
+		return status, nil
+	}
+
+	status := &nettypes.NetworkStatus{
+		Name:       networkStatusName(device),
+		Interface:  device.IfName,
+		Mac:        item.NetworkDeviceData.HardwareAddress,
+		DeviceInfo: deviceInfo,
+	}
+	for _, address := range item.NetworkDeviceData.IPs {
+		// network-status reports addresses without prefix length
+		if ip, _, err := net.ParseCIDR(address); err == nil {
+			address = ip.String()
+		}
+		status.IPs = append(status.IPs, address)
+	}
+	return status, nil
+}
+
+// networkStatusName returns the network name of a device in the network
+// status: the NetworkAttachmentDefinition as Multus reports it, or the claim
+// for inline and netlink configurations.
+func networkStatusName(device *types.PreparedDevice) string {
+	claim := device.ClaimNamespacedName
+	if device.Config != nil && device.Config.NetAttachDefName != "" && !device.Config.IsNetlinkAttachMode() {
+		namespace := claim.Namespace
+		if device.Config.NetAttachDefNamespace != "" {
+			namespace = device.Config.NetAttachDefNamespace
+		}
+		return namespace + "/" + device.Config.NetAttachDefName
+	}
+	return claim.Namespace + "/" + claim.Name
+}
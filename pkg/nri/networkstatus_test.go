@@ -0,0 +1,169 @@
+package nri
+
+import (
+	"context"
+	"encoding/json"
+
+	nettypes "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	ctrlclientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/flags"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("NRI pod network status", func() {
+	const multusStatus = `[{"name":"default/ovn","interface":"eth0","ips":["10.244.0.5"],"default":true}]`
+
+	var (
+		ctx        context.Context
+		pm         *podmanager.PodManager
+		plugin     *Plugin
+		fakeClient *k8sfake.Clientset
+		prepared   types.PreparedDevices
+		cniResult  map[string]interface{}
+	)
+
+	podNetworkStatus := func() []nettypes.NetworkStatus {
+		pod, err := fakeClient.CoreV1().Pods("default").Get(ctx, "pod-a", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		statuses := []nettypes.NetworkStatus{}
+		Expect(json.Unmarshal([]byte(pod.Annotations[nettypes.NetworkStatusAnnot]), &statuses)).To(Succeed())
+		return statuses
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		cfg := &types.Config{Flags: &types.Flags{KubeletPluginsDirectoryPath: GinkgoT().TempDir()}}
+		var err error
+		pm, err = podmanager.NewPodManager(cfg)
+		Expect(err).NotTo(HaveOccurred())
+
+		claimUID := k8stypes.UID("claim-a-uid")
+		prepared = types.PreparedDevices{
+			{
+				ClaimNamespacedName: kubeletplugin.NamespacedObject{
+					NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "claim-a"},
+					UID:            claimUID,
+				},
+				Device:     drapbv1.Device{PoolName: "pool-a", DeviceName: "dev-a"},
+				Config:     &configapi.VfConfig{NetAttachDefName: "sriov-net"},
+				IfName:     "net1",
+				PciAddress: "0000:01:00.1",
+				PodUID:     "pod-a-uid",
+			},
+		}
+		Expect(pm.Set(k8stypes.UID("pod-a-uid"), claimUID, prepared)).To(Succeed())
+
+		claim := &resourceapi.ResourceClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "claim-a", Namespace: "default", UID: claimUID},
+			Status: resourceapi.ResourceClaimStatus{
+				Devices: []resourceapi.AllocatedDeviceStatus{
+					{Driver: consts.DriverName, Pool: "pool-a", Device: "dev-a"},
+				},
+				ReservedFor: []resourceapi.ResourceClaimConsumerReference{
+					{Resource: "pods", Name: "pod-a", UID: "pod-a-uid"},
+				},
+			},
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "pod-a",
+				Namespace:   "default",
+				UID:         "pod-a-uid",
+				Annotations: map[string]string{nettypes.NetworkStatusAnnot: multusStatus},
+			},
+		}
+		fakeClient = k8sfake.NewSimpleClientset(claim.DeepCopy(), pod)
+		plugin = &Plugin{
+			podManager:       pm,
+			claimStatusQueue: newClaimStatusQueue(),
+			k8sClient: flags.ClientSets{
+				Interface: fakeClient,
+				Client:    ctrlclientfake.NewClientBuilder().WithScheme(flags.Scheme).WithRuntimeObjects(claim.DeepCopy()).Build(),
+			},
+		}
+
+		cniResult = map[string]interface{}{
+			"cniVersion": "1.0.0",
+			"interfaces": []interface{}{
+				map[string]interface{}{"name": "net1", "mac": "02:00:00:00:00:01", "sandbox": "/var/run/netns/pod-a"},
+			},
+			"ips": []interface{}{
+				map[string]interface{}{"interface": 0, "address": "192.168.1.10/24"},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		plugin.claimStatusQueue.ShutDown()
+	})
+
+	It("adds the device with its device-info and keeps the Multus entries", func() {
+		plugin.enqueueNetworkDeviceData(types.NetworkDataChanStructList{
+			{PreparedDevice: prepared[0], NetworkDeviceData: &resourceapi.NetworkDeviceData{InterfaceName: "net1"}, CNIResult: cniResult},
+		})
+		Expect(plugin.processNextClaimUpdate(ctx)).To(BeTrue())
+
+		statuses := podNetworkStatus()
+		Expect(statuses).To(HaveLen(2))
+		Expect(statuses[0].Name).To(Equal("default/ovn"))
+		Expect(statuses[1].Name).To(Equal("default/sriov-net"))
+		Expect(statuses[1].Interface).To(Equal("net1"))
+		Expect(statuses[1].IPs).To(Equal([]string{"192.168.1.10"}))
+		Expect(statuses[1].Mac).To(Equal("02:00:00:00:00:01"))
+		Expect(statuses[1].DeviceInfo).NotTo(BeNil())
+		Expect(statuses[1].DeviceInfo.Pci.PciAddress).To(Equal("0000:01:00.1"))
+	})
+
+	It("removes the entry of a detached device", func() {
+		plugin.enqueueNetworkDeviceData(types.NetworkDataChanStructList{
+			{PreparedDevice: prepared[0], NetworkDeviceData: &resourceapi.NetworkDeviceData{InterfaceName: "net1"}, CNIResult: cniResult},
+		})
+		Expect(plugin.processNextClaimUpdate(ctx)).To(BeTrue())
+
+		plugin.enqueueNetworkDeviceData(types.NetworkDataChanStructList{{PreparedDevice: prepared[0]}})
+		Expect(plugin.processNextClaimUpdate(ctx)).To(BeTrue())
+
+		statuses := podNetworkStatus()
+		Expect(statuses).To(HaveLen(1))
+		Expect(statuses[0].Name).To(Equal("default/ovn"))
+	})
+
+	It("keeps the existing entry when rebuilding from the checkpoint", func() {
+		plugin.enqueueNetworkDeviceData(types.NetworkDataChanStructList{
+			{PreparedDevice: prepared[0], NetworkDeviceData: &resourceapi.NetworkDeviceData{InterfaceName: "net1"}, CNIResult: cniResult},
+		})
+		Expect(plugin.processNextClaimUpdate(ctx)).To(BeTrue())
+		actions := len(fakeClient.Actions())
+
+		plugin.enqueuePreparedNetworkDeviceData(ctx)
+		Expect(plugin.processNextClaimUpdate(ctx)).To(BeTrue())
+
+		for _, action := range fakeClient.Actions()[actions:] {
+			Expect(action.GetVerb()).NotTo(Equal("patch"))
+		}
+		Expect(podNetworkStatus()[1].Mac).To(Equal("02:00:00:00:00:01"))
+	})
+
+	It("only updates the pods the devices are attached to", func() {
+		prepared[0].PodUID = "other-pod-uid"
+		plugin.enqueueNetworkDeviceData(types.NetworkDataChanStructList{
+			{PreparedDevice: prepared[0], NetworkDeviceData: &resourceapi.NetworkDeviceData{InterfaceName: "net1"}, CNIResult: cniResult},
+		})
+		Expect(plugin.processNextClaimUpdate(ctx)).To(BeTrue())
+
+		Expect(podNetworkStatus()).To(HaveLen(1))
+	})
+})
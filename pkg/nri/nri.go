@@ -3,24 +3,34 @@ package nri
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/containerd/nri/pkg/api"
 	"github.com/containerd/nri/pkg/stub"
+	"go.etcd.io/etcd/client/pkg/v3/fileutil"
+	"go.opentelemetry.io/otel/trace"
 	resourceapi "k8s.io/api/resource/v1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/runtime"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/dynamic-resource-allocation/kubeletplugin"
 	"k8s.io/klog/v2"
-	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cni"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/events"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/flags"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/tracing"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 )
 
@@ -31,23 +41,50 @@ type Plugin struct {
 	podManager *podmanager.PodManager
 	cniRuntime cni.Interface
 
-	k8sClient                   flags.ClientSets
-	networkDeviceDataUpdateChan chan types.NetworkDataChanStructList
-	interfacePrefix             string
-	enableDeviceMetadata        bool
-	metadataUpdater             types.MetadataUpdater
+	k8sClient            flags.ClientSets
+	claimStatusQueue     workqueue.TypedRateLimitingInterface[k8stypes.UID]
+	interfacePrefix      string
+	enableDeviceMetadata bool
+	metadataUpdater      types.MetadataUpdater
+	healthUpdater        types.DeviceHealthUpdater
+	checkInterval        time.Duration
+	recorder             record.EventRecorder
+
+	// sandboxes holds the running pod sandboxes with attached networks keyed
+	// by pod UID, networkHealth the last CNI CHECK result per device and
+	// pendingClaimUpdates the network data not yet written to the claims.
+	mu                  sync.Mutex
+	sandboxes           map[string]*api.PodSandbox
+	networkHealth       map[claimStatusDeviceKey]string
+	pendingClaimUpdates map[k8stypes.UID]*claimNetworkUpdate
+
+	// hookMu serializes the CDI hook requests, which run concurrently for
+	// the containers of a pod.
+	hookMu sync.Mutex
+
+	// connected is set while the stub is registered to the runtime.
+	connected atomic.Bool
+
+	// lockPath is the NRI lock file handed over between the driver instances
+	// of a rolling update, empty without rolling updates. standby is set
+	// while the previous instance holds it. startMu serializes the start
+	// after the handover with Stop.
+	lockPath      string
+	lock          *fileutil.LockedFile
+	standby       atomic.Bool
+	startMu       sync.Mutex
+	cancelMainCtx func(error)
 }
 
+// handoverInterval is how often a driver instance in standby checks whether
+// the NRI plugin of the previous instance stopped.
+const handoverInterval = time.Second
+
 // NewNRIPlugin creates a new NRI plugin.
-func NewNRIPlugin(config *types.Config, podManager *podmanager.PodManager, cniRuntime cni.Interface, metadataUpdater types.MetadataUpdater) (*Plugin, error) {
-	p := &Plugin{
-		podManager:                  podManager,
-		cniRuntime:                  cniRuntime,
-		k8sClient:                   config.K8sClient,
-		interfacePrefix:             config.Flags.DefaultInterfacePrefix,
-		enableDeviceMetadata:        config.Flags.EnableDeviceMetadata,
-		metadataUpdater:             metadataUpdater,
-		networkDeviceDataUpdateChan: make(chan types.NetworkDataChanStructList, 100),
+func NewNRIPlugin(config *types.Config, podManager *podmanager.PodManager, cniRuntime cni.Interface, metadataUpdater types.MetadataUpdater, healthUpdater types.DeviceHealthUpdater) (*Plugin, error) {
+	p := newPlugin(config, podManager, cniRuntime, metadataUpdater, healthUpdater)
+	if config.Flags.PodUID != "" {
+		p.lockPath = filepath.Join(config.DriverPluginPath(), consts.NRIPluginLockFile)
 	}
 	var err error
 	// register the NRI plugin
@@ -55,6 +92,7 @@ func NewNRIPlugin(config *types.Config, podManager *podmanager.PodManager, cniRu
 		// https://github.com/containerd/nri/pull/173
 		// Otherwise it silently exits the program
 		stub.WithOnClose(func() {
+			p.connected.Store(false)
 			klog.Infof("%s NRI plugin closed canceling context", consts.DriverName)
 			config.CancelMainCtx(fmt.Errorf("NRI plugin closed"))
 		}),
@@ -68,32 +106,143 @@ func NewNRIPlugin(config *types.Config, podManager *podmanager.PodManager, cniRu
 	return p, nil
 }
 
-// Start starts the NRI plugin.
+// newPlugin creates a plugin attaching the networks that is not registered to
+// NRI.
+func newPlugin(config *types.Config, podManager *podmanager.PodManager, cniRuntime cni.Interface, metadataUpdater types.MetadataUpdater, healthUpdater types.DeviceHealthUpdater) *Plugin {
+	return &Plugin{
+		podManager:           podManager,
+		cniRuntime:           cniRuntime,
+		k8sClient:            config.K8sClient,
+		interfacePrefix:      config.Flags.DefaultInterfacePrefix,
+		enableDeviceMetadata: config.Flags.EnableDeviceMetadata,
+		metadataUpdater:      metadataUpdater,
+		healthUpdater:        healthUpdater,
+		checkInterval:        config.Flags.CNICheckInterval,
+		recorder:             config.EventRecorder,
+		claimStatusQueue:     newClaimStatusQueue(),
+		cancelMainCtx:        config.CancelMainCtx,
+	}
+}
+
+// Start starts the NRI plugin. During a rolling update the NRI plugin of the
+// previous driver instance is still registered, this one waits in standby
+// until it stops: only one instance attaches the networks at a time and
+// Synchronize catches up on the pod sandboxes started or stopped meanwhile.
 func (p *Plugin) Start(ctx context.Context) error {
+	logger := klog.FromContext(ctx).WithName("NRI Start")
+	if p.lockPath != "" {
+		acquired, err := p.tryLock()
+		if err != nil {
+			return err
+		}
+		if !acquired {
+			logger.Info("Waiting for the NRI plugin of the previous driver instance to stop")
+			p.standby.Store(true)
+			go p.startAfterHandover(ctx)
+			return nil
+		}
+	}
+	return p.start(ctx)
+}
+
+// startAfterHandover starts the plugin once the previous driver instance
+// released the NRI lock.
+func (p *Plugin) startAfterHandover(ctx context.Context) {
+	logger := klog.FromContext(ctx).WithName("NRI Start")
+	err := wait.PollUntilContextCancel(ctx, handoverInterval, false, func(context.Context) (bool, error) {
+		p.startMu.Lock()
+		defer p.startMu.Unlock()
+		return p.tryLock()
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			p.cancelMainCtx(err)
+		}
+		return
+	}
+
+	p.startMu.Lock()
+	defer p.startMu.Unlock()
+	if ctx.Err() != nil {
+		return
+	}
+	logger.Info("NRI plugin of the previous driver instance stopped")
+	if err := p.start(ctx); err != nil {
+		p.cancelMainCtx(err)
+		return
+	}
+	p.standby.Store(false)
+}
+
+// tryLock takes the NRI lock, it reports false while another driver instance
+// holds it.
+func (p *Plugin) tryLock() (bool, error) {
+	lock, err := fileutil.TryLockFile(p.lockPath, os.O_RDWR|os.O_CREATE, 0o600)
+	if errors.Is(err, fileutil.ErrLocked) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to lock NRI plugin: %w", err)
+	}
+	p.lock = lock
+	return true, nil
+}
+
+func (p *Plugin) start(ctx context.Context) error {
 	logger := klog.FromContext(ctx).WithName("NRI Start")
 	logger.Info("Starting NRI plugin")
+	// Claim updates that were pending when the driver stopped are rebuilt from
+	// the checkpoint before Synchronize, called while the stub connects, adds
+	// its own.
+	p.enqueuePreparedNetworkDeviceData(ctx)
+	go p.runClaimStatusWorker(ctx)
+	if p.checkInterval > 0 {
+		go p.checkNetworksRunner(ctx)
+	}
+
 	err := p.stub.Start(ctx)
 	if err != nil {
 		logger.Error(err, "Failed to start NRI plugin")
 		return fmt.Errorf("failed to start NRI plugin: %w", err)
 	}
+	p.connected.Store(true)
+	return nil
+}
 
-	go p.updateNetworkDeviceDataRunner(ctx)
+// Connected reports an error unless the plugin is registered to the runtime
+// or waits for the previous driver instance to hand it over.
+func (p *Plugin) Connected(_ context.Context) error {
+	if !p.connected.Load() && !p.standby.Load() {
+		return fmt.Errorf("NRI plugin is not connected to the runtime")
+	}
 	return nil
 }
 
-// Stop stops the NRI plugin.
+// Stop stops the NRI plugin and hands it over to the next driver instance.
 func (p *Plugin) Stop() {
+	p.startMu.Lock()
+	defer p.startMu.Unlock()
 	p.stub.Stop()
-	close(p.networkDeviceDataUpdateChan)
+	p.StopWorkers()
+	if p.lock != nil {
+		_ = p.lock.Close()
+		p.lock = nil
+	}
 }
 
 // RunPodSandbox runs the CNI ADD operation for each device in the devices list.
 func (p *Plugin) RunPodSandbox(ctx context.Context, pod *api.PodSandbox) error {
+	ctx, span := tracing.Start(ctx, "RunPodSandbox", podSpanAttributes(pod)...)
+	err := p.runPodSandbox(ctx, pod)
+	tracing.End(span, err)
+	return err
+}
+
+func (p *Plugin) runPodSandbox(ctx context.Context, pod *api.PodSandbox) error {
 	logger := klog.FromContext(ctx).WithName("NRI RunPodSandbox")
 	logger.Info("RunPodSandbox", "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace)
 
-	devices, found := p.podManager.GetDevicesByPodUID(k8stypes.UID(pod.Uid))
+	devices, found := p.podNetworkDevices(k8stypes.UID(pod.Uid))
 	if !found {
 		logger.Info("No prepared devices found for pod", "pod.UID", pod.Uid)
 		return nil
@@ -107,29 +256,16 @@ func (p *Plugin) RunPodSandbox(ctx context.Context, pod *api.PodSandbox) error {
 		return nil
 	}
 
-	networkDevicesData := types.NetworkDataChanStructList{}
-	for _, device := range devices {
-		networkDeviceData, cniResultMap, err := p.cniRuntime.AttachNetwork(ctx, pod, networkNamespace, device)
-		if err != nil {
-			logger.Error(err, "Failed to attach network", "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace)
-			return fmt.Errorf("failed to attach network: %w", err)
-		}
-		// Parse NetAttachDefConfig into map[string]interface{} for CNIConfig
-		cniConfigMap := map[string]interface{}{}
-		if device.NetAttachDefConfig != "" {
-			if err := json.Unmarshal([]byte(device.NetAttachDefConfig), &cniConfigMap); err != nil {
-				logger.V(2).Info("Failed to unmarshal NetAttachDefConfig, proceeding with empty CNIConfig", "error", err.Error())
-				cniConfigMap = map[string]interface{}{}
-			}
-		}
+	// Record the sandbox before running CNI ADD so a DEL can still be issued
+	// for it if the driver restarts before the sandbox is stopped.
+	if err := p.podManager.UpdatePreparedDevicesSandbox(devices, pod.Id, networkNamespace); err != nil {
+		logger.Error(err, "Failed to record pod sandbox for prepared devices", "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace)
+		return fmt.Errorf("failed to record pod sandbox for prepared devices: %w", err)
+	}
 
-		networkDevicesData = append(networkDevicesData, &types.NetworkDataChanStruct{
-			PreparedDevice:    device,
-			NetworkDeviceData: networkDeviceData,
-			CNIConfig:         cniConfigMap,
-			CNIResult:         cniResultMap,
-		})
-		logger.Info("Attached network", "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace, "networkDeviceData", networkDeviceData)
+	networkDevicesData, err := p.attachNetworks(ctx, pod, networkNamespace, devices)
+	if err != nil {
+		return err
 	}
 
 	// Refresh request metadata synchronously so runtime CNI fields are available
@@ -139,108 +275,94 @@ func (p *Plugin) RunPodSandbox(ctx context.Context, pod *api.PodSandbox) error {
 		return fmt.Errorf("failed to update request metadata before pod start: %w", err)
 	}
 
-	// Claim status and checkpoint updates are still done asynchronously to keep
-	// the NRI hook within its timeout budget.
-	p.networkDeviceDataUpdateChan <- networkDevicesData
+	p.trackSandbox(pod)
+
+	// The network data is persisted before the sandbox starts, only the claim
+	// status is written asynchronously to keep the NRI hook within its
+	// timeout budget.
+	if err := p.enqueueNetworkDeviceData(networkDevicesData); err != nil {
+		logger.Error(err, "Failed to persist network data", "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace)
+		return err
+	}
 	return nil
 }
 
 // StopPodSandbox runs the CNI DEL operation for each device in the devices list.
+// Every device is detached even if others fail, the failures are aggregated in
+// the returned error.
 func (p *Plugin) StopPodSandbox(ctx context.Context, pod *api.PodSandbox) error {
+	ctx, span := tracing.Start(ctx, "StopPodSandbox", podSpanAttributes(pod)...)
+	err := p.stopPodSandbox(ctx, pod)
+	tracing.End(span, err)
+	return err
+}
+
+func (p *Plugin) stopPodSandbox(ctx context.Context, pod *api.PodSandbox) error {
 	logger := klog.FromContext(ctx).WithName("NRI StopPodSandbox")
 	logger.Info("StopPodSandbox", "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace)
 
-	devices, found := p.podManager.GetDevicesByPodUID(k8stypes.UID(pod.Uid))
+	devices, found := p.podNetworkDevices(k8stypes.UID(pod.Uid))
 	if !found {
 		logger.Info("No prepared devices found for pod", "pod.UID", pod.Uid)
 		return nil
 	}
 
+	// CNI DEL accepts an empty network namespace, it must still run when the
+	// namespace is already gone so IPAM leases and VF state are released.
 	networkNamespace := getNetworkNamespace(pod)
 	if networkNamespace == "" {
-		return fmt.Errorf("error getting network namespace for pod '%s' in namespace '%s'", pod.Name, pod.Namespace)
+		logger.Info("No network namespace for pod, detaching networks without it", "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace)
 	}
 
+	// detach the devices best-effort, a failing device must not keep the
+	// others attached
+	var errs []error
+	detached := types.PreparedDevices{}
 	for _, device := range devices {
 		logger.Info("Detaching network", "device", device)
 		err := p.cniRuntime.DetachNetwork(ctx, pod, networkNamespace, device)
 		if err != nil {
 			logger.Error(err, "Failed to detach network", "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace)
-			return fmt.Errorf("error CNI.DetachNetwork for pod '%s' (uid: %s) in namespace '%s': %v", pod.Name, pod.Uid, pod.Namespace, err)
+			p.emitCNIFailed(events.ReasonCNIDelFailed, pod.Name, k8stypes.UID(pod.Uid), device, err)
+			errs = append(errs, fmt.Errorf("device %s: %w", device.Device.DeviceName, err))
+			continue
 		}
+		detached = append(detached, device)
 	}
-	return nil
-}
-
-// updateNetworkDeviceDataRunner is a goroutine that updates the network device data
-// for each pod in the networkDeviceDataUpdateChan.
-// we use it so we don't block the CNI ADD/DEL operations as we are limited by the NRI plugin timeout
-func (p *Plugin) updateNetworkDeviceDataRunner(ctx context.Context) {
-	for {
-		select {
-		case networkDeviceDataList := <-p.networkDeviceDataUpdateChan:
-			p.updateNetworkDeviceData(ctx, networkDeviceDataList)
-		case <-ctx.Done():
-			return
-		}
+	// devices failing to detach keep their sandbox so the DEL is retried on
+	// the next NRI Synchronize
+	if err := p.podManager.UpdatePreparedDevicesSandbox(detached, "", ""); err != nil {
+		logger.Error(err, "Failed to clear pod sandbox of prepared devices", "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace)
+	}
+	p.untrackSandbox(pod, devices)
+	if len(errs) > 0 {
+		return fmt.Errorf("error CNI.DetachNetwork for pod '%s' (uid: %s) in namespace '%s', %d of %d devices failed: %w", pod.Name, pod.Uid, pod.Namespace, len(errs), len(devices), errors.Join(errs...))
 	}
+	return nil
 }
 
-// updateNetworkDeviceData updates claim status and persisted prepared-device state
-// for each pod in the networkDataChanStructList. This runs asynchronously so CNI
-// ADD/DEL operations are not blocked by API retries.
-func (p *Plugin) updateNetworkDeviceData(ctx context.Context, networkDataChanStructList types.NetworkDataChanStructList) {
-	logger := klog.FromContext(ctx).WithName("updateNetworkDeviceData")
-	logger.Info("Updating network device data", "networkDataChanStructList", networkDataChanStructList)
-
-	groupedByClaim := p.groupNetworkDataByClaim(networkDataChanStructList)
-	logger.V(2).Info("Grouped network updates by claim", "claimCount", len(groupedByClaim))
-	for claimKey, claimUpdates := range groupedByClaim {
-		claim := &resourceapi.ResourceClaim{}
-		err := p.k8sClient.Client.Get(ctx, client.ObjectKey{
-			Name:      claimKey.name,
-			Namespace: claimKey.namespace,
-		}, claim)
+// attachNetworks runs the CNI ADD operation for each device and returns the
+// network data to report on the claims.
+func (p *Plugin) attachNetworks(ctx context.Context, pod *api.PodSandbox, networkNamespace string, devices types.PreparedDevices) (types.NetworkDataChanStructList, error) {
+	logger := klog.FromContext(ctx).WithName("attachNetworks")
+	networkDevicesData := types.NetworkDataChanStructList{}
+	for _, device := range devices {
+		networkDeviceData, cniResultMap, err := p.cniRuntime.AttachNetwork(ctx, pod, networkNamespace, device)
 		if err != nil {
-			logger.Error(err, "Failed to get claim object", "claimName", claimKey.name, "claimNamespace", claimKey.namespace)
-			continue
-		}
-
-		statusDeviceIndex := p.buildClaimStatusDeviceIndex(claim)
-		hasClaimStatusUpdates := false
-		for _, networkDataChanStruct := range claimUpdates {
-			if networkDataChanStruct == nil || networkDataChanStruct.PreparedDevice == nil {
-				logger.V(2).Info("Skipping invalid network update entry", "claimName", claimKey.name, "claimNamespace", claimKey.namespace)
-				continue
-			}
-			if err := p.podManager.UpdatePreparedDeviceNetworkData(
-				networkDataChanStruct.PreparedDevice,
-				networkDataChanStruct.NetworkDeviceData,
-			); err != nil {
-				logger.Error(err, "Failed to persist device network data before claim update", "claim", claim.UID, "deviceName", networkDataChanStruct.PreparedDevice.Device.DeviceName)
-				continue
-			}
-
-			if p.updateClaimDeviceStatus(claim, statusDeviceIndex, networkDataChanStruct) {
-				hasClaimStatusUpdates = true
-			}
-		}
-
-		if !hasClaimStatusUpdates {
-			logger.V(2).Info("No claim status updates generated for claim", "claim", claim.UID, "claimName", claimKey.name, "claimNamespace", claimKey.namespace)
-			continue
-		}
-		if err := p.updateClaimNetworkDataWithRetry(ctx, claim); err != nil {
-			logger.Error(err, "Failed to update claim network data", "claim", claim.UID)
-			continue
+			logger.Error(err, "Failed to attach network", "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace)
+			p.emitCNIFailed(events.ReasonCNIAddFailed, pod.Name, k8stypes.UID(pod.Uid), device, err)
+			return nil, fmt.Errorf("failed to attach network: %w", err)
 		}
-		logger.V(2).Info("Successfully updated claim network data", "claim", claim.UID, "claimName", claimKey.name, "claimNamespace", claimKey.namespace)
+		networkDevicesData = append(networkDevicesData, &types.NetworkDataChanStruct{
+			PreparedDevice:    device,
+			NetworkDeviceData: networkDeviceData,
+			CNIConfig:         cniConfigMap(ctx, device),
+			CNIResult:         cniResultMap,
+			SpanContext:       trace.SpanContextFromContext(ctx),
+		})
+		logger.Info("Attached network", "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace, "networkDeviceData", networkDeviceData)
 	}
-}
-
-type networkClaimKey struct {
-	namespace string
-	name      string
+	return networkDevicesData, nil
 }
 
 type claimStatusDeviceKey struct {
@@ -249,24 +371,6 @@ type claimStatusDeviceKey struct {
 	device string
 }
 
-func (p *Plugin) groupNetworkDataByClaim(
-	networkDataChanStructList types.NetworkDataChanStructList,
-) map[networkClaimKey]types.NetworkDataChanStructList {
-	grouped := make(map[networkClaimKey]types.NetworkDataChanStructList)
-	for _, item := range networkDataChanStructList {
-		if item == nil || item.PreparedDevice == nil {
-			continue
-		}
-		claim := item.PreparedDevice.ClaimNamespacedName
-		key := networkClaimKey{
-			namespace: claim.Namespace,
-			name:      claim.Name,
-		}
-		grouped[key] = append(grouped[key], item)
-	}
-	return grouped
-}
-
 func (p *Plugin) buildClaimStatusDeviceIndex(claim *resourceapi.ResourceClaim) map[claimStatusDeviceKey][]int {
 	index := make(map[claimStatusDeviceKey][]int, len(claim.Status.Devices))
 	for idx, device := range claim.Status.Devices {
@@ -303,13 +407,22 @@ func (p *Plugin) updateClaimDeviceStatus(
 	}
 	raw, rawErr := json.Marshal(combined)
 
+	updated := false
 	for _, idx := range deviceIndexes {
+		// Updates rebuilt from the checkpoint carry no CNI result, leave the
+		// status alone when it already reports their network data.
+		if networkDataChanStruct.CNIResult == nil &&
+			networkDataChanStruct.NetworkDeviceData != nil &&
+			apiequality.Semantic.DeepEqual(claim.Status.Devices[idx].NetworkData, networkDataChanStruct.NetworkDeviceData) {
+			continue
+		}
+		updated = true
 		claim.Status.Devices[idx].NetworkData = networkDataChanStruct.NetworkDeviceData
 		if rawErr == nil {
 			claim.Status.Devices[idx].Data = &runtime.RawExtension{Raw: raw}
 		}
 	}
-	return true
+	return updated
 }
 
 // updateRequestMetadataBeforeSandboxStart refreshes kubelet plugin request metadata
@@ -427,40 +540,3 @@ func (p *Plugin) buildRequestMetadataUpdates(
 	logger.V(2).Info("Built request metadata updates", "requestUpdateCount", len(updates))
 	return updates
 }
-
-// updateClaimNetworkDataWithRetry updates the network device data for a claim with retries.
-func (p *Plugin) updateClaimNetworkDataWithRetry(ctx context.Context, claim *resourceapi.ResourceClaim) error {
-	logger := klog.FromContext(ctx).WithName("updateClaimNetworkDataWithRetry")
-	originalDevices := claim.Status.Devices
-	err := wait.ExponentialBackoffWithContext(ctx, consts.Backoff, func(ctx context.Context) (bool, error) {
-		_, updateErr := p.k8sClient.ResourceV1().ResourceClaims(claim.Namespace).UpdateStatus(ctx, claim, metav1.UpdateOptions{})
-		if updateErr != nil {
-			// If this is a conflict error, fetch fresh claim and copy over devices list
-			if apierrors.IsConflict(updateErr) {
-				logger.V(2).Info("Conflict detected, refreshing claim", "claim", claim.UID)
-
-				freshClaim, fetchErr := p.k8sClient.ResourceV1().ResourceClaims(claim.Namespace).Get(ctx, claim.Name, metav1.GetOptions{})
-				if fetchErr != nil {
-					logger.V(2).Info("Failed to fetch fresh claim", "claim", claim.UID, "error", fetchErr.Error())
-					return false, nil // Continue retrying
-				}
-
-				// Copy original devices list to fresh claim
-				freshClaim.Status.Devices = originalDevices
-				claim = freshClaim // Use fresh claim for next retry
-
-				logger.V(2).Info("Refreshed claim, retrying status update", "claim", claim.UID)
-			} else {
-				logger.V(2).Info("Retrying claim status update", "claim", claim.UID, "error", updateErr.Error())
-			}
-			return false, nil // Return false to continue retrying, nil to not fail immediately
-		}
-		return true, nil // Success
-	})
-
-	if err != nil {
-		logger.Error(err, "Failed to update claim status after retries", "claim", claim.UID)
-		return err
-	}
-	return nil
-}
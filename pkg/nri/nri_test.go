@@ -19,6 +19,7 @@ import (
 	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
 	ctrlclientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
 	cnimock "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cni/mock"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/flags"
@@ -84,11 +85,11 @@ var _ = Describe("NRI Plugin", func() {
 		}
 
 		plugin = &Plugin{
-			podManager:                  podManager,
-			cniRuntime:                  mockCNI,
-			k8sClient:                   cfg.K8sClient,
-			interfacePrefix:             flags.DefaultInterfacePrefix,
-			networkDeviceDataUpdateChan: make(chan types.NetworkDataChanStructList, 10),
+			podManager:       podManager,
+			cniRuntime:       mockCNI,
+			k8sClient:        cfg.K8sClient,
+			interfacePrefix:  flags.DefaultInterfacePrefix,
+			claimStatusQueue: newClaimStatusQueue(),
 			// don't initialize stub here; Start/Stop are not exercised in unit tests
 		}
 	})
@@ -109,13 +110,37 @@ var _ = Describe("NRI Plugin", func() {
 		Expect(podManager.Set(k8stypes.UID(pod.Uid), k8stypes.UID("claim-1"), prepared)).To(Succeed())
 
 		mockCNI.EXPECT().
-			AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).
+			AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", sameDevice(prepared[0])).
 			Return(nil, map[string]interface{}{"dummy": true}, nil)
 
 		// The goroutine uses a channel to update claim status; we don't rely on it here
 		Expect(plugin.RunPodSandbox(ctx, pod)).To(Succeed())
 	})
 
+	It("leaves the devices of MULTUS claims to Multus in HYBRID mode", func() {
+		prepared := types.PreparedDevices{
+			&types.PreparedDevice{
+				IfName:             "vfnet0",
+				NetAttachDefConfig: `{"type":"sriov","name":"net1"}`,
+				PciAddress:         "0000:00:00.1",
+				PodUID:             pod.Uid,
+			},
+			&types.PreparedDevice{
+				PciAddress: "0000:00:00.2",
+				PodUID:     pod.Uid,
+				Config:     &configapi.VfConfig{ConfigurationMode: string(consts.ConfigurationModeMultus)},
+			},
+		}
+		Expect(podManager.Set(k8stypes.UID(pod.Uid), k8stypes.UID("claim-1"), prepared)).To(Succeed())
+
+		mockCNI.EXPECT().
+			AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", sameDevice(prepared[0])).
+			Return(nil, nil, nil)
+
+		Expect(plugin.RunPodSandbox(ctx, pod)).To(Succeed())
+		Expect(prepared[1].SandboxID).To(BeEmpty())
+	})
+
 	It("returns error when CNI attach fails", func() {
 		prepared := types.PreparedDevices{
 			&types.PreparedDevice{
@@ -128,7 +153,7 @@ var _ = Describe("NRI Plugin", func() {
 		Expect(podManager.Set(k8stypes.UID(pod.Uid), k8stypes.UID("claim-1"), prepared)).To(Succeed())
 
 		mockCNI.EXPECT().
-			AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).
+			AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", sameDevice(prepared[0])).
 			Return(nil, nil, errors.New("boom"))
 
 		err := plugin.RunPodSandbox(ctx, pod)
@@ -186,7 +211,7 @@ var _ = Describe("NRI Plugin", func() {
 			IPs:           []string{"10.10.0.10/24"},
 		}
 		mockCNI.EXPECT().
-			AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).
+			AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", sameDevice(prepared[0])).
 			Return(expectedNetworkData, map[string]interface{}{"dummy": true}, nil)
 
 		Expect(plugin.RunPodSandbox(ctx, pod)).To(Succeed())
@@ -232,7 +257,7 @@ var _ = Describe("NRI Plugin", func() {
 		Expect(podManager.Set(k8stypes.UID(pod.Uid), claimUID, prepared)).To(Succeed())
 
 		mockCNI.EXPECT().
-			AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).
+			AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", sameDevice(prepared[0])).
 			Return(&resourceapi.NetworkDeviceData{InterfaceName: "net1"}, map[string]interface{}{"dummy": true}, nil)
 
 		err := plugin.RunPodSandbox(ctx, pod)
@@ -252,7 +277,7 @@ var _ = Describe("NRI Plugin", func() {
 		Expect(podManager.Set(k8stypes.UID(pod.Uid), k8stypes.UID("claim-1"), prepared)).To(Succeed())
 
 		mockCNI.EXPECT().
-			DetachNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).
+			DetachNetwork(gomock.Any(), pod, "/proc/123/ns/net", sameDevice(prepared[0])).
 			Return(nil)
 
 		Expect(plugin.StopPodSandbox(ctx, pod)).To(Succeed())
@@ -308,13 +333,73 @@ var _ = Describe("NRI Plugin", func() {
 		Expect(podManager.Set(k8stypes.UID(pod.Uid), k8stypes.UID("claim-1"), prepared)).To(Succeed())
 
 		mockCNI.EXPECT().
-			DetachNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).
+			DetachNetwork(gomock.Any(), pod, "/proc/123/ns/net", sameDevice(prepared[0])).
 			Return(errors.New("detach failed"))
 
 		err := plugin.StopPodSandbox(ctx, pod)
 		Expect(err).To(HaveOccurred())
 		Expect(err.Error()).To(ContainSubstring("detach"))
 	})
+
+	It("detaches the other devices when one fails in StopPodSandbox", func() {
+		prepared := types.PreparedDevices{
+			&types.PreparedDevice{
+				Device:              drapbv1.Device{DeviceName: "dev-a"},
+				ClaimNamespacedName: kubeletplugin.NamespacedObject{UID: "claim-1"},
+				IfName:              "vfnet0",
+				PciAddress:          "0000:00:00.1",
+				PodUID:              pod.Uid,
+			},
+			&types.PreparedDevice{
+				Device:              drapbv1.Device{DeviceName: "dev-b"},
+				ClaimNamespacedName: kubeletplugin.NamespacedObject{UID: "claim-1"},
+				IfName:              "vfnet1",
+				PciAddress:          "0000:00:00.2",
+				PodUID:              pod.Uid,
+			},
+		}
+		Expect(podManager.Set(k8stypes.UID(pod.Uid), k8stypes.UID("claim-1"), prepared)).To(Succeed())
+		Expect(podManager.UpdatePreparedDevicesSandbox(prepared, pod.Id, "/proc/123/ns/net")).To(Succeed())
+
+		mockCNI.EXPECT().
+			DetachNetwork(gomock.Any(), pod, "/proc/123/ns/net", sameDevice(prepared[0])).
+			Return(errors.New("ipam release failed"))
+		mockCNI.EXPECT().
+			DetachNetwork(gomock.Any(), pod, "/proc/123/ns/net", sameDevice(prepared[1])).
+			Return(nil)
+
+		err := plugin.StopPodSandbox(ctx, pod)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("1 of 2 devices failed"))
+		Expect(err.Error()).To(ContainSubstring("device dev-a: ipam release failed"))
+		stored, found := podManager.Get(k8stypes.UID(pod.Uid), k8stypes.UID("claim-1"))
+		Expect(found).To(BeTrue())
+		Expect(stored[0].SandboxID).To(Equal(pod.Id))
+		Expect(stored[1].SandboxID).To(BeEmpty())
+	})
+
+	It("runs CNI DEL without network namespace in StopPodSandbox", func() {
+		prepared := types.PreparedDevices{
+			&types.PreparedDevice{
+				IfName:     "vfnet0",
+				PciAddress: "0000:00:00.1",
+				PodUID:     pod.Uid,
+			},
+		}
+		Expect(podManager.Set(k8stypes.UID(pod.Uid), k8stypes.UID("claim-1"), prepared)).To(Succeed())
+
+		podNoNetNS := &api.PodSandbox{
+			Id:        pod.Id,
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+			Uid:       pod.Uid,
+		}
+		mockCNI.EXPECT().
+			DetachNetwork(gomock.Any(), podNoNetNS, "", sameDevice(prepared[0])).
+			Return(nil)
+
+		Expect(plugin.StopPodSandbox(ctx, podNoNetNS)).To(Succeed())
+	})
 })
 
 var _ = Describe("NRI Plugin Creation", func() {
@@ -337,7 +422,7 @@ var _ = Describe("NRI Plugin Creation", func() {
 		defer ctrl.Finish()
 		mockCNI := cnimock.NewMockInterface(ctrl)
 
-		plugin, err := NewNRIPlugin(cfg, podManager, mockCNI, nil)
+		plugin, err := NewNRIPlugin(cfg, podManager, mockCNI, nil, nil)
 		// NRI stub creation will fail in test environment (no NRI socket/runtime)
 		// but we can verify the function at least initializes fields and attempts creation
 		if err == nil {
@@ -345,7 +430,7 @@ var _ = Describe("NRI Plugin Creation", func() {
 			Expect(plugin.podManager).To(Equal(podManager))
 			Expect(plugin.cniRuntime).To(Equal(mockCNI))
 			Expect(plugin.interfacePrefix).To(Equal("net"))
-			Expect(plugin.networkDeviceDataUpdateChan).ToNot(BeNil())
+			Expect(plugin.claimStatusQueue).ToNot(BeNil())
 		} else {
 			// Expected to fail without NRI runtime - could fail for various reasons
 			// (e.g., invalid plugin name in test, no NRI socket, etc.)
@@ -354,17 +439,39 @@ var _ = Describe("NRI Plugin Creation", func() {
 	})
 })
 
-var _ = Describe("NRI Update Network Device Data Runner", func() {
+var _ = Describe("CDI Hook Plugin Creation", func() {
+	It("creates the plugin without registering to NRI", func() {
+		cfg := &types.Config{
+			Flags: &types.Flags{
+				DefaultInterfacePrefix:      "net",
+				KubeletPluginsDirectoryPath: GinkgoT().TempDir(),
+				PodUID:                      "pod-uid",
+			},
+		}
+		podManager, err := podmanager.NewPodManager(&types.Config{Flags: &types.Flags{KubeletPluginsDirectoryPath: cfg.Flags.KubeletPluginsDirectoryPath}})
+		Expect(err).ToNot(HaveOccurred())
+		mockCNI := cnimock.NewMockInterface(gomock.NewController(GinkgoT()))
+
+		plugin := NewCDIHookPlugin(cfg, podManager, mockCNI, nil, nil)
+		Expect(plugin.stub).To(BeNil())
+		Expect(plugin.lockPath).To(BeEmpty())
+		Expect(plugin.podManager).To(Equal(podManager))
+		Expect(plugin.interfacePrefix).To(Equal("net"))
+		Expect(plugin.claimStatusQueue).ToNot(BeNil())
+	})
+})
+
+var _ = Describe("NRI Claim Status Worker", func() {
 	It("stops when context is cancelled", func() {
 		ctx, cancel := context.WithCancel(context.Background())
 
 		plugin := &Plugin{
-			networkDeviceDataUpdateChan: make(chan types.NetworkDataChanStructList, 10),
+			claimStatusQueue: newClaimStatusQueue(),
 		}
 
 		done := make(chan bool)
 		go func() {
-			plugin.updateNetworkDeviceDataRunner(ctx)
+			plugin.runClaimStatusWorker(ctx)
 			done <- true
 		}()
 
@@ -536,7 +643,8 @@ var _ = Describe("NRI updateNetworkDeviceData ordering", func() {
 		}
 
 		plugin := &Plugin{
-			podManager: pm,
+			podManager:       pm,
+			claimStatusQueue: newClaimStatusQueue(),
 			k8sClient: flags.ClientSets{
 				Interface: k8sfake.NewSimpleClientset(claim.DeepCopy()),
 				Client:    ctrlclientfake.NewClientBuilder().WithScheme(flags.Scheme).WithRuntimeObjects(claim.DeepCopy()).Build(),
@@ -555,7 +663,8 @@ var _ = Describe("NRI updateNetworkDeviceData ordering", func() {
 			},
 		}
 
-		plugin.updateNetworkDeviceData(context.Background(), networkDataList)
+		Expect(plugin.enqueueNetworkDeviceData(networkDataList)).To(MatchError(ContainSubstring("failed to persist device network data")))
+		Expect(plugin.claimStatusQueue.Len()).To(BeZero())
 
 		updatedClaim, err := plugin.k8sClient.ResourceV1().ResourceClaims("default").Get(context.Background(), "claim-a", metav1.GetOptions{})
 		Expect(err).NotTo(HaveOccurred())
@@ -610,7 +719,8 @@ var _ = Describe("NRI updateNetworkDeviceData ordering", func() {
 		}
 
 		plugin := &Plugin{
-			podManager: pm,
+			podManager:       pm,
+			claimStatusQueue: newClaimStatusQueue(),
 			k8sClient: flags.ClientSets{
 				Interface: k8sfake.NewSimpleClientset(claim.DeepCopy()),
 				Client:    ctrlclientfake.NewClientBuilder().WithScheme(flags.Scheme).WithRuntimeObjects(claim.DeepCopy()).Build(),
@@ -631,7 +741,8 @@ var _ = Describe("NRI updateNetworkDeviceData ordering", func() {
 			},
 		}
 
-		plugin.updateNetworkDeviceData(context.Background(), networkDataList)
+		plugin.enqueueNetworkDeviceData(networkDataList)
+		Expect(plugin.processNextClaimUpdate(context.Background())).To(BeTrue())
 
 		updatedClaim, err := plugin.k8sClient.ResourceV1().ResourceClaims("default").Get(context.Background(), "claim-a", metav1.GetOptions{})
 		Expect(err).NotTo(HaveOccurred())
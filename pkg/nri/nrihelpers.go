@@ -1,7 +1,16 @@
 package nri
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/containerd/nri/pkg/api"
+	"go.opentelemetry.io/otel/attribute"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/events"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/tracing"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 )
 
 func getNetworkNamespace(pod *api.PodSandbox) string {
@@ -13,3 +22,55 @@ func getNetworkNamespace(pod *api.PodSandbox) string {
 
 	return ""
 }
+
+// networkNamespaceExists reports whether a network namespace path is still
+// present, it disappears once the pod sandbox is stopped.
+func networkNamespaceExists(path string) bool {
+	if path == "" {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// podNetworkDevices returns the prepared devices of a pod whose network the
+// driver attaches, and whether there is any.
+func (p *Plugin) podNetworkDevices(podUID k8stypes.UID) (types.PreparedDevices, bool) {
+	devices, _ := p.podManager.GetDevicesByPodUID(podUID)
+	devices = networkDevices(devices)
+	return devices, len(devices) > 0
+}
+
+// networkDevices filters out the devices of MULTUS claims, in HYBRID mode
+// Multus attaches them, and the devices recovered without their network
+// configuration.
+func networkDevices(devices types.PreparedDevices) types.PreparedDevices {
+	filtered := types.PreparedDevices{}
+	for _, device := range devices {
+		if device.Phase == types.DevicePhaseRecovered {
+			continue
+		}
+		if device.Config == nil || !device.Config.IsMultusConfigurationMode() {
+			filtered = append(filtered, device)
+		}
+	}
+	return filtered
+}
+
+// podSpanAttributes returns the span attributes of a pod sandbox.
+func podSpanAttributes(pod *api.PodSandbox) []attribute.KeyValue {
+	return append(tracing.PodAttributes(pod.GetNamespace(), pod.GetName(), k8stypes.UID(pod.GetUid())),
+		tracing.AttributeSandboxID.String(pod.GetId()))
+}
+
+// emitCNIFailed emits a Warning event on the claim of a device and on its pod
+// for a failing CNI operation.
+func (p *Plugin) emitCNIFailed(reason string, podName string, podUID k8stypes.UID, device *types.PreparedDevice, err error) {
+	if podName == "" {
+		podName = device.PodName
+	}
+	claim := device.ClaimNamespacedName
+	events.Warning(p.recorder, reason, fmt.Sprintf("network of device %s: %v", device.Device.DeviceName, err),
+		events.ClaimReference(claim.Namespace, claim.Name, claim.UID),
+		events.PodReference(claim.Namespace, podName, podUID))
+}
@@ -0,0 +1,125 @@
+package nri
+
+import (
+	"context"
+	"slices"
+
+	"github.com/containerd/nri/pkg/api"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/events"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// Synchronize reconciles the pod sandboxes known to the runtime with the
+// prepared devices when the plugin connects, so sandboxes started or stopped
+// while the driver was down get their missing CNI ADD or DEL. Devices record
+// the sandbox they are attached to, so devices of a running sandbox are only
+// attached when they record none, and only devices recording another sandbox
+// are detached. Checkpoints of versions not recording the sandbox hold the
+// network data of attached devices only, such devices get the sandbox
+// recorded instead of being attached again.
+// Failures are logged and never returned as they would prevent the plugin
+// from registering.
+func (p *Plugin) Synchronize(ctx context.Context, pods []*api.PodSandbox, _ []*api.Container) ([]*api.ContainerUpdate, error) {
+	logger := klog.FromContext(ctx).WithName("NRI Synchronize")
+	logger.Info("Synchronize", "pods", len(pods))
+
+	running := make(map[k8stypes.UID]*api.PodSandbox, len(pods))
+	for _, pod := range pods {
+		if networkNamespaceExists(getNetworkNamespace(pod)) {
+			running[k8stypes.UID(pod.Uid)] = pod
+		}
+	}
+
+	networkDevicesData := types.NetworkDataChanStructList{}
+	for podUID, devices := range p.podManager.ListDevicesByPodUID() {
+		devices = networkDevices(devices)
+		pod, found := running[podUID]
+
+		stale := types.PreparedDevices{}
+		for _, device := range devices {
+			if device.SandboxID != "" && (!found || device.SandboxID != pod.Id) {
+				stale = append(stale, device)
+			}
+		}
+		networkDevicesData = append(networkDevicesData, p.detachStaleDevices(ctx, podUID, stale)...)
+
+		if !found {
+			continue
+		}
+
+		networkNamespace := getNetworkNamespace(pod)
+		missing := types.PreparedDevices{}
+		attachedBefore := types.PreparedDevices{}
+		for _, device := range devices {
+			switch {
+			case device.SandboxID != "":
+			case device.NetworkDeviceData != nil && !slices.Contains(stale, device):
+				attachedBefore = append(attachedBefore, device)
+			default:
+				missing = append(missing, device)
+			}
+		}
+		if len(attachedBefore) > 0 {
+			logger.Info("Recording pod sandbox of attached devices", "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace, "devices", len(attachedBefore))
+			if err := p.podManager.UpdatePreparedDevicesSandbox(attachedBefore, pod.Id, networkNamespace); err != nil {
+				logger.Error(err, "Failed to record pod sandbox for prepared devices", "pod.UID", pod.Uid)
+			}
+		}
+		if len(missing) > 0 {
+			logger.Info("Attaching networks missed while the driver was down", "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace, "devices", len(missing))
+			if err := p.podManager.UpdatePreparedDevicesSandbox(missing, pod.Id, networkNamespace); err != nil {
+				logger.Error(err, "Failed to record pod sandbox for prepared devices", "pod.UID", pod.Uid)
+				continue
+			}
+			attached, err := p.attachNetworks(ctx, pod, networkNamespace, missing)
+			if err != nil {
+				logger.Error(err, "Failed to attach networks", "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace)
+				continue
+			}
+			networkDevicesData = append(networkDevicesData, attached...)
+		}
+		p.trackSandbox(pod)
+	}
+
+	if err := p.enqueueNetworkDeviceData(networkDevicesData); err != nil {
+		logger.Error(err, "Failed to persist network data")
+	}
+	return nil, nil
+}
+
+// detachStaleDevices runs the CNI DEL operation for devices still attached to
+// a sandbox that no longer runs. It returns updates clearing the network data
+// of the detached devices.
+func (p *Plugin) detachStaleDevices(ctx context.Context, podUID k8stypes.UID, devices types.PreparedDevices) types.NetworkDataChanStructList {
+	logger := klog.FromContext(ctx).WithName("detachStaleDevices")
+	networkDevicesData := types.NetworkDataChanStructList{}
+	for _, device := range devices {
+		pod := &api.PodSandbox{
+			Id:  device.SandboxID,
+			Uid: string(podUID),
+		}
+		// CNI DEL accepts an empty network namespace once it is gone.
+		networkNamespace := device.NetworkNamespace
+		if !networkNamespaceExists(networkNamespace) {
+			networkNamespace = ""
+		}
+
+		logger.Info("Detaching network of stopped sandbox", "deviceName", device.Device.DeviceName, "pod.UID", podUID, "sandboxID", device.SandboxID)
+		if err := p.cniRuntime.DetachNetwork(ctx, pod, networkNamespace, device); err != nil {
+			logger.Error(err, "Failed to detach network", "deviceName", device.Device.DeviceName, "pod.UID", podUID, "sandboxID", device.SandboxID)
+			p.emitCNIFailed(events.ReasonCNIDelFailed, "", podUID, device, err)
+			continue
+		}
+		if err := p.podManager.UpdatePreparedDevicesSandbox(types.PreparedDevices{device}, "", ""); err != nil {
+			logger.Error(err, "Failed to clear pod sandbox of prepared device", "deviceName", device.Device.DeviceName, "pod.UID", podUID)
+			continue
+		}
+		networkDevicesData = append(networkDevicesData, &types.NetworkDataChanStruct{
+			PreparedDevice: device,
+		})
+	}
+	return networkDevicesData
+}
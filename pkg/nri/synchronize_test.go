@@ -0,0 +1,166 @@
+package nri
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/containerd/nri/pkg/api"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	resourceapi "k8s.io/api/resource/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+
+	cnimock "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cni/mock"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("NRI Synchronize", func() {
+	var (
+		ctx       context.Context
+		mockCNI   *cnimock.MockInterface
+		pm        *podmanager.PodManager
+		plugin    *Plugin
+		netnsPath string
+		pod       *api.PodSandbox
+		prepared  types.PreparedDevices
+	)
+
+	// stored returns the prepared device as tracked by the PodManager.
+	stored := func() *types.PreparedDevice {
+		devices, found := pm.Get(k8stypes.UID(pod.Uid), prepared[0].ClaimNamespacedName.UID)
+		Expect(found).To(BeTrue())
+		return devices[0]
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		ctrl := gomock.NewController(GinkgoT())
+		mockCNI = cnimock.NewMockInterface(ctrl)
+
+		tmpDir := GinkgoT().TempDir()
+		cfg := &types.Config{Flags: &types.Flags{KubeletPluginsDirectoryPath: tmpDir}}
+		var err error
+		pm, err = podmanager.NewPodManager(cfg)
+		Expect(err).NotTo(HaveOccurred())
+
+		// a regular file stands in for the network namespace of a running sandbox
+		netnsPath = filepath.Join(tmpDir, "netns")
+		Expect(os.WriteFile(netnsPath, nil, 0o600)).To(Succeed())
+
+		pod = &api.PodSandbox{
+			Id:        "sandbox-2",
+			Name:      "pod-name",
+			Namespace: "default",
+			Uid:       "pod-uid",
+			Linux: &api.LinuxPodSandbox{
+				Namespaces: []*api.LinuxNamespace{{Type: "network", Path: netnsPath}},
+			},
+		}
+
+		claimUID := k8stypes.UID("claim-uid")
+		prepared = types.PreparedDevices{
+			{
+				ClaimNamespacedName: kubeletplugin.NamespacedObject{
+					NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "claim"},
+					UID:            claimUID,
+				},
+				Device: drapbv1.Device{PoolName: "pool", DeviceName: "dev"},
+				IfName: "net1",
+			},
+		}
+		Expect(pm.Set(k8stypes.UID(pod.Uid), claimUID, prepared)).To(Succeed())
+
+		plugin = &Plugin{
+			podManager:       pm,
+			cniRuntime:       mockCNI,
+			claimStatusQueue: newClaimStatusQueue(),
+		}
+	})
+
+	It("attaches devices of running sandboxes that missed CNI ADD", func() {
+		networkData := &resourceapi.NetworkDeviceData{InterfaceName: "net1"}
+		mockCNI.EXPECT().AttachNetwork(gomock.Any(), pod, netnsPath, sameDevice(prepared[0])).Return(networkData, nil, nil)
+
+		updates, err := plugin.Synchronize(ctx, []*api.PodSandbox{pod}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updates).To(BeEmpty())
+
+		Expect(stored().SandboxID).To(Equal(pod.Id))
+		Expect(stored().NetworkNamespace).To(Equal(netnsPath))
+		queued := pendingNetworkData(plugin)
+		Expect(queued).To(HaveLen(1))
+		Expect(queued[0].NetworkDeviceData).To(Equal(networkData))
+		Expect(plugin.runningSandboxes()).To(ConsistOf(pod))
+	})
+
+	It("does not attach devices already attached to the sandbox", func() {
+		Expect(pm.UpdatePreparedDevicesSandbox(prepared, pod.Id, netnsPath)).To(Succeed())
+
+		_, err := plugin.Synchronize(ctx, []*api.PodSandbox{pod}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pendingNetworkData(plugin)).To(BeEmpty())
+		Expect(plugin.runningSandboxes()).To(ConsistOf(pod))
+	})
+
+	It("records the sandbox of devices attached before sandboxes were recorded", func() {
+		networkData := &resourceapi.NetworkDeviceData{InterfaceName: "net1"}
+		Expect(pm.UpdatePreparedDeviceNetworkData(prepared[0], networkData)).To(Succeed())
+
+		_, err := plugin.Synchronize(ctx, []*api.PodSandbox{pod}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(stored().SandboxID).To(Equal(pod.Id))
+		Expect(stored().NetworkNamespace).To(Equal(netnsPath))
+		Expect(stored().NetworkDeviceData).To(Equal(networkData))
+		Expect(pendingNetworkData(plugin)).To(BeEmpty())
+		Expect(plugin.runningSandboxes()).To(ConsistOf(pod))
+	})
+
+	It("detaches devices of sandboxes stopped while the driver was down", func() {
+		goneNetns := filepath.Join(GinkgoT().TempDir(), "gone")
+		Expect(pm.UpdatePreparedDevicesSandbox(prepared, "sandbox-1", goneNetns)).To(Succeed())
+		mockCNI.EXPECT().DetachNetwork(gomock.Any(), &api.PodSandbox{Id: "sandbox-1", Uid: pod.Uid}, "", sameDevice(prepared[0])).Return(nil)
+
+		_, err := plugin.Synchronize(ctx, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(stored().SandboxID).To(BeEmpty())
+		queued := pendingNetworkData(plugin)
+		Expect(queued).To(HaveLen(1))
+		Expect(queued[0].NetworkDeviceData).To(BeNil())
+	})
+
+	It("detaches the previous sandbox before attaching the new one", func() {
+		Expect(pm.UpdatePreparedDevicesSandbox(prepared, "sandbox-1", "")).To(Succeed())
+		gomock.InOrder(
+			mockCNI.EXPECT().DetachNetwork(gomock.Any(), &api.PodSandbox{Id: "sandbox-1", Uid: pod.Uid}, "", sameDevice(prepared[0])).Return(nil),
+			mockCNI.EXPECT().AttachNetwork(gomock.Any(), pod, netnsPath, sameDevice(prepared[0])).Return(&resourceapi.NetworkDeviceData{}, nil, nil),
+		)
+
+		_, err := plugin.Synchronize(ctx, []*api.PodSandbox{pod}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stored().SandboxID).To(Equal(pod.Id))
+	})
+
+	It("ignores sandboxes whose network namespace is gone", func() {
+		pod.Linux.Namespaces[0].Path = filepath.Join(GinkgoT().TempDir(), "gone")
+
+		_, err := plugin.Synchronize(ctx, []*api.PodSandbox{pod}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pendingNetworkData(plugin)).To(BeEmpty())
+	})
+})
+
+// sameDevice matches a copy of the prepared device.
+func sameDevice(device *types.PreparedDevice) gomock.Matcher {
+	return gomock.Cond(func(x any) bool {
+		other, ok := x.(*types.PreparedDevice)
+		return ok && other.ClaimNamespacedName.UID == device.ClaimNamespacedName.UID &&
+			other.Device.DeviceName == device.Device.DeviceName
+	})
+}
@@ -0,0 +1,340 @@
+package podmanager_test
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/sys/unix"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	"k8s.io/kubernetes/pkg/kubelet/checkpointmanager/checksum"
+	cdiapi "tags.cncf.io/container-device-interface/pkg/cdi"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/flags"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+	draTypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("PodManager checkpoint", func() {
+	var (
+		config   *draTypes.Config
+		podUID   types.UID
+		claimUID types.UID
+		devices  draTypes.PreparedDevices
+	)
+
+	checkpointPath := func(name string) string {
+		return filepath.Join(config.DriverPluginPath(), name)
+	}
+
+	readCheckpoint := func(name string) *draTypes.Checkpoint {
+		data, err := os.ReadFile(checkpointPath(name))
+		Expect(err).NotTo(HaveOccurred())
+		checkpoint := &draTypes.Checkpoint{}
+		Expect(checkpoint.UnmarshalCheckpoint(data)).To(Succeed())
+		Expect(checkpoint.VerifyChecksum()).To(Succeed())
+		return checkpoint
+	}
+
+	BeforeEach(func() {
+		tempDir, err := os.MkdirTemp("", "podmanager-checkpoint-test-*")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, tempDir)
+
+		config = &draTypes.Config{
+			Flags: &draTypes.Flags{
+				KubeletPluginsDirectoryPath: tempDir,
+				NodeName:                    "node-a",
+			},
+			K8sClient: flags.ClientSets{},
+		}
+		Expect(os.MkdirAll(config.DriverPluginPath(), 0o750)).To(Succeed())
+
+		podUID = types.UID("pod-uid")
+		claimUID = types.UID("claim-uid")
+		devices = draTypes.PreparedDevices{{
+			Device: drapbv1.Device{DeviceName: "0000-01-00-1"},
+			ClaimNamespacedName: kubeletplugin.NamespacedObject{
+				NamespacedName: types.NamespacedName{Namespace: "default", Name: "claim"},
+				UID:            claimUID,
+			},
+			PciAddress: "0000:01:00.1",
+		}}
+	})
+
+	It("writes version 2 checkpoints keyed by claim", func() {
+		pm, err := podmanager.NewPodManager(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pm.Set(podUID, claimUID, devices)).To(Succeed())
+
+		checkpoint := readCheckpoint(consts.DriverPluginCheckpointFile)
+		Expect(checkpoint.V1.PreparedClaimsByPodUID[podUID]).To(HaveKey(claimUID))
+		Expect(checkpoint.V2.PreparedClaims).To(HaveKey(claimUID))
+		claim := checkpoint.V2.PreparedClaims[claimUID]
+		Expect(claim.Namespace).To(Equal("default"))
+		Expect(claim.Name).To(Equal("claim"))
+		Expect(claim.Consumers).To(Equal([]types.UID{podUID}))
+		Expect(claim.Devices[0].Phase).To(Equal(draTypes.DevicePhasePrepared))
+		Expect(claim.Devices[0].PreparedTime).NotTo(BeNil())
+		Expect(claim.Devices[0].PhaseTime).NotTo(BeNil())
+	})
+
+	It("writes checkpoints the versions before version 2 load", func() {
+		pm, err := podmanager.NewPodManager(config)
+		Expect(err).NotTo(HaveOccurred())
+		devices[0].SandboxID = "sandbox-1"
+		devices[0].Config = &configapi.VfConfig{Driver: "vfio-pci", CNIConfig: &runtime.RawExtension{Raw: []byte(`{"type":"sriov"}`)}}
+		Expect(pm.Set(podUID, claimUID, devices)).To(Succeed())
+
+		// the checksum verification of the versions before version 2
+		data, err := os.ReadFile(checkpointPath(consts.DriverPluginCheckpointFile))
+		Expect(err).NotTo(HaveOccurred())
+		checkpoint := &v1Checkpoint{}
+		Expect(json.Unmarshal(data, checkpoint)).To(Succeed())
+		ck := checkpoint.Checksum
+		checkpoint.Checksum = 0
+		out, err := json.Marshal(*checkpoint)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ck.Verify(out)).To(Succeed())
+
+		loaded := checkpoint.V1.PreparedClaimsByPodUID[podUID][claimUID]
+		Expect(loaded).To(HaveLen(1))
+		Expect(loaded[0].PciAddress).To(Equal("0000:01:00.1"))
+		Expect(loaded[0].Config.Driver).To(Equal("vfio-pci"))
+	})
+
+	It("tracks the phase of the devices", func() {
+		pm, err := podmanager.NewPodManager(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pm.Set(podUID, claimUID, devices)).To(Succeed())
+
+		Expect(pm.UpdatePreparedDevicesSandbox(devices, "sandbox-1", "/var/run/netns/test")).To(Succeed())
+		Expect(devices[0].Phase).To(Equal(draTypes.DevicePhaseAttached))
+		Expect(pm.UpdatePreparedDevicesSandbox(devices, "", "")).To(Succeed())
+		Expect(devices[0].Phase).To(Equal(draTypes.DevicePhasePrepared))
+	})
+
+	It("migrates version 1 checkpoints", func() {
+		devices[0].SandboxID = "sandbox-1"
+		v1 := &draTypes.Checkpoint{V1: &draTypes.CheckpointV1{PreparedClaimsByPodUID: draTypes.PreparedClaimsByPodUID{
+			podUID: {claimUID: devices},
+		}}}
+		data, err := v1.MarshalCheckpoint()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(checkpointPath(consts.DriverPluginCheckpointFile), data, 0o600)).To(Succeed())
+
+		pm, err := podmanager.NewPodManager(config)
+		Expect(err).NotTo(HaveOccurred())
+		loaded, found := pm.Get(podUID, claimUID)
+		Expect(found).To(BeTrue())
+		Expect(loaded[0].PciAddress).To(Equal("0000:01:00.1"))
+		Expect(loaded[0].Phase).To(Equal(draTypes.DevicePhaseAttached))
+
+		Expect(readCheckpoint(consts.DriverPluginCheckpointFile).V2.PreparedClaims).To(HaveKey(claimUID))
+		Expect(readCheckpoint(consts.DriverPluginCheckpointBackupFile).V1).NotTo(BeNil())
+	})
+
+	It("keeps the last good checkpoint and falls back to it", func() {
+		pm, err := podmanager.NewPodManager(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pm.Set(podUID, claimUID, devices)).To(Succeed())
+		Expect(pm.Set(types.UID("other-pod"), types.UID("other-claim"), draTypes.PreparedDevices{})).To(Succeed())
+		Expect(readCheckpoint(consts.DriverPluginCheckpointBackupFile).V2.PreparedClaims).To(HaveLen(1))
+
+		Expect(os.WriteFile(checkpointPath(consts.DriverPluginCheckpointFile), []byte(`{"checksum":1,"v2":{}}`), 0o600)).To(Succeed())
+
+		pm, err = podmanager.NewPodManager(config)
+		Expect(err).NotTo(HaveOccurred())
+		_, found := pm.Get(podUID, claimUID)
+		Expect(found).To(BeTrue())
+		_, found = pm.Get(types.UID("other-pod"), types.UID("other-claim"))
+		Expect(found).To(BeFalse())
+
+		Expect(checkpointPath(consts.DriverPluginCheckpointFile + ".corrupted")).To(BeAnExistingFile())
+		Expect(readCheckpoint(consts.DriverPluginCheckpointFile).V2.PreparedClaims).To(HaveKey(claimUID))
+	})
+
+	It("does not replace the last good checkpoint with an unreadable one", func() {
+		pm, err := podmanager.NewPodManager(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pm.Set(podUID, claimUID, devices)).To(Succeed())
+		Expect(pm.Set(types.UID("other-pod"), types.UID("other-claim"), draTypes.PreparedDevices{})).To(Succeed())
+
+		Expect(os.WriteFile(checkpointPath(consts.DriverPluginCheckpointFile), []byte("corrupted"), 0o600)).To(Succeed())
+		Expect(pm.DeletePod(types.UID("other-pod"))).To(Succeed())
+		Expect(readCheckpoint(consts.DriverPluginCheckpointBackupFile).V2.PreparedClaims).To(HaveLen(1))
+		Expect(readCheckpoint(consts.DriverPluginCheckpointFile).V2.PreparedClaims).To(HaveLen(1))
+	})
+
+	It("rebuilds the prepared claims from the ResourceClaims when no checkpoint is readable", func() {
+		for _, name := range []string{consts.DriverPluginCheckpointFile, consts.DriverPluginCheckpointBackupFile} {
+			Expect(os.WriteFile(checkpointPath(name), []byte("corrupted"), 0o600)).To(Succeed())
+		}
+		clientset := fake.NewSimpleClientset(
+			&resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "claim", UID: claimUID},
+				Status: resourceapi.ResourceClaimStatus{
+					Allocation: &resourceapi.AllocationResult{Devices: resourceapi.DeviceAllocationResult{
+						Results: []resourceapi.DeviceRequestAllocationResult{
+							{Request: "vf", Driver: consts.DriverName, Pool: "node-a", Device: "0000-01-00-1"},
+							{Request: "vf", Driver: consts.DriverName, Pool: "node-b", Device: "0000-02-00-1"},
+							{Request: "gpu", Driver: "gpu.example.com", Pool: "node-a", Device: "gpu-0"},
+						},
+					}},
+					ReservedFor: []resourceapi.ResourceClaimConsumerReference{{Resource: "pods", Name: "pod", UID: podUID}},
+					Devices: []resourceapi.AllocatedDeviceStatus{{
+						Driver:      consts.DriverName,
+						Pool:        "node-a",
+						Device:      "0000-01-00-1",
+						NetworkData: &resourceapi.NetworkDeviceData{InterfaceName: "net1"},
+					}},
+				},
+			},
+			&resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "unreserved", UID: "unreserved-uid"},
+				Status: resourceapi.ResourceClaimStatus{
+					Allocation: &resourceapi.AllocationResult{Devices: resourceapi.DeviceAllocationResult{
+						Results: []resourceapi.DeviceRequestAllocationResult{
+							{Request: "vf", Driver: consts.DriverName, Pool: "node-a", Device: "0000-01-00-2"},
+						},
+					}},
+				},
+			},
+		)
+		config.K8sClient.Interface = clientset
+
+		pm, err := podmanager.NewPodManager(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(clientset.Actions()).NotTo(BeEmpty())
+		expectAllowedByChart(clientset.Actions())
+		Expect(pm.ListDevicesByPodUID()).To(HaveLen(1))
+		recovered, found := pm.Get(podUID, claimUID)
+		Expect(found).To(BeTrue())
+		Expect(recovered).To(HaveLen(1))
+		Expect(recovered[0].Device.DeviceName).To(Equal("0000-01-00-1"))
+		Expect(recovered[0].Device.RequestNames).To(Equal([]string{"vf"}))
+		Expect(recovered[0].PciAddress).To(Equal("0000:01:00.1"))
+		Expect(recovered[0].PodName).To(Equal("pod"))
+		Expect(recovered[0].ClaimNamespacedName.Name).To(Equal("claim"))
+		Expect(recovered[0].NetworkDeviceData.InterfaceName).To(Equal("net1"))
+		Expect(recovered[0].Phase).To(Equal(draTypes.DevicePhaseRecovered))
+
+		Expect(readCheckpoint(consts.DriverPluginCheckpointFile).V2.PreparedClaims).To(HaveKey(claimUID))
+	})
+
+	It("shares the checkpoint between the driver instances of a rolling update", func() {
+		config.Flags.PodUID = "old-pod"
+		oldInstance, err := podmanager.NewPodManager(config)
+		Expect(err).NotTo(HaveOccurred())
+		config.Flags.PodUID = "new-pod"
+		newInstance, err := podmanager.NewPodManager(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checkpointPath(consts.DriverPluginCheckpointLockFile)).To(BeAnExistingFile())
+
+		Expect(newInstance.Set(podUID, claimUID, devices)).To(Succeed())
+		oldDevices, found := oldInstance.GetDevicesByPodUID(podUID)
+		Expect(found).To(BeTrue())
+		Expect(oldDevices).To(HaveLen(1))
+
+		// devices got before the other instance changed the checkpoint
+		Expect(newInstance.Set(types.UID("other-pod"), types.UID("other-claim"), draTypes.PreparedDevices{})).To(Succeed())
+		Expect(oldInstance.UpdatePreparedDevicesSandbox(oldDevices, "sandbox-1", "/var/run/netns/test")).To(Succeed())
+
+		newDevices, found := newInstance.Get(podUID, claimUID)
+		Expect(found).To(BeTrue())
+		Expect(newDevices[0].SandboxID).To(Equal("sandbox-1"))
+		Expect(newDevices[0].Phase).To(Equal(draTypes.DevicePhaseAttached))
+		_, found = newInstance.Get(types.UID("other-pod"), types.UID("other-claim"))
+		Expect(found).To(BeTrue())
+
+		Expect(oldInstance.DeleteClaim(devices[0].ClaimNamespacedName)).To(Succeed())
+		_, found = newInstance.Get(podUID, claimUID)
+		Expect(found).To(BeFalse())
+	})
+
+	It("refuses to run next to a driver instance without rolling updates", func() {
+		listener, err := net.Listen("unix", config.UnsuffixedDRASocketPath())
+		Expect(err).NotTo(HaveOccurred())
+		config.Flags.PodUID = "new-pod"
+
+		_, err = podmanager.NewPodManager(config)
+		Expect(err).To(MatchError(ContainSubstring("without rolling updates")))
+
+		// the socket left behind once the instance stopped
+		listener.(*net.UnixListener).SetUnlinkOnClose(false)
+		Expect(listener.Close()).To(Succeed())
+		Expect(config.UnsuffixedDRASocketPath()).To(BeAnExistingFile())
+		_, err = podmanager.NewPodManager(config)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("reads the prepared claims while the other driver instance reads them", func() {
+		config.Flags.PodUID = "new-pod"
+		pm, err := podmanager.NewPodManager(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pm.Set(podUID, claimUID, devices)).To(Succeed())
+
+		// the other instance reading the checkpoint
+		lockFile, err := os.OpenFile(checkpointPath(consts.DriverPluginCheckpointLockFile), os.O_RDWR, 0o600)
+		Expect(err).NotTo(HaveOccurred())
+		defer lockFile.Close()
+		Expect(unix.Flock(int(lockFile.Fd()), unix.LOCK_SH)).To(Succeed())
+
+		read := make(chan int)
+		go func() {
+			defer GinkgoRecover()
+			listed := pm.ListDevicesByPodUID()
+			read <- len(listed)
+		}()
+		Eventually(read).Should(Receive(Equal(1)))
+	})
+
+	It("fails when no checkpoint is readable and the claims cannot be listed", func() {
+		Expect(os.WriteFile(checkpointPath(consts.DriverPluginCheckpointFile), []byte("corrupted"), 0o600)).To(Succeed())
+
+		_, err := podmanager.NewPodManager(config)
+		Expect(err).To(MatchError(ContainSubstring("unable to load checkpoint")))
+	})
+})
+
+// v1Checkpoint is the checkpoint of the versions before version 2.
+type v1Checkpoint struct {
+	Checksum checksum.Checksum `json:"checksum"`
+	V1       *struct {
+		PreparedClaimsByPodUID map[types.UID]map[types.UID][]*v1PreparedDevice `json:"preparedClaimsByPodUID,omitempty"`
+	} `json:"v1,omitempty"`
+}
+
+type v1PreparedDevice struct {
+	Device              drapbv1.Device
+	ClaimNamespacedName kubeletplugin.NamespacedObject
+	ContainerEdits      *cdiapi.ContainerEdits
+	Config              *struct {
+		metav1.TypeMeta       `json:",inline"`
+		Driver                string `json:"driver,omitempty"`
+		AddVhostMount         bool   `json:"addVhostMount,omitempty"`
+		IfName                string `json:"ifName,omitempty"`
+		NetAttachDefName      string `json:"netAttachDefName,omitempty"`
+		NetAttachDefNamespace string `json:"netAttachDefNamespace,omitempty"`
+	}
+	IfName             string
+	PciAddress         string
+	MultusDeviceID     string
+	MultusResourceName string
+	DeviceAttributes   map[string]resourceapi.DeviceAttribute
+	NetworkDeviceData  *resourceapi.NetworkDeviceData
+	PodUID             string
+	NetAttachDefConfig string
+	OriginalDriver     string
+}
@@ -1,14 +1,24 @@
 package podmanager
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
+	"os"
+	"path/filepath"
 	"sync"
+	"time"
 
+	"go.etcd.io/etcd/client/pkg/v3/fileutil"
+	"golang.org/x/sys/unix"
 	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/dynamic-resource-allocation/kubeletplugin"
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/kubelet/checkpointmanager"
+	checkpointerrors "k8s.io/kubernetes/pkg/kubelet/checkpointmanager/errors"
 
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
 	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
@@ -16,73 +26,148 @@ import (
 
 // PodManager provides a thread-safe, centralized store for all prepared network devices
 // across multiple Pods. It is indexed by the Pod's UID, and for each Pod, it maps
-// claim IDs to their specific PreparedDevices.
+// claim IDs to their specific PreparedDevices. It stores and returns copies of
+// the devices, they are only changed through its update methods.
+//
+// With rolling updates the driver instances running side by side share the
+// checkpoint: it is locked while the prepared claims are accessed, and
+// reloaded when the other instance changed it.
 type PodManager struct {
 	mu                     sync.RWMutex
 	preparedClaimsByPodUID drasriovtypes.PreparedClaimsByPodUID
 	checkpointManager      checkpointmanager.CheckpointManager
+	checkpointDir          string
+
+	// lockPath is the checkpoint lock file, empty without rolling updates.
+	// checkpointInfo is the checkpoint last loaded or written by this
+	// instance, another file means the other instance wrote it since.
+	lockPath       string
+	checkpointInfo os.FileInfo
 }
 
+// NewPodManager loads the checkpoint of the prepared claims. A corrupted
+// checkpoint is set aside and the last good one is loaded instead, when both
+// are unreadable the prepared claims are rebuilt from the ResourceClaims of
+// the node. The checkpoint is rewritten in the current version.
 func NewPodManager(config *drasriovtypes.Config) (*PodManager, error) {
 	checkpointManager, err := checkpointmanager.NewCheckpointManager(config.DriverPluginPath())
 	if err != nil {
 		return nil, fmt.Errorf("unable to create checkpoint manager: %v", err)
 	}
 
-	checkpoints, err := checkpointManager.ListCheckpoints()
-	if err != nil {
-		return nil, fmt.Errorf("unable to list checkpoints: %v", err)
-	}
-
 	podmManager := &PodManager{
 		mu:                     sync.RWMutex{},
 		checkpointManager:      checkpointManager,
+		checkpointDir:          config.DriverPluginPath(),
 		preparedClaimsByPodUID: make(drasriovtypes.PreparedClaimsByPodUID),
 	}
-
-	for _, c := range checkpoints {
-		if c == consts.DriverPluginCheckpointFile {
-			klog.Infof("Found checkpoint: %s", c)
-			checkpoint := drasriovtypes.NewCheckpoint()
-			if err := checkpointManager.GetCheckpoint(consts.DriverPluginCheckpointFile, checkpoint); err != nil {
-				return nil, fmt.Errorf("unable to load checkpoint: %v", err)
-			}
-			podmManager.preparedClaimsByPodUID = checkpoint.V1.PreparedClaimsByPodUID
-			klog.Infof("Loaded checkpoint with %d pods", len(podmManager.preparedClaimsByPodUID))
-			return podmManager, nil
+	if config.Flags.PodUID != "" {
+		if err := refuseUnlockedInstance(config); err != nil {
+			return nil, err
 		}
+		podmManager.lockPath = filepath.Join(config.DriverPluginPath(), consts.DriverPluginCheckpointLockFile)
 	}
 
-	checkpoint := drasriovtypes.NewCheckpoint()
-	if err := checkpointManager.CreateCheckpoint(consts.DriverPluginCheckpointFile, checkpoint); err != nil {
-		return nil, fmt.Errorf("unable to sync to checkpoint: %v", err)
+	unlock, err := podmManager.lockCheckpoint()
+	if err != nil {
+		return nil, err
 	}
-	klog.Infof("Created checkpoint: %v", *checkpoint)
+	defer unlock()
 
+	preparedClaimsByPodUID, corrupted, err := podmManager.loadCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	if corrupted {
+		klog.Warning("No readable checkpoint, rebuilding prepared claims from the ResourceClaims of the node")
+		preparedClaimsByPodUID, err = recoverPreparedClaims(context.Background(), config.K8sClient.Interface, config.Flags.NodeName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load checkpoint: %w", err)
+		}
+	}
+	podmManager.preparedClaimsByPodUID = preparedClaimsByPodUID
+	klog.Infof("Loaded %d pods with prepared claims", len(preparedClaimsByPodUID))
+
+	if err := podmManager.syncToCheckpoint(); err != nil {
+		return nil, err
+	}
 	return podmManager, nil
 }
 
+// refuseUnlockedInstance returns an error while a driver instance without
+// rolling updates serves on the node. Such an instance, e.g. of a version
+// before rolling updates, neither locks nor reloads the checkpoint and would
+// overwrite the claims prepared by this one. Failing keeps it running until
+// it is replaced without rolling update.
+func refuseUnlockedInstance(config *drasriovtypes.Config) error {
+	socketPath := config.UnsuffixedDRASocketPath()
+	conn, err := net.DialTimeout("unix", socketPath, time.Second)
+	if err != nil {
+		return nil
+	}
+	_ = conn.Close()
+	return fmt.Errorf("a driver instance without rolling updates serves %s, it does not lock the checkpoint: "+
+		"replace it with rolling updates disabled before enabling them", socketPath)
+}
+
+// loadCheckpoint returns the prepared claims of the checkpoint or, when it is
+// missing or corrupted, of the last good checkpoint. Corrupted files are
+// renamed with a .corrupted suffix, corrupted reports when none was readable.
+func (s *PodManager) loadCheckpoint() (drasriovtypes.PreparedClaimsByPodUID, bool, error) {
+	corrupted := false
+	for _, name := range []string{consts.DriverPluginCheckpointFile, consts.DriverPluginCheckpointBackupFile} {
+		checkpoint := &drasriovtypes.Checkpoint{}
+		err := s.checkpointManager.GetCheckpoint(name, checkpoint)
+		if errors.Is(err, checkpointerrors.ErrCheckpointNotFound) {
+			continue
+		}
+		if err != nil {
+			klog.Errorf("Checkpoint %s is corrupted: %v", name, err)
+			corrupted = true
+			path := filepath.Join(s.checkpointDir, name)
+			if err := os.Rename(path, path+".corrupted"); err != nil {
+				return nil, false, fmt.Errorf("unable to set corrupted checkpoint %s aside: %w", name, err)
+			}
+			continue
+		}
+		if checkpoint.V2 == nil {
+			klog.Infof("Migrating checkpoint %s to version 2", name)
+		}
+		klog.Infof("Found checkpoint: %s", name)
+		return checkpoint.PreparedClaimsByPodUID(), false, nil
+	}
+	return make(drasriovtypes.PreparedClaimsByPodUID), corrupted, nil
+}
+
 // Set stores the configuration for all prepared devices under a given Pod UID.
 // If a configuration for the Pod UID or claim ID already exists, it will be overwritten.
 func (s *PodManager) Set(podUID types.UID, claimID types.UID, preparedDevices drasriovtypes.PreparedDevices) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	if _, ok := s.preparedClaimsByPodUID[podUID]; !ok {
 		s.preparedClaimsByPodUID[podUID] = make(drasriovtypes.PreparedDevicesByClaimID)
 	}
-	s.preparedClaimsByPodUID[podUID][claimID] = preparedDevices
-
+	now := time.Now()
+	for _, preparedDevice := range preparedDevices {
+		if preparedDevice.PreparedTime == nil {
+			preparedDevice.PreparedTime = &metav1.Time{Time: now}
+		}
+		preparedDevice.SetPhase(drasriovtypes.DevicePhasePrepared, now)
+	}
+	s.preparedClaimsByPodUID[podUID][claimID] = preparedDevices.DeepCopy()
 	return s.syncToCheckpoint()
 }
 
 // Get retrieves the configuration for a specific claim under a given Pod UID.
 // It returns the Config and true if found, otherwise an empty Config and false.
 func (s *PodManager) Get(podUID types.UID, claimID types.UID) (drasriovtypes.PreparedDevices, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	defer s.rlock()()
 	if podConfigs, ok := s.preparedClaimsByPodUID[podUID]; ok {
 		configs, found := podConfigs[claimID]
-		return configs, found
+		return configs.DeepCopy(), found
 	}
 	return drasriovtypes.PreparedDevices{}, false
 }
@@ -90,36 +175,37 @@ func (s *PodManager) Get(podUID types.UID, claimID types.UID) (drasriovtypes.Pre
 // GetDevicesByPodUID retrieves the configuration for all claims under a given Pod UID.
 // It returns the Config and true if found, otherwise an empty Config and false.
 func (s *PodManager) GetDevicesByPodUID(podUID types.UID) (drasriovtypes.PreparedDevices, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	defer s.rlock()()
 	claims, exists := s.preparedClaimsByPodUID[podUID]
 	if !exists {
 		return drasriovtypes.PreparedDevices{}, false
 	}
 	preparedDevices := drasriovtypes.PreparedDevices{}
 	for _, devices := range claims {
-		preparedDevices = append(preparedDevices, devices...)
+		preparedDevices = append(preparedDevices, devices.DeepCopy()...)
 	}
 	return preparedDevices, true
 }
 
 // DeletePod removes all configurations associated with a given Pod UID.
 func (s *PodManager) DeletePod(podUID types.UID) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	delete(s.preparedClaimsByPodUID, podUID)
 	return s.syncToCheckpoint()
 }
 
 // GetByClaim retrieves the configuration for a specific claim.
 func (s *PodManager) GetByClaim(claim kubeletplugin.NamespacedObject) (drasriovtypes.PreparedDevices, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	defer s.rlock()()
 	preparedDevices := drasriovtypes.PreparedDevices{}
 	for _, preparedDevicesByClaimID := range s.preparedClaimsByPodUID {
 		devices, found := preparedDevicesByClaimID[claim.UID]
 		if found {
-			preparedDevices = append(preparedDevices, devices...)
+			preparedDevices = append(preparedDevices, devices.DeepCopy()...)
 			return preparedDevices, true
 		}
 	}
@@ -132,18 +218,77 @@ func (s *PodManager) UpdatePreparedDeviceNetworkData(preparedDevice *drasriovtyp
 	if preparedDevice == nil {
 		return fmt.Errorf("prepared device is nil")
 	}
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	s.updateDevices(drasriovtypes.PreparedDevices{preparedDevice}, func(preparedDevice *drasriovtypes.PreparedDevice) {
+		preparedDevice.SetNetworkDeviceData(networkData)
+	})
+	return s.syncToCheckpoint()
+}
+
+// UpdatePreparedDevicesNetworkData persists the runtime network data of
+// already tracked prepared devices and syncs the checkpoint once.
+func (s *PodManager) UpdatePreparedDevicesNetworkData(networkDataList drasriovtypes.NetworkDataChanStructList) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for _, item := range networkDataList {
+		s.updateDevices(drasriovtypes.PreparedDevices{item.PreparedDevice}, func(preparedDevice *drasriovtypes.PreparedDevice) {
+			preparedDevice.SetNetworkDeviceData(item.NetworkDeviceData)
+		})
+	}
+	return s.syncToCheckpoint()
+}
+
+// UpdatePreparedDevicesSandbox records the pod sandbox the prepared devices are
+// attached to and syncs the checkpoint. Empty values mark the devices as detached.
+func (s *PodManager) UpdatePreparedDevicesSandbox(preparedDevices drasriovtypes.PreparedDevices, sandboxID, networkNamespace string) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-	preparedDevice.SetNetworkDeviceData(networkData)
+	phase := drasriovtypes.DevicePhaseAttached
+	if sandboxID == "" {
+		phase = drasriovtypes.DevicePhasePrepared
+	}
+	now := time.Now()
+	s.updateDevices(preparedDevices, func(preparedDevice *drasriovtypes.PreparedDevice) {
+		preparedDevice.SandboxID = sandboxID
+		preparedDevice.NetworkNamespace = networkNamespace
+		preparedDevice.SetPhase(phase, now)
+	})
 	return s.syncToCheckpoint()
 }
 
+// ListDevicesByPodUID returns the prepared devices of all claims grouped by Pod UID.
+func (s *PodManager) ListDevicesByPodUID() map[types.UID]drasriovtypes.PreparedDevices {
+	defer s.rlock()()
+	devicesByPodUID := make(map[types.UID]drasriovtypes.PreparedDevices, len(s.preparedClaimsByPodUID))
+	for podUID, claims := range s.preparedClaimsByPodUID {
+		for _, devices := range claims {
+			devicesByPodUID[podUID] = append(devicesByPodUID[podUID], devices.DeepCopy()...)
+		}
+	}
+	return devicesByPodUID
+}
+
 // DeleteClaim removes all configurations associated with a given claim.
 // NOTE: for now we only support one pod per claim as VFs are not shared between pods
 func (s *PodManager) DeleteClaim(claim kubeletplugin.NamespacedObject) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	podsToDelete := []types.UID{}
 	for uid, preparedDevicesByClaimID := range s.preparedClaimsByPodUID {
 		_, found := preparedDevicesByClaimID[claim.UID]
@@ -162,11 +307,167 @@ func (s *PodManager) DeleteClaim(claim kubeletplugin.NamespacedObject) error {
 	return nil
 }
 
+// CheckpointWritable reports an error when the checkpoint directory no longer
+// accepts writes, preparing a claim would then fail. The directory is only
+// checked, nothing is written: the probe runs on every readiness check.
+func (s *PodManager) CheckpointWritable() error {
+	if err := unix.Access(s.checkpointDir, unix.W_OK); err != nil {
+		return fmt.Errorf("checkpoint directory %s is not writable: %w", s.checkpointDir, err)
+	}
+	return nil
+}
+
+// syncToCheckpoint writes the checkpoint. Once it is verified, the previous
+// checkpoint is kept as the last good checkpoint when it was readable.
 func (s *PodManager) syncToCheckpoint() error {
-	checkpoint := drasriovtypes.NewCheckpoint()
-	checkpoint.V1.PreparedClaimsByPodUID = s.preparedClaimsByPodUID
+	previous := &drasriovtypes.Checkpoint{}
+	previousErr := s.checkpointManager.GetCheckpoint(consts.DriverPluginCheckpointFile, previous)
+
+	checkpoint := drasriovtypes.NewCheckpointFromPods(s.preparedClaimsByPodUID)
 	if err := s.checkpointManager.CreateCheckpoint(consts.DriverPluginCheckpointFile, checkpoint); err != nil {
 		return fmt.Errorf("unable to sync to checkpoint: %v", err)
 	}
+	if err := s.checkpointManager.GetCheckpoint(consts.DriverPluginCheckpointFile, &drasriovtypes.Checkpoint{}); err != nil {
+		return fmt.Errorf("unable to verify checkpoint: %v", err)
+	}
+	s.checkpointInfo, _ = os.Stat(filepath.Join(s.checkpointDir, consts.DriverPluginCheckpointFile))
+
+	if previousErr == nil {
+		if err := s.checkpointManager.CreateCheckpoint(consts.DriverPluginCheckpointBackupFile, previous); err != nil {
+			klog.Errorf("Unable to keep the last good checkpoint: %v", err)
+		}
+	}
+	return nil
+}
+
+// lock locks the prepared claims for writing, reloaded from the checkpoint
+// when the other driver instance changed it. The returned function releases
+// the locks.
+func (s *PodManager) lock() (func(), error) {
+	unlock, err := s.lockCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.reloadCheckpoint(); err != nil {
+		unlock()
+		return nil, err
+	}
+	return unlock, nil
+}
+
+// rlock locks the prepared claims for reading. With rolling updates the
+// readers share the checkpoint lock, which keeps the other instance from
+// writing the checkpoint meanwhile, and only take it exclusively to reload the
+// checkpoint when the other instance changed it. They fall back to the
+// prepared claims in memory when that fails.
+func (s *PodManager) rlock() func() {
+	s.mu.RLock()
+	if s.lockPath == "" {
+		return s.mu.RUnlock
+	}
+	file, err := os.OpenFile(s.lockPath, os.O_RDWR|os.O_CREATE, 0o600)
+	if err == nil {
+		err = unix.Flock(int(file.Fd()), unix.LOCK_SH)
+		if err != nil {
+			_ = file.Close()
+		}
+	}
+	if err != nil {
+		klog.Errorf("Reading the prepared claims in memory: unable to lock checkpoint: %v", err)
+		return s.mu.RUnlock
+	}
+	if _, changed, err := s.checkpointChanged(); err == nil && !changed {
+		return func() {
+			_ = file.Close()
+			s.mu.RUnlock()
+		}
+	}
+	_ = file.Close()
+	s.mu.RUnlock()
+
+	unlock, err := s.lock()
+	if err != nil {
+		klog.Errorf("Reading the prepared claims in memory: %v", err)
+		s.mu.RLock()
+		return s.mu.RUnlock
+	}
+	return unlock
+}
+
+// lockCheckpoint takes the in-memory lock and, with rolling updates, the
+// checkpoint lock file shared with the other driver instance.
+func (s *PodManager) lockCheckpoint() (func(), error) {
+	s.mu.Lock()
+	if s.lockPath == "" {
+		return s.mu.Unlock, nil
+	}
+	file, err := fileutil.LockFile(s.lockPath, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("unable to lock checkpoint: %w", err)
+	}
+	return func() {
+		_ = file.Close()
+		s.mu.Unlock()
+	}, nil
+}
+
+// reloadCheckpoint loads the checkpoint when it was written by the other
+// driver instance since this one last loaded or wrote it.
+func (s *PodManager) reloadCheckpoint() error {
+	if s.lockPath == "" {
+		return nil
+	}
+	info, changed, err := s.checkpointChanged()
+	if err != nil || !changed {
+		return err
+	}
+	checkpoint := &drasriovtypes.Checkpoint{}
+	if err := s.checkpointManager.GetCheckpoint(consts.DriverPluginCheckpointFile, checkpoint); err != nil {
+		return fmt.Errorf("unable to reload checkpoint: %w", err)
+	}
+	klog.V(2).Infof("Reloaded checkpoint written by another driver instance")
+	s.preparedClaimsByPodUID = checkpoint.PreparedClaimsByPodUID()
+	s.checkpointInfo = info
+	return nil
+}
+
+// checkpointChanged reports whether the checkpoint is another file, or was
+// modified, since this instance last loaded or wrote it. A missing checkpoint
+// is unchanged.
+func (s *PodManager) checkpointChanged() (os.FileInfo, bool, error) {
+	info, err := os.Stat(filepath.Join(s.checkpointDir, consts.DriverPluginCheckpointFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("unable to stat checkpoint: %w", err)
+	}
+	unchanged := s.checkpointInfo != nil && os.SameFile(info, s.checkpointInfo) &&
+		info.ModTime().Equal(s.checkpointInfo.ModTime()) && info.Size() == s.checkpointInfo.Size()
+	return info, !unchanged, nil
+}
+
+// updateDevices runs update on the copies of the prepared devices of the
+// caller and on the devices tracked for the same claims.
+func (s *PodManager) updateDevices(preparedDevices drasriovtypes.PreparedDevices, update func(*drasriovtypes.PreparedDevice)) {
+	for _, preparedDevice := range preparedDevices {
+		update(preparedDevice)
+		if tracked := s.trackedDevice(preparedDevice); tracked != nil {
+			update(tracked)
+		}
+	}
+}
+
+// trackedDevice returns the prepared device of the same claim and device.
+func (s *PodManager) trackedDevice(preparedDevice *drasriovtypes.PreparedDevice) *drasriovtypes.PreparedDevice {
+	for _, claims := range s.preparedClaimsByPodUID {
+		for _, device := range claims[preparedDevice.ClaimNamespacedName.UID] {
+			if device.Device.GetPoolName() == preparedDevice.Device.GetPoolName() &&
+				device.Device.GetDeviceName() == preparedDevice.Device.GetDeviceName() {
+				return device
+			}
+		}
+	}
 	return nil
 }
@@ -122,6 +122,28 @@ var _ = Describe("PodManager", func() {
 		})
 	})
 
+	Context("CheckpointWritable", func() {
+		BeforeEach(func() {
+			var err error
+			pm, err = podmanager.NewPodManager(config)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should succeed without leaving files in the checkpoint directory", func() {
+			Expect(pm.CheckpointWritable()).To(Succeed())
+
+			entries, err := os.ReadDir(config.DriverPluginPath())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Name()).To(Equal("checkpoint.json"))
+		})
+
+		It("should fail when the checkpoint directory is gone", func() {
+			Expect(os.RemoveAll(config.DriverPluginPath())).To(Succeed())
+			Expect(pm.CheckpointWritable()).NotTo(Succeed())
+		})
+	})
+
 	Context("Set and Get operations", func() {
 		BeforeEach(func() {
 			var err error
@@ -448,6 +470,59 @@ var _ = Describe("PodManager", func() {
 		})
 	})
 
+	Context("Sandbox tracking", func() {
+		BeforeEach(func() {
+			var err error
+			pm, err = podmanager.NewPodManager(config)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should persist the sandbox of attached devices", func() {
+			Expect(pm.Set(podUID, claimUID, devices)).To(Succeed())
+			Expect(pm.UpdatePreparedDevicesSandbox(devices, "sandbox-1", "/var/run/netns/test")).To(Succeed())
+
+			pm2, err := podmanager.NewPodManager(config)
+			Expect(err).NotTo(HaveOccurred())
+			retrievedDevices, found := pm2.Get(podUID, claimUID)
+			Expect(found).To(BeTrue())
+			for _, device := range retrievedDevices {
+				Expect(device.SandboxID).To(Equal("sandbox-1"))
+				Expect(device.NetworkNamespace).To(Equal("/var/run/netns/test"))
+			}
+
+			Expect(pm.UpdatePreparedDevicesSandbox(devices, "", "")).To(Succeed())
+			pm3, err := podmanager.NewPodManager(config)
+			Expect(err).NotTo(HaveOccurred())
+			retrievedDevices, _ = pm3.Get(podUID, claimUID)
+			Expect(retrievedDevices[0].SandboxID).To(BeEmpty())
+		})
+
+		It("should list devices of all pods", func() {
+			otherPodUID := types.UID("other-pod")
+			Expect(pm.Set(podUID, claimUID, devices)).To(Succeed())
+			Expect(pm.Set(otherPodUID, types.UID("other-claim"), devices[:1])).To(Succeed())
+
+			listed := pm.ListDevicesByPodUID()
+			Expect(listed).To(HaveLen(2))
+			Expect(listed[podUID]).To(HaveLen(len(devices)))
+			Expect(listed[otherPodUID]).To(HaveLen(1))
+		})
+
+		It("should not change devices returned before an update", func() {
+			Expect(pm.Set(podUID, claimUID, devices)).To(Succeed())
+			listed := pm.ListDevicesByPodUID()
+			byPod, found := pm.GetDevicesByPodUID(podUID)
+			Expect(found).To(BeTrue())
+
+			Expect(pm.UpdatePreparedDevicesSandbox(devices, "sandbox-1", "/var/run/netns/test")).To(Succeed())
+
+			Expect(listed[podUID][0].SandboxID).To(BeEmpty())
+			Expect(byPod[0].SandboxID).To(BeEmpty())
+			retrievedDevices, _ := pm.Get(podUID, claimUID)
+			Expect(retrievedDevices[0].SandboxID).To(Equal("sandbox-1"))
+		})
+	})
+
 	Context("Concurrent access", func() {
 		BeforeEach(func() {
 			var err error
@@ -0,0 +1,57 @@
+package podmanager_test
+
+import (
+	"os"
+	"regexp"
+
+	. "github.com/onsi/gomega"
+	rbacv1 "k8s.io/api/rbac/v1"
+	k8stesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/yaml"
+)
+
+// clusterRolePath is the ClusterRole of the driver shipped in the chart.
+const clusterRolePath = "../../deployments/helm/dra-driver-sriov/templates/clusterrole.yaml"
+
+// chartRules returns the rules of the ClusterRole of the chart, the template
+// actions are only in its metadata.
+func chartRules() []rbacv1.PolicyRule {
+	data, err := os.ReadFile(clusterRolePath)
+	Expect(err).NotTo(HaveOccurred())
+	data = regexp.MustCompile(`{{[^}]*}}`).ReplaceAll(data, []byte("template"))
+	role := &rbacv1.ClusterRole{}
+	Expect(yaml.Unmarshal(data, role)).To(Succeed())
+	return role.Rules
+}
+
+// expectAllowedByChart checks that the ClusterRole of the chart grants the
+// requests of actions.
+func expectAllowedByChart(actions []k8stesting.Action) {
+	rules := chartRules()
+	for _, action := range actions {
+		resource := action.GetResource().Resource
+		if action.GetSubresource() != "" {
+			resource += "/" + action.GetSubresource()
+		}
+		allowed := false
+		for _, rule := range rules {
+			if contains(rule.APIGroups, action.GetResource().Group) &&
+				contains(rule.Resources, resource) &&
+				contains(rule.Verbs, action.GetVerb()) {
+				allowed = true
+				break
+			}
+		}
+		Expect(allowed).To(BeTrue(), "the chart does not grant %s on %s in group %q",
+			action.GetVerb(), resource, action.GetResource().Group)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
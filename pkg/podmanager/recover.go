@@ -0,0 +1,106 @@
+package podmanager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	"k8s.io/klog/v2"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// recoverTimeout bounds the listing of the ResourceClaims.
+const recoverTimeout = time.Minute
+
+// recoverPreparedClaims rebuilds the prepared claims from the ResourceClaims
+// with devices of the driver allocated on the node and reserved for pods. The
+// devices are in the Recovered phase: their allocation and network data are
+// known, not how they were configured, unpreparing them only removes their
+// CDI specs.
+func recoverPreparedClaims(ctx context.Context, client kubernetes.Interface, nodeName string) (drasriovtypes.PreparedClaimsByPodUID, error) {
+	if client == nil {
+		return nil, fmt.Errorf("no Kubernetes client to rebuild the prepared claims")
+	}
+	ctx, cancel := context.WithTimeout(ctx, recoverTimeout)
+	defer cancel()
+	claims, err := client.ResourceV1().ResourceClaims("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list ResourceClaims: %w", err)
+	}
+
+	now := time.Now()
+	preparedClaimsByPodUID := make(drasriovtypes.PreparedClaimsByPodUID)
+	for i := range claims.Items {
+		claim := &claims.Items[i]
+		if claim.Status.Allocation == nil {
+			continue
+		}
+		for _, consumer := range claim.Status.ReservedFor {
+			if consumer.APIGroup != "" || consumer.Resource != "pods" {
+				continue
+			}
+			devices := recoverClaimDevices(claim, consumer, nodeName, now)
+			if len(devices) == 0 {
+				continue
+			}
+			if _, ok := preparedClaimsByPodUID[consumer.UID]; !ok {
+				preparedClaimsByPodUID[consumer.UID] = make(drasriovtypes.PreparedDevicesByClaimID)
+			}
+			preparedClaimsByPodUID[consumer.UID][claim.UID] = devices
+			klog.Infof("Recovered claim %s/%s prepared for pod %s with %d devices", claim.Namespace, claim.Name, consumer.Name, len(devices))
+		}
+	}
+	return preparedClaimsByPodUID, nil
+}
+
+// recoverClaimDevices returns the devices of the driver allocated on the node
+// to a claim, as prepared for consumer.
+func recoverClaimDevices(claim *resourceapi.ResourceClaim, consumer resourceapi.ResourceClaimConsumerReference, nodeName string, now time.Time) drasriovtypes.PreparedDevices {
+	var devices drasriovtypes.PreparedDevices
+	for _, result := range claim.Status.Allocation.Devices.Results {
+		if result.Driver != consts.DriverName || result.Pool != nodeName {
+			continue
+		}
+		device := &drasriovtypes.PreparedDevice{
+			Device: drapbv1.Device{
+				RequestNames: []string{result.Request},
+				PoolName:     result.Pool,
+				DeviceName:   result.Device,
+			},
+			ClaimNamespacedName: kubeletplugin.NamespacedObject{
+				NamespacedName: k8stypes.NamespacedName{Namespace: claim.Namespace, Name: claim.Name},
+				UID:            claim.UID,
+			},
+			PciAddress: pciAddressFromDeviceName(result.Device),
+			PodUID:     string(consumer.UID),
+			PodName:    consumer.Name,
+		}
+		for _, status := range claim.Status.Devices {
+			if status.Driver == result.Driver && status.Pool == result.Pool && status.Device == result.Device {
+				device.SetNetworkDeviceData(status.NetworkData)
+			}
+		}
+		device.SetPhase(drasriovtypes.DevicePhaseRecovered, now)
+		devices = append(devices, device)
+	}
+	return devices
+}
+
+// pciAddressFromDeviceName reverts the device naming of the discovery, the
+// PCI address with dashes as separators.
+func pciAddressFromDeviceName(deviceName string) string {
+	parts := strings.Split(deviceName, "-")
+	if len(parts) != 4 {
+		return ""
+	}
+	return fmt.Sprintf("%s:%s:%s.%s", parts[0], parts[1], parts[2], parts[3])
+}
@@ -0,0 +1,173 @@
+// Package tracing exports OpenTelemetry traces of the claim preparation and
+// network attachment steps to an OTLP collector. Spans carry the claim and pod
+// UIDs so the steps of one pod start can be found across traces, the trace
+// context of the kubelet is continued when its gRPC calls carry one.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+)
+
+const tracerName = "github.com/k8snetworkplumbingwg/dra-driver-sriov"
+
+// Span attributes.
+const (
+	AttributeClaimUID       = attribute.Key("k8s.resourceclaim.uid")
+	AttributeClaimName      = attribute.Key("k8s.resourceclaim.name")
+	AttributeNamespace      = attribute.Key("k8s.namespace.name")
+	AttributePodUID         = attribute.Key("k8s.pod.uid")
+	AttributePodName        = attribute.Key("k8s.pod.name")
+	AttributeDeviceName     = attribute.Key("dra.device.name")
+	AttributePciAddress     = attribute.Key("sriov.pci_address")
+	AttributeDriver         = attribute.Key("sriov.driver")
+	AttributeSandboxID      = attribute.Key("container.sandbox.id")
+	AttributeDeviceCount    = attribute.Key("dra.device.count")
+	AttributeGRPCFullMethod = attribute.Key("rpc.method")
+)
+
+// Options configures the export of the traces.
+type Options struct {
+	// Endpoint is the host:port of the OTLP gRPC collector, traces are not
+	// exported without it.
+	Endpoint string
+	// Insecure disables TLS towards the collector.
+	Insecure bool
+	// SamplingRatio is the ratio of the traces started by the driver that
+	// are sampled, traces continued from the kubelet follow its decision.
+	SamplingRatio float64
+	// NodeName is reported as the host of the traces.
+	NodeName string
+}
+
+// Setup installs the W3C trace context propagator and, with an endpoint, a
+// tracer provider exporting to the collector. The returned function flushes
+// and stops the export.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if opts.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporterOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(opts.Endpoint)}
+	if opts.Insecure {
+		exporterOpts = append(exporterOpts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, exporterOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SamplingRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", consts.DriverName),
+			attribute.String("host.name", opts.NodeName),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span of the driver.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartLinked starts a span of the driver linked to the spans it follows from,
+// for work done asynchronously. Invalid span contexts are skipped.
+func StartLinked(ctx context.Context, name string, linked []trace.SpanContext, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	var links []trace.Link
+	for _, spanContext := range linked {
+		if spanContext.IsValid() {
+			links = append(links, trace.Link{SpanContext: spanContext})
+		}
+	}
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...), trace.WithLinks(links...))
+}
+
+// End records the error of a span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// ClaimAttributes returns the attributes identifying a claim.
+func ClaimAttributes(namespace, name string, uid k8stypes.UID) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		AttributeNamespace.String(namespace),
+		AttributeClaimName.String(name),
+		AttributeClaimUID.String(string(uid)),
+	}
+}
+
+// PodAttributes returns the attributes identifying a pod.
+func PodAttributes(namespace, name string, uid k8stypes.UID) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{AttributePodUID.String(string(uid))}
+	if namespace != "" {
+		attrs = append(attrs, AttributeNamespace.String(namespace))
+	}
+	if name != "" {
+		attrs = append(attrs, AttributePodName.String(name))
+	}
+	return attrs
+}
+
+// GRPCInterceptor returns a server interceptor continuing the trace context
+// the kubelet sends in the gRPC metadata and tracing the call.
+func GRPCInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+		}
+		ctx, span := otel.Tracer(tracerName).Start(ctx, info.FullMethod,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(AttributeGRPCFullMethod.String(info.FullMethod)))
+		resp, err := handler(ctx, req)
+		End(span, err)
+		return resp, err
+	}
+}
+
+// metadataCarrier adapts the gRPC metadata to the propagators.
+type metadataCarrier metadata.MD
+
+var _ propagation.TextMapCarrier = metadataCarrier{}
+
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
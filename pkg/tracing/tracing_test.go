@@ -0,0 +1,95 @@
+package tracing_test
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/tracing"
+)
+
+var _ = Describe("Tracing", func() {
+	var recorder *tracetest.SpanRecorder
+
+	BeforeEach(func() {
+		shutdown, err := tracing.Setup(context.Background(), tracing.Options{})
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(shutdown, context.Background())
+
+		recorder = tracetest.NewSpanRecorder()
+		previous := otel.GetTracerProvider()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		DeferCleanup(otel.SetTracerProvider, previous)
+	})
+
+	It("continues the trace context of the kubelet gRPC call", func() {
+		const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		ctx := metadata.NewIncomingContext(context.Background(),
+			metadata.Pairs("traceparent", fmt.Sprintf("00-%s-00f067aa0ba902b7-01", traceID)))
+
+		var handlerSpan trace.SpanContext
+		_, err := tracing.GRPCInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/v1.DRAPlugin/NodePrepareResources"},
+			func(ctx context.Context, _ any) (any, error) {
+				handlerSpan = trace.SpanContextFromContext(ctx)
+				return nil, nil
+			})
+		Expect(err).NotTo(HaveOccurred())
+
+		spans := recorder.Ended()
+		Expect(spans).To(HaveLen(1))
+		Expect(spans[0].Name()).To(Equal("/v1.DRAPlugin/NodePrepareResources"))
+		Expect(spans[0].SpanKind()).To(Equal(trace.SpanKindServer))
+		Expect(spans[0].Parent().TraceID().String()).To(Equal(traceID))
+		Expect(handlerSpan.SpanID()).To(Equal(spans[0].SpanContext().SpanID()))
+	})
+
+	It("starts a new trace without trace context", func() {
+		_, err := tracing.GRPCInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/v1.DRAPlugin/NodeUnprepareResources"},
+			func(context.Context, any) (any, error) { return nil, fmt.Errorf("failed") })
+		Expect(err).To(HaveOccurred())
+
+		spans := recorder.Ended()
+		Expect(spans).To(HaveLen(1))
+		Expect(spans[0].Parent().IsValid()).To(BeFalse())
+		Expect(spans[0].Status().Code).To(Equal(codes.Error))
+	})
+
+	It("records the claim and pod attributes", func() {
+		_, span := tracing.Start(context.Background(), "PrepareResourceClaim",
+			append(tracing.ClaimAttributes("default", "claim", "claim-uid"),
+				tracing.PodAttributes("default", "pod", "pod-uid")...)...)
+		tracing.End(span, nil)
+
+		spans := recorder.Ended()
+		Expect(spans).To(HaveLen(1))
+		Expect(spans[0].Status().Code).To(Equal(codes.Unset))
+		Expect(spans[0].Attributes()).To(ContainElements(
+			tracing.AttributeClaimUID.String("claim-uid"),
+			tracing.AttributePodUID.String("pod-uid"),
+			tracing.AttributePodName.String("pod"),
+		))
+	})
+
+	It("links asynchronous spans to the valid span contexts", func() {
+		ctx, attach := tracing.Start(context.Background(), "CNI ADD")
+		tracing.End(attach, nil)
+
+		_, update := tracing.StartLinked(context.Background(), "UpdateClaimNetworkStatus",
+			[]trace.SpanContext{trace.SpanContextFromContext(ctx), {}})
+		tracing.End(update, nil)
+
+		spans := recorder.Ended()
+		Expect(spans).To(HaveLen(2))
+		Expect(spans[1].Links()).To(HaveLen(1))
+		Expect(spans[1].Links()[0].SpanContext.SpanID()).To(Equal(attach.SpanContext().SpanID()))
+	})
+})
@@ -0,0 +1,101 @@
+package types
+
+import (
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	"k8s.io/kubernetes/pkg/kubelet/checkpointmanager/checksum"
+	cdiapi "tags.cncf.io/container-device-interface/pkg/cdi"
+)
+
+// The versions before V2 verify the checksum of the checkpoint over the
+// fields they know, the types below are the checkpoint of these versions and
+// must not change.
+
+// legacyCheckpoint is the checkpoint file written with V2, the versions
+// before V2 ignore V2 and V2Checksum.
+type legacyCheckpoint struct {
+	Checksum   checksum.Checksum   `json:"checksum"`
+	V1         *legacyCheckpointV1 `json:"v1,omitempty"`
+	V2         *CheckpointV2       `json:"v2,omitempty"`
+	V2Checksum checksum.Checksum   `json:"v2Checksum,omitempty"`
+}
+
+type legacyCheckpointV1 struct {
+	PreparedClaimsByPodUID map[k8stypes.UID]map[k8stypes.UID][]*legacyPreparedDevice `json:"preparedClaimsByPodUID,omitempty"`
+}
+
+type legacyPreparedDevice struct {
+	Device              *drapbv1.Device // a value in the versions before V2, marshaled the same
+	ClaimNamespacedName kubeletplugin.NamespacedObject
+	ContainerEdits      *cdiapi.ContainerEdits
+	Config              *legacyVfConfig
+	IfName              string
+	PciAddress          string
+	MultusDeviceID      string
+	MultusResourceName  string
+	DeviceAttributes    map[string]resourceapi.DeviceAttribute
+	NetworkDeviceData   *resourceapi.NetworkDeviceData
+	PodUID              string
+	NetAttachDefConfig  string
+	OriginalDriver      string
+}
+
+type legacyVfConfig struct {
+	metav1.TypeMeta       `json:",inline"`
+	Driver                string `json:"driver,omitempty"`
+	AddVhostMount         bool   `json:"addVhostMount,omitempty"`
+	IfName                string `json:"ifName,omitempty"`
+	NetAttachDefName      string `json:"netAttachDefName,omitempty"`
+	NetAttachDefNamespace string `json:"netAttachDefNamespace,omitempty"`
+}
+
+func newLegacyCheckpointV1(preparedClaimsByPodUID PreparedClaimsByPodUID) *legacyCheckpointV1 {
+	v1 := &legacyCheckpointV1{
+		PreparedClaimsByPodUID: make(map[k8stypes.UID]map[k8stypes.UID][]*legacyPreparedDevice, len(preparedClaimsByPodUID)),
+	}
+	for podUID, claims := range preparedClaimsByPodUID {
+		v1.PreparedClaimsByPodUID[podUID] = make(map[k8stypes.UID][]*legacyPreparedDevice, len(claims))
+		for claimUID, devices := range claims {
+			legacyDevices := make([]*legacyPreparedDevice, 0, len(devices))
+			for _, device := range devices {
+				legacyDevices = append(legacyDevices, newLegacyPreparedDevice(device))
+			}
+			v1.PreparedClaimsByPodUID[podUID][claimUID] = legacyDevices
+		}
+	}
+	return v1
+}
+
+func newLegacyPreparedDevice(device *PreparedDevice) *legacyPreparedDevice {
+	if device == nil {
+		return nil
+	}
+	legacy := &legacyPreparedDevice{
+		Device:              &device.Device,
+		ClaimNamespacedName: device.ClaimNamespacedName,
+		ContainerEdits:      device.ContainerEdits,
+		IfName:              device.IfName,
+		PciAddress:          device.PciAddress,
+		MultusDeviceID:      device.MultusDeviceID,
+		MultusResourceName:  device.MultusResourceName,
+		DeviceAttributes:    device.DeviceAttributes,
+		NetworkDeviceData:   device.NetworkDeviceData,
+		PodUID:              device.PodUID,
+		NetAttachDefConfig:  device.NetAttachDefConfig,
+		OriginalDriver:      device.OriginalDriver,
+	}
+	if config := device.Config; config != nil {
+		legacy.Config = &legacyVfConfig{
+			TypeMeta:              config.TypeMeta,
+			Driver:                config.Driver,
+			AddVhostMount:         config.AddVhostMount,
+			IfName:                config.IfName,
+			NetAttachDefName:      config.NetAttachDefName,
+			NetAttachDefNamespace: config.NetAttachDefNamespace,
+		}
+	}
+	return legacy
+}
@@ -2,6 +2,9 @@ package types
 
 import (
 	"path/filepath"
+	"time"
+
+	"k8s.io/client-go/tools/record"
 
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/flags"
@@ -17,17 +20,69 @@ type Flags struct {
 	KubeletRegistrarDirectoryPath string
 	KubeletPluginsDirectoryPath   string
 	HealthcheckPort               int
+	HealthcheckHTTPPort           int
 	DefaultInterfacePrefix        string
 	ConfigurationMode             string
 	EnableDeviceMetadata          bool
+	CNICacheDir                   string
+	CNICheckInterval              time.Duration
+	NetworkAttachMethod           string
+	CDIHookPath                   string
+	MetricsBindAddress            string
+	TracingEndpoint               string
+	TracingInsecure               bool
+	TracingSamplingRatio          float64
+	// PodUID enables rolling updates when set: the sockets of the instance
+	// are suffixed with it so a new instance starts next to the old one.
+	PodUID string
 }
 
 type Config struct {
 	Flags         *Flags
 	K8sClient     flags.ClientSets
 	CancelMainCtx func(error)
+	// EventRecorder emits the Warning events of the driver, nil when no
+	// events are emitted.
+	EventRecorder record.EventRecorder
 }
 
 func (c Config) DriverPluginPath() string {
 	return filepath.Join(c.Flags.KubeletPluginsDirectoryPath, consts.DriverName)
 }
+
+// RegistrarSocketPath returns the path of the socket the kubelet plugin
+// registers on, suffixed with the pod UID when rolling updates are enabled.
+func (c Config) RegistrarSocketPath() string {
+	return filepath.Join(c.Flags.KubeletRegistrarDirectoryPath, consts.DriverName+c.socketUIDSuffix()+"-reg.sock")
+}
+
+// DRASocketPath returns the path of the socket serving the DRA gRPC API,
+// suffixed with the pod UID when rolling updates are enabled.
+func (c Config) DRASocketPath() string {
+	return filepath.Join(c.DriverPluginPath(), "dra"+c.socketUIDSuffix()+".sock")
+}
+
+// UnsuffixedDRASocketPath returns the path of the socket serving the DRA
+// gRPC API of a driver instance without rolling updates.
+func (c Config) UnsuffixedDRASocketPath() string {
+	return filepath.Join(c.DriverPluginPath(), "dra.sock")
+}
+
+// socketUIDSuffix follows the socket naming of the kubeletplugin helper.
+func (c Config) socketUIDSuffix() string {
+	if c.Flags.PodUID == "" {
+		return ""
+	}
+	return "-" + c.Flags.PodUID
+}
+
+// CDIHookSocketPath returns the path of the socket serving the CDI network hook.
+func (c Config) CDIHookSocketPath() string {
+	return filepath.Join(c.DriverPluginPath(), consts.CDIHookSocketName)
+}
+
+// IntrospectionSocketPath returns the path of the socket serving the
+// introspection API.
+func (c Config) IntrospectionSocketPath() string {
+	return filepath.Join(c.DriverPluginPath(), consts.IntrospectionSocketName)
+}
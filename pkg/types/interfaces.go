@@ -17,3 +17,14 @@ type MetadataUpdater interface {
 		devices []kubeletplugin.Device,
 	) error
 }
+
+// DeviceHealthUpdater reports the health of a prepared device.
+type DeviceHealthUpdater interface {
+	UpdateDeviceHealth(ctx context.Context, poolName, deviceName string, healthy bool, message string)
+}
+
+// NetworkDetacher detaches the networks of prepared devices still attached to
+// a pod sandbox.
+type NetworkDetacher interface {
+	DetachNetworks(ctx context.Context, devices PreparedDevices) error
+}
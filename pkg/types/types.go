@@ -3,8 +3,12 @@ package types
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"time"
 
+	"go.opentelemetry.io/otel/trace"
 	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/dynamic-resource-allocation/kubeletplugin"
@@ -18,6 +22,21 @@ import (
 // AllocatableDevices is a map of device pci address to dra device objects
 type AllocatableDevices map[string]resourceapi.Device
 
+// DevicePolicyMatch records the SriovResourcePolicy selecting a device and
+// the DeviceAttributes object each policy attribute of the device comes from.
+// MergedPolicies are the policies in Merge overlap mode adding attributes.
+type DevicePolicyMatch struct {
+	Policy           string
+	AttributeSources map[resourceapi.QualifiedName]string
+	MergedPolicies   []string
+}
+
+// PublishStatus is the result of the last ResourceSlice publication.
+type PublishStatus struct {
+	Time time.Time
+	Err  error
+}
+
 // PreparedDevices is a slice of prepared devices
 type PreparedDevices []*PreparedDevice
 
@@ -32,10 +51,18 @@ type NetworkDataChanStruct struct {
 	NetworkDeviceData *resourceapi.NetworkDeviceData
 	CNIConfig         map[string]interface{}
 	CNIResult         map[string]interface{}
+	// SpanContext is the span attaching the network, the claim status
+	// update written later links to it.
+	SpanContext trace.SpanContext
 }
 type NetworkDataChanStructList []*NetworkDataChanStruct
 
-// AddDeviceIDToNetConf adds the deviceID (PCI address) to the netconf
+// sriovCNIPluginType is the plugin type of sriov-cni inside a conflist.
+const sriovCNIPluginType = "sriov"
+
+// AddDeviceIDToNetConf adds the deviceID (PCI address) to the netconf.
+// For a conflist the deviceID is only set on the sriov plugin entries, or on
+// the first plugin when the chain has no sriov entry.
 func AddDeviceIDToNetConf(originalConfig, deviceID string) (string, error) {
 	// Unmarshal the existing configuration into a raw map
 	var rawConfig map[string]interface{}
@@ -43,8 +70,33 @@ func AddDeviceIDToNetConf(originalConfig, deviceID string) (string, error) {
 		return "", fmt.Errorf("failed to unmarshal existing config: %w", err)
 	}
 
-	// Set the deviceID (PCI address)
-	rawConfig["deviceID"] = deviceID
+	if rawPlugins, isList := rawConfig["plugins"]; isList {
+		plugins, ok := rawPlugins.([]interface{})
+		if !ok || len(plugins) == 0 {
+			return "", fmt.Errorf("invalid plugins list in conflist")
+		}
+		injected := false
+		for _, rawPlugin := range plugins {
+			plugin, ok := rawPlugin.(map[string]interface{})
+			if !ok {
+				return "", fmt.Errorf("invalid plugin entry in conflist")
+			}
+			if plugin["type"] == sriovCNIPluginType {
+				plugin["deviceID"] = deviceID
+				injected = true
+			}
+		}
+		if !injected {
+			first, ok := plugins[0].(map[string]interface{})
+			if !ok {
+				return "", fmt.Errorf("invalid plugin entry in conflist")
+			}
+			first["deviceID"] = deviceID
+		}
+	} else {
+		// Set the deviceID (PCI address)
+		rawConfig["deviceID"] = deviceID
+	}
 
 	// Marshal the modified configuration back to a JSON string
 	modifiedConfig, err := json.Marshal(rawConfig)
@@ -72,8 +124,91 @@ type PreparedDevice struct {
 	DeviceAttributes    map[string]resourceapi.DeviceAttribute
 	NetworkDeviceData   *resourceapi.NetworkDeviceData
 	PodUID              string
+	PodName             string `json:",omitempty"`
 	NetAttachDefConfig  string
 	OriginalDriver      string // Store original driver for restoration during unprepare
+	// SandboxID and NetworkNamespace identify the pod sandbox the device is
+	// attached to, they are empty while no CNI ADD is in effect. They are
+	// omitted when empty so checkpoints written by older versions still verify.
+	SandboxID        string `json:",omitempty"`
+	NetworkNamespace string `json:",omitempty"`
+	// Phase is the stage the device reached on the node, PreparedTime when
+	// it was prepared and PhaseTime when it entered its phase.
+	Phase        DevicePhase  `json:",omitempty"`
+	PreparedTime *metav1.Time `json:",omitempty"`
+	PhaseTime    *metav1.Time `json:",omitempty"`
+}
+
+// DevicePhase is the stage of a prepared device on the node.
+type DevicePhase string
+
+const (
+	// DevicePhasePrepared devices are bound to their driver and have their
+	// CDI spec written.
+	DevicePhasePrepared DevicePhase = "Prepared"
+	// DevicePhaseAttached devices have their network attached to the pod
+	// sandbox.
+	DevicePhaseAttached DevicePhase = "Attached"
+	// DevicePhaseRecovered devices were rebuilt from the ResourceClaims after
+	// the checkpoints were lost, only their allocation is known.
+	DevicePhaseRecovered DevicePhase = "Recovered"
+)
+
+// DeepCopy returns a copy of the device its updates do not change. The
+// container edits and the device attributes are set when the device is
+// prepared and never modified, they are shared with the copy.
+func (p *PreparedDevice) DeepCopy() *PreparedDevice {
+	if p == nil {
+		return nil
+	}
+	return &PreparedDevice{
+		Device: drapbv1.Device{
+			RequestNames: append([]string(nil), p.Device.RequestNames...),
+			PoolName:     p.Device.PoolName,
+			DeviceName:   p.Device.DeviceName,
+			CdiDeviceIds: append([]string(nil), p.Device.CdiDeviceIds...),
+		},
+		ClaimNamespacedName: p.ClaimNamespacedName,
+		ContainerEdits:      p.ContainerEdits,
+		Config:              p.Config.DeepCopy(),
+		IfName:              p.IfName,
+		PciAddress:          p.PciAddress,
+		MultusDeviceID:      p.MultusDeviceID,
+		MultusResourceName:  p.MultusResourceName,
+		DeviceAttributes:    p.DeviceAttributes,
+		NetworkDeviceData:   p.NetworkDeviceData.DeepCopy(),
+		PodUID:              p.PodUID,
+		PodName:             p.PodName,
+		NetAttachDefConfig:  p.NetAttachDefConfig,
+		OriginalDriver:      p.OriginalDriver,
+		SandboxID:           p.SandboxID,
+		NetworkNamespace:    p.NetworkNamespace,
+		Phase:               p.Phase,
+		PreparedTime:        p.PreparedTime.DeepCopy(),
+		PhaseTime:           p.PhaseTime.DeepCopy(),
+	}
+}
+
+// DeepCopy returns copies of the devices, see PreparedDevice.DeepCopy.
+func (p PreparedDevices) DeepCopy() PreparedDevices {
+	if p == nil {
+		return nil
+	}
+	out := make(PreparedDevices, 0, len(p))
+	for _, device := range p {
+		out = append(out, device.DeepCopy())
+	}
+	return out
+}
+
+// SetPhase moves the device to phase, the phase time is kept when the phase
+// does not change.
+func (p *PreparedDevice) SetPhase(phase DevicePhase, now time.Time) {
+	if p.Phase == phase && p.PhaseTime != nil {
+		return
+	}
+	p.Phase = phase
+	p.PhaseTime = &metav1.Time{Time: now}
 }
 
 func (p *PreparedDevice) ToKubeletPluginDevice(networkData *resourceapi.NetworkDeviceData) kubeletplugin.Device {
@@ -104,40 +239,160 @@ func (p *PreparedDevice) MetadataAttributes() map[string]resourceapi.DeviceAttri
 	return p.DeviceAttributes
 }
 
+// Checkpoint is the checkpoint of the prepared claims. V2 checkpoints are
+// written along with V1 in the format of the versions before V2, so that a
+// node rolled back to one of them still loads its prepared claims: Checksum
+// only covers V1, as read by those versions, and V2Checksum covers V2.
+// Checkpoints without V2Checksum have a single Checksum over all versions.
 type Checkpoint struct {
-	Checksum checksum.Checksum `json:"checksum"`
-	V1       *CheckpointV1     `json:"v1,omitempty"`
+	Checksum   checksum.Checksum `json:"checksum"`
+	V1         *CheckpointV1     `json:"v1,omitempty"`
+	V2         *CheckpointV2     `json:"v2,omitempty"`
+	V2Checksum checksum.Checksum `json:"v2Checksum,omitempty"`
 }
 
 type CheckpointV1 struct {
 	PreparedClaimsByPodUID PreparedClaimsByPodUID `json:"preparedClaimsByPodUID,omitempty"`
 }
 
+// CheckpointV2 records the prepared claims by claim UID.
+type CheckpointV2 struct {
+	PreparedClaims map[k8stypes.UID]*PreparedClaim `json:"preparedClaims,omitempty"`
+}
+
+// PreparedClaim is a claim prepared on the node.
+type PreparedClaim struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	// Consumers are the UIDs of the pods the claim is prepared for.
+	Consumers []k8stypes.UID  `json:"consumers"`
+	Devices   PreparedDevices `json:"devices,omitempty"`
+}
+
 func NewCheckpoint() *Checkpoint {
 	pc := &Checkpoint{
 		Checksum: 0,
-		V1: &CheckpointV1{
-			PreparedClaimsByPodUID: make(PreparedClaimsByPodUID),
+		V2: &CheckpointV2{
+			PreparedClaims: make(map[k8stypes.UID]*PreparedClaim),
 		},
 	}
 	return pc
 }
 
+// NewCheckpointFromPods returns a V2 checkpoint of the claims prepared for
+// each pod. Devices of a claim prepared for several pods are taken from the
+// first pod by UID.
+func NewCheckpointFromPods(preparedClaimsByPodUID PreparedClaimsByPodUID) *Checkpoint {
+	cp := NewCheckpoint()
+	podUIDs := make([]k8stypes.UID, 0, len(preparedClaimsByPodUID))
+	for podUID := range preparedClaimsByPodUID {
+		podUIDs = append(podUIDs, podUID)
+	}
+	sort.Slice(podUIDs, func(i, j int) bool { return podUIDs[i] < podUIDs[j] })
+
+	for _, podUID := range podUIDs {
+		for claimUID, devices := range preparedClaimsByPodUID[podUID] {
+			claim, ok := cp.V2.PreparedClaims[claimUID]
+			if !ok {
+				claim = &PreparedClaim{Devices: devices}
+				for _, device := range devices {
+					if device != nil {
+						claim.Namespace = device.ClaimNamespacedName.Namespace
+						claim.Name = device.ClaimNamespacedName.Name
+						break
+					}
+				}
+				cp.V2.PreparedClaims[claimUID] = claim
+			}
+			claim.Consumers = append(claim.Consumers, podUID)
+		}
+	}
+	return cp
+}
+
+// PreparedClaimsByPodUID returns the claims of the checkpoint prepared for
+// each pod. V1 devices are moved to the phase matching their sandbox.
+func (cp *Checkpoint) PreparedClaimsByPodUID() PreparedClaimsByPodUID {
+	preparedClaimsByPodUID := make(PreparedClaimsByPodUID)
+	switch {
+	case cp.V2 != nil:
+		for claimUID, claim := range cp.V2.PreparedClaims {
+			if claim == nil {
+				continue
+			}
+			for _, podUID := range claim.Consumers {
+				if _, ok := preparedClaimsByPodUID[podUID]; !ok {
+					preparedClaimsByPodUID[podUID] = make(PreparedDevicesByClaimID)
+				}
+				preparedClaimsByPodUID[podUID][claimUID] = claim.Devices
+			}
+		}
+	case cp.V1 != nil:
+		for podUID, claims := range cp.V1.PreparedClaimsByPodUID {
+			preparedClaimsByPodUID[podUID] = make(PreparedDevicesByClaimID, len(claims))
+			for claimUID, devices := range claims {
+				for _, device := range devices {
+					if device == nil || device.Phase != "" {
+						continue
+					}
+					device.Phase = DevicePhasePrepared
+					if device.SandboxID != "" {
+						device.Phase = DevicePhaseAttached
+					}
+				}
+				preparedClaimsByPodUID[podUID][claimUID] = devices
+			}
+		}
+	}
+	return preparedClaimsByPodUID
+}
+
 func (cp *Checkpoint) MarshalCheckpoint() ([]byte, error) {
-	cp.Checksum = 0
-	out, err := json.Marshal(*cp)
+	if cp.V2 == nil {
+		cp.Checksum = 0
+		cp.V2Checksum = 0
+		out, err := json.Marshal(*cp)
+		if err != nil {
+			return nil, err
+		}
+		cp.Checksum = checksum.New(out)
+		return json.Marshal(*cp)
+	}
+
+	v1 := legacyCheckpoint{V1: newLegacyCheckpointV1(cp.PreparedClaimsByPodUID())}
+	out, err := json.Marshal(v1)
+	if err != nil {
+		return nil, err
+	}
+	v2, err := json.Marshal(cp.V2)
 	if err != nil {
 		return nil, err
 	}
 	cp.Checksum = checksum.New(out)
-	return json.Marshal(*cp)
+	cp.V2Checksum = checksum.New(v2)
+	return json.Marshal(legacyCheckpoint{
+		Checksum:   cp.Checksum,
+		V1:         v1.V1,
+		V2:         cp.V2,
+		V2Checksum: cp.V2Checksum,
+	})
 }
 
 func (cp *Checkpoint) UnmarshalCheckpoint(data []byte) error {
 	return json.Unmarshal(data, cp)
 }
 
+// VerifyChecksum verifies V2 when the checkpoint has its own checksum, the
+// V1 written along with it is only read by older versions.
 func (cp *Checkpoint) VerifyChecksum() error {
+	if cp.V2 != nil && cp.V2Checksum != 0 {
+		out, err := json.Marshal(cp.V2)
+		if err != nil {
+			return err
+		}
+		return cp.V2Checksum.Verify(out)
+	}
+
 	ck := cp.Checksum
 	cp.Checksum = 0
 	defer func() {
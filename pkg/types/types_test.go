@@ -7,6 +7,7 @@ import (
 	. "github.com/onsi/gomega"
 	resourceapi "k8s.io/api/resource/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
 	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
 
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
@@ -118,6 +119,52 @@ var _ = Describe("Types", func() {
 			Expect(err).NotTo(HaveOccurred())
 			Expect(config["deviceID"]).To(Equal(""))
 		})
+		It("should add deviceID only to the sriov plugin of a conflist", func() {
+			originalConfig := `{
+				"cniVersion": "1.0.0",
+				"name": "mynet",
+				"plugins": [
+					{"type": "sriov", "vlan": 100},
+					{"type": "tuning", "mtu": 9000},
+					{"type": "sbr"}
+				]
+			}`
+			deviceID := "0000:01:00.0"
+
+			result, err := draTypes.AddDeviceIDToNetConf(originalConfig, deviceID)
+			Expect(err).NotTo(HaveOccurred())
+
+			var config map[string]interface{}
+			err = json.Unmarshal([]byte(result), &config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config).NotTo(HaveKey("deviceID"))
+			plugins := config["plugins"].([]interface{})
+			Expect(plugins).To(HaveLen(3))
+			Expect(plugins[0].(map[string]interface{})["deviceID"]).To(Equal(deviceID))
+			Expect(plugins[1].(map[string]interface{})).NotTo(HaveKey("deviceID"))
+			Expect(plugins[2].(map[string]interface{})).NotTo(HaveKey("deviceID"))
+		})
+
+		It("should add deviceID to the first plugin of a conflist without sriov", func() {
+			originalConfig := `{"name": "mynet", "plugins": [{"type": "host-device"}, {"type": "tuning"}]}`
+			deviceID := "0000:01:00.0"
+
+			result, err := draTypes.AddDeviceIDToNetConf(originalConfig, deviceID)
+			Expect(err).NotTo(HaveOccurred())
+
+			var config map[string]interface{}
+			err = json.Unmarshal([]byte(result), &config)
+			Expect(err).NotTo(HaveOccurred())
+			plugins := config["plugins"].([]interface{})
+			Expect(plugins[0].(map[string]interface{})["deviceID"]).To(Equal(deviceID))
+			Expect(plugins[1].(map[string]interface{})).NotTo(HaveKey("deviceID"))
+		})
+
+		It("should return error for a conflist with an empty plugins list", func() {
+			_, err := draTypes.AddDeviceIDToNetConf(`{"name": "mynet", "plugins": []}`, "0000:01:00.0")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid plugins list"))
+		})
 	})
 
 	Context("Checkpoint operations", func() {
@@ -130,9 +177,10 @@ var _ = Describe("Types", func() {
 		It("should create new checkpoint with correct structure", func() {
 			Expect(checkpoint).NotTo(BeNil())
 			Expect(uint64(checkpoint.Checksum)).To(Equal(uint64(0)))
-			Expect(checkpoint.V1).NotTo(BeNil())
-			Expect(checkpoint.V1.PreparedClaimsByPodUID).NotTo(BeNil())
-			Expect(len(checkpoint.V1.PreparedClaimsByPodUID)).To(Equal(0))
+			Expect(checkpoint.V1).To(BeNil())
+			Expect(checkpoint.V2).NotTo(BeNil())
+			Expect(checkpoint.V2.PreparedClaims).NotTo(BeNil())
+			Expect(len(checkpoint.V2.PreparedClaims)).To(Equal(0))
 		})
 
 		It("should marshal and unmarshal checkpoint correctly", func() {
@@ -140,8 +188,7 @@ var _ = Describe("Types", func() {
 			podUID := types.UID("test-pod-uid")
 			claimUID := types.UID("test-claim-uid")
 
-			checkpoint.V1.PreparedClaimsByPodUID[podUID] = make(draTypes.PreparedDevicesByClaimID)
-			checkpoint.V1.PreparedClaimsByPodUID[podUID][claimUID] = draTypes.PreparedDevices{}
+			checkpoint.V2.PreparedClaims[claimUID] = &draTypes.PreparedClaim{Consumers: []types.UID{podUID}}
 
 			// Marshal
 			data, err := checkpoint.MarshalCheckpoint()
@@ -149,13 +196,14 @@ var _ = Describe("Types", func() {
 			Expect(len(data)).To(BeNumerically(">", 0))
 
 			// Unmarshal to new checkpoint
-			newCheckpoint := draTypes.NewCheckpoint()
+			newCheckpoint := &draTypes.Checkpoint{}
 			err = newCheckpoint.UnmarshalCheckpoint(data)
 			Expect(err).NotTo(HaveOccurred())
 
 			// Verify data is preserved
-			Expect(newCheckpoint.V1.PreparedClaimsByPodUID).To(HaveKey(podUID))
-			Expect(newCheckpoint.V1.PreparedClaimsByPodUID[podUID]).To(HaveKey(claimUID))
+			Expect(newCheckpoint.V2.PreparedClaims).To(HaveKey(claimUID))
+			Expect(newCheckpoint.PreparedClaimsByPodUID()).To(HaveKey(podUID))
+			Expect(newCheckpoint.PreparedClaimsByPodUID()[podUID]).To(HaveKey(claimUID))
 		})
 
 		It("should verify checksum correctly", func() {
@@ -163,8 +211,7 @@ var _ = Describe("Types", func() {
 			podUID := types.UID("test-pod-uid")
 			claimUID := types.UID("test-claim-uid")
 
-			checkpoint.V1.PreparedClaimsByPodUID[podUID] = make(draTypes.PreparedDevicesByClaimID)
-			checkpoint.V1.PreparedClaimsByPodUID[podUID][claimUID] = draTypes.PreparedDevices{}
+			checkpoint.V2.PreparedClaims[claimUID] = &draTypes.PreparedClaim{Consumers: []types.UID{podUID}}
 
 			// Marshal to calculate checksum
 			data, err := checkpoint.MarshalCheckpoint()
@@ -190,10 +237,10 @@ var _ = Describe("Types", func() {
 			Expect(err).NotTo(HaveOccurred())
 
 			// Corrupt the data by modifying it
-			if corruptCheckpoint.V1.PreparedClaimsByPodUID == nil {
-				corruptCheckpoint.V1.PreparedClaimsByPodUID = make(draTypes.PreparedClaimsByPodUID)
+			if corruptCheckpoint.V2.PreparedClaims == nil {
+				corruptCheckpoint.V2.PreparedClaims = make(map[types.UID]*draTypes.PreparedClaim)
 			}
-			corruptCheckpoint.V1.PreparedClaimsByPodUID[types.UID("corrupt-data")] = make(draTypes.PreparedDevicesByClaimID)
+			corruptCheckpoint.V2.PreparedClaims[types.UID("corrupt-data")] = &draTypes.PreparedClaim{}
 
 			// Verify should fail
 			err = corruptCheckpoint.VerifyChecksum()
@@ -212,7 +259,47 @@ var _ = Describe("Types", func() {
 			Expect(err).NotTo(HaveOccurred())
 
 			// Verify empty state is preserved
-			Expect(len(newCheckpoint.V1.PreparedClaimsByPodUID)).To(Equal(0))
+			Expect(newCheckpoint.PreparedClaimsByPodUID()).To(BeEmpty())
+		})
+
+		It("should verify checkpoints written before sandbox tracking", func() {
+			data := []byte(`{"checksum":1182516493,"v1":{"preparedClaimsByPodUID":{"uid-1":{"claim-1":[{"Device":{},"ClaimNamespacedName":{"Namespace":"","Name":"","UID":""},"ContainerEdits":null,"Config":null,"IfName":"vfnet0","PciAddress":"0000:00:00.1","MultusDeviceID":"","MultusResourceName":"","DeviceAttributes":null,"NetworkDeviceData":null,"PodUID":"uid-1","NetAttachDefConfig":"{\"type\":\"sriov\",\"name\":\"net1\"}","OriginalDriver":""}]}}}}`)
+
+			oldCheckpoint := &draTypes.Checkpoint{}
+			Expect(oldCheckpoint.UnmarshalCheckpoint(data)).To(Succeed())
+			Expect(oldCheckpoint.VerifyChecksum()).To(Succeed())
+		})
+
+		It("should migrate V1 checkpoints with the phase of their devices", func() {
+			v1 := &draTypes.Checkpoint{V1: &draTypes.CheckpointV1{PreparedClaimsByPodUID: draTypes.PreparedClaimsByPodUID{
+				"pod-1": {"claim-1": draTypes.PreparedDevices{{PciAddress: "0000:00:00.1"}, {PciAddress: "0000:00:00.2", SandboxID: "sandbox-1"}}},
+			}}}
+
+			preparedClaimsByPodUID := v1.PreparedClaimsByPodUID()
+			Expect(preparedClaimsByPodUID["pod-1"]["claim-1"]).To(HaveLen(2))
+			Expect(preparedClaimsByPodUID["pod-1"]["claim-1"][0].Phase).To(Equal(draTypes.DevicePhasePrepared))
+			Expect(preparedClaimsByPodUID["pod-1"]["claim-1"][1].Phase).To(Equal(draTypes.DevicePhaseAttached))
+		})
+
+		It("should key V2 checkpoints by claim with their consumers", func() {
+			devices := draTypes.PreparedDevices{{
+				ClaimNamespacedName: kubeletplugin.NamespacedObject{
+					NamespacedName: types.NamespacedName{Namespace: "default", Name: "claim"},
+					UID:            "claim-1",
+				},
+			}}
+			v2 := draTypes.NewCheckpointFromPods(draTypes.PreparedClaimsByPodUID{
+				"pod-2": {"claim-1": devices},
+				"pod-1": {"claim-1": devices},
+			})
+
+			Expect(v2.V1).To(BeNil())
+			Expect(v2.V2.PreparedClaims).To(HaveKey(types.UID("claim-1")))
+			claim := v2.V2.PreparedClaims["claim-1"]
+			Expect(claim.Namespace).To(Equal("default"))
+			Expect(claim.Name).To(Equal("claim"))
+			Expect(claim.Consumers).To(Equal([]types.UID{"pod-1", "pod-2"}))
+			Expect(v2.PreparedClaimsByPodUID()).To(HaveLen(2))
 		})
 
 		It("should handle invalid JSON in unmarshal", func() {
@@ -256,6 +343,22 @@ var _ = Describe("Types", func() {
 		})
 	})
 
+	Context("Config socket paths", func() {
+		It("should suffix the kubelet plugin sockets with the pod UID for rolling updates", func() {
+			config := draTypes.Config{Flags: &draTypes.Flags{
+				KubeletRegistrarDirectoryPath: "/registry",
+				KubeletPluginsDirectoryPath:   "/plugins",
+			}}
+			Expect(config.RegistrarSocketPath()).To(Equal("/registry/" + consts.DriverName + "-reg.sock"))
+			Expect(config.DRASocketPath()).To(Equal("/plugins/" + consts.DriverName + "/dra.sock"))
+
+			config.Flags.PodUID = "uid-1"
+			Expect(config.RegistrarSocketPath()).To(Equal("/registry/" + consts.DriverName + "-uid-1-reg.sock"))
+			Expect(config.DRASocketPath()).To(Equal("/plugins/" + consts.DriverName + "/dra-uid-1.sock"))
+			Expect(config.UnsuffixedDRASocketPath()).To(Equal("/plugins/" + consts.DriverName + "/dra.sock"))
+		})
+	})
+
 	Context("PreparedDevice metadata conversion", func() {
 		It("includes discovered attributes and interface metadata", func() {
 			pciAddress := "0000:08:00.1"
@@ -0,0 +1,77 @@
+// Package unixsocket serves HTTP on the unix sockets of the driver plugin
+// directory, which the driver instances of a rolling update share.
+package unixsocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"k8s.io/klog/v2"
+)
+
+// Server serves an HTTP handler on a unix socket only accessible to its owner.
+type Server struct {
+	name       string
+	socketPath string
+	server     *http.Server
+
+	// socket is the socket file created by Start, the socket of the driver
+	// instance replacing this one during a rolling update is another file.
+	socket os.FileInfo
+}
+
+// NewServer creates a server for handler on socketPath, name is the name of
+// the server in the logs.
+func NewServer(name, socketPath string, handler http.Handler) *Server {
+	return &Server{
+		name:       name,
+		socketPath: socketPath,
+		server:     &http.Server{Handler: handler},
+	}
+}
+
+// Start listens on the socket and serves the requests until Stop is called.
+// A socket left by a previous driver instance is replaced.
+func (s *Server) Start(ctx context.Context) error {
+	logger := klog.FromContext(ctx)
+	if err := os.Remove(s.socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale socket %s: %w", s.socketPath, err)
+	}
+	listener, err := net.Listen("unix", s.socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.socketPath, err)
+	}
+	if err := os.Chmod(s.socketPath, 0o600); err != nil {
+		_ = listener.Close()
+		return fmt.Errorf("failed to restrict socket %s: %w", s.socketPath, err)
+	}
+	// The socket is removed by Stop, only while it is still the one of this
+	// server.
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	if s.socket, err = os.Stat(s.socketPath); err != nil {
+		_ = listener.Close()
+		return fmt.Errorf("failed to stat socket %s: %w", s.socketPath, err)
+	}
+	s.server.BaseContext = func(net.Listener) context.Context { return ctx }
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error(err, "Server stopped", "server", s.name)
+		}
+	}()
+	logger.Info("Server started", "server", s.name, "socket", s.socketPath)
+	return nil
+}
+
+// Stop closes the server and removes its socket unless another driver
+// instance replaced it.
+func (s *Server) Stop() {
+	_ = s.server.Close()
+	if info, err := os.Stat(s.socketPath); err == nil && s.socket != nil && os.SameFile(info, s.socket) {
+		_ = os.Remove(s.socketPath)
+	}
+}
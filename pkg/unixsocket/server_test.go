@@ -0,0 +1,88 @@
+package unixsocket_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/unixsocket"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		ctx        context.Context
+		socketPath string
+		client     *http.Client
+	)
+
+	// newServer starts a server answering with name.
+	newServer := func(name string) *unixsocket.Server {
+		server := unixsocket.NewServer(name, socketPath, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = io.WriteString(w, name)
+		}))
+		Expect(server.Start(ctx)).To(Succeed())
+		DeferCleanup(server.Stop)
+		return server
+	}
+
+	get := func() string {
+		resp, err := client.Get("http://server/")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return string(body)
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		// unix socket paths are limited in length, keep it short
+		dir, err := os.MkdirTemp("", "unixsocket")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, dir)
+		socketPath = filepath.Join(dir, "server.sock")
+
+		client = &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var dialer net.Dialer
+					return dialer.DialContext(ctx, "unix", socketPath)
+				},
+				DisableKeepAlives: true,
+			},
+		}
+	})
+
+	It("serves the handler on a socket only accessible to its owner", func() {
+		newServer("current")
+		Expect(get()).To(Equal("current"))
+
+		info, err := os.Stat(socketPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o600)))
+	})
+
+	It("replaces a stale socket", func() {
+		Expect(os.WriteFile(socketPath, nil, 0o600)).To(Succeed())
+		newServer("current")
+		Expect(get()).To(Equal("current"))
+	})
+
+	It("leaves the socket of the driver instance replacing it", func() {
+		previous := newServer("previous")
+		next := newServer("next")
+
+		previous.Stop()
+		Expect(socketPath).To(BeAnExistingFile())
+		Expect(get()).To(Equal("next"))
+
+		next.Stop()
+		Expect(socketPath).NotTo(BeAnExistingFile())
+	})
+})
@@ -0,0 +1,13 @@
+package unixsocket_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestUnixSocket(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Unix Socket Suite")
+}